@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	docker "github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newDiscoveryService creates the discovery service of the backend and starts its background watchers
+func newDiscoveryService(ctx context.Context, logger *zap.Logger, backend string) discovery.Service {
+	switch backend {
+	case "static":
+		staticService, err := discovery.NewStaticService(viper.GetViper())
+		if err != nil {
+			logger.Fatal("Failed to load static S3 instances", zap.Error(err))
+		}
+
+		if viper.GetBool("DISCOVERY_WATCH") {
+			staticService.WatchConfig()
+		}
+
+		return staticService
+	case "dns":
+		dnsService := discovery.NewDNSService(net.DefaultResolver, discovery.DNSOptions{
+			Hostname:  viper.GetString("DISCOVERY_DNS_HOSTNAME"),
+			UseSRV:    viper.GetBool("DISCOVERY_DNS_SRV"),
+			Port:      viper.GetString("DISCOVERY_DNS_PORT"),
+			AccessKey: viper.GetString("S3_ACCESS_KEY"),
+			SecretKey: viper.GetString("S3_SECRET_KEY"),
+			Debounce:  viper.GetInt("DISCOVERY_DNS_DEBOUNCE"),
+		})
+		go dnsService.Run(ctx, viper.GetDuration("DISCOVERY_DNS_INTERVAL"))
+
+		return dnsService
+	case "kubernetes":
+		kubernetesConfig, err := rest.InClusterConfig()
+		if err != nil {
+			logger.Fatal("Failed to load the in-cluster Kubernetes config", zap.Error(err))
+		}
+
+		clientset, err := kubernetes.NewForConfig(kubernetesConfig)
+		if err != nil {
+			logger.Fatal("Failed to create Kubernetes client", zap.Error(err))
+		}
+
+		kubernetesService := discovery.NewKubernetesService(clientset, discovery.KubernetesOptions{
+			Namespace:      viper.GetString("DISCOVERY_KUBERNETES_NAMESPACE"),
+			ServiceName:    viper.GetString("DISCOVERY_KUBERNETES_SERVICE"),
+			InstanceLabel:  viper.GetString("DISCOVERY_KUBERNETES_INSTANCE_LABEL"),
+			SecretName:     viper.GetString("DISCOVERY_KUBERNETES_SECRET"),
+			AccessKeyField: viper.GetString("DISCOVERY_KUBERNETES_ACCESS_KEY_FIELD"),
+			SecretKeyField: viper.GetString("DISCOVERY_KUBERNETES_SECRET_KEY_FIELD"),
+		})
+		go kubernetesService.Run(ctx)
+
+		return kubernetesService
+	default:
+		// Connect to the Docker daemon
+		dockerClient, err := newDockerClient(ctx, logger)
+		if err != nil {
+			logger.Fatal("Failed to create Docker client", zap.Error(err))
+		}
+
+		discoveryOptions := discovery.Options{
+			ContainerPrefix:       viper.GetString("DISCOVERY_CONTAINER_PREFIX"),
+			MemberLabel:           viper.GetString("DISCOVERY_MEMBER_LABEL"),
+			InstanceLabel:         viper.GetString("DISCOVERY_INSTANCE_LABEL"),
+			InstanceKeyLabel:      viper.GetString("DISCOVERY_INSTANCE_KEY_LABEL"),
+			DialPublishedPort:     viper.GetBool("DISCOVERY_DIAL_PUBLISHED_PORT"),
+			PublishedHost:         viper.GetString("DISCOVERY_PUBLISHED_HOST"),
+			StrictInspection:      viper.GetBool("DISCOVERY_STRICT_INSPECTION"),
+			StrictCredentials:     viper.GetBool("DISCOVERY_STRICT_CREDENTIALS"),
+			FilterUnhealthy:       viper.GetBool("DISCOVERY_FILTER_UNHEALTHY"),
+			MaxRestartCount:       viper.GetInt("DISCOVERY_MAX_RESTART_COUNT"),
+			MaxStaleness:          viper.GetDuration("DISCOVERY_MAX_STALENESS"),
+			TLSCACert:             viper.GetString("S3_TLS_CA_CERT"),
+			TLSInsecureSkipVerify: viper.GetBool("S3_TLS_INSECURE_SKIP_VERIFY"),
+		}
+
+		var serviceOptions []discovery.Option
+		if interval := viper.GetDuration("HEALTH_CHECK_INTERVAL"); interval > 0 {
+			serviceOptions = append(serviceOptions, discovery.WithHealthMonitor(interval))
+		}
+		if timeout := viper.GetDuration("DOCKER_TIMEOUT"); timeout > 0 {
+			serviceOptions = append(serviceOptions, discovery.WithDockerTimeout(timeout))
+		}
+		if network := viper.GetString("DOCKER_NETWORK"); network != "" {
+			serviceOptions = append(serviceOptions, discovery.WithDockerNetwork(network))
+		}
+		dockerService := discovery.NewServiceV1(dockerClient, discoveryOptions, serviceOptions...)
+		go dockerService.MonitorHealth(ctx)
+
+		// Keep the instance set current by subscribing to the Docker events
+		if viper.GetBool("DISCOVERY_WATCH") {
+			go dockerService.Run(ctx, viper.GetDuration("DISCOVERY_RECONCILE_INTERVAL"))
+		}
+
+		return dockerService
+	}
+}
+
+// newDockerClient creates the Docker client with the configured host, TLS, API version and timeout,
+// and fails if the daemon is unreachable. The configuration falls back to the DOCKER_* environment of the Docker CLI.
+func newDockerClient(ctx context.Context, logger *zap.Logger) (*docker.Client, error) {
+	host := viper.GetString("DOCKER_ENDPOINT")
+	certPath := viper.GetString("DOCKER_CERT_PATH")
+	tlsVerify := viper.GetBool("DOCKER_TLS_VERIFY")
+	if tlsVerify && certPath == "" {
+		return nil, errors.New("DOCKER_TLS_VERIFY requires DOCKER_CERT_PATH")
+	}
+
+	opts := []docker.Opt{docker.FromEnv, docker.WithAPIVersionNegotiation()}
+	if certPath != "" {
+		tlsConfig, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             filepath.Join(certPath, "ca.pem"),
+			CertFile:           filepath.Join(certPath, "cert.pem"),
+			KeyFile:            filepath.Join(certPath, "key.pem"),
+			InsecureSkipVerify: !tlsVerify,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load the Docker TLS certificates from %s", certPath)
+		}
+
+		opts = append(opts, docker.WithHTTPClient(&http.Client{
+			Transport:     &http.Transport{TLSClientConfig: tlsConfig},
+			CheckRedirect: docker.CheckRedirect,
+		}))
+
+		// The new transport has to be configured for the host
+		if host == "" {
+			host = os.Getenv(docker.EnvOverrideHost)
+		}
+		if host == "" {
+			host = docker.DefaultDockerHost
+		}
+	}
+
+	if host != "" {
+		opts = append(opts, docker.WithHost(host))
+	}
+
+	// A configured API version disables the negotiation
+	if apiVersion := viper.GetString("DOCKER_API_VERSION"); apiVersion != "" {
+		opts = append(opts, docker.WithVersion(apiVersion))
+	}
+
+	dockerClient, err := docker.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout bounds the single requests, not the client - it would cut off the event stream
+	pingCtx := ctx
+	if timeout := viper.GetDuration("DOCKER_TIMEOUT"); timeout > 0 {
+		var cancel context.CancelFunc
+		pingCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	_, err = dockerClient.Ping(pingCtx)
+	if err != nil {
+		_ = dockerClient.Close()
+		return nil, errors.Wrapf(err, "docker daemon at %s is unreachable", dockerClient.DaemonHost())
+	}
+
+	logger.Info("Connected to the Docker daemon", zap.String("host", dockerClient.DaemonHost()), zap.String("apiVersion", dockerClient.ClientVersion()))
+	return dockerClient, nil
+}
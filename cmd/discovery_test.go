@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// setConfig overrides the configuration value for the test
+func setConfig(t *testing.T, key string, value any) {
+	previous := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, previous) })
+}
+
+// newFakeDaemon serves the Docker daemon ping, after the delay
+func newFakeDaemon(t *testing.T, delay time.Duration) *httptest.Server {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		w.Header().Set("API-Version", "1.44")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(daemon.Close)
+	return daemon
+}
+
+func TestNewDockerClient(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	daemon := newFakeDaemon(t, 0)
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://"+daemon.Listener.Addr().String())
+
+	client, err := newDockerClient(context.Background(), zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "tcp://"+daemon.Listener.Addr().String(), client.DaemonHost())
+}
+
+func TestNewDockerClient_APIVersion(t *testing.T) {
+	daemon := newFakeDaemon(t, 0)
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://"+daemon.Listener.Addr().String())
+	setConfig(t, "DOCKER_API_VERSION", "1.41")
+
+	client, err := newDockerClient(context.Background(), zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+
+	// The configured version is not negotiated
+	assert.Equal(t, "1.41", client.ClientVersion())
+}
+
+func TestNewDockerClient_Timeout(t *testing.T) {
+	daemon := newFakeDaemon(t, time.Minute)
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://"+daemon.Listener.Addr().String())
+	setConfig(t, "DOCKER_TIMEOUT", 100*time.Millisecond)
+
+	start := time.Now()
+	_, err := newDockerClient(context.Background(), zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is unreachable")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestNewDockerClient_TimeoutSparesEventStream(t *testing.T) {
+	// The daemon sends an event only after the timeout, keeping the stream open
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.44")
+		if !strings.HasSuffix(r.URL.Path, "/events") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(events.Message{Type: events.ContainerEventType, Action: events.ActionStart})
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(daemon.Close)
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://"+daemon.Listener.Addr().String())
+	setConfig(t, "DOCKER_TIMEOUT", 100*time.Millisecond)
+
+	client, err := newDockerClient(context.Background(), zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages, errs := client.Events(ctx, types.EventsOptions{})
+	select {
+	case message := <-messages:
+		assert.Equal(t, events.ActionStart, message.Action)
+	case err := <-errs:
+		t.Fatalf("the event stream was cut off: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestNewDockerClient_Unreachable(t *testing.T) {
+	daemon := newFakeDaemon(t, 0)
+	address := daemon.Listener.Addr().String()
+	daemon.Close()
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://"+address)
+
+	_, err := newDockerClient(context.Background(), zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docker daemon at tcp://"+address+" is unreachable")
+}
+
+func TestNewDockerClient_InvalidHost(t *testing.T) {
+	setConfig(t, "DOCKER_ENDPOINT", "not a host")
+
+	_, err := newDockerClient(context.Background(), zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestNewDockerClient_TLSVerifyWithoutCertificates(t *testing.T) {
+	setConfig(t, "DOCKER_TLS_VERIFY", true)
+	setConfig(t, "DOCKER_CERT_PATH", "")
+
+	_, err := newDockerClient(context.Background(), zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DOCKER_TLS_VERIFY requires DOCKER_CERT_PATH")
+}
+
+// newTLSDaemon serves the Docker daemon ping over TLS
+func newTLSDaemon(t *testing.T) *httptest.Server {
+	daemon := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.44")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(daemon.Close)
+	return daemon
+}
+
+// writeCertPath writes the Docker client certificates to a directory, trusting the CA certificate
+func writeCertPath(t *testing.T, ca *x509.Certificate) string {
+	certPath := t.TempDir()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gateway"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	files := map[string]*pem.Block{
+		"ca.pem":   {Type: "CERTIFICATE", Bytes: ca.Raw},
+		"cert.pem": {Type: "CERTIFICATE", Bytes: cert},
+		"key.pem":  {Type: "EC PRIVATE KEY", Bytes: keyBytes},
+	}
+	for name, block := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(certPath, name), pem.EncodeToMemory(block), 0o600))
+	}
+
+	return certPath
+}
+
+func TestNewDockerClient_RemoteTLS(t *testing.T) {
+	daemon := newTLSDaemon(t)
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://"+daemon.Listener.Addr().String())
+	setConfig(t, "DOCKER_TLS_VERIFY", true)
+	setConfig(t, "DOCKER_CERT_PATH", writeCertPath(t, daemon.Certificate()))
+
+	client, err := newDockerClient(context.Background(), zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "tcp://"+daemon.Listener.Addr().String(), client.DaemonHost())
+}
+
+func TestNewDockerClient_RemoteTLSUntrusted(t *testing.T) {
+	daemon := newTLSDaemon(t)
+	address := daemon.Listener.Addr().String()
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://"+address)
+	setConfig(t, "DOCKER_TLS_VERIFY", true)
+
+	// A CA that didn't sign the certificate of the daemon
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caBytes, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caBytes)
+	require.NoError(t, err)
+	setConfig(t, "DOCKER_CERT_PATH", writeCertPath(t, ca))
+
+	_, err = newDockerClient(context.Background(), zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docker daemon at tcp://"+address+" is unreachable")
+}
+
+func TestNewDockerClient_MissingCertificates(t *testing.T) {
+	certPath := t.TempDir()
+	setConfig(t, "DOCKER_ENDPOINT", "tcp://127.0.0.1:2376")
+	setConfig(t, "DOCKER_TLS_VERIFY", true)
+	setConfig(t, "DOCKER_CERT_PATH", certPath)
+
+	_, err := newDockerClient(context.Background(), zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load the Docker TLS certificates from "+certPath)
+}
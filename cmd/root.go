@@ -1,54 +1,424 @@
 package cmd
 
 import (
-	docker "github.com/docker/docker/client"
+	"context"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
 	"github.com/spacelift-io/homework-object-storage/internal/api/http"
 	"github.com/spacelift-io/homework-object-storage/internal/discovery"
 	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
 	"github.com/spacelift-io/homework-object-storage/internal/pkg/observability"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
-	"os"
 )
 
 var cfgFile string
 
+// logLevel is the level of the global logger, changed at runtime on the admin routes
+var logLevel zap.AtomicLevel
+
 var rootCmd = &cobra.Command{
 	Use:   "s3-gateway",
 	Short: "S3 Gateway server",
 	Long:  ``,
 	Run: func(cmd *cobra.Command, args []string) {
-		// ctx, end := signal.NotifyContext(context.Background(), os.Interrupt)
-		// defer end()
+		// The prefork master handles the signals and forwards the shutdown, the children stop once the master is gone
+		var ctx context.Context
+		var end context.CancelFunc
+		if http.IsPreforkChild() {
+			ctx, end = http.PreforkChildContext(context.Background())
+		} else {
+			ctx, end = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		}
+		defer end()
 
 		logger := zap.L()
 		logger.Info("Starting S3 gateway server")
 
-		// Connect to the Docker daemon
-		dockerClient, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
+		// The prefork children can't share the pprof port
+		if viper.GetBool("DEBUG_PPROF") && !http.IsPreforkChild() {
+			startPprofServer(ctx, logger, viper.GetInt("PPROF_PORT"))
+		}
+
+		// The prefork children don't share their state, so the features relying on it would break silently
+		if viper.GetBool("PREFORK") {
+			if errs := preforkConflicts(); len(errs) > 0 {
+				for _, err := range errs {
+					logger.Error("Invalid configuration", zap.Error(err))
+				}
+
+				os.Exit(1)
+			}
+		}
+
+		discoveryService := newDiscovery(ctx, logger)
+		s3Options := newS3Options()
+
+		// Enforce the storage quotas, if configured
+		var quotaEnforcer gateway.QuotaEnforcer
+		if quotaFile := viper.GetString("QUOTA_FILE"); quotaFile != "" {
+			var err error
+			quotaEnforcer, err = gateway.NewInMemoryQuotaEnforcerFromFile(quotaFile)
+			if err != nil {
+				logger.Fatal("Failed to load quotas", zap.Error(err))
+			}
+		}
+
+		hasher, err := gateway.NewHasher(viper.GetString("SHARD_HASH"))
+		if err != nil {
+			logger.Fatal("Invalid shard hash function", zap.Error(err))
+		}
+
+		writeLocking, err := gateway.ParseWriteLocking(viper.GetString("WRITE_LOCKING"))
 		if err != nil {
-			logger.Fatal("Failed to create Docker client", zap.Error(err))
+			logger.Fatal("Invalid write locking mode", zap.Error(err))
 		}
-		defer dockerClient.Close()
 
-		discoveryService := discovery.NewServiceV1(dockerClient)
-		gatewayService := gateway.NewServiceV1(discoveryService)
+		var shardStrategy gateway.ShardStrategy = gateway.ModuloShardStrategy{Hasher: hasher}
+		if viper.GetString("SHARD_STRATEGY") == "weighted" {
+			shardStrategy = &gateway.WeightedHashShardStrategy{Hasher: hasher}
+		}
 
-		httpServer := http.NewServer(logger, gatewayService)
-		httpServer.Run(":3000")
+		gatewayService := gateway.NewServiceV1WithOptions(discoveryService, s3Options,
+			gateway.WithQuotaEnforcer(quotaEnforcer),
+			gateway.WithShardStrategy(shardStrategy),
+			gateway.WithReplicationFactor(viper.GetInt("REPLICATION_FACTOR")),
+			gateway.WithWorkerCount(viper.GetInt("LIST_WORKER_COUNT")),
+			gateway.WithMaxBulkDeleteCount(viper.GetInt("MAX_BULK_DELETE_COUNT")),
+			gateway.WithMaxListedObjects(viper.GetInt("MAX_LISTED_OBJECTS")),
+			gateway.WithMaxObjectSize(viper.GetInt64("MAX_OBJECT_SIZE")),
+			gateway.WithAuditLogger(gateway.NewZapAuditLogger(logger)),
+			gateway.WithAutoRebalance(autoRebalanceDelay()),
+			gateway.WithClientCreationRateWarn(viper.GetInt64("CLIENT_CREATION_RATE_WARN")),
+			gateway.WithWriteLocking(writeLocking),
+			gateway.WithClientPoolLimits(viper.GetInt("S3_CLIENT_POOL_MAX_SIZE"), viper.GetDuration("S3_CLIENT_IDLE_TIMEOUT")),
+			gateway.WithExportTargets(viper.GetStringSlice("EXPORT_ALLOWED_ENDPOINTS")),
+			gateway.WithCircuitBreaker(viper.GetInt("CIRCUIT_BREAKER_FAILURES"), viper.GetDuration("CIRCUIT_BREAKER_OPEN_DURATION")),
+		)
+		// Report all configuration errors at once, before accepting any requests
+		if errs := gatewayService.Validate(ctx); len(errs) > 0 {
+			for _, err := range errs {
+				logger.Error("Invalid configuration", zap.Error(err))
+			}
 
-		// <-ctx.Done()
+			os.Exit(1)
+		}
+
+		// With prefork, the jobs affecting all instances run once in the master, while the children serving the
+		// requests maintain their own client pool
+		prefork := viper.GetBool("PREFORK")
+		if !prefork || !http.IsPreforkChild() {
+			go gatewayService.WatchInstances(ctx)
+			go gatewayService.SweepExpiredObjects(ctx, viper.GetDuration("EXPIRY_SWEEP_INTERVAL"))
+			go gatewayService.ReconcileReplicas(ctx, viper.GetDuration("REPLICA_RECONCILE_INTERVAL"))
+		}
+		if !prefork || http.IsPreforkChild() {
+			go gatewayService.RecalculateQuotas(ctx)
+			go gatewayService.MonitorClientCreations(ctx)
+			go gatewayService.EvictIdleClients(ctx)
+		}
+
+		// The requests are served through the caching, metrics and tracing layers
+		cacheMaxBytes, cacheTTL := readCacheOptions()
+		objectService := gateway.TracedService(
+			gateway.InstrumentedService(
+				gateway.CachingServiceWithCache(gatewayService, gateway.NewReadCache(cacheMaxBytes), cacheTTL),
+				observability.Registry,
+			),
+			otel.Tracer("github.com/spacelift-io/homework-object-storage/gateway"),
+		)
+
+		// Serve the health of the instances, if the discovery backend monitors it
+		healthReporter, _ := discoveryService.(discovery.HealthReporter)
+		daemonReporter, _ := discoveryService.(discovery.DaemonReporter)
+		skipReporter, _ := discoveryService.(discovery.SkipReporter)
+		statusReporter, _ := discoveryService.(discovery.StatusReporter)
+
+		// Restart the process if the heartbeat goes stale, if configured
+		var heartbeat *observability.Heartbeat
+		if threshold := viper.GetDuration("LIVENESS_HEARTBEAT_THRESHOLD"); threshold > 0 {
+			heartbeat = observability.NewHeartbeat(threshold)
+			go heartbeat.Run(ctx, threshold/3)
+		}
+
+		serverConfig := http.Config{
+			AdminAPIKey:                viper.GetString("ADMIN_API_KEY"),
+			TrustedProxyCIDRs:          viper.GetStringSlice("TRUSTED_PROXY_CIDRS"),
+			AllowInstancePinning:       viper.GetBool("ALLOW_INSTANCE_PINNING"),
+			LogLevel:                   &logLevel,
+			KeyPrefix:                  viper.GetString("KEY_PREFIX"),
+			Heartbeat:                  heartbeat,
+			ObjectNotificationsEnabled: viper.GetBool("MINIO_NOTIFY_ENABLED"),
+			VersioningEnabled:          viper.GetBool("S3_VERSIONING_ENABLED"),
+			HealthReporter:             healthReporter,
+			DaemonReporter:             daemonReporter,
+			SkipReporter:               skipReporter,
+			StatusReporter:             statusReporter,
+			ClientCreationReporter:     gatewayService,
+			RejectEmptyUploads:         viper.GetBool("REJECT_EMPTY_UPLOADS"),
+			IdempotencyTTL:             viper.GetDuration("IDEMPOTENCY_TTL"),
+			MaxObjectSize:              viper.GetInt64("MAX_OBJECT_SIZE"),
+			ReadAfterWriteConsistency:  viper.GetBool("READ_AFTER_WRITE_CONSISTENCY"),
+			Prefork:                    viper.GetBool("PREFORK"),
+			UploadContentTypeAllowlist: viper.GetStringSlice("UPLOAD_CONTENT_TYPE_ALLOWLIST"),
+			UploadContentTypeDenylist:  viper.GetStringSlice("UPLOAD_CONTENT_TYPE_DENYLIST"),
+		}
+
+		// Serve the S3 clients under the bucket the objects are stored in, if enabled
+		if viper.GetBool("S3_COMPATIBLE_API") {
+			serverConfig.S3CompatibleBucket = s3Options.Bucket
+		}
+
+		// Authenticate the requests with a JWT, if configured
+		if jwksURL := viper.GetString("JWT_JWKS_URL"); jwksURL != "" {
+			serverConfig.AuthHandlers = append(serverConfig.AuthHandlers, middleware.JWTMiddleware(jwksURL,
+				middleware.WithIssuer(viper.GetString("JWT_ISSUER")),
+				middleware.WithAudience(viper.GetString("JWT_AUDIENCE")),
+			))
+		}
+
+		httpServer := http.NewServer(logger, objectService, serverConfig)
+
+		tlsConfig := http.TLSConfig{
+			CertFile:     viper.GetString("TLS_CERT_FILE"),
+			KeyFile:      viper.GetString("TLS_KEY_FILE"),
+			ClientCAFile: viper.GetString("TLS_CLIENT_CA_FILE"),
+		}
+
+		// Drain the requests on interrupt, then release the connections to the S3 instances
+		go func() {
+			<-ctx.Done()
+
+			err := httpServer.Shutdown(viper.GetDuration("SHUTDOWN_TIMEOUT"))
+			if err != nil {
+				logger.Error("Failed to shut down server", zap.Error(err))
+			}
+		}()
+
+		httpServer.Run(":3000", tlsConfig)
+
+		err = gatewayService.Close()
+		if err != nil {
+			logger.Error("Failed to close S3 clients", zap.Error(err))
+		}
+
+		// Release the connections of the discovery backend, e.g. to the Docker daemon
+		if closer, ok := discoveryService.(io.Closer); ok {
+			err = closer.Close()
+			if err != nil {
+				logger.Error("Failed to close the discovery service", zap.Error(err))
+			}
+		}
 	},
 	Version: "0.0.1",
 }
 
+// newDiscovery creates the configured discovery service. Multiple comma-separated backends are queried in order,
+// falling back to the next one.
+func newDiscovery(ctx context.Context, logger *zap.Logger) discovery.Service {
+	backendNames := strings.Split(viper.GetString("DISCOVERY"), ",")
+	if len(backendNames) == 1 {
+		return newDiscoveryService(ctx, logger, backendNames[0])
+	}
+
+	backends := []discovery.Backend{}
+	for _, name := range backendNames {
+		name = strings.TrimSpace(name)
+		backends = append(backends, discovery.Backend{Name: name, Service: newDiscoveryService(ctx, logger, name)})
+	}
+
+	return discovery.NewCompositeService(backends...)
+}
+
+// readCacheOptions returns the size and the TTL of the object read cache. OBJECT_CACHE_TTL alone enables the cache
+// of the default size.
+func readCacheOptions() (int64, time.Duration) {
+	maxBytes, ttl := viper.GetInt64("READ_CACHE_MAX_BYTES"), viper.GetDuration("READ_CACHE_TTL")
+	if objectCacheTTL := viper.GetDuration("OBJECT_CACHE_TTL"); objectCacheTTL > 0 && maxBytes == 0 {
+		return gateway.DefaultReadCacheMaxBytes, objectCacheTTL
+	}
+
+	return maxBytes, ttl
+}
+
+// preforkConflicts returns the errors of the features keeping their state in the process, which the prefork children
+// would each keep on their own
+func preforkConflicts() []error {
+	var errs []error
+	if viper.GetString("WRITE_LOCKING") != string(gateway.WriteLockingNone) {
+		errs = append(errs, errors.New("WRITE_LOCKING locks the writes within a prefork child only"))
+	}
+
+	if viper.GetString("QUOTA_FILE") != "" {
+		errs = append(errs, errors.New("QUOTA_FILE tracks the usage within a prefork child only"))
+	}
+
+	if viper.GetInt64("READ_CACHE_MAX_BYTES") > 0 || viper.GetDuration("OBJECT_CACHE_TTL") > 0 {
+		errs = append(errs, errors.New("READ_CACHE_MAX_BYTES and OBJECT_CACHE_TTL invalidate the cache within a prefork child only"))
+	}
+
+	return errs
+}
+
+// newS3Options creates the configured options of the Minio clients. The bucket name is validated with the rest of
+// the gateway configuration.
+func newS3Options() s3.Options {
+	return s3.Options{
+		Bucket:           viper.GetString("S3_BUCKET"),
+		Region:           viper.GetString("S3_REGION"),
+		AutoCreateBucket: viper.GetBool("AUTO_CREATE_BUCKET"),
+		ExpirationDays:   viper.GetInt("BUCKET_EXPIRATION_DAYS"),
+		Client: s3.ClientOptions{
+			DialTimeout:           viper.GetDuration("S3_CONNECT_TIMEOUT"),
+			ResponseHeaderTimeout: viper.GetDuration("S3_RESPONSE_HEADER_TIMEOUT"),
+			TLSHandshakeTimeout:   viper.GetDuration("S3_TLS_HANDSHAKE_TIMEOUT"),
+			MaxIdleConns:          viper.GetInt("S3_MAX_IDLE_CONNS"),
+			OperationTimeout:      viper.GetDuration("S3_OPERATION_TIMEOUT"),
+		},
+	}
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.homework-object-storage.yaml)")
 
 	rootCmd.Flags().BoolP("debug", "d", false, "Enable debug mode")
+	rootCmd.Flags().String("discovery", "docker", "S3 instance discovery backend: docker, static (instances listed in the config file), dns or kubernetes. A comma-separated list falls back to the next backend in order")
+	cobra.CheckErr(viper.BindPFlag("DISCOVERY", rootCmd.Flags().Lookup("discovery")))
+	rootCmd.Flags().String("jwt-jwks-url", "", "JWKS URL used to verify the JWTs, enables JWT authentication")
+	rootCmd.Flags().String("jwt-audience", "", "Audience required in the JWTs")
+	rootCmd.Flags().String("jwt-issuer", "", "Issuer required in the JWTs")
+	cobra.CheckErr(viper.BindPFlag("JWT_JWKS_URL", rootCmd.Flags().Lookup("jwt-jwks-url")))
+	cobra.CheckErr(viper.BindPFlag("JWT_AUDIENCE", rootCmd.Flags().Lookup("jwt-audience")))
+	cobra.CheckErr(viper.BindPFlag("JWT_ISSUER", rootCmd.Flags().Lookup("jwt-issuer")))
+	rootCmd.Flags().String("s3-bucket", s3.BucketName, "Bucket the objects are stored in, e.g. per environment or pre-provisioned")
+	cobra.CheckErr(viper.BindPFlag("S3_BUCKET", rootCmd.Flags().Lookup("s3-bucket")))
+	rootCmd.Flags().Bool("s3-compatible-api", false, "Serve the path-style object operations of the S3 REST API under /<bucket>")
+	cobra.CheckErr(viper.BindPFlag("S3_COMPATIBLE_API", rootCmd.Flags().Lookup("s3-compatible-api")))
+	rootCmd.Flags().Bool("prefork", false, "Serve the requests from a child process per CPU core. Can't be combined with the write locking, quotas or caches, ignores the Idempotency-Key header")
+	cobra.CheckErr(viper.BindPFlag("PREFORK", rootCmd.Flags().Lookup("prefork")))
+	rootCmd.Flags().Duration("s3-connect-timeout", time.Second*5, "Timeout of connecting to an S3 instance, so the unreachable instances fail fast")
+	cobra.CheckErr(viper.BindPFlag("S3_CONNECT_TIMEOUT", rootCmd.Flags().Lookup("s3-connect-timeout")))
+	rootCmd.Flags().Int("max-bulk-delete-count", 10000, "Maximum number of objects deleted by a single bulk delete")
+	cobra.CheckErr(viper.BindPFlag("MAX_BULK_DELETE_COUNT", rootCmd.Flags().Lookup("max-bulk-delete-count")))
+	rootCmd.Flags().Int("max-listed-objects", 10000, "Maximum number of objects returned by a single listing")
+	cobra.CheckErr(viper.BindPFlag("MAX_LISTED_OBJECTS", rootCmd.Flags().Lookup("max-listed-objects")))
+	rootCmd.Flags().Int64("client-creation-rate-warn", 10, "Warn when more S3 clients are created per second, zero disables the warning")
+	cobra.CheckErr(viper.BindPFlag("CLIENT_CREATION_RATE_WARN", rootCmd.Flags().Lookup("client-creation-rate-warn")))
+	rootCmd.Flags().String("write-locking", "", "Lock the objects while they are written: serialize the concurrent writes or reject them with 409 (serialize, exclusive)")
+	cobra.CheckErr(viper.BindPFlag("WRITE_LOCKING", rootCmd.Flags().Lookup("write-locking")))
+	rootCmd.Flags().Int("circuit-breaker-failures", 5, "Stop sending requests to an S3 instance after this many consecutive failures, zero disables the circuit breaker")
+	rootCmd.Flags().Duration("circuit-breaker-open-duration", time.Second*30, "How long the requests to a failing S3 instance are rejected before a probe request is sent")
+	cobra.CheckErr(viper.BindPFlag("CIRCUIT_BREAKER_FAILURES", rootCmd.Flags().Lookup("circuit-breaker-failures")))
+	cobra.CheckErr(viper.BindPFlag("CIRCUIT_BREAKER_OPEN_DURATION", rootCmd.Flags().Lookup("circuit-breaker-open-duration")))
+	rootCmd.Flags().StringSlice("export-allowed-endpoints", nil, "Endpoints the objects may be exported to, e.g. https://s3.amazonaws.com. Exports are disabled if empty")
+	cobra.CheckErr(viper.BindPFlag("EXPORT_ALLOWED_ENDPOINTS", rootCmd.Flags().Lookup("export-allowed-endpoints")))
+	rootCmd.Flags().StringSlice("trusted-proxy-cidrs", []string{"127.0.0.0/8", "10.0.0.0/8"}, "CIDRs of the proxies whose X-Forwarded-For and X-Real-IP headers are trusted")
+	cobra.CheckErr(viper.BindPFlag("TRUSTED_PROXY_CIDRS", rootCmd.Flags().Lookup("trusted-proxy-cidrs")))
+	rootCmd.Flags().Bool("allow-instance-pinning", false, "Allow the clients to bypass the sharding with the X-Instance-Pin header")
+	cobra.CheckErr(viper.BindPFlag("ALLOW_INSTANCE_PINNING", rootCmd.Flags().Lookup("allow-instance-pinning")))
+	rootCmd.Flags().Bool("minio-notify-enabled", false, "Serve the object watch endpoint, requires the bucket notifications to be enabled on the Minio instances")
+	cobra.CheckErr(viper.BindPFlag("MINIO_NOTIFY_ENABLED", rootCmd.Flags().Lookup("minio-notify-enabled")))
+	rootCmd.Flags().Bool("debug-pprof", false, "Serve the pprof profiles on a separate port. Exposes sensitive runtime data")
+	rootCmd.Flags().Int("pprof-port", 6060, "Port of the pprof server")
+	cobra.CheckErr(viper.BindPFlag("DEBUG_PPROF", rootCmd.Flags().Lookup("debug-pprof")))
+	cobra.CheckErr(viper.BindPFlag("PPROF_PORT", rootCmd.Flags().Lookup("pprof-port")))
+	rootCmd.Flags().Bool("versioning-enabled", false, "Serve the object versions, requires the versioning to be enabled on the bucket")
+	cobra.CheckErr(viper.BindPFlag("S3_VERSIONING_ENABLED", rootCmd.Flags().Lookup("versioning-enabled")))
+	rootCmd.Flags().String("docker-host", "", "Docker daemon address, e.g. tcp://docker.example.com:2376 or unix:///run/user/1000/docker.sock (default from DOCKER_HOST)")
+	rootCmd.Flags().Bool("docker-tls-verify", false, "Verify the certificate of the Docker daemon, requires --docker-cert-path")
+	rootCmd.Flags().String("docker-cert-path", "", "Directory with the ca.pem, cert.pem and key.pem used to connect to the Docker daemon")
+	rootCmd.Flags().String("docker-api-version", "", "Docker API version, negotiated with the daemon if empty")
+	cobra.CheckErr(viper.BindPFlag("DOCKER_ENDPOINT", rootCmd.Flags().Lookup("docker-host")))
+	cobra.CheckErr(viper.BindPFlag("DOCKER_TLS_VERIFY", rootCmd.Flags().Lookup("docker-tls-verify")))
+	cobra.CheckErr(viper.BindPFlag("DOCKER_CERT_PATH", rootCmd.Flags().Lookup("docker-cert-path")))
+	cobra.CheckErr(viper.BindPFlag("DOCKER_API_VERSION", rootCmd.Flags().Lookup("docker-api-version")))
+	rootCmd.Flags().String("docker-network", "", "Discover only the S3 instances attached to this Docker network, dialing their address on it")
+	cobra.CheckErr(viper.BindPFlag("DOCKER_NETWORK", rootCmd.Flags().Lookup("docker-network")))
+
+	viper.SetDefault("SHUTDOWN_TIMEOUT", time.Second*30)
+	viper.SetDefault("LIVENESS_HEARTBEAT_THRESHOLD", time.Duration(0))
+	viper.SetDefault("MAX_OBJECT_SIZE", 0)
+	viper.SetDefault("SHARD_HASH", "fnv")
+	viper.SetDefault("KEY_PREFIX", "")
+	viper.SetDefault("OBJECT_CACHE_TTL", time.Duration(0))
+	viper.SetDefault("READ_CACHE_MAX_BYTES", 0)
+	viper.SetDefault("READ_CACHE_TTL", time.Minute)
+	viper.SetDefault("REJECT_EMPTY_UPLOADS", false)
+	viper.SetDefault("IDEMPOTENCY_TTL", time.Minute*10)
+	viper.SetDefault("READ_AFTER_WRITE_CONSISTENCY", false)
+	viper.SetDefault("S3_CLIENT_POOL_MAX_SIZE", 0)
+	viper.SetDefault("S3_CLIENT_IDLE_TIMEOUT", time.Minute*5)
+	viper.SetDefault("AUTO_REBALANCE", false)
+	viper.SetDefault("AUTO_REBALANCE_DELAY", time.Minute)
+	viper.SetDefault("DISCOVERY_WATCH", false)
+	viper.SetDefault("DISCOVERY_RECONCILE_INTERVAL", time.Minute)
+	viper.SetDefault("DISCOVERY_CONTAINER_PREFIX", "amazin-object-storage-node-")
+	viper.SetDefault("DISCOVERY_MEMBER_LABEL", "object-storage.member=true")
+	viper.SetDefault("DISCOVERY_INSTANCE_LABEL", "object-storage.instance")
+	viper.SetDefault("DISCOVERY_INSTANCE_KEY_LABEL", "object-storage.instance-id")
+	viper.SetDefault("DISCOVERY_DIAL_PUBLISHED_PORT", false)
+	viper.SetDefault("DISCOVERY_PUBLISHED_HOST", "localhost")
+	viper.SetDefault("DISCOVERY_STRICT_INSPECTION", false)
+	viper.SetDefault("DISCOVERY_STRICT_CREDENTIALS", false)
+	viper.SetDefault("DISCOVERY_FILTER_UNHEALTHY", false)
+	viper.SetDefault("DISCOVERY_MAX_RESTART_COUNT", 5)
+	viper.SetDefault("DISCOVERY_MAX_STALENESS", time.Minute)
+	viper.SetDefault("HEALTH_CHECK_INTERVAL", time.Duration(0))
+	viper.SetDefault("DOCKER_ENDPOINT", "")
+	viper.SetDefault("DOCKER_TLS_VERIFY", false)
+	viper.SetDefault("DOCKER_CERT_PATH", "")
+	viper.SetDefault("DOCKER_API_VERSION", "")
+	viper.SetDefault("DOCKER_NETWORK", "")
+	viper.SetDefault("DOCKER_TIMEOUT", time.Second*10)
+	viper.SetDefault("DISCOVERY_DNS_HOSTNAME", "")
+	viper.SetDefault("DISCOVERY_DNS_SRV", false)
+	viper.SetDefault("DISCOVERY_DNS_PORT", "9000")
+	viper.SetDefault("DISCOVERY_DNS_INTERVAL", time.Second*30)
+	viper.SetDefault("DISCOVERY_DNS_DEBOUNCE", 2)
+	viper.SetDefault("DISCOVERY_KUBERNETES_NAMESPACE", "default")
+	viper.SetDefault("DISCOVERY_KUBERNETES_SERVICE", "minio")
+	viper.SetDefault("DISCOVERY_KUBERNETES_INSTANCE_LABEL", "")
+	viper.SetDefault("DISCOVERY_KUBERNETES_SECRET", "minio-credentials")
+	viper.SetDefault("DISCOVERY_KUBERNETES_ACCESS_KEY_FIELD", "accessKey")
+	viper.SetDefault("DISCOVERY_KUBERNETES_SECRET_KEY_FIELD", "secretKey")
+	viper.SetDefault("S3_ACCESS_KEY", "")
+	viper.SetDefault("S3_SECRET_KEY", "")
+	viper.SetDefault("S3_BUCKET", s3.BucketName)
+	viper.SetDefault("S3_REGION", "")
+	viper.SetDefault("S3_TLS_CA_CERT", "")
+	viper.SetDefault("S3_TLS_INSECURE_SKIP_VERIFY", false)
+	viper.SetDefault("AUTO_CREATE_BUCKET", true)
+	viper.SetDefault("BUCKET_EXPIRATION_DAYS", 0)
+	viper.SetDefault("S3_RESPONSE_HEADER_TIMEOUT", time.Second*15)
+	viper.SetDefault("S3_TLS_HANDSHAKE_TIMEOUT", time.Second*10)
+	viper.SetDefault("S3_MAX_IDLE_CONNS", 256)
+	viper.SetDefault("S3_OPERATION_TIMEOUT", time.Second*10)
+	viper.SetDefault("EXPIRY_SWEEP_INTERVAL", time.Minute)
+	viper.SetDefault("SHARD_STRATEGY", "modulo")
+	viper.SetDefault("REPLICATION_FACTOR", 1)
+	viper.SetDefault("REPLICA_RECONCILE_INTERVAL", time.Minute*10)
+	viper.SetDefault("LIST_WORKER_COUNT", 0)
+	viper.SetDefault("TLS_CERT_FILE", "")
+	viper.SetDefault("TLS_KEY_FILE", "")
+	viper.SetDefault("TLS_CLIENT_CA_FILE", "")
+	viper.SetDefault("QUOTA_FILE", "")
+	viper.SetDefault("ADMIN_API_KEY", "")
+	viper.SetDefault("UPLOAD_CONTENT_TYPE_ALLOWLIST", []string{})
+	viper.SetDefault("UPLOAD_CONTENT_TYPE_DENYLIST", []string{})
 }
 
 func Execute() {
@@ -81,5 +451,41 @@ func initConfig() {
 		zap.L().Debug("Using config file", zap.String("file", viper.ConfigFileUsed()))
 	}
 
-	zap.ReplaceGlobals(observability.NewLogger("debug"))
+	var logger *zap.Logger
+	logger, logLevel = observability.NewLogger("debug")
+	zap.ReplaceGlobals(logger)
+}
+
+// startPprofServer serves the pprof profiles until the context is cancelled
+func startPprofServer(ctx context.Context, logger *zap.Logger, port int) {
+	pprofServer := observability.NewPprofServer(fmt.Sprintf(":%d", port))
+	logger.Warn("pprof is enabled, the profiles expose sensitive runtime data", zap.String("address", pprofServer.Addr))
+
+	go func() {
+		err := pprofServer.ListenAndServe()
+		if err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			logger.Error("pprof server failed", zap.Error(err))
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("SHUTDOWN_TIMEOUT"))
+		defer cancel()
+
+		err := pprofServer.Shutdown(shutdownCtx)
+		if err != nil {
+			logger.Error("Failed to shut down pprof server", zap.Error(err))
+		}
+	}()
+}
+
+// autoRebalanceDelay returns the delay of the rebalance after the instance membership changes, zero if disabled
+func autoRebalanceDelay() time.Duration {
+	if !viper.GetBool("AUTO_REBALANCE") {
+		return 0
+	}
+
+	return viper.GetDuration("AUTO_REBALANCE_DELAY")
 }
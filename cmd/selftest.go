@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Write, read and delete a canary object on each S3 instance",
+	Long: `Discovers the S3 instances and writes a small canary object to each of them, bypassing the sharding.
+The object is read back, verified and deleted. Exits with a non-zero code if any instance fails.
+Catches the credential and network misconfiguration before serving traffic.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, end := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer end()
+
+		logger := zap.L()
+		gatewayService := gateway.NewServiceV1WithOptions(newDiscovery(ctx, logger), newS3Options())
+		defer gatewayService.Close()
+
+		if !runSelfTest(ctx, os.Stdout, gatewayService) {
+			os.Exit(1)
+		}
+	},
+}
+
+// runSelfTest validates the configuration and self-tests the instances of the service, printing pass/fail per instance.
+// Returns false if the configuration is invalid or any instance fails.
+func runSelfTest(ctx context.Context, w io.Writer, gatewayService *gateway.ServiceV1) bool {
+	if errs := gatewayService.Validate(ctx); len(errs) > 0 {
+		for _, err := range errs {
+			_, _ = fmt.Fprintf(w, "FAIL configuration: %v\n", err)
+		}
+		return false
+	}
+
+	results, err := gatewayService.SelfTest(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "FAIL discovery: %v\n", err)
+		return false
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			_, _ = fmt.Fprintf(w, "FAIL instance %d (%s:%s): %v\n", result.Instance.InstanceNum, result.Instance.Hostname, result.Instance.Port, result.Err)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "PASS instance %d (%s:%s)\n", result.Instance.InstanceNum, result.Instance.Hostname, result.Instance.Port)
+	}
+
+	if failed > 0 {
+		_, _ = fmt.Fprintf(w, "%d of %d instances failed\n", failed, len(results))
+		return false
+	}
+
+	return true
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSelfTestService creates a gateway storing the objects on n in-memory instances numbered from 1
+func newSelfTestService(t *testing.T, n int, options s3.Options) (*gateway.ServiceV1, map[int]*s3test.Client) {
+	t.Helper()
+
+	clients := map[int]*s3test.Client{}
+	for i := 1; i <= n; i++ {
+		clients[i] = s3test.NewClient()
+	}
+
+	factory := gateway.WithClientFactory(func(instance discovery.S3Instance) (s3.Client, error) {
+		return clients[instance.InstanceNum], nil
+	})
+	service := gateway.NewServiceV1WithOptions(discoverytest.NewService(discoverytest.Instances(n)...), options, factory)
+	t.Cleanup(func() { _ = service.Close() })
+	return service, clients
+}
+
+func TestRunSelfTest(t *testing.T) {
+	service, _ := newSelfTestService(t, 2, s3.Options{})
+
+	output := &bytes.Buffer{}
+	assert.True(t, runSelfTest(context.Background(), output, service))
+	assert.Equal(t, "PASS instance 1 (minio-1:9000)\nPASS instance 2 (minio-2:9000)\n", output.String())
+}
+
+func TestRunSelfTest_InstanceFails(t *testing.T) {
+	service, clients := newSelfTestService(t, 2, s3.Options{})
+	clients[2].FailMethod("AddOrUpdateObject", s3.ErrInvalidCredentials)
+
+	output := &bytes.Buffer{}
+	assert.False(t, runSelfTest(context.Background(), output, service))
+	assert.Contains(t, output.String(), "PASS instance 1 (minio-1:9000)\n")
+	assert.Contains(t, output.String(), "FAIL instance 2 (minio-2:9000): failed to write the canary object")
+	assert.Contains(t, output.String(), "1 of 2 instances failed\n")
+}
+
+func TestRunSelfTest_InvalidConfiguration(t *testing.T) {
+	service, clients := newSelfTestService(t, 1, s3.Options{Bucket: "Invalid_Bucket"})
+
+	output := &bytes.Buffer{}
+	assert.False(t, runSelfTest(context.Background(), output, service))
+	assert.Contains(t, output.String(), "FAIL configuration: ")
+
+	// No instance is tested with an invalid configuration
+	assert.Zero(t, clients[1].CallCount("AddOrUpdateObject"))
+}
+
+func TestRunSelfTest_NoInstances(t *testing.T) {
+	service, _ := newSelfTestService(t, 0, s3.Options{})
+
+	output := &bytes.Buffer{}
+	assert.False(t, runSelfTest(context.Background(), output, service))
+	assert.Contains(t, output.String(), "FAIL discovery: ")
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// clientCreationRate reports a fixed client creation rate
+type clientCreationRate int64
+
+func (r clientCreationRate) ClientCreationRate() int64 {
+	return int64(r)
+}
+
+func TestClientCreationRateHandler(t *testing.T) {
+	server, _ := newTestServer(t, 1, Config{ClientCreationReporter: clientCreationRate(15)})
+
+	res, _ := do(t, server, newRequest(http.MethodGet, "/admin/metrics/client-creation-rate", nil))
+	assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode)
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/admin/metrics/client-creation-rate", nil, middleware.APIKeyHeader, testAdminAPIKey))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"perSecond":15}`, body)
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAuditLogger records the audit events
+type recordingAuditLogger struct {
+	mu     sync.Mutex
+	events []gateway.AuditEvent
+}
+
+func (r *recordingAuditLogger) Audit(_ context.Context, event gateway.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestHandlers_AuditClientIP(t *testing.T) {
+	auditLogger := &recordingAuditLogger{}
+	// The test requests come from 0.0.0.0, trusted as a proxy
+	server, _ := newTestServer(t, 1, Config{TrustedProxyCIDRs: []string{"0.0.0.0/32"}}, gateway.WithAuditLogger(auditLogger))
+
+	res, _ := do(t, server, newUploadRequest(t, "object", []byte("data"), fiber.HeaderXForwardedFor, "203.0.113.7"))
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+	res, _ = do(t, server, newRequest(http.MethodDelete, "/object/object", nil, fiber.HeaderXForwardedFor, "198.51.100.4"))
+	require.Equal(t, fiber.StatusNoContent, res.StatusCode)
+
+	require.Len(t, auditLogger.events, 2)
+	assert.Equal(t, "put", auditLogger.events[0].Operation)
+	assert.Equal(t, "203.0.113.7", auditLogger.events[0].ClientIP)
+	assert.Equal(t, "delete", auditLogger.events[1].Operation)
+	assert.Equal(t, "198.51.100.4", auditLogger.events[1].ClientIP)
+}
+
+func TestHandlers_AuditSubject(t *testing.T) {
+	auditLogger := &recordingAuditLogger{}
+	// Authenticates the requests as the JWT middleware does
+	authenticate := func(c *fiber.Ctx) error {
+		c.Locals(middleware.SubjectKey, "client")
+		return c.Next()
+	}
+	server, _ := newTestServer(t, 1, Config{AuthHandlers: []fiber.Handler{authenticate}}, gateway.WithAuditLogger(auditLogger))
+
+	res, _ := do(t, server, newUploadRequest(t, "object", []byte("data")))
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+
+	require.Len(t, auditLogger.events, 1)
+	assert.Equal(t, "client", auditLogger.events[0].Subject)
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteByPrefixHandler_RequiresAPIKey(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	clients[1].Put("a", []byte("a"))
+	clients[2].Put("b", []byte("b"))
+
+	res, _ := do(t, server, newRequest(http.MethodDelete, "/objects?all=true", nil))
+	assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode)
+
+	res, _ = do(t, server, newRequest(http.MethodDelete, "/objects?all=true", nil, middleware.APIKeyHeader, "wrong"))
+	assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode)
+
+	assert.Len(t, clients[1].Keys(), 1)
+	assert.Len(t, clients[2].Keys(), 1)
+}
+
+func TestDeleteByPrefixHandler(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	clients[1].Put("photos/a", []byte("a"))
+	clients[1].Put("docs/a", []byte("a"))
+	clients[2].Put("photos/b", []byte("b"))
+
+	res, body := do(t, server, newRequest(http.MethodDelete, "/objects?prefix=photos/", nil, middleware.APIKeyHeader, testAdminAPIKey))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"prefix":"photos/","deleted":2,"dryRun":false}`, body)
+
+	assert.Equal(t, []string{"docs/a"}, clients[1].Keys())
+	assert.Empty(t, clients[2].Keys())
+}
+
+func TestDeleteByPrefixHandler_NamespaceScoped(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	clients[1].Put("team-a/x", []byte("x"))
+	clients[2].Put("team-b/y", []byte("y"))
+
+	res, body := do(t, server, newRequest(http.MethodDelete, "/objects?all=true", nil, middleware.APIKeyHeader, testAdminAPIKey, namespaceHeader, "team-a"))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"prefix":"","deleted":1,"dryRun":false}`, body)
+
+	assert.Empty(t, clients[1].Keys())
+	assert.Equal(t, []string{"team-b/y"}, clients[2].Keys())
+}
+
+func TestDeleteByPrefixHandler_EmptyPrefixRequiresConfirmation(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{})
+	clients[1].Put("a", []byte("a"))
+
+	res, _ := do(t, server, newRequest(http.MethodDelete, "/objects", nil, middleware.APIKeyHeader, testAdminAPIKey))
+	assert.Equal(t, fiber.StatusBadRequest, res.StatusCode)
+	assert.Len(t, clients[1].Keys(), 1)
+}
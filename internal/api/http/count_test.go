@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountHandler(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	clients[1].Put("a", []byte("a"))
+	clients[1].Put("b", []byte("b"))
+	clients[2].Put("c", []byte("c"))
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/objects/count", nil))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"total":3}`, body)
+
+	res, body = do(t, server, newRequest(http.MethodGet, "/objects/count?perInstance=true", nil))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"total":3,"instances":{"1":2,"2":1}}`, body)
+}
+
+func TestCountHandler_NamespaceScoped(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	clients[1].Put("team-a/x", []byte("x"))
+	clients[2].Put("team-a/y", []byte("y"))
+	clients[2].Put("team-b/z", []byte("z"))
+
+	// The per instance counts are not available in a namespace
+	res, body := do(t, server, newRequest(http.MethodGet, "/objects/count?perInstance=true", nil, namespaceHeader, "team-a"))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"total":2}`, body)
+}
+
+func TestCountHandler_InstanceFailure(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	clients[2].Fail(errors.New("connection refused"))
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/objects/count", nil))
+	assert.Equal(t, fiber.StatusInternalServerError, res.StatusCode)
+	assert.Contains(t, body, "Failed to count objects")
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers_MapRejectedCredentials(t *testing.T) {
+	requests := map[string]func(t *testing.T) *http.Request{
+		"upload": func(t *testing.T) *http.Request {
+			return newUploadRequest(t, "object", []byte("data"))
+		},
+		"download": func(*testing.T) *http.Request {
+			return newRequest(http.MethodGet, "/object/object", nil)
+		},
+		"head": func(*testing.T) *http.Request {
+			return newRequest(http.MethodHead, "/object/object", nil)
+		},
+		"delete": func(*testing.T) *http.Request {
+			return newRequest(http.MethodDelete, "/object/object", nil)
+		},
+		"versions": func(*testing.T) *http.Request {
+			return newRequest(http.MethodGet, "/object/object/versions", nil)
+		},
+		"rename": func(*testing.T) *http.Request {
+			return newRenameRequest("object", `{"new_id":"renamed"}`)
+		},
+		"list": func(*testing.T) *http.Request {
+			return newRequest(http.MethodGet, "/objects", nil)
+		},
+		"count": func(*testing.T) *http.Request {
+			return newRequest(http.MethodGet, "/objects/count", nil)
+		},
+		"delete by prefix": func(*testing.T) *http.Request {
+			return newRequest(http.MethodDelete, "/objects?prefix=object", nil, middleware.APIKeyHeader, testAdminAPIKey)
+		},
+		"bulk delete": func(*testing.T) *http.Request {
+			return newRequest(http.MethodPost, "/admin/bulk-delete-by-prefix", strings.NewReader(`{"prefix":"object"}`),
+				middleware.APIKeyHeader, testAdminAPIKey, fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		},
+	}
+
+	for _, rejection := range []error{s3.ErrInvalidCredentials, s3.ErrAccessDenied} {
+		for name, request := range requests {
+			t.Run(rejection.Error()+"/"+name, func(t *testing.T) {
+				server, clients := newTestServer(t, 1, Config{VersioningEnabled: true})
+				clients[1].Put("object", []byte("data"))
+				clients[1].Fail(errors.Wrap(rejection, "SignatureDoesNotMatch"))
+
+				res, body := do(t, server, request(t))
+				assert.Equal(t, fiber.StatusBadGateway, res.StatusCode)
+				if res.Request.Method != http.MethodHead {
+					assert.Contains(t, body, string(api.ErrorCodeInstanceCredentialsRejected))
+				}
+			})
+		}
+	}
+}
+
+func TestS3Compatible_MapsRejectedCredentials(t *testing.T) {
+	for _, rejection := range []error{s3.ErrInvalidCredentials, s3.ErrAccessDenied} {
+		t.Run(rejection.Error(), func(t *testing.T) {
+			server, clients := newTestServer(t, 1, Config{S3CompatibleBucket: testS3Bucket})
+			clients[1].Put("object", []byte("data"))
+			clients[1].Fail(rejection)
+
+			res, body := do(t, server, newRequest(http.MethodGet, "/"+testS3Bucket+"/object", nil))
+			assert.Equal(t, fiber.StatusBadGateway, res.StatusCode)
+			assert.Contains(t, body, "<Code>AccessDenied</Code>")
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largeObjectSize is the size of the downloaded object, well above the buffers of the server and the client
+const largeObjectSize = 64 << 20
+
+// downloadLargeObject downloads the object through a local port and returns its size
+func downloadLargeObject(t testing.TB, address string) int64 {
+	res, err := http.Get("http://" + address + "/object/large")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.EqualValues(t, largeObjectSize, res.ContentLength)
+
+	n, err := io.Copy(io.Discard, res.Body)
+	require.NoError(t, err)
+	return n
+}
+
+func TestServer_DownloadStreamsLargeObject(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{})
+	clients[1].Put("large", bytes.Repeat([]byte("a"), largeObjectSize))
+	address := listen(t, server)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	n := downloadLargeObject(t, address)
+	runtime.ReadMemStats(&after)
+
+	// The object is streamed in chunks, not buffered whole
+	assert.EqualValues(t, largeObjectSize, n)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(largeObjectSize/8))
+}
+
+func BenchmarkDownload(b *testing.B) {
+	server, clients := newTestServer(b, 1, Config{})
+	clients[1].Put("large", bytes.Repeat([]byte("a"), largeObjectSize))
+	address := listen(b, server)
+
+	b.ReportAllocs()
+	b.SetBytes(largeObjectSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		downloadLargeObject(b, address)
+	}
+}
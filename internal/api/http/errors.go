@@ -0,0 +1,59 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// errorResponse maps the error of an object operation to the status and the error response, the same way for all
+// routes. The unexpected errors are responded with the failure message. The handlers map their specific errors first.
+func errorResponse(err error, failure string) (int, api.ErrorResponse) {
+	switch {
+	case errors.Is(err, s3.ErrObjectNotFound):
+		return fiber.StatusNotFound, api.ErrorResponse{Code: api.ErrorCodeObjectNotFound, Message: "Object not found"}
+	case errors.Is(err, s3.ErrSizeMismatch):
+		return fiber.StatusBadRequest, api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "The uploaded file does not match its size"}
+	case errors.Is(err, gateway.ErrWriteInProgress):
+		return fiber.StatusConflict, api.ErrorResponse{Code: api.ErrorCodeWriteInProgress, Message: "The object is already being written, retry later"}
+	case errors.Is(err, gateway.ErrObjectTooLarge):
+		return fiber.StatusRequestEntityTooLarge, api.ErrorResponse{Code: api.ErrorCodeObjectTooLarge, Message: err.Error()}
+	case errors.Is(err, gateway.ErrQuotaExceeded):
+		return fiber.StatusInsufficientStorage, api.ErrorResponse{Code: api.ErrorCodeQuotaExceeded, Message: err.Error()}
+	case errors.Is(err, gateway.ErrNoInstancesAvailable):
+		return fiber.StatusServiceUnavailable, api.ErrorResponse{Code: api.ErrorCodeInstanceUnavailable, Message: "No S3 instances available, retry later"}
+	case errors.Is(err, s3.ErrInvalidCredentials), errors.Is(err, s3.ErrAccessDenied):
+		return fiber.StatusBadGateway, api.ErrorResponse{Code: api.ErrorCodeInstanceCredentialsRejected, Message: "The S3 instance rejected the credentials of the gateway"}
+	case errors.Is(err, gateway.ErrInstanceUnavailable):
+		return fiber.StatusServiceUnavailable, api.ErrorResponse{Code: api.ErrorCodeInstanceUnavailable, Message: "No S3 instance available"}
+	case errors.Is(err, fiber.ErrRequestTimeout):
+		return fiber.StatusServiceUnavailable, api.ErrorResponse{Code: api.ErrorCodeTimeout, Message: "Request timed out"}
+	default:
+		return fiber.StatusInternalServerError, api.ErrorResponse{Code: api.ErrorCodeInternalError, Message: failure}
+	}
+}
+
+// sendError responds with the error response of the error
+func (s *Server) sendError(c *fiber.Ctx, err error, failure string) error {
+	status, response := errorResponse(err, failure)
+	s.reportError(c, err, status)
+	return c.Status(status).JSON(response)
+}
+
+// reportError logs the error responded with the status, and asks the client to retry once the instances are back
+func (s *Server) reportError(c *fiber.Ctx, err error, status int) {
+	switch {
+	case errors.Is(err, gateway.ErrNoInstancesAvailable):
+		s.logger.Warn("No S3 instances available", zap.Error(err))
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds)
+	case errors.Is(err, s3.ErrInvalidCredentials), errors.Is(err, s3.ErrAccessDenied):
+		s.logger.Error("S3 instance rejected the credentials", zap.Error(err))
+	case errors.Is(err, gateway.ErrObjectTooLarge), errors.Is(err, gateway.ErrQuotaExceeded):
+		s.logger.Warn("Object rejected", zap.Error(err))
+	case status >= fiber.StatusInternalServerError:
+		s.logger.Error("Failed to process request", zap.Error(err))
+	}
+}
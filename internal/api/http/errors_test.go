@@ -0,0 +1,286 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// failingService fails every object operation with the error
+type failingService struct {
+	gateway.Service
+	err error
+}
+
+func (f *failingService) AddOrUpdateObject(context.Context, string, multipart.File, gateway.UploadOptions) (*gateway.UploadResult, error) {
+	return nil, f.err
+}
+
+func (f *failingService) StatObject(context.Context, string) (*s3.ObjectInfo, error) {
+	return nil, f.err
+}
+
+func (f *failingService) GetObject(context.Context, string) (io.Reader, error) {
+	return nil, f.err
+}
+
+func (f *failingService) DeleteObject(context.Context, string) error {
+	return f.err
+}
+
+func (f *failingService) ListObjectVersions(context.Context, string) ([]s3.ObjectVersion, error) {
+	return nil, f.err
+}
+
+func (f *failingService) RenameObject(context.Context, string, string) error {
+	return f.err
+}
+
+func (f *failingService) GetObjects(context.Context, s3.ListFilter) ([]string, error) {
+	return nil, f.err
+}
+
+func (f *failingService) CountObjects(context.Context) (*gateway.ObjectCount, error) {
+	return nil, f.err
+}
+
+func (f *failingService) DeleteObjectsByPrefix(context.Context, string) (int, error) {
+	return 0, f.err
+}
+
+func (f *failingService) CountObjectsByPrefix(context.Context, string) (int, error) {
+	return 0, f.err
+}
+
+func (f *failingService) MigrateObject(context.Context, string, int) (*gateway.MigrationResult, error) {
+	return nil, f.err
+}
+
+func (f *failingService) CheckMigration(context.Context, string, int) (*gateway.MigrationResult, error) {
+	return nil, f.err
+}
+
+func (f *failingService) ExportObject(context.Context, string, gateway.ExportTarget) (string, error) {
+	return "", f.err
+}
+
+func newFailingServer(err error) *Server {
+	server := NewServer(zap.NewNop(), &failingService{err: err}, Config{AdminAPIKey: testAdminAPIKey, VersioningEnabled: true})
+	server.gatewayRoutes()
+	return server
+}
+
+// assertErrorResponse asserts the response has the status and the error code
+func assertErrorResponse(t *testing.T, res *http.Response, body string, status int, code api.ErrorCode) {
+	t.Helper()
+
+	assert.Equal(t, status, res.StatusCode)
+	response := map[string]any{}
+	require.NoError(t, json.Unmarshal([]byte(body), &response), body)
+	assert.Equal(t, string(code), response["error_code"], body)
+	assert.NotEmpty(t, response["message"])
+}
+
+type errorCase struct {
+	err    error
+	status int
+	code   api.ErrorCode
+}
+
+// commonErrorCases are mapped the same way by all the routes
+var commonErrorCases = []errorCase{
+	{err: gateway.ErrNoInstancesAvailable, status: fiber.StatusServiceUnavailable, code: api.ErrorCodeInstanceUnavailable},
+	{err: gateway.ErrInstanceUnavailable, status: fiber.StatusServiceUnavailable, code: api.ErrorCodeInstanceUnavailable},
+	{err: s3.ErrInvalidCredentials, status: fiber.StatusBadGateway, code: api.ErrorCodeInstanceCredentialsRejected},
+	{err: s3.ErrAccessDenied, status: fiber.StatusBadGateway, code: api.ErrorCodeInstanceCredentialsRejected},
+	{err: fiber.ErrRequestTimeout, status: fiber.StatusServiceUnavailable, code: api.ErrorCodeTimeout},
+	{err: errors.New("unexpected"), status: fiber.StatusInternalServerError, code: api.ErrorCodeInternalError},
+}
+
+func TestServer_ErrorCodes(t *testing.T) {
+	routes := []struct {
+		name    string
+		request func(t *testing.T) *http.Request
+		cases   []errorCase
+	}{
+		{
+			name:    "upload",
+			request: func(t *testing.T) *http.Request { return newUploadRequest(t, "object", []byte("data")) },
+			cases: []errorCase{
+				{err: gateway.ErrInstanceNotFound, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+				{err: s3.ErrSizeMismatch, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+				{err: gateway.ErrWriteInProgress, status: fiber.StatusConflict, code: api.ErrorCodeWriteInProgress},
+				{err: gateway.ErrObjectTooLarge, status: fiber.StatusRequestEntityTooLarge, code: api.ErrorCodeObjectTooLarge},
+				{err: gateway.ErrQuotaExceeded, status: fiber.StatusInsufficientStorage, code: api.ErrorCodeQuotaExceeded},
+			},
+		},
+		{
+			name:    "download",
+			request: func(*testing.T) *http.Request { return newRequest(http.MethodGet, "/object/object", nil) },
+			cases: []errorCase{
+				{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+				{err: gateway.ErrInstanceNotFound, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+			},
+		},
+		{
+			name:    "delete",
+			request: func(*testing.T) *http.Request { return newRequest(http.MethodDelete, "/object/object", nil) },
+			cases: []errorCase{
+				{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+			},
+		},
+		{
+			name: "migrate",
+			request: func(*testing.T) *http.Request {
+				return newMigrateRequest("", "object", 2, middleware.APIKeyHeader, testAdminAPIKey)
+			},
+			cases: []errorCase{
+				{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+				{err: gateway.ErrInstanceNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeInstanceNotFound},
+				{err: gateway.ErrObjectAlreadyExists, status: fiber.StatusConflict, code: api.ErrorCodeObjectAlreadyExists},
+			},
+		},
+		{
+			name: "migrate dry run",
+			request: func(*testing.T) *http.Request {
+				return newMigrateRequest("?dry-run=true", "object", 2, middleware.APIKeyHeader, testAdminAPIKey)
+			},
+			cases: []errorCase{
+				{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+			},
+		},
+		{
+			name:    "versions",
+			request: func(*testing.T) *http.Request { return newRequest(http.MethodGet, "/object/object/versions", nil) },
+			cases: []errorCase{
+				{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+			},
+		},
+		{
+			name:    "rename",
+			request: func(*testing.T) *http.Request { return newRenameRequest("object", `{"new_id":"renamed"}`) },
+			cases: []errorCase{
+				{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+				{err: gateway.ErrObjectAlreadyExists, status: fiber.StatusConflict, code: api.ErrorCodeObjectAlreadyExists},
+			},
+		},
+		{
+			name:    "list",
+			request: func(*testing.T) *http.Request { return newRequest(http.MethodGet, "/objects", nil) },
+		},
+		{
+			name:    "count",
+			request: func(*testing.T) *http.Request { return newRequest(http.MethodGet, "/objects/count", nil) },
+		},
+		{
+			name: "delete by prefix",
+			request: func(*testing.T) *http.Request {
+				return newRequest(http.MethodDelete, "/objects?prefix=object", nil, middleware.APIKeyHeader, testAdminAPIKey)
+			},
+			cases: []errorCase{
+				{err: gateway.ErrBulkDeleteLimitExceeded, status: fiber.StatusUnprocessableEntity, code: api.ErrorCodeBulkLimitExceeded},
+			},
+		},
+		{
+			name: "bulk delete",
+			request: func(*testing.T) *http.Request {
+				return newRequest(http.MethodPost, "/admin/bulk-delete-by-prefix", strings.NewReader(`{"prefix":"object"}`),
+					middleware.APIKeyHeader, testAdminAPIKey, fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			},
+			cases: []errorCase{
+				{err: gateway.ErrBulkDeleteLimitExceeded, status: fiber.StatusUnprocessableEntity, code: api.ErrorCodeBulkLimitExceeded},
+			},
+		},
+		{
+			name: "export",
+			request: func(*testing.T) *http.Request {
+				return newRequest(http.MethodPost, "/object/object/export", strings.NewReader(`{"endpoint":"https://backup.example.com","bucket":"backup"}`),
+					middleware.APIKeyHeader, testAdminAPIKey, fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			},
+			cases: []errorCase{
+				{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+				{err: gateway.ErrExportTargetNotAllowed, status: fiber.StatusForbidden, code: api.ErrorCodeExportTargetNotAllowed},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		for _, tt := range append(route.cases, commonErrorCases...) {
+			t.Run(route.name+"/"+tt.err.Error(), func(t *testing.T) {
+				server := newFailingServer(errors.Wrap(tt.err, "failed"))
+
+				res, body := do(t, server, route.request(t))
+				assertErrorResponse(t, res, body, tt.status, tt.code)
+				if errors.Is(tt.err, gateway.ErrNoInstancesAvailable) {
+					assert.Equal(t, retryAfterSeconds, res.Header.Get(fiber.HeaderRetryAfter))
+				}
+			})
+		}
+	}
+}
+
+func TestServer_HeadErrorStatuses(t *testing.T) {
+	cases := append([]errorCase{{err: s3.ErrObjectNotFound, status: fiber.StatusNotFound}}, commonErrorCases...)
+	for _, tt := range cases {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			server := newFailingServer(errors.Wrap(tt.err, "failed"))
+
+			res, body := do(t, server, newRequest(http.MethodHead, "/object/object", nil))
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Empty(t, body)
+		})
+	}
+}
+
+func TestServer_RequestErrorCodes(t *testing.T) {
+	server := newFailingServer(nil)
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+		code    api.ErrorCode
+	}{
+		{name: "invalid object ID", request: newRequest(http.MethodGet, "/object/not-valid", nil), status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidObjectId},
+		{name: "object ID too long", request: newRequest(http.MethodDelete, "/object/abcdefghijklmnopqrstuvwxyz1234567", nil), status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidObjectId},
+		{name: "invalid content type", request: newRequest(http.MethodPut, "/object/object", nil, fiber.HeaderContentType, fiber.MIMEApplicationJSON), status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+		{name: "invalid expiry", request: newUploadRequest(t, "object", []byte("data"), expireSecondsHeader, "soon"), status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+		{name: "pinning disabled", request: newUploadRequest(t, "object", []byte("data"), instancePinHeader, "1"), status: fiber.StatusForbidden, code: api.ErrorCodeForbidden},
+		{name: "missing API key", request: newMigrateRequest("", "object", 2), status: fiber.StatusUnauthorized, code: api.ErrorCodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := do(t, server, tt.request)
+			assertErrorResponse(t, res, body, tt.status, tt.code)
+		})
+	}
+}
+
+func TestServer_ObjectNotFound(t *testing.T) {
+	server, _ := newTestServer(t, 2, Config{})
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/object/missing", nil))
+	assertErrorResponse(t, res, body, fiber.StatusNotFound, api.ErrorCodeObjectNotFound)
+
+	res, body = do(t, server, newRequest(http.MethodHead, "/object/missing", nil))
+	assert.Equal(t, fiber.StatusNotFound, res.StatusCode)
+	assert.Empty(t, body)
+
+	res, body = do(t, server, newRequest(http.MethodDelete, "/object/missing", nil))
+	assertErrorResponse(t, res, body, fiber.StatusNotFound, api.ErrorCodeObjectNotFound)
+}
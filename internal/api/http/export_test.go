@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportHandler(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{}, gateway.WithExportTargets([]string{"https://backup.example.com"}))
+	clients[1].Put("object", []byte("data"))
+
+	tests := []struct {
+		name     string
+		apiKey   string
+		endpoint string
+		status   int
+	}{
+		{name: "without API key", endpoint: "https://backup.example.com", status: fiber.StatusUnauthorized},
+		{name: "allowed target", apiKey: testAdminAPIKey, endpoint: "https://backup.example.com", status: fiber.StatusAccepted},
+		{name: "target not allowed", apiKey: testAdminAPIKey, endpoint: "http://10.0.0.1:9000", status: fiber.StatusForbidden},
+		{name: "target with path", apiKey: testAdminAPIKey, endpoint: "https://backup.example.com/bucket", status: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(`{"endpoint":"` + tt.endpoint + `","bucket":"backup"}`)
+			res, _ := do(t, server, newRequest(http.MethodPost, "/object/object/export", body, fiber.HeaderContentType, fiber.MIMEApplicationJSON, middleware.APIKeyHeader, tt.apiKey))
+			assert.Equal(t, tt.status, res.StatusCode)
+		})
+	}
+}
+
+func TestExportStatusHandler_RequiresAPIKey(t *testing.T) {
+	server, _ := newTestServer(t, 1, Config{})
+
+	res, _ := do(t, server, newRequest(http.MethodGet, "/object/object/export/job", nil))
+	assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode)
+
+	res, _ = do(t, server, newRequest(http.MethodGet, "/object/object/export/job", nil, middleware.APIKeyHeader, testAdminAPIKey))
+	assert.Equal(t, fiber.StatusNotFound, res.StatusCode)
+}
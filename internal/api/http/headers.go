@@ -0,0 +1,178 @@
+package http
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+const maxFilenameLength = 255
+
+const (
+	contentMD5Header          = "Content-MD5"
+	contentMD5ValidatedHeader = "X-Content-MD5-Validated"
+	instancePinHeader         = "X-Instance-Pin"
+	objectSizeHeader          = "X-Object-Size"
+	namespaceHeader           = "X-Namespace"
+	listTruncatedHeader       = "X-Objects-Truncated"
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	writtenInstanceHeader     = "X-Written-Instance"
+	readFromInstanceHeader    = "X-Read-From-Instance"
+)
+
+var (
+	// errInvalidContentMD5 is returned when the Content-MD5 header is not a base64-encoded MD5 digest
+	errInvalidContentMD5 = fmt.Errorf("invalid %s header", contentMD5Header)
+	// errInvalidInstancePin is returned when the X-Instance-Pin header is not an instance number
+	errInvalidInstancePin = fmt.Errorf("invalid %s header", instancePinHeader)
+	// errInstancePinningDisabled is returned when the X-Instance-Pin header is set, but pinning is not allowed
+	errInstancePinningDisabled = errors.New("instance pinning is disabled")
+	// errVersioningDisabled is returned when a version is requested, but the versioning is disabled
+	errVersioningDisabled = errors.New("object versioning is disabled")
+	// errInvalidNamespace is returned when the X-Namespace header is not a valid namespace
+	errInvalidNamespace = fmt.Errorf("invalid %s header", namespaceHeader)
+)
+
+// namespaceRegex matches the namespaces - alphanumeric with dashes and underscores, up to 32 characters
+var namespaceRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// objectVersion returns the requested version of the object, empty for the latest version
+func objectVersion(versionId string, enabled bool) (string, error) {
+	if versionId != "" && !enabled {
+		return "", errVersioningDisabled
+	}
+
+	return versionId, nil
+}
+
+// keyPrefix combines the configured key prefix with the namespace from the X-Namespace header
+func keyPrefix(configured, namespace string) (string, error) {
+	if namespace == "" {
+		return configured, nil
+	}
+
+	if !namespaceRegex.MatchString(namespace) {
+		return "", errInvalidNamespace
+	}
+
+	return configured + namespace + "/", nil
+}
+
+// instancePin parses the X-Instance-Pin header. Returns nil if the header is not set.
+func instancePin(header string, allowed bool) (*int, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	if !allowed {
+		return nil, errInstancePinningDisabled
+	}
+
+	instanceNum, err := strconv.Atoi(header)
+	if err != nil || instanceNum < 0 {
+		return nil, errInvalidInstancePin
+	}
+
+	return &instanceNum, nil
+}
+
+// objectSize returns the size of the object read, falling back to the size it was stat'ed with
+func objectSize(object io.Reader, info *s3.ObjectInfo) int64 {
+	if size, ok := s3.ObjectSize(object); ok {
+		return size
+	}
+
+	return info.Size
+}
+
+// matchesContentMD5 hashes the file and compares the digest with the base64-encoded Content-MD5 header.
+// The file is rewound, so it can be uploaded afterward.
+func matchesContentMD5(contentMD5 string, file multipart.File) (bool, error) {
+	expected, err := base64.StdEncoding.DecodeString(contentMD5)
+	if err != nil || len(expected) != md5.Size {
+		return false, errInvalidContentMD5
+	}
+
+	hash := md5.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(hash.Sum(nil), expected), nil
+}
+
+// sanitizeFilename strips path separators and non-printable ASCII characters from the filename and limits its length.
+func sanitizeFilename(filename string) string {
+	builder := strings.Builder{}
+	for _, r := range filename {
+		if r == '/' || r == '\\' || r < 0x20 || r > 0x7e {
+			continue
+		}
+
+		builder.WriteRune(r)
+	}
+
+	sanitized := builder.String()
+	if len(sanitized) > maxFilenameLength {
+		sanitized = sanitized[:maxFilenameLength]
+	}
+
+	return sanitized
+}
+
+// contentDisposition returns the Content-Disposition header value for the filename.
+// The filename is sent as a quoted-string (RFC 6266), so double quotes and backslashes are escaped.
+func contentDisposition(filename string) string {
+	filename = sanitizeFilename(filename)
+	if filename == "" {
+		return "inline"
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return fmt.Sprintf(`attachment; filename="%s"`, escaper.Replace(filename))
+}
+
+// notModified evaluates the conditional request headers against the object (RFC 7232).
+// If-None-Match takes precedence - If-Modified-Since is ignored when both are present.
+func notModified(ifNoneMatch, ifModifiedSince string, info *s3.ObjectInfo) bool {
+	if ifNoneMatch != "" {
+		for _, etag := range strings.Split(ifNoneMatch, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || strings.Trim(etag, `"`) == strings.Trim(info.ETag, `"`) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+
+		// HTTP dates have a second precision
+		return !info.LastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
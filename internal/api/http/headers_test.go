@@ -0,0 +1,121 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		header   string
+		parsed   string
+	}{
+		{name: "inline", filename: "", header: "inline"},
+		{name: "plain", filename: "report.pdf", header: `attachment; filename="report.pdf"`, parsed: "report.pdf"},
+		{name: "spaces", filename: "annual report 2024.pdf", header: `attachment; filename="annual report 2024.pdf"`, parsed: "annual report 2024.pdf"},
+		{name: "double quotes", filename: `the "final" draft.txt`, header: `attachment; filename="the \"final\" draft.txt"`, parsed: `the "final" draft.txt`},
+		{name: "semicolon and equals", filename: "a;b=c.txt", header: `attachment; filename="a;b=c.txt"`, parsed: "a;b=c.txt"},
+		{name: "path separators", filename: `../etc\passwd`, header: `attachment; filename="..etcpasswd"`, parsed: "..etcpasswd"},
+		{name: "non-printable and non-ASCII", filename: "na\x00me\r\n-ž.txt", header: `attachment; filename="name-.txt"`, parsed: "name-.txt"},
+		{name: "only separators", filename: "//", header: "inline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := contentDisposition(tt.filename)
+			assert.Equal(t, tt.header, header)
+
+			// The header parses back to the sanitized filename
+			disposition, params, err := mime.ParseMediaType(header)
+			require.NoError(t, err)
+			if tt.parsed == "" {
+				assert.Equal(t, "inline", disposition)
+				return
+			}
+
+			assert.Equal(t, "attachment", disposition)
+			assert.Equal(t, tt.parsed, params["filename"])
+		})
+	}
+}
+
+func TestContentDisposition_Length(t *testing.T) {
+	_, params, err := mime.ParseMediaType(contentDisposition(strings.Repeat("a", 300)))
+	require.NoError(t, err)
+	assert.Len(t, params["filename"], maxFilenameLength)
+}
+
+func TestServer_DownloadContentDisposition(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{})
+	clients[1].Put("object", []byte("data"))
+
+	res, _ := do(t, server, newRequest(http.MethodGet, "/object/object?filename="+url.QueryEscape(`my "report".pdf`), nil))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.Equal(t, `attachment; filename="my \"report\".pdf"`, res.Header.Get(fiber.HeaderContentDisposition))
+
+	res, _ = do(t, server, newRequest(http.MethodGet, "/object/object", nil))
+	assert.Equal(t, "inline", res.Header.Get(fiber.HeaderContentDisposition))
+
+	// The header is readable by the cross-origin requests
+	res, _ = do(t, server, newRequest(http.MethodGet, "/object/object", nil, fiber.HeaderOrigin, "https://example.com"))
+	assert.Contains(t, res.Header.Get(fiber.HeaderAccessControlExposeHeaders), fiber.HeaderContentDisposition)
+}
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+	info := &s3.ObjectInfo{ETag: `"abc"`, LastModified: lastModified}
+
+	tests := []struct {
+		name            string
+		ifNoneMatch     string
+		ifModifiedSince string
+		notModified     bool
+	}{
+		{name: "no conditions"},
+		{name: "unmodified since", ifModifiedSince: lastModified.Format(http.TimeFormat), notModified: true},
+		{name: "unmodified since later", ifModifiedSince: lastModified.Add(time.Hour).Format(http.TimeFormat), notModified: true},
+		{name: "modified since", ifModifiedSince: lastModified.Add(-time.Second).Format(http.TimeFormat)},
+		{name: "invalid date", ifModifiedSince: "yesterday"},
+		{name: "matching ETag", ifNoneMatch: `"abc"`, notModified: true},
+		{name: "weak matching ETag", ifNoneMatch: `"xyz", W/"abc"`, notModified: true},
+		{name: "any ETag", ifNoneMatch: "*", notModified: true},
+		{name: "different ETag", ifNoneMatch: `"xyz"`},
+		{name: "ETag takes precedence when matching", ifNoneMatch: `"abc"`, ifModifiedSince: lastModified.Add(-time.Hour).Format(http.TimeFormat), notModified: true},
+		{name: "ETag takes precedence when different", ifNoneMatch: `"xyz"`, ifModifiedSince: lastModified.Add(time.Hour).Format(http.TimeFormat)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.notModified, notModified(tt.ifNoneMatch, tt.ifModifiedSince, info))
+		})
+	}
+}
+
+func TestServer_DownloadIfModifiedSince(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{})
+	clients[1].Put("object", []byte("data"))
+	lastModified := clients[1].Object("object").LastModified
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/object/object", nil, fiber.HeaderIfModifiedSince, lastModified.Add(time.Second).UTC().Format(http.TimeFormat)))
+	assert.Equal(t, fiber.StatusNotModified, res.StatusCode)
+	assert.Empty(t, body)
+	assert.Equal(t, lastModified.UTC().Format(http.TimeFormat), res.Header.Get(fiber.HeaderLastModified))
+
+	res, body = do(t, server, newRequest(http.MethodGet, "/object/object", nil, fiber.HeaderIfModifiedSince, lastModified.Add(-time.Hour).UTC().Format(http.TimeFormat)))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.Equal(t, "data", body)
+
+	// The object is not read when it is not modified
+	assert.Equal(t, 1, clients[1].CallCount("GetObject"))
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"crypto/md5"
+	"io"
+	"mime/multipart"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+)
+
+const (
+	// Maximum number of uploads remembered by their idempotency key
+	idempotencyMaxEntries = 10000
+	// Maximum length of the Idempotency-Key header
+	idempotencyKeyMaxLength = 255
+)
+
+// idempotencyStore remembers the results of the successful uploads by their idempotency key for a TTL, so the retried
+// uploads return the original result instead of uploading the object again. The uploads are remembered with the
+// fingerprint of their file, so a key reused for a different file is detected. Concurrent uploads with the same key
+// are not deduplicated - both store the same object.
+type idempotencyStore struct {
+	uploads *expirable.LRU[string, idempotentUpload]
+}
+
+// idempotentUpload is the remembered result of an upload
+type idempotentUpload struct {
+	response    api.UploadResponse
+	fingerprint uploadFingerprint
+}
+
+// uploadFingerprint identifies the uploaded file by its size and MD5 digest
+type uploadFingerprint struct {
+	size int64
+	md5  [md5.Size]byte
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{uploads: expirable.NewLRU[string, idempotentUpload](idempotencyMaxEntries, nil, ttl)}
+}
+
+// fingerprintUpload hashes the file. The file is rewound, so it can be uploaded afterward.
+func fingerprintUpload(file multipart.File) (uploadFingerprint, error) {
+	fingerprint := uploadFingerprint{}
+
+	hash := md5.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return fingerprint, err
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return fingerprint, err
+	}
+
+	fingerprint.size = size
+	copy(fingerprint.md5[:], hash.Sum(nil))
+	return fingerprint, nil
+}
+
+// storeKey scopes the idempotency key to the object, including its namespace
+func (s *idempotencyStore) storeKey(keyPrefix, objectId, idempotencyKey string) string {
+	return keyPrefix + "\x00" + objectId + "\x00" + idempotencyKey
+}
+
+// get returns the result of the upload with the idempotency key and the fingerprint of its file, if it is remembered
+func (s *idempotencyStore) get(keyPrefix, objectId, idempotencyKey string) (idempotentUpload, bool) {
+	return s.uploads.Get(s.storeKey(keyPrefix, objectId, idempotencyKey))
+}
+
+// add remembers the result of the upload of the file with the fingerprint with the idempotency key
+func (s *idempotencyStore) add(keyPrefix, objectId, idempotencyKey string, fingerprint uploadFingerprint, response api.UploadResponse) {
+	s.uploads.Add(s.storeKey(keyPrefix, objectId, idempotencyKey), idempotentUpload{response: response, fingerprint: fingerprint})
+}
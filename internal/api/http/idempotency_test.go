@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload_IdempotencyKey(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{IdempotencyTTL: time.Minute})
+
+	res, _ := do(t, server, newUploadRequest(t, "object", []byte("data"), idempotencyKeyHeader, "key"))
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Empty(t, res.Header.Get(idempotentReplayedHeader))
+
+	// The retry of the same upload is replayed
+	clients[1].Put("object", []byte("overwritten"))
+	res, _ = do(t, server, newUploadRequest(t, "object", []byte("data"), idempotencyKeyHeader, "key"))
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, "true", res.Header.Get(idempotentReplayedHeader))
+	assert.Equal(t, []byte("overwritten"), clients[1].Object("object").Data)
+
+	// The key reused for a different file of the same size is rejected
+	res, body := do(t, server, newUploadRequest(t, "object", []byte("diff"), idempotencyKeyHeader, "key"))
+	assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	assert.Contains(t, body, "IDEMPOTENCY_KEY_REUSED")
+	assert.Equal(t, []byte("overwritten"), clients[1].Object("object").Data)
+
+	// And for a file of a different size
+	res, _ = do(t, server, newUploadRequest(t, "object", []byte("more data"), idempotencyKeyHeader, "key"))
+	assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+
+	// The key is scoped to the object
+	res, _ = do(t, server, newUploadRequest(t, "other", []byte("diff"), idempotencyKeyHeader, "key"))
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Empty(t, res.Header.Get(idempotentReplayedHeader))
+}
+
+func TestUpload_IdempotencyKeyExpires(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{IdempotencyTTL: 50 * time.Millisecond})
+
+	res, _ := do(t, server, newUploadRequest(t, "object", []byte("data"), idempotencyKeyHeader, "key"))
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// Once the key expires, it may be used for a different file
+	time.Sleep(100 * time.Millisecond)
+	res, _ = do(t, server, newUploadRequest(t, "object", []byte("different"), idempotencyKeyHeader, "key"))
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Empty(t, res.Header.Get(idempotentReplayedHeader))
+	assert.Equal(t, []byte("different"), clients[1].Object("object").Data)
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_ListObjectsFilter(t *testing.T) {
+	server, _ := newTestServer(t, 3, Config{})
+
+	for i := 0; i < 10; i++ {
+		res, _ := do(t, server, newUploadRequest(t, fmt.Sprintf("report%02d", i), []byte("data")))
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		res, _ = do(t, server, newUploadRequest(t, fmt.Sprintf("image%02d", i), []byte("data")))
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "prefix=image0", expected: []string{"image00", "image01", "image02", "image03", "image04", "image05", "image06", "image07", "image08", "image09"}},
+		{query: "suffix=7", expected: []string{"image07", "report07"}},
+		{query: "prefix=report&suffix=3", expected: []string{"report03"}},
+		{query: "prefix=video", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			res, body := do(t, server, newRequest(http.MethodGet, "/objects?"+tt.query, nil))
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			var objectIds []string
+			require.NoError(t, json.Unmarshal([]byte(body), &objectIds))
+			assert.ElementsMatch(t, tt.expected, objectIds)
+		})
+	}
+}
+
+func TestServer_ListObjectsInvalidFilter(t *testing.T) {
+	server, _ := newTestServer(t, 1, Config{})
+
+	for _, query := range []string{"prefix=a%20b", "suffix=*.txt", "prefix=" + strings.Repeat("a", 40) + "&suffix=" + strings.Repeat("b", 25)} {
+		t.Run(query, func(t *testing.T) {
+			res, body := do(t, server, newRequest(http.MethodGet, "/objects?"+query, nil))
+			assertErrorResponse(t, res, body, http.StatusBadRequest, api.ErrorCodeInvalidRequest)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/observability"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Live(t *testing.T) {
+	server, _ := newTestServer(t, 1, Config{})
+
+	// Always live without a heartbeat
+	res, _ := do(t, server, newRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestServer_LiveStaleHeartbeat(t *testing.T) {
+	heartbeat := observability.NewHeartbeat(20 * time.Millisecond)
+	server, _ := newTestServer(t, 1, Config{Heartbeat: heartbeat})
+
+	res, _ := do(t, server, newRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// The stale heartbeat flips the liveness, regardless of the downstream instances
+	time.Sleep(40 * time.Millisecond)
+	res, _ = do(t, server, newRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+	heartbeat.Beat()
+	res, _ = do(t, server, newRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
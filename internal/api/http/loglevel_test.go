@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newLogLevelRequest(method, body string, headers ...string) *http.Request {
+	return newRequest(method, "/admin/log-level", strings.NewReader(body), append([]string{fiber.HeaderContentType, fiber.MIMEApplicationJSON}, headers...)...)
+}
+
+func TestServer_LogLevel(t *testing.T) {
+	// The gateway logs with the global logger
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	core, logs := observer.New(level)
+	restore := zap.ReplaceGlobals(zap.New(core))
+	t.Cleanup(restore)
+
+	server, _ := newTestServer(t, 1, Config{LogLevel: &level})
+	debugLogs := func() int {
+		return logs.FilterLevelExact(zapcore.DebugLevel).Len()
+	}
+
+	res, _ := do(t, server, newUploadRequest(t, "object", []byte("data")))
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Zero(t, debugLogs())
+
+	res, body := do(t, server, newLogLevelRequest(http.MethodPut, `{"level":"debug"}`, middleware.APIKeyHeader, testAdminAPIKey))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"level":"debug"}`, body)
+
+	res, body = do(t, server, newLogLevelRequest(http.MethodGet, "", middleware.APIKeyHeader, testAdminAPIKey))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"level":"debug"}`, body)
+
+	res, _ = do(t, server, newRequest(http.MethodGet, "/object/object", nil))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotZero(t, debugLogs())
+	assert.NotZero(t, logs.FilterMessage("Assigning object to instance").FilterLevelExact(zapcore.DebugLevel).Len())
+}
+
+func TestServer_LogLevelErrors(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	server, _ := newTestServer(t, 1, Config{LogLevel: &level})
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+		code    api.ErrorCode
+	}{
+		{name: "get without the API key", request: newLogLevelRequest(http.MethodGet, ""), status: http.StatusUnauthorized, code: api.ErrorCodeUnauthorized},
+		{name: "set without the API key", request: newLogLevelRequest(http.MethodPut, `{"level":"debug"}`), status: http.StatusUnauthorized, code: api.ErrorCodeUnauthorized},
+		{name: "unknown level", request: newLogLevelRequest(http.MethodPut, `{"level":"verbose"}`, middleware.APIKeyHeader, testAdminAPIKey), status: http.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+		{name: "invalid body", request: newLogLevelRequest(http.MethodPut, `level=debug`, middleware.APIKeyHeader, testAdminAPIKey), status: http.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := do(t, server, tt.request)
+			assertErrorResponse(t, res, body, tt.status, tt.code)
+		})
+	}
+
+	// The level didn't change
+	assert.Equal(t, zapcore.InfoLevel, level.Level())
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMigrateRequest(query string, objectId string, targetInstance int, headers ...string) *http.Request {
+	body := fmt.Sprintf(`{"objectId":%q,"targetInstance":%d}`, objectId, targetInstance)
+	return newRequest(http.MethodPost, "/objects/migrate"+query, strings.NewReader(body), append([]string{fiber.HeaderContentType, fiber.MIMEApplicationJSON}, headers...)...)
+}
+
+// storedObject uploads the object and returns the numbers of the instance storing it and of the other instance
+func storedObject(t *testing.T, server *Server, clients map[int]*s3test.Client, objectId string) (int, int) {
+	t.Helper()
+
+	res, _ := do(t, server, newUploadRequest(t, objectId, []byte("data")))
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+	if clients[1].Object(objectId) != nil {
+		return 1, 2
+	}
+
+	return 2, 1
+}
+
+func TestMigrateHandler(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	source, target := storedObject(t, server, clients, "object")
+
+	// The dry run only checks the migration
+	res, body := do(t, server, newMigrateRequest("?dry-run=true", "object", target, middleware.APIKeyHeader, testAdminAPIKey))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	response := api.MigrateResponse{}
+	require.NoError(t, json.Unmarshal([]byte(body), &response))
+	assert.Equal(t, api.MigrateResponse{ObjectId: "object", SourceInstance: source, TargetInstance: target, DryRun: true}, response)
+	assert.NotNil(t, clients[source].Object("object"))
+
+	res, body = do(t, server, newMigrateRequest("", "object", target, middleware.APIKeyHeader, testAdminAPIKey))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	response = api.MigrateResponse{}
+	require.NoError(t, json.Unmarshal([]byte(body), &response))
+	assert.Equal(t, api.MigrateResponse{ObjectId: "object", SourceInstance: source, TargetInstance: target}, response)
+	assert.Nil(t, clients[source].Object("object"))
+	assert.Equal(t, []byte("data"), clients[target].Object("object").Data)
+}
+
+func TestMigrateHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		objectId string
+		target   func(source, target int) int
+		headers  []string
+		status   int
+		code     api.ErrorCode
+	}{
+		{name: "destination exists", objectId: "object", target: func(source, _ int) int { return source }, status: fiber.StatusConflict, code: api.ErrorCodeObjectAlreadyExists},
+		{name: "object not found", objectId: "absent", target: func(_, target int) int { return target }, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+		{name: "instance not found", objectId: "object", target: func(int, int) int { return 7 }, status: fiber.StatusNotFound, code: api.ErrorCodeInstanceNotFound},
+		{name: "invalid object ID", objectId: "not/valid", target: func(_, target int) int { return target }, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+		{name: "missing API key", objectId: "object", target: func(_, target int) int { return target }, headers: []string{}, status: fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clients := newTestServer(t, 2, Config{})
+			source, target := storedObject(t, server, clients, "object")
+
+			headers := tt.headers
+			if headers == nil {
+				headers = []string{middleware.APIKeyHeader, testAdminAPIKey}
+			}
+
+			res, body := do(t, server, newMigrateRequest("", tt.objectId, tt.target(source, target), headers...))
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Contains(t, body, string(tt.code))
+			assert.NotNil(t, clients[source].Object("object"))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"encoding/json"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/docs"
+	"go.uber.org/zap"
+)
+
+// openAPISpec converts the Swagger 2.0 spec generated from the handler annotations to OpenAPI 3
+func openAPISpec() ([]byte, error) {
+	swaggerSpec := openapi2.T{}
+	err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &swaggerSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the generated swagger spec")
+	}
+
+	binaryFileResponses(&swaggerSpec)
+
+	spec, err := openapi2conv.ToV3(&swaggerSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert the swagger spec to OpenAPI 3")
+	}
+
+	return json.Marshal(spec)
+}
+
+// binaryFileResponses replaces the Swagger 2.0 file type of the responses, which is not valid in OpenAPI 3 and is not
+// converted, with a binary string
+func binaryFileResponses(swaggerSpec *openapi2.T) {
+	for _, path := range swaggerSpec.Paths {
+		for _, operation := range path.Operations() {
+			for _, response := range operation.Responses {
+				if response.Schema != nil && response.Schema.Value != nil && response.Schema.Value.Type == "file" {
+					response.Schema.Value.Type = openapi3.TypeString
+					response.Schema.Value.Format = "binary"
+				}
+			}
+		}
+	}
+}
+
+// openAPIHandler serves the OpenAPI 3 spec
+func (s *Server) openAPIHandler() fiber.Handler {
+	spec, err := openAPISpec()
+
+	return func(c *fiber.Ctx) error {
+		if err != nil {
+			s.logger.Error("Failed to serve the OpenAPI spec", zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Error{Message: "OpenAPI spec is unavailable"})
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(spec)
+	}
+}
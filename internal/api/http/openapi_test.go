@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_OpenAPISpec(t *testing.T) {
+	server, _ := newTestServer(t, 1, Config{})
+	server.docsRoutes()
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.Equal(t, fiber.MIMEApplicationJSON, res.Header.Get(fiber.HeaderContentType))
+
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, spec.Validate(context.Background()))
+	assert.Regexp(t, `^3\.`, spec.OpenAPI)
+
+	object := spec.Paths.Find("/object/{id}")
+	require.NotNil(t, object)
+	assert.NotNil(t, object.Put)
+	assert.NotNil(t, object.Get)
+	assert.NotNil(t, object.Head)
+	assert.NotNil(t, object.Delete)
+	assert.NotNil(t, spec.Paths.Find("/objects"))
+
+	require.NotNil(t, spec.Components)
+	assert.Contains(t, spec.Components.Schemas, "api.ErrorResponse")
+	assert.Contains(t, spec.Components.Schemas["api.ErrorResponse"].Value.Properties, "error_code")
+}
+
+func TestServer_SwaggerUI(t *testing.T) {
+	server, _ := newTestServer(t, 1, Config{})
+	server.docsRoutes()
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/docs/index.html", nil))
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+	assert.Contains(t, body, "swagger-ui")
+}
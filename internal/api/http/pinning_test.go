@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_InstancePinning(t *testing.T) {
+	server, clients := newTestServer(t, 3, Config{AllowInstancePinning: true})
+
+	// Pin the object to every instance in turn, regardless of the sharding
+	for instanceNum := 1; instanceNum <= 3; instanceNum++ {
+		pin := strconv.Itoa(instanceNum)
+		objectId := "pinned" + pin
+
+		res, body := do(t, server, newUploadRequest(t, objectId, []byte("data"+pin), instancePinHeader, pin))
+		require.Equal(t, http.StatusCreated, res.StatusCode, body)
+		for otherNum, client := range clients {
+			assert.Equal(t, otherNum == instanceNum, client.Object(objectId) != nil, "instance %d", otherNum)
+		}
+
+		res, body = do(t, server, newRequest(http.MethodGet, "/object/"+objectId, nil, instancePinHeader, pin))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "data"+pin, body)
+	}
+
+	// The object is not on the other pinned instance
+	res, body := do(t, server, newRequest(http.MethodGet, "/object/pinned1", nil, instancePinHeader, "2"))
+	assertErrorResponse(t, res, body, http.StatusNotFound, api.ErrorCodeObjectNotFound)
+}
+
+func TestServer_InstancePinningInvalid(t *testing.T) {
+	server, clients := newTestServer(t, 3, Config{AllowInstancePinning: true})
+	clients[1].Put("object", []byte("data"))
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{name: "upload to a non-existent instance", request: newUploadRequest(t, "object", []byte("data"), instancePinHeader, "9")},
+		{name: "download from a non-existent instance", request: newRequest(http.MethodGet, "/object/object", nil, instancePinHeader, "9")},
+		{name: "upload with an invalid pin", request: newUploadRequest(t, "object", []byte("data"), instancePinHeader, "first")},
+		{name: "download with an invalid pin", request: newRequest(http.MethodGet, "/object/object", nil, instancePinHeader, "first")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := do(t, server, tt.request)
+			assertErrorResponse(t, res, body, http.StatusBadRequest, api.ErrorCodeInvalidRequest)
+		})
+	}
+
+	// Nothing was stored
+	for instanceNum, client := range clients {
+		if instanceNum != 1 {
+			assert.Empty(t, client.Keys(), "instance %d", instanceNum)
+		}
+	}
+}
+
+func TestServer_InstancePinningDisabled(t *testing.T) {
+	server, clients := newTestServer(t, 3, Config{})
+	clients[1].Put("object", []byte("data"))
+
+	res, body := do(t, server, newUploadRequest(t, "pinned", []byte("data"), instancePinHeader, "1"))
+	assertErrorResponse(t, res, body, http.StatusForbidden, api.ErrorCodeForbidden)
+	for _, client := range clients {
+		assert.Nil(t, client.Object("pinned"))
+	}
+
+	res, body = do(t, server, newRequest(http.MethodGet, "/object/object", nil, instancePinHeader, "1"))
+	assertErrorResponse(t, res, body, http.StatusForbidden, api.ErrorCodeForbidden)
+}
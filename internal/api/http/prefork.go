@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// IsPreforkChild returns true in the child processes serving the requests in the prefork mode. The children re-execute
+// the binary, so each of them creates its own logger, Docker client and connections to the S3 instances.
+func IsPreforkChild() bool {
+	return fiber.IsChild()
+}
+
+// preforkMasterCheckInterval is how often the prefork children check whether the master is still running
+const preforkMasterCheckInterval = 500 * time.Millisecond
+
+// PreforkChildContext returns the context of a prefork child, cancelled once the master goes away. The children don't
+// handle the signals, the master forwards the shutdown to them instead. The interrupts of the terminal, which reach the
+// whole process group, are ignored. Fiber exits the children orphaned to init, the context stops the ones re-parented
+// to a subreaper, e.g. tini.
+func PreforkChildContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	signal.Ignore(os.Interrupt)
+	return watchMaster(ctx, os.Getppid, preforkMasterCheckInterval)
+}
+
+// watchMaster cancels the context once the parent process changes, i.e. the master exited and the child was
+// re-parented
+func watchMaster(ctx context.Context, getppid func() int, interval time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	master := getppid()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if getppid() != master {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// recordPreforkChildren remembers the children started by the prefork master, so the shutdown can be forwarded to them
+func (s *Server) recordPreforkChildren() {
+	s.app.Hooks().OnFork(func(pid int) error {
+		s.childrenMu.Lock()
+		defer s.childrenMu.Unlock()
+
+		s.children = append(s.children, pid)
+		return nil
+	})
+}
+
+// shutdownPreforkChildren forwards the shutdown to the children, which don't handle the signals and exit right away.
+// The master returns from Run once a child exits.
+func (s *Server) shutdownPreforkChildren() error {
+	s.childrenMu.Lock()
+	defer s.childrenMu.Unlock()
+
+	s.childrenStopped = true
+	var errs []error
+	for _, pid := range s.children {
+		s.logger.Info("Shutting down prefork child", zap.Int("pid", pid))
+
+		child, err := os.FindProcess(pid)
+		if err == nil {
+			err = child.Signal(syscall.SIGTERM)
+		}
+		if err != nil && !errors.Is(err, os.ErrProcessDone) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// preforkChildrenStopped returns true once the master forwarded the shutdown to the children
+func (s *Server) preforkChildrenStopped() bool {
+	s.childrenMu.Lock()
+	defer s.childrenMu.Unlock()
+	return s.childrenStopped
+}
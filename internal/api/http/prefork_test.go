@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// preforkTestAddress is the address the prefork children of the test binary listen on
+const preforkTestAddress = "PREFORK_TEST_ADDRESS"
+
+// TestMain serves the requests in the prefork children, which re-execute the test binary
+func TestMain(m *testing.M) {
+	if address := os.Getenv(preforkTestAddress); address != "" && IsPreforkChild() {
+		runPreforkChild(address)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func newPreforkServer(config Config) *Server {
+	client := s3test.NewClient()
+	service := gateway.NewServiceV1WithOptions(discoverytest.NewService(discoverytest.Instances(1)...), s3.Options{},
+		gateway.WithClientFactory(func(discovery.S3Instance) (s3.Client, error) {
+			return client, nil
+		}),
+	)
+
+	config.Prefork = true
+	return NewServer(zap.NewNop(), service, config)
+}
+
+// runPreforkChild serves the requests until the master forwards the shutdown or goes away
+func runPreforkChild(address string) {
+	server := newPreforkServer(Config{})
+
+	ctx, stop := PreforkChildContext(context.Background())
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(time.Second)
+	}()
+
+	server.Run(address, TLSConfig{})
+}
+
+func TestServer_Prefork(t *testing.T) {
+	// Start a child per core
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	t.Setenv(preforkTestAddress, address)
+
+	server := newPreforkServer(Config{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		server.Run(address, TLSConfig{})
+	}()
+
+	// The children serve the requests
+	require.Eventually(t, func() bool {
+		res, err := http.Get("http://" + address + "/live")
+		if err != nil {
+			return false
+		}
+		_ = res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 10*time.Second, 50*time.Millisecond)
+
+	server.childrenMu.Lock()
+	children := append([]int{}, server.children...)
+	server.childrenMu.Unlock()
+	assert.Len(t, children, 2)
+
+	// The shutdown is forwarded to the children, which stop serving the requests
+	require.NoError(t, server.Shutdown(time.Second))
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the master didn't stop after the children were shut down")
+	}
+
+	require.Eventually(t, func() bool {
+		_, err := http.Get("http://" + address + "/live")
+		return err != nil
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestWatchMaster(t *testing.T) {
+	var ppid atomic.Int32
+	ppid.Store(100)
+	getppid := func() int { return int(ppid.Load()) }
+
+	ctx, cancel := watchMaster(context.Background(), getppid, 10*time.Millisecond)
+	defer cancel()
+
+	// The context stays open while the master runs
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, ctx.Err())
+
+	// The child is re-parented once the master exits
+	ppid.Store(1)
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context wasn't cancelled after the master went away")
+	}
+}
+
+func TestNewServer_PreforkIgnoresIdempotencyKeys(t *testing.T) {
+	server := newPreforkServer(Config{IdempotencyTTL: time.Minute})
+	assert.Nil(t, server.idempotency)
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubDaemonReporter reports the fixed daemon info or error
+type stubDaemonReporter struct {
+	info *discovery.DaemonInfo
+	err  error
+}
+
+func (r stubDaemonReporter) DaemonInfo(context.Context) (*discovery.DaemonInfo, error) {
+	return r.info, r.err
+}
+
+func TestServer_Ready(t *testing.T) {
+	info := &discovery.DaemonInfo{Host: "tcp://docker.internal:2376", APIVersion: "1.44", ServerVersion: "26.0.0", OS: "linux", Arch: "amd64"}
+
+	tests := []struct {
+		name      string
+		instances int
+		reporter  discovery.DaemonReporter
+		status    int
+		expected  string
+	}{
+		{
+			name:      "ready without a daemon",
+			instances: 1,
+			status:    http.StatusOK,
+			expected:  `{"ready":true}`,
+		},
+		{
+			name:      "ready with the daemon info",
+			instances: 1,
+			reporter:  stubDaemonReporter{info: info},
+			status:    http.StatusOK,
+			expected:  `{"ready":true,"docker":{"host":"tcp://docker.internal:2376","apiVersion":"1.44","serverVersion":"26.0.0","os":"linux","arch":"amd64"}}`,
+		},
+		{
+			name:      "daemon info unavailable",
+			instances: 1,
+			reporter:  stubDaemonReporter{err: errors.New("daemon unreachable")},
+			status:    http.StatusOK,
+			expected:  `{"ready":true}`,
+		},
+		{
+			name:      "not ready",
+			instances: 0,
+			reporter:  stubDaemonReporter{info: info},
+			status:    http.StatusServiceUnavailable,
+			expected:  `{"ready":false,"docker":{"host":"tcp://docker.internal:2376","apiVersion":"1.44","serverVersion":"26.0.0","os":"linux","arch":"amd64"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, tt.instances, Config{DaemonReporter: tt.reporter})
+			server.app.Get("/ready", server.readyHandler)
+
+			res, body := do(t, server, newRequest(http.MethodGet, "/ready", nil))
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.JSONEq(t, tt.expected, body)
+		})
+	}
+}
+
+// stubSkipReporter reports the fixed skipped instances
+type stubSkipReporter []discovery.SkippedInstance
+
+func (r stubSkipReporter) SkippedInstances() []discovery.SkippedInstance {
+	return r
+}
+
+func TestServer_ReadySkippedInstances(t *testing.T) {
+	reporter := stubSkipReporter{{ContainerId: "c2", Reason: "incomplete S3 instance: missing access key"}}
+	server, _ := newTestServer(t, 1, Config{SkipReporter: reporter})
+	server.app.Get("/ready", server.readyHandler)
+
+	res, body := do(t, server, newRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"ready":true,"skipped":[{"containerId":"c2","reason":"incomplete S3 instance: missing access key"}]}`, body)
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRenameRequest(objectId, body string) *http.Request {
+	return newRequest(http.MethodPost, "/object/"+objectId+"/rename", strings.NewReader(body), fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+}
+
+func TestRenameHandler(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{})
+	clients[1].Put("draft", []byte("data"))
+
+	res, _ := do(t, server, newRenameRequest("draft", `{"new_id":"final"}`))
+	assert.Equal(t, fiber.StatusNoContent, res.StatusCode)
+
+	assert.Equal(t, []string{"final"}, clients[1].Keys())
+	assert.Equal(t, "draft", clients[1].Object("final").Metadata[gateway.RenamedFromMetadata])
+}
+
+func TestRenameHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		objectId string
+		body     string
+		status   int
+		code     api.ErrorCode
+	}{
+		{name: "conflict", objectId: "draft", body: `{"new_id":"final"}`, status: fiber.StatusConflict, code: api.ErrorCodeObjectAlreadyExists},
+		{name: "not found", objectId: "missing", body: `{"new_id":"renamed"}`, status: fiber.StatusNotFound, code: api.ErrorCodeObjectNotFound},
+		{name: "invalid new id", objectId: "draft", body: `{"new_id":"not/valid"}`, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidObjectId},
+		{name: "missing new id", objectId: "draft", body: `{}`, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidObjectId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clients := newTestServer(t, 1, Config{})
+			clients[1].Put("draft", []byte("draft"))
+			clients[1].Put("final", []byte("final"))
+
+			res, body := do(t, server, newRenameRequest(tt.objectId, tt.body))
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Contains(t, body, string(tt.code))
+
+			assert.Equal(t, []string{"draft", "final"}, clients[1].Keys())
+		})
+	}
+}
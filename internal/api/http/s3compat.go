@@ -0,0 +1,365 @@
+package http
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/timeout"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// s3MaxKeys is the default and the maximum number of objects in a ListObjectsV2 page, as in S3
+const s3MaxKeys = 1000
+
+// s3Error is the error body of the S3 REST API
+type s3Error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource"`
+	RequestId string   `xml:"RequestId"`
+}
+
+// listBucketResult is the ListObjectsV2 response body
+type listBucketResult struct {
+	XMLName               xml.Name         `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	StartAfter            string           `xml:"StartAfter,omitempty"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int              `xml:"KeyCount"`
+	MaxKeys               int              `xml:"MaxKeys"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	Contents              []listedS3Object `xml:"Contents"`
+}
+
+type listedS3Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// locationConstraint is the GetBucketLocation response body. Empty is the default region.
+type locationConstraint struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint"`
+	Location string   `xml:",chardata"`
+}
+
+// uploadedBody adapts the request body to the file expected by the gateway service
+type uploadedBody struct {
+	*bytes.Reader
+}
+
+func (uploadedBody) Close() error {
+	return nil
+}
+
+// s3CompatibleRoutes serves the path-style object operations of the S3 REST API under the bucket, so the S3 clients
+// can use the gateway. Only the object PUT, GET, HEAD and DELETE, ListObjectsV2 and GetBucketLocation are supported -
+// notably not the multipart uploads, used by the S3 clients for the objects of an unknown size. The request
+// signatures are not verified - the routes are protected by the same auth handlers as the gateway routes.
+func (s *Server) s3CompatibleRoutes(router fiber.Router) {
+	bucket := router.Group("/" + s.config.S3CompatibleBucket)
+	bucket.Get("/", timeout.NewWithContext(s.s3ListObjectsHandler, time.Second*30))
+	bucket.Put("/:key", s.validateS3Key, timeout.NewWithContext(s.s3PutObjectHandler, time.Second*30))
+	bucket.Get("/:key", s.validateS3Key, timeout.NewWithContext(s.s3GetObjectHandler, time.Second*30))
+	bucket.Head("/:key", s.validateS3Key, timeout.NewWithContext(s.s3HeadObjectHandler, time.Second*30))
+	bucket.Delete("/:key", s.validateS3Key, timeout.NewWithContext(s.s3DeleteObjectHandler, time.Second*30))
+}
+
+// validateS3Key rejects the keys which are not valid object ids
+func (s *Server) validateS3Key(c *fiber.Ctx) error {
+	if !middleware.IsValidObjectId(c.Params("key")) {
+		return s3ErrorResponse(c, fiber.StatusBadRequest, "InvalidArgument", "The key must be alphanumeric, up to 32 characters")
+	}
+
+	return c.Next()
+}
+
+// s3PutObjectHandler stores the request body as the object. The upload is validated as on the gateway upload route:
+// the content type lists, the empty uploads and the Content-MD5 checksum. The parts of the multipart uploads are
+// refused, as they would be stored as whole objects.
+func (s *Server) s3PutObjectHandler(c *fiber.Ctx) error {
+	if c.Request().URI().QueryArgs().Has("uploadId") {
+		return s3ErrorResponse(c, fiber.StatusNotImplemented, "NotImplemented", "Multipart uploads are not supported")
+	}
+
+	contentType, allowed := middleware.IsAllowedContentType(c.Get(fiber.HeaderContentType), s.config.UploadContentTypeAllowlist, s.config.UploadContentTypeDenylist)
+	if !allowed {
+		return s3ErrorResponse(c, fiber.StatusUnsupportedMediaType, "InvalidArgument", "Unsupported content type "+contentType)
+	}
+
+	data := c.Body()
+
+	// The S3 clients sign the body in chunks when sending it over plain HTTP
+	if strings.HasPrefix(c.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		var err error
+		data, err = decodeAWSChunked(data)
+		if err != nil {
+			return s3ErrorResponse(c, fiber.StatusBadRequest, "IncompleteBody", err.Error())
+		}
+	}
+
+	if len(data) == 0 && s.config.RejectEmptyUploads {
+		return s3ErrorResponse(c, fiber.StatusBadRequest, "InvalidArgument", "Empty objects are not allowed")
+	}
+
+	body := uploadedBody{Reader: bytes.NewReader(data)}
+
+	// Verify the optional checksum before storing the object
+	if contentMD5 := c.Get(contentMD5Header); contentMD5 != "" {
+		matches, err := matchesContentMD5(contentMD5, body)
+		switch {
+		case errors.Is(err, errInvalidContentMD5):
+			return s3ErrorResponse(c, fiber.StatusBadRequest, "InvalidDigest", "The Content-MD5 you specified was invalid")
+		case err != nil:
+			return err
+		case !matches:
+			return s3ErrorResponse(c, fiber.StatusBadRequest, "BadDigest", "The Content-MD5 you specified did not match what we received")
+		}
+	}
+
+	result, err := s.objects(c).AddOrUpdateObject(s.requestContext(c), c.Params("key"), body, gateway.UploadOptions{})
+	if err != nil {
+		return s.s3ServiceError(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, quotedETag(result.ETag))
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// s3GetObjectHandler streams the object
+func (s *Server) s3GetObjectHandler(c *fiber.Ctx) error {
+	objectId := c.Params("key")
+
+	info, err := s.objects(c).StatObject(c.Context(), objectId)
+	if err != nil {
+		return s.s3ServiceError(c, err)
+	}
+
+	res, err := s.objects(c).GetObject(c.Context(), objectId)
+	if err != nil {
+		return s.s3ServiceError(c, err)
+	}
+
+	setS3ObjectHeaders(c, info)
+	return c.Status(fiber.StatusOK).SendStream(res, int(objectSize(res, info)))
+}
+
+// s3HeadObjectHandler returns the metadata of the object
+func (s *Server) s3HeadObjectHandler(c *fiber.Ctx) error {
+	info, err := s.objects(c).StatObject(c.Context(), c.Params("key"))
+	if err != nil {
+		// The HEAD responses have no body
+		return c.SendStatus(s3ErrorStatus(err))
+	}
+
+	setS3ObjectHeaders(c, info)
+	c.Response().Header.SetContentLength(int(info.Size))
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// s3DeleteObjectHandler deletes the object. As in S3, deleting a missing object succeeds.
+func (s *Server) s3DeleteObjectHandler(c *fiber.Ctx) error {
+	err := s.objects(c).DeleteObject(s.requestContext(c), c.Params("key"))
+	if err != nil && !errors.Is(err, s3.ErrObjectNotFound) {
+		return s.s3ServiceError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// s3ListObjectsHandler lists a page of the objects in the bucket, as ListObjectsV2. The continuation token is the
+// last key of the previous page. Also serves GetBucketLocation, addressed to the same path.
+func (s *Server) s3ListObjectsHandler(c *fiber.Ctx) error {
+	// The S3 clients look up the region of the bucket before the first request
+	if c.Request().URI().QueryArgs().Has("location") {
+		return c.Status(fiber.StatusOK).XML(locationConstraint{})
+	}
+
+	if c.Query("list-type") != "2" {
+		return s3ErrorResponse(c, fiber.StatusNotImplemented, "NotImplemented", "Only ListObjectsV2 is supported")
+	}
+
+	prefix := c.Query("prefix")
+	if !middleware.IsValidListFilter(prefix, "") {
+		return s3ErrorResponse(c, fiber.StatusBadRequest, "InvalidArgument", "Invalid prefix")
+	}
+
+	maxKeys := s3MaxKeys
+	if value := c.Query("max-keys"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return s3ErrorResponse(c, fiber.StatusBadRequest, "InvalidArgument", "Invalid max-keys")
+		}
+
+		maxKeys = min(parsed, s3MaxKeys)
+	}
+
+	summaries, err := s.objects(c).GetObjectsWithMetadata(c.Context(), s3.ListFilter{Prefix: prefix})
+	if err != nil && !errors.Is(err, gateway.ErrListTruncated) {
+		return s.s3ServiceError(c, err)
+	}
+
+	// The objects are listed from all instances - order them by key to page through them
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ObjectId < summaries[j].ObjectId
+	})
+
+	startAfter := c.Query("start-after")
+	if token := c.Query("continuation-token"); token != "" {
+		startAfter = token
+	}
+	first := sort.Search(len(summaries), func(i int) bool {
+		return summaries[i].ObjectId > startAfter
+	})
+	page := summaries[first:]
+
+	result := listBucketResult{
+		Name:              s.config.S3CompatibleBucket,
+		Prefix:            prefix,
+		StartAfter:        c.Query("start-after"),
+		ContinuationToken: c.Query("continuation-token"),
+		MaxKeys:           maxKeys,
+		Contents:          []listedS3Object{},
+	}
+	if len(page) > maxKeys {
+		page = page[:maxKeys]
+		result.IsTruncated = true
+		if maxKeys > 0 {
+			result.NextContinuationToken = page[len(page)-1].ObjectId
+		}
+	}
+
+	for _, summary := range page {
+		result.Contents = append(result.Contents, listedS3Object{
+			Key:          summary.ObjectId,
+			LastModified: summary.LastModified.UTC().Format(time.RFC3339),
+			ETag:         quotedETag(summary.ETag),
+			Size:         summary.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	return c.Status(fiber.StatusOK).XML(result)
+}
+
+// decodeAWSChunked decodes the aws-chunked encoded body: the chunks prefixed with their hex size and signature,
+// terminated by an empty chunk and the optional trailers. The chunk signatures are not verified.
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	decoded := make([]byte, 0, len(body))
+	for {
+		header, rest, found := bytes.Cut(body, []byte("\r\n"))
+		if !found {
+			return nil, errors.New("malformed aws-chunked body")
+		}
+
+		size, _, _ := strings.Cut(string(header), ";")
+		chunkSize, err := strconv.ParseInt(size, 16, 64)
+		if err != nil || chunkSize < 0 || chunkSize > int64(len(rest)) {
+			return nil, errors.New("malformed aws-chunked chunk size")
+		}
+
+		// The trailers after the last chunk are ignored
+		if chunkSize == 0 {
+			return decoded, nil
+		}
+
+		decoded = append(decoded, rest[:chunkSize]...)
+		body, found = bytes.CutPrefix(rest[chunkSize:], []byte("\r\n"))
+		if !found {
+			return nil, errors.New("malformed aws-chunked chunk")
+		}
+	}
+}
+
+// s3ServiceError responds with the S3 error matching the error of the gateway service
+func (s *Server) s3ServiceError(c *fiber.Ctx, err error) error {
+	status := s3ErrorStatus(err)
+	if status == fiber.StatusInternalServerError || status == fiber.StatusBadGateway {
+		s.logger.Error("Failed to process request", zap.Error(err))
+	}
+	if errors.Is(err, gateway.ErrNoInstancesAvailable) {
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds)
+	}
+
+	switch status {
+	case fiber.StatusNotFound:
+		return s3ErrorResponse(c, status, "NoSuchKey", "The specified key does not exist")
+	case fiber.StatusBadRequest:
+		return s3ErrorResponse(c, status, "InvalidArgument", err.Error())
+	case fiber.StatusConflict:
+		return s3ErrorResponse(c, status, "OperationAborted", "A conflicting write to the object is in progress")
+	case fiber.StatusRequestEntityTooLarge:
+		return s3ErrorResponse(c, status, "EntityTooLarge", "The object exceeds the size limit")
+	case fiber.StatusInsufficientStorage:
+		return s3ErrorResponse(c, status, "QuotaExceeded", "The object does not fit into the quota")
+	case fiber.StatusBadGateway:
+		return s3ErrorResponse(c, status, "AccessDenied", "The S3 instance rejected the credentials of the gateway")
+	case fiber.StatusServiceUnavailable:
+		return s3ErrorResponse(c, status, "ServiceUnavailable", "No S3 instance available, retry later")
+	default:
+		return s3ErrorResponse(c, status, "InternalError", "We encountered an internal error, please try again")
+	}
+}
+
+// s3ErrorStatus maps the error of the gateway service to the status of the S3 error
+func s3ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, s3.ErrObjectNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, s3.ErrSizeMismatch):
+		return fiber.StatusBadRequest
+	case errors.Is(err, gateway.ErrWriteInProgress):
+		return fiber.StatusConflict
+	case errors.Is(err, gateway.ErrObjectTooLarge):
+		return fiber.StatusRequestEntityTooLarge
+	case errors.Is(err, gateway.ErrQuotaExceeded):
+		return fiber.StatusInsufficientStorage
+	case errors.Is(err, s3.ErrInvalidCredentials), errors.Is(err, s3.ErrAccessDenied):
+		return fiber.StatusBadGateway
+	case errors.Is(err, gateway.ErrNoInstancesAvailable), errors.Is(err, gateway.ErrInstanceUnavailable), errors.Is(err, fiber.ErrRequestTimeout):
+		return fiber.StatusServiceUnavailable
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+// s3ErrorResponse responds with the S3 error body
+func s3ErrorResponse(c *fiber.Ctx, status int, code, message string) error {
+	return c.Status(status).XML(s3Error{
+		Code:      code,
+		Message:   message,
+		Resource:  c.Path(),
+		RequestId: c.GetRespHeader(fiber.HeaderXRequestID),
+	})
+}
+
+// setS3ObjectHeaders sets the metadata headers of the object responses
+func setS3ObjectHeaders(c *fiber.Ctx, info *s3.ObjectInfo) {
+	c.Set(fiber.HeaderLastModified, info.LastModified.UTC().Format(http.TimeFormat))
+	c.Set(fiber.HeaderETag, quotedETag(info.ETag))
+	if info.ContentType != "" {
+		c.Set(fiber.HeaderContentType, info.ContentType)
+	}
+}
+
+// quotedETag quotes the ETag, as it is sent by S3
+func quotedETag(etag string) string {
+	return fmt.Sprintf(`"%s"`, strings.Trim(etag, `"`))
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testS3Bucket = "bucket"
+
+// newS3CompatibleClient serves the router on a local port and returns a MinIO client using its S3-compatible routes
+func newS3CompatibleClient(t *testing.T, server *Server) *minio.Client {
+	t.Helper()
+
+	client, err := minio.New(listen(t, server), &minio.Options{
+		Creds:        credentials.NewStaticV4("access", "secret", ""),
+		BucketLookup: minio.BucketLookupPath,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestS3Compatible_PutObject(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{S3CompatibleBucket: testS3Bucket})
+	client := newS3CompatibleClient(t, server)
+	ctx := context.Background()
+
+	data := []byte("hello world")
+	_, err := client.PutObject(ctx, testS3Bucket, "object", bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{SendContentMd5: true})
+	require.NoError(t, err)
+	assert.Equal(t, data, clients[1].Object("object").Data)
+
+	object, err := client.GetObject(ctx, testS3Bucket, "object", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	defer object.Close()
+	downloaded, err := io.ReadAll(object)
+	require.NoError(t, err)
+	assert.Equal(t, data, downloaded)
+}
+
+func TestS3Compatible_PutObjectValidation(t *testing.T) {
+	data := []byte("hello world")
+	tests := []struct {
+		name    string
+		config  Config
+		data    []byte
+		options minio.PutObjectOptions
+		status  int
+		code    string
+	}{
+		{
+			name:    "denied content type",
+			config:  Config{UploadContentTypeDenylist: []string{"application/x-msdownload"}},
+			data:    data,
+			options: minio.PutObjectOptions{ContentType: "application/x-msdownload"},
+			status:  http.StatusUnsupportedMediaType,
+			code:    "InvalidArgument",
+		},
+		{
+			name:    "content type not allowed",
+			config:  Config{UploadContentTypeAllowlist: []string{"image/*"}},
+			data:    data,
+			options: minio.PutObjectOptions{ContentType: "text/plain"},
+			status:  http.StatusUnsupportedMediaType,
+			code:    "InvalidArgument",
+		},
+		{
+			name:   "empty object",
+			config: Config{RejectEmptyUploads: true},
+			status: http.StatusBadRequest,
+			code:   "InvalidArgument",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.S3CompatibleBucket = testS3Bucket
+			server, clients := newTestServer(t, 1, tt.config)
+			client := newS3CompatibleClient(t, server)
+
+			_, err := client.PutObject(context.Background(), testS3Bucket, "object", bytes.NewReader(tt.data), int64(len(tt.data)), tt.options)
+			require.Error(t, err)
+			response := minio.ToErrorResponse(err)
+			assert.Equal(t, tt.status, response.StatusCode)
+			assert.Equal(t, tt.code, response.Code)
+			assert.Empty(t, clients[1].Keys())
+		})
+	}
+
+	t.Run("allowed content type", func(t *testing.T) {
+		server, _ := newTestServer(t, 1, Config{S3CompatibleBucket: testS3Bucket, UploadContentTypeAllowlist: []string{"text/*"}})
+		client := newS3CompatibleClient(t, server)
+
+		_, err := client.PutObject(context.Background(), testS3Bucket, "object", bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: "text/plain"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestS3Compatible_PutObjectContentMD5(t *testing.T) {
+	data := []byte("hello world")
+	otherDigest := md5.Sum([]byte("other"))
+
+	tests := []struct {
+		name       string
+		contentMD5 string
+		code       string
+	}{
+		{name: "mismatch", contentMD5: base64.StdEncoding.EncodeToString(otherDigest[:]), code: "BadDigest"},
+		{name: "invalid", contentMD5: "not-a-digest", code: "InvalidDigest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clients := newTestServer(t, 1, Config{S3CompatibleBucket: testS3Bucket})
+
+			res, body := do(t, server, newRequest(http.MethodPut, "/"+testS3Bucket+"/object", bytes.NewReader(data), contentMD5Header, tt.contentMD5))
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			assert.Contains(t, body, "<Code>"+tt.code+"</Code>")
+			assert.Empty(t, clients[1].Keys())
+		})
+	}
+}
+
+func TestS3Compatible_RefusesMultipartParts(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{S3CompatibleBucket: testS3Bucket})
+
+	res, body := do(t, server, newRequest(http.MethodPut, "/"+testS3Bucket+"/object?partNumber=1&uploadId=upload", bytes.NewReader([]byte("part"))))
+	assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+	assert.Contains(t, body, "<Code>NotImplemented</Code>")
+	assert.Empty(t, clients[1].Keys())
+}
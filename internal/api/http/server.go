@@ -1,153 +1,1334 @@
 package http
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/gofiber/contrib/fiberzap/v2"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/healthcheck"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/timeout"
+	"github.com/gofiber/swagger"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
 	"github.com/spacelift-io/homework-object-storage/internal/gateway"
 	"github.com/spacelift-io/homework-object-storage/internal/models/api"
 	"github.com/spacelift-io/homework-object-storage/internal/pkg/http/middleware"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/observability"
 	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+const expireSecondsHeader = "X-Expire-Seconds"
+
+// retryAfterSeconds is the Retry-After sent when no instances are available
+const retryAfterSeconds = "5"
+
+// watchKeepAliveInterval is the interval of the comments sent to the clients watching an object
+var watchKeepAliveInterval = time.Second * 15
+
+// keyPrefixLocal is the key of the request's object key prefix in the fiber locals
+const keyPrefixLocal = "keyPrefix"
+
+// multipartOverhead is the room left in the body limit for the multipart form around the uploaded file
+const multipartOverhead = 64 * 1024
+
+// Config configures the HTTP server
+type Config struct {
+	// AdminAPIKey protects the admin routes
+	AdminAPIKey string
+	// AuthHandlers protect the gateway routes
+	AuthHandlers []fiber.Handler
+	// HealthReporter serves the pre-computed health of the instances on /healthz. Optional.
+	HealthReporter discovery.HealthReporter
+	// DaemonReporter adds the Docker daemon info to the /ready payload. Optional.
+	DaemonReporter discovery.DaemonReporter
+	// StatusReporter adds the state of the discovery to the /ready payload. Optional.
+	StatusReporter discovery.StatusReporter
+	// ClientCreationReporter serves the S3 client creation rate on the admin routes. Optional.
+	ClientCreationReporter gateway.ClientCreationReporter
+	// SkipReporter adds the containers skipped by the discovery to the /ready payload. Optional.
+	SkipReporter discovery.SkipReporter
+	// VersioningEnabled serves the object versions. Requires the versioning to be enabled on the bucket.
+	VersioningEnabled bool
+	// ObjectNotificationsEnabled serves the object watch route. Requires the bucket notifications to be enabled on the
+	// S3 instances.
+	ObjectNotificationsEnabled bool
+	// Heartbeat fails the liveness probe when it goes stale. The process is always live if not set.
+	Heartbeat *observability.Heartbeat
+	// KeyPrefix is prepended to all object keys. The X-Namespace header appends a namespace to it.
+	KeyPrefix string
+	// LogLevel is changed on the admin log level routes. The routes are not served if not set.
+	LogLevel *zap.AtomicLevel
+	// AllowInstancePinning lets the clients bypass the sharding with the X-Instance-Pin header
+	AllowInstancePinning bool
+	// TrustedProxyCIDRs are the addresses of the proxies whose X-Forwarded-For and X-Real-IP headers are trusted
+	TrustedProxyCIDRs []string
+	// RejectEmptyUploads rejects the zero-byte files instead of storing empty objects
+	RejectEmptyUploads bool
+	// UploadContentTypeAllowlist and UploadContentTypeDenylist restrict the content types of the uploaded files.
+	// Disabled when both are empty.
+	UploadContentTypeAllowlist []string
+	UploadContentTypeDenylist  []string
+	// ReadAfterWriteConsistency returns the instance an object was written to in the X-Written-Instance header. The
+	// clients forward it in the X-Read-From-Instance header to read their own writes before the replicas catch up.
+	ReadAfterWriteConsistency bool
+	// S3CompatibleBucket serves the path-style object operations of the S3 REST API under /<bucket>, if set
+	S3CompatibleBucket string
+	// Prefork serves the requests from a child process per CPU core, listening on the same port. The master process
+	// only supervises the children. Each child keeps its own metrics, the Idempotency-Key header is ignored.
+	Prefork bool
+	// IdempotencyTTL is how long the uploads are remembered by their Idempotency-Key header, so the retried uploads
+	// return the original result. Disabled if not positive.
+	IdempotencyTTL time.Duration
+	// MaxObjectSize bounds the request bodies to the largest object and the multipart form around it. Fiber's default
+	// body limit applies if not positive.
+	MaxObjectSize int64
+}
+
 type Server struct {
 	logger         *zap.Logger
+	logLevel       *zap.AtomicLevel
 	gatewayService gateway.Service
 	app            *fiber.App
+	config         Config
+	idempotency    *idempotencyStore
+
+	// Processes serving the requests, started by the prefork master
+	childrenMu sync.Mutex
+	children   []int
+	// Set once the shutdown was forwarded to the children, so their exit is expected
+	childrenStopped bool
 }
 
-func NewServer(logger *zap.Logger, service gateway.Service) *Server {
+// NewServer creates a new HTTP server
+func NewServer(logger *zap.Logger, service gateway.Service, serverConfig Config) *Server {
 	// Initialize a new Fiber app with a custom error handler
 	fiberConfig := fiber.Config{
 		ErrorHandler: middleware.FiberErrorHandler(),
 		AppName:      "S3 Gateway",
 		ServerHeader: "S3-Gateway",
+		Prefork:      serverConfig.Prefork,
+		BodyLimit:    bodyLimit(serverConfig.MaxObjectSize),
+		// The object IDs outlive the requests, e.g. as the read cache keys - they must not point to the reused buffers
+		Immutable: true,
 	}
 	app := fiber.New(fiberConfig)
 
-	// Use zap logger middleware
-	config := fiberzap.ConfigDefault
-	config.Logger = logger
-
-	// Create a new health check middleware
+	// Create a new health check middleware - the readiness is served by the readyHandler, which adds a payload
 	healthCheck := healthcheck.New(healthcheck.Config{
+		Next: func(c *fiber.Ctx) bool {
+			return c.Path() == "/ready"
+		},
 		LivenessProbe: func(c *fiber.Ctx) bool {
-			return true
+			if serverConfig.Heartbeat == nil {
+				return true
+			}
+
+			return serverConfig.Heartbeat.Alive()
 		},
 		LivenessEndpoint: "/live",
-		ReadinessProbe: func(c *fiber.Ctx) bool {
-			return service.Ready(c.Context())
-		},
-		ReadinessEndpoint: "/ready",
 	})
 
 	recoveryConfig := recover.Config{
 		EnableStackTrace: true,
 	}
-	// Add logger, recovery, timeout and health check middleware
-	app.Use(fiberzap.New(config), recover.New(recoveryConfig), healthCheck)
+	// Expose the download headers to browsers
+	corsConfig := cors.Config{
+		ExposeHeaders: strings.Join([]string{fiber.HeaderContentDisposition, fiber.HeaderXRequestID, contentMD5ValidatedHeader, objectSizeHeader, listTruncatedHeader, idempotentReplayedHeader, writtenInstanceHeader}, ","),
+	}
+
+	// Add request ID, logger, recovery, CORS, timeout and health check middleware
+	app.Use(middleware.RequestIDMiddleware(), middleware.TrustProxies(serverConfig.TrustedProxyCIDRs), middleware.RequestLogger(logger), recover.New(recoveryConfig), cors.New(corsConfig), healthCheck)
 
-	return &Server{
+	server := &Server{
 		logger:         logger,
+		logLevel:       serverConfig.LogLevel,
 		gatewayService: service,
 		app:            app,
+		config:         serverConfig,
+	}
+	// The children don't share the remembered uploads, so a retry served by another child would be uploaded again
+	switch {
+	case serverConfig.IdempotencyTTL > 0 && serverConfig.Prefork:
+		logger.Warn("Idempotency keys are disabled in the prefork mode")
+	case serverConfig.IdempotencyTTL > 0:
+		server.idempotency = newIdempotencyStore(serverConfig.IdempotencyTTL)
+	}
+	if serverConfig.Prefork && !IsPreforkChild() {
+		server.recordPreforkChildren()
 	}
+
+	return server
+}
+
+// TLSConfig configures TLS termination of the server. TLS is disabled when no certificate is configured.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS - clients must present a certificate signed by the CA
+	ClientCAFile string
+}
+
+// Enabled returns true if a certificate and key are configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
 }
 
 // Run starts the server that will listen on the given address
-func (s *Server) Run(listenAddress string) {
-	// Mount gateway routes
+func (s *Server) Run(listenAddress string, tlsConfig TLSConfig) {
+	// Mount documentation, health, metrics and gateway routes - all but the gateway routes are public
+	s.docsRoutes()
+	s.app.Get("/healthz", s.healthzHandler)
+	s.app.Get("/ready", s.readyHandler)
+	s.app.Get("/metrics", observability.MetricsHandler())
 	s.gatewayRoutes()
 
-	// Start the server on port 3000
-	err := s.app.Listen(listenAddress)
+	var err error
+	switch {
+	case tlsConfig.Enabled() && tlsConfig.ClientCAFile != "":
+		s.logger.Info("Starting server with mutual TLS")
+		err = s.app.ListenMutualTLS(listenAddress, tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ClientCAFile)
+	case tlsConfig.Enabled():
+		s.logger.Info("Starting server with TLS")
+		err = s.app.ListenTLS(listenAddress, tlsConfig.CertFile, tlsConfig.KeyFile)
+	default:
+		err = s.app.Listen(listenAddress)
+	}
+	if err != nil && s.preforkChildrenStopped() {
+		return
+	}
 	if err != nil {
 		s.logger.Fatal("failed to start server", zap.Error(err))
 	}
 }
 
+// Shutdown stops accepting connections and waits for the requests in flight to complete, up to the timeout.
+// Run returns once the server is shut down. The prefork master forwards the shutdown to the children instead.
+func (s *Server) Shutdown(timeout time.Duration) error {
+	s.logger.Info("Shutting down server")
+	if s.config.Prefork && !IsPreforkChild() {
+		return s.shutdownPreforkChildren()
+	}
+
+	return s.app.ShutdownWithTimeout(timeout)
+}
+
 // gatewayRoutes defines the routes for the gateway gatewayService
 func (s *Server) gatewayRoutes() {
-	group := s.app.Group("/object")
+	routerHandlers := append(append([]fiber.Handler{}, s.config.AuthHandlers...), s.namespaceMiddleware())
+	router := s.app.Group("", routerHandlers...)
+
+	uploadHandlers := []fiber.Handler{middleware.ValidateContentType("multipart/form-data"), middleware.ValidateObjectId(), middleware.ValidateMultipartFile("file")}
+	if len(s.config.UploadContentTypeAllowlist) > 0 || len(s.config.UploadContentTypeDenylist) > 0 {
+		uploadHandlers = append(uploadHandlers, middleware.ValidateFileContentType("file", s.config.UploadContentTypeAllowlist, s.config.UploadContentTypeDenylist))
+	}
+
+	group := router.Group("/object")
+	group.Put("/:id", append(uploadHandlers, timeout.NewWithContext(s.uploadHandler, time.Second*30))...)
+	group.Head("/:id", middleware.ValidateObjectId(), timeout.NewWithContext(s.headHandler, time.Second*30))
+	group.Get("/:id", middleware.ValidateObjectId(), timeout.NewWithContext(s.downloadHandler, time.Second*30))
+	group.Delete("/:id", middleware.ValidateObjectId(), timeout.NewWithContext(s.deleteHandler, time.Second*30))
+	group.Post("/:id/rename", middleware.ValidateObjectId(), timeout.NewWithContext(s.renameHandler, time.Minute))
+	group.Post("/:id/export", middleware.APIKeyMiddleware(s.config.AdminAPIKey), middleware.ValidateObjectId(), timeout.NewWithContext(s.exportHandler, time.Second*30))
+	group.Get("/:id/export/:jobId", middleware.APIKeyMiddleware(s.config.AdminAPIKey), middleware.ValidateObjectId(), s.exportStatusHandler)
+
+	router.Get("/objects", timeout.NewWithContext(s.listHandler, time.Second*30))
+	router.Delete("/objects", middleware.APIKeyMiddleware(s.config.AdminAPIKey), timeout.NewWithContext(s.deleteByPrefixHandler, time.Minute*5))
+	router.Get("/objects/stream", s.streamHandler)
+	if s.config.VersioningEnabled {
+		router.Get("/object/:id/versions", middleware.ValidateObjectId(), timeout.NewWithContext(s.versionsHandler, time.Second*30))
+	}
+	if s.config.ObjectNotificationsEnabled {
+		router.Post("/object/:id/watch", middleware.ValidateObjectId(), s.watchHandler)
+	}
+	router.Get("/objects/count", timeout.NewWithContext(s.countHandler, time.Second*30))
+	router.Post("/objects/migrate", middleware.APIKeyMiddleware(s.config.AdminAPIKey), timeout.NewWithContext(s.migrateHandler, time.Minute*5))
 
-	uploadHandler := func(c *fiber.Ctx) error {
-		c.Accepts("application/json")
+	if s.config.S3CompatibleBucket != "" {
+		s.s3CompatibleRoutes(router)
+	}
 
-		objectId := c.Params("id")
-		// Validate objectId
+	admin := router.Group("/admin", middleware.APIKeyMiddleware(s.config.AdminAPIKey))
+	admin.Post("/bulk-delete-by-prefix", timeout.NewWithContext(s.bulkDeleteHandler, time.Minute*5))
+	admin.Get("/instances", timeout.NewWithContext(s.instancesHandler, time.Second*30))
+	if s.config.ClientCreationReporter != nil {
+		admin.Get("/metrics/client-creation-rate", s.clientCreationRateHandler)
+	}
+	if s.logLevel != nil {
+		admin.Get("/log-level", s.getLogLevelHandler)
+		admin.Put("/log-level", s.setLogLevelHandler)
+	}
+}
 
-		// Get file from form
-		file, err := c.FormFile("file")
+// namespaceMiddleware resolves the key prefix of the request from the configured prefix and the X-Namespace header
+func (s *Server) namespaceMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		prefix, err := keyPrefix(s.config.KeyPrefix, c.Get(namespaceHeader))
 		if err != nil {
-			return err
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: err.Error()})
+		}
+
+		c.Locals(keyPrefixLocal, prefix)
+		return c.Next()
+	}
+}
+
+// objects returns the gateway service storing the objects under the key prefix of the request.
+// The admin routes use the gateway service directly and address the full keys.
+func (s *Server) objects(c *fiber.Ctx) gateway.Service {
+	prefix, _ := c.Locals(keyPrefixLocal).(string)
+	return gateway.WithNamespace(s.gatewayService, prefix)
+}
+
+// bodyLimit returns the body limit of the requests uploading the objects of at most the given size, zero for the
+// default limit
+func bodyLimit(maxObjectSize int64) int {
+	if maxObjectSize <= 0 {
+		return 0
+	}
+
+	return int(maxObjectSize) + multipartOverhead
+}
+
+// requestContext returns the context of the request carrying the requester, recorded in the audit events of the
+// operations modifying the objects
+func (s *Server) requestContext(c *fiber.Ctx) context.Context {
+	subject, _ := c.Locals(middleware.SubjectKey).(string)
+	return gateway.WithRequester(c.Context(), gateway.Requester{ClientIP: middleware.ClientIP(c), Subject: subject})
+}
+
+// docsRoutes serves the OpenAPI spec and the interactive Swagger UI
+func (s *Server) docsRoutes() {
+	s.app.Get("/openapi.json", s.openAPIHandler())
+	s.app.Get("/docs/*", swagger.HandlerDefault)
+}
+
+// uploadHandler uploads an object to one of the S3 instances
+//
+//	@Summary		Upload an object
+//	@Description	Upload a file with the given id. If the object already exists, it is overwritten. The form must contain exactly one file, named with an extension. Empty files are rejected with 400, if configured.
+//	@Tags			objects
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			id					path		string	true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			file				formData	file	true	"Object content"
+//	@Param			X-Expire-Seconds	header		int		false	"Delete the object after the given number of seconds"
+//	@Param			Content-MD5			header		string	false	"Base64-encoded MD5 of the file, verified before storing the object"
+//	@Param			X-Instance-Pin		header		int		false	"Store the object on this instance, bypassing the sharding (if pinning is allowed)"
+//	@Param			X-Namespace			header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Param			Idempotency-Key		header		string	false	"Return the result of the earlier upload of the same file with the same key instead of uploading it again (if enabled). Reusing the key for a different file fails with 422."
+//	@Success		201					{object}	api.UploadResponse
+//	@Header			201					{string}	Location				"Path of the uploaded object"
+//	@Header			201					{string}	X-Content-MD5-Validated	"true if the Content-MD5 header was verified"
+//	@Header			201					{integer}	X-Object-Size			"Number of bytes received and stored, to compare with the local file size"
+//	@Header			201					{string}	Idempotent-Replayed		"true if the result of an earlier upload with the same Idempotency-Key was returned"
+//	@Header			201					{integer}	X-Written-Instance		"Instance the object was written to, to be forwarded in the X-Read-From-Instance header (if read-after-write consistency is enabled)"
+//	@Failure		400					{object}	api.ErrorResponse
+//	@Failure		403					{object}	api.ErrorResponse
+//	@Failure		409					{object}	api.ErrorResponse
+//	@Failure		413					{object}	api.ErrorResponse
+//	@Failure		415					{object}	api.ErrorResponse
+//	@Failure		422					{object}	api.ErrorResponse
+//	@Failure		500					{object}	api.ErrorResponse
+//	@Failure		502					{object}	api.ErrorResponse
+//	@Failure		503					{object}	api.ErrorResponse
+//	@Header			503					{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Failure		507					{object}	api.ErrorResponse
+//	@Router			/object/{id} [put]
+func (s *Server) uploadHandler(c *fiber.Ctx) error {
+	c.Accepts("application/json")
+
+	objectId := c.Params("id")
+	// Validate objectId
+
+	idempotencyKey := c.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > idempotencyKeyMaxLength {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: fmt.Sprintf("Invalid %s header", idempotencyKeyHeader)})
+	}
+
+	// Get file from form
+	file, err := c.FormFile("file")
+	if err != nil {
+		return err
+	}
+
+	if file.Size == 0 && s.config.RejectEmptyUploads {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Empty files are not allowed"})
+	}
+
+	// Parse the optional object expiry
+	options := gateway.UploadOptions{}
+	if expireSeconds := c.Get(expireSecondsHeader); expireSeconds != "" {
+		seconds, err := strconv.Atoi(expireSeconds)
+		if err != nil || seconds <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: fmt.Sprintf("Invalid %s header", expireSecondsHeader)})
 		}
 
-		buffer, err := file.Open()
+		options.ExpiresIn = time.Duration(seconds) * time.Second
+	}
+
+	// Store the object on the pinned instance, if allowed
+	options.PinnedInstance, err = instancePin(c.Get(instancePinHeader), s.config.AllowInstancePinning)
+	switch {
+	case errors.Is(err, errInstancePinningDisabled):
+		return c.Status(fiber.StatusForbidden).JSON(api.ErrorResponse{Code: api.ErrorCodeForbidden, Message: err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: err.Error()})
+	}
+
+	buffer, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer buffer.Close()
+
+	// Return the result of the earlier upload with the same idempotency key, if remembered. The key may not be reused
+	// for a different file.
+	keyPrefix, _ := c.Locals(keyPrefixLocal).(string)
+	var fingerprint uploadFingerprint
+	if s.idempotency != nil && idempotencyKey != "" {
+		fingerprint, err = fingerprintUpload(buffer)
 		if err != nil {
 			return err
 		}
-		defer buffer.Close()
 
-		// Call the gatewayService to upload the object
-		err = s.gatewayService.AddOrUpdateObject(c.Context(), objectId, buffer)
+		if upload, ok := s.idempotency.get(keyPrefix, objectId, idempotencyKey); ok {
+			if upload.fingerprint != fingerprint {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(api.ErrorResponse{Code: api.ErrorCodeIdempotencyKeyReused, Message: fmt.Sprintf("The %s was used for an upload of a different file", idempotencyKeyHeader)})
+			}
+
+			c.Location("/object/" + objectId)
+			c.Set(objectSizeHeader, strconv.FormatInt(upload.response.Size, 10))
+			c.Set(idempotentReplayedHeader, "true")
+			s.setWrittenInstance(c, upload.response.Instance)
+			return c.Status(fiber.StatusCreated).JSON(upload.response)
+		}
+	}
+
+	// Verify the optional checksum before storing the object
+	if contentMD5 := c.Get(contentMD5Header); contentMD5 != "" {
+		matches, err := matchesContentMD5(contentMD5, buffer)
 		switch {
-		case err == nil:
-			return c.Status(fiber.StatusCreated).JSON(api.ErrorResponse{Message: "Object uploaded successfully"})
-		case errors.Is(err, fiber.ErrRequestTimeout):
-			s.logger.Error("Failed to process request", zap.Error(err))
-			return c.Status(fiber.StatusServiceUnavailable).JSON(api.ErrorResponse{Message: "Request timed out"})
-		default:
-			s.logger.Error("Failed to process request", zap.Error(err))
-			return c.Status(fiber.StatusInternalServerError).JSON(api.ErrorResponse{Message: "Failed to upload object"})
+		case errors.Is(err, errInvalidContentMD5):
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: err.Error()})
+		case err != nil:
+			return err
+		case !matches:
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeChecksumMismatch, Message: "Content-MD5 does not match the uploaded file"})
 		}
+
+		c.Set(contentMD5ValidatedHeader, "true")
 	}
 
-	downloadHandler := func(c *fiber.Ctx) error {
-		c.Accepts("multipart/form-data")
+	// Call the gatewayService to upload the object
+	result, err := s.objects(c).AddOrUpdateObject(s.requestContext(c), objectId, buffer, options)
+	switch {
+	case err == nil:
+		response := api.UploadResponse{
+			Id:       objectId,
+			Instance: result.InstanceNum,
+			ETag:     result.ETag,
+			Size:     result.Size,
+		}
+		if s.idempotency != nil && idempotencyKey != "" {
+			s.idempotency.add(keyPrefix, objectId, idempotencyKey, fingerprint, response)
+		}
 
-		objectId := c.Params("id")
+		c.Location("/object/" + objectId)
+		c.Set(objectSizeHeader, strconv.FormatInt(result.Size, 10))
+		s.setWrittenInstance(c, result.InstanceNum)
+		return c.Status(fiber.StatusCreated).JSON(response)
+	case errors.Is(err, gateway.ErrInstanceNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Pinned instance does not exist"})
+	default:
+		return s.sendError(c, err, "Failed to upload object")
+	}
+}
 
-		// Call the gatewayService to download the object
-		res, err := s.gatewayService.GetObject(c.Context(), objectId)
-		switch {
-		case err == nil:
-			return c.Status(fiber.StatusOK).SendStream(res)
-		case errors.Is(err, s3.ErrObjectNotFound):
-			s.logger.Error("Failed to process request", zap.Error(err))
-			return c.Status(fiber.StatusNotFound).JSON(api.ErrorResponse{Message: "Object not found"})
-		case errors.Is(err, fiber.ErrRequestTimeout):
-			s.logger.Error("Failed to process request", zap.Error(err))
-			return c.Status(fiber.StatusServiceUnavailable).JSON(api.ErrorResponse{Message: "Request timed out"})
-		default:
-			s.logger.Error("Failed to process request", zap.Error(err))
-			return c.Status(fiber.StatusInternalServerError).JSON(api.ErrorResponse{Message: "Failed to download object"})
+// downloadHandler downloads an object from one of the S3 instances
+//
+//	@Summary		Download an object
+//	@Description	Get the content of the object with the given id
+//	@Tags			objects
+//	@Produce		octet-stream
+//	@Param			id						path		string	true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			filename				query		string	false	"Prompt the browser to save the object with this filename"
+//	@Param			If-None-Match			header		string	false	"Return 304 if the object ETag matches"
+//	@Param			If-Modified-Since		header		string	false	"Return 304 if the object was not modified since"
+//	@Param			X-Instance-Pin			header		int		false	"Read the object from this instance, bypassing the sharding (if pinning is allowed)"
+//	@Param			X-Read-From-Instance	header		int		false	"Read the object from the instance it was written to, from the X-Written-Instance upload header (if read-after-write consistency is enabled)"
+//	@Param			versionId				query		string	false	"Version of the object (if versioning is enabled)"
+//	@Param			X-Namespace				header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200						{file}		binary
+//	@Header			200						{string}	Content-Disposition	"attachment; filename=\"<filename>\" or inline"
+//	@Success		304
+//	@Failure		400	{object}	api.ErrorResponse
+//	@Failure		403	{object}	api.ErrorResponse
+//	@Failure		404	{object}	api.ErrorResponse
+//	@Failure		500	{object}	api.ErrorResponse
+//	@Failure		502	{object}	api.ErrorResponse
+//	@Failure		503	{object}	api.ErrorResponse
+//	@Header			503	{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/object/{id} [get]
+func (s *Server) downloadHandler(c *fiber.Ctx) error {
+	c.Accepts("multipart/form-data")
+
+	objectId := c.Params("id")
+
+	// Read the object from the pinned instance, if allowed
+	pin, err := instancePin(c.Get(instancePinHeader), s.config.AllowInstancePinning)
+	switch {
+	case errors.Is(err, errInstancePinningDisabled):
+		return c.Status(fiber.StatusForbidden).JSON(api.ErrorResponse{Code: api.ErrorCodeForbidden, Message: err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: err.Error()})
+	}
+
+	versionId, err := objectVersion(c.Query("versionId"), s.config.VersioningEnabled)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: err.Error()})
+	}
+
+	if versionId != "" && pin != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: fmt.Sprintf("versionId can't be combined with the %s header", instancePinHeader)})
+	}
+
+	// Read the object from the instance it was written to, so a fresh write is not missed on a lagging replica
+	readAfterWrite := false
+	if pin == nil && versionId == "" && s.config.ReadAfterWriteConsistency && c.Get(readFromInstanceHeader) != "" {
+		pin, err = instancePin(c.Get(readFromInstanceHeader), true)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: fmt.Sprintf("Invalid %s header", readFromInstanceHeader)})
 		}
+
+		readAfterWrite = true
+	}
+
+	// Stat the object first, so the conditional request headers can be evaluated before streaming the body
+	var info *s3.ObjectInfo
+	switch {
+	case versionId != "":
+		info, err = s.objects(c).StatObjectVersion(c.Context(), objectId, versionId)
+	case pin != nil:
+		info, err = s.objects(c).StatObjectFromInstance(c.Context(), objectId, *pin)
+	default:
+		info, err = s.objects(c).StatObject(c.Context(), objectId)
 	}
 
-	group.Put("/:id", middleware.ValidateContentType("multipart/form-data"), middleware.ValidateObjectId(), timeout.NewWithContext(uploadHandler, time.Second*30))
-	group.Get("/:id", middleware.ValidateObjectId(), timeout.NewWithContext(downloadHandler, time.Second*30))
+	// The object may have moved since it was written, e.g. by a rebalance
+	if readAfterWrite && (errors.Is(err, s3.ErrObjectNotFound) || errors.Is(err, gateway.ErrInstanceNotFound)) {
+		pin = nil
+		info, err = s.objects(c).StatObject(c.Context(), objectId)
+	}
 
-	listHandler := func(c *fiber.Ctx) error {
-		// List all objects from s3 instances
+	if err == nil {
+		c.Set(fiber.HeaderLastModified, info.LastModified.UTC().Format(http.TimeFormat))
+		c.Set(fiber.HeaderETag, fmt.Sprintf(`"%s"`, strings.Trim(info.ETag, `"`)))
 
-		res, err := s.gatewayService.GetObjects(c.Context())
-		switch {
-		case err == nil:
-			return c.Status(fiber.StatusOK).JSON(res)
-		case errors.Is(err, fiber.ErrRequestTimeout):
-			s.logger.Error("Failed to process request", zap.Error(err))
-			return c.Status(fiber.StatusServiceUnavailable).JSON(api.ErrorResponse{Message: "Request timed out"})
-		default:
-			s.logger.Error("Failed to process request", zap.Error(err))
-			return c.Status(fiber.StatusInternalServerError).JSON(api.ErrorResponse{Message: "Failed to download object"})
+		if notModified(c.Get(fiber.HeaderIfNoneMatch), c.Get(fiber.HeaderIfModifiedSince), info) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+	}
+
+	// Call the gatewayService to download the object
+	var res io.Reader
+	switch {
+	case err == nil && versionId != "":
+		res, err = s.objects(c).GetObjectVersion(c.Context(), objectId, versionId)
+	case err == nil && pin != nil:
+		res, err = s.objects(c).GetObjectFromInstance(c.Context(), objectId, *pin)
+	case err == nil:
+		res, err = s.objects(c).GetObject(c.Context(), objectId)
+	}
+
+	switch {
+	case err == nil:
+		c.Set(fiber.HeaderContentDisposition, contentDisposition(c.Query("filename")))
+
+		// A known size streams the body with a fixed Content-Length instead of buffering it. The size of the object
+		// read is preferred, the object may have been overwritten since it was stat'ed.
+		return c.Status(fiber.StatusOK).SendStream(res, int(objectSize(res, info)))
+	case errors.Is(err, gateway.ErrInstanceNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Pinned instance does not exist"})
+	default:
+		return s.sendError(c, err, "Failed to download object")
+	}
+}
+
+// headHandler returns the metadata of an object without its content
+//
+//	@Summary		Get object metadata
+//	@Description	Get the size, ETag and last modification time of the object with the given id
+//	@Tags			objects
+//	@Param			id			path	string	true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			versionId	query	string	false	"Version of the object (if versioning is enabled)"
+//	@Param			X-Namespace	header	string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200
+//	@Header			200	{integer}	Content-Length	"Object size"
+//	@Header			200	{string}	ETag			"Object ETag"
+//	@Header			200	{string}	Last-Modified	"Last modification time"
+//	@Failure		400
+//	@Failure		404
+//	@Failure		500
+//	@Failure		502
+//	@Failure		503
+//	@Header			503	{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/object/{id} [head]
+func (s *Server) headHandler(c *fiber.Ctx) error {
+	versionId, err := objectVersion(c.Query("versionId"), s.config.VersioningEnabled)
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	var info *s3.ObjectInfo
+	if versionId != "" {
+		info, err = s.objects(c).StatObjectVersion(c.Context(), c.Params("id"), versionId)
+	} else {
+		info, err = s.objects(c).StatObject(c.Context(), c.Params("id"))
+	}
+
+	switch {
+	case err == nil:
+		c.Set(fiber.HeaderLastModified, info.LastModified.UTC().Format(http.TimeFormat))
+		c.Set(fiber.HeaderETag, fmt.Sprintf(`"%s"`, strings.Trim(info.ETag, `"`)))
+		c.Response().Header.SetContentLength(int(info.Size))
+		if info.ContentType != "" {
+			c.Set(fiber.HeaderContentType, info.ContentType)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	default:
+		// A HEAD response has no body, only the status of the error is sent
+		status, _ := errorResponse(err, "")
+		s.reportError(c, err, status)
+		return c.SendStatus(status)
+	}
+}
+
+// deleteHandler deletes an object
+//
+//	@Summary		Delete an object
+//	@Description	Delete the object with the given id, or permanently delete one of its versions
+//	@Tags			objects
+//	@Produce		json
+//	@Param			id			path	string	true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			versionId	query	string	false	"Version of the object (if versioning is enabled)"
+//	@Param			X-Namespace	header	string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		204
+//	@Failure		400	{object}	api.ErrorResponse
+//	@Failure		404	{object}	api.ErrorResponse
+//	@Failure		500	{object}	api.ErrorResponse
+//	@Failure		502	{object}	api.ErrorResponse
+//	@Failure		503	{object}	api.ErrorResponse
+//	@Header			503	{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/object/{id} [delete]
+func (s *Server) deleteHandler(c *fiber.Ctx) error {
+	versionId, err := objectVersion(c.Query("versionId"), s.config.VersioningEnabled)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: err.Error()})
+	}
+
+	if versionId != "" {
+		err = s.objects(c).DeleteObjectVersion(s.requestContext(c), c.Params("id"), versionId)
+	} else {
+		err = s.objects(c).DeleteObject(s.requestContext(c), c.Params("id"))
+	}
+
+	switch {
+	case err == nil:
+		return c.SendStatus(fiber.StatusNoContent)
+	default:
+		return s.sendError(c, err, "Failed to delete object")
+	}
+}
+
+// versionsHandler lists the versions of an object
+//
+//	@Summary		List object versions
+//	@Description	List the versions of the object with the given id, the latest first. Served if versioning is enabled.
+//	@Tags			objects
+//	@Produce		json
+//	@Param			id			path		string	true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			X-Namespace	header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200			{array}		api.ObjectVersionResponse
+//	@Failure		400			{object}	api.ErrorResponse
+//	@Failure		404			{object}	api.ErrorResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Failure		502			{object}	api.ErrorResponse
+//	@Failure		503			{object}	api.ErrorResponse
+//	@Header			503			{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/object/{id}/versions [get]
+func (s *Server) versionsHandler(c *fiber.Ctx) error {
+	versions, err := s.objects(c).ListObjectVersions(c.Context(), c.Params("id"))
+	switch {
+	case err == nil:
+		response := make([]api.ObjectVersionResponse, 0, len(versions))
+		for _, version := range versions {
+			response = append(response, api.ObjectVersionResponse{
+				VersionId:      version.VersionId,
+				Size:           version.Size,
+				LastModified:   version.LastModified,
+				ETag:           strings.Trim(version.ETag, `"`),
+				IsLatest:       version.IsLatest,
+				IsDeleteMarker: version.IsDeleteMarker,
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(response)
+	default:
+		return s.sendError(c, err, "Failed to list object versions")
+	}
+}
+
+// renameHandler renames an object
+//
+//	@Summary		Rename an object
+//	@Description	Copy the object to the new id and delete the original. The renamed object has the Renamed-From user metadata - if the original still exists next to it, the rename was interrupted.
+//	@Tags			objects
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	string				true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			request		body	api.RenameRequest	true	"New object ID"
+//	@Param			X-Namespace	header	string				false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		204
+//	@Failure		400	{object}	api.ErrorResponse
+//	@Failure		404	{object}	api.ErrorResponse
+//	@Failure		409	{object}	api.ErrorResponse
+//	@Failure		500	{object}	api.ErrorResponse
+//	@Failure		502	{object}	api.ErrorResponse
+//	@Failure		503	{object}	api.ErrorResponse
+//	@Router			/object/{id}/rename [post]
+func (s *Server) renameHandler(c *fiber.Ctx) error {
+	request := api.RenameRequest{}
+	err := c.BodyParser(&request)
+	if err != nil || !middleware.IsValidObjectId(request.NewId) {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidObjectId, Message: "Invalid new object ID"})
+	}
+
+	err = s.objects(c).RenameObject(s.requestContext(c), c.Params("id"), request.NewId)
+	switch {
+	case err == nil:
+		return c.SendStatus(fiber.StatusNoContent)
+	case errors.Is(err, gateway.ErrObjectAlreadyExists):
+		return c.Status(fiber.StatusConflict).JSON(api.ErrorResponse{Code: api.ErrorCodeObjectAlreadyExists, Message: "An object with the new ID already exists"})
+	default:
+		return s.sendError(c, err, "Failed to rename object")
+	}
+}
+
+// listHandler lists all objects from the S3 instances
+//
+//	@Summary		List objects
+//	@Description	Get all object ids from the S3 instances, optionally filtered by a prefix and a suffix. With detailed=true, the objects are listed with their size, last modification and ETag (api.ObjectSummaryResponse). The number of listed objects is limited - the X-Objects-Truncated header is set if more objects match.
+//	@Tags			objects
+//	@Produce		json
+//	@Param			prefix		query		string	false	"Only list the ids starting with the prefix"
+//	@Param			suffix		query		string	false	"Only list the ids ending with the suffix"
+//	@Param			detailed	query		bool	false	"List the objects with their metadata"
+//	@Param			X-Namespace	header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200			{array}		string
+//	@Header			200			{boolean}	X-Objects-Truncated	"Set if the listing was truncated at the limit"
+//	@Failure		400			{object}	api.ErrorResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Failure		502			{object}	api.ErrorResponse
+//	@Failure		503			{object}	api.ErrorResponse
+//	@Header			503			{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/objects [get]
+func (s *Server) listHandler(c *fiber.Ctx) error {
+	filter := s3.ListFilter{
+		Prefix: c.Query("prefix"),
+		Suffix: c.Query("suffix"),
+	}
+	if !middleware.IsValidListFilter(filter.Prefix, filter.Suffix) {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Invalid prefix or suffix"})
+	}
+
+	// List the matching objects from s3 instances, with their metadata if requested
+	var res any
+	var err error
+	if c.QueryBool("detailed") {
+		var summaries []s3.ObjectSummary
+		summaries, err = s.objects(c).GetObjectsWithMetadata(c.Context(), filter)
+		res = objectSummaries(summaries)
+	} else {
+		res, err = s.objects(c).GetObjects(c.Context(), filter)
+	}
+
+	switch {
+	case err == nil:
+		return c.Status(fiber.StatusOK).JSON(res)
+	case errors.Is(err, gateway.ErrListTruncated):
+		c.Set(listTruncatedHeader, "true")
+		return c.Status(fiber.StatusOK).JSON(res)
+	default:
+		return s.sendError(c, err, "Failed to list objects")
+	}
+}
+
+// setWrittenInstance returns the instance the object was written to, if read-after-write consistency is enabled
+func (s *Server) setWrittenInstance(c *fiber.Ctx, instanceNum int) {
+	if s.config.ReadAfterWriteConsistency {
+		c.Set(writtenInstanceHeader, strconv.Itoa(instanceNum))
+	}
+}
+
+// objectSummaries converts the listed objects to the response
+func objectSummaries(summaries []s3.ObjectSummary) []api.ObjectSummaryResponse {
+	response := make([]api.ObjectSummaryResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		response = append(response, api.ObjectSummaryResponse{
+			Id:           summary.ObjectId,
+			Size:         summary.Size,
+			LastModified: summary.LastModified,
+			ETag:         strings.Trim(summary.ETag, `"`),
+		})
+	}
+
+	return response
+}
+
+// readyHandler returns the readiness of the gateway, with the info of the Docker daemon the instances are discovered from
+//
+//	@Summary		Readiness
+//	@Description	Check if the gateway is ready to serve requests. Includes the Docker daemon version, the negotiated API version, the state of the discovery and the skipped containers, when discovering from Docker.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	api.ReadinessResponse
+//	@Failure		503	{object}	api.ReadinessResponse
+//	@Router			/ready [get]
+func (s *Server) readyHandler(c *fiber.Ctx) error {
+	response := api.ReadinessResponse{Ready: s.gatewayService.Ready(c.Context())}
+
+	if s.config.DaemonReporter != nil {
+		info, err := s.config.DaemonReporter.DaemonInfo(c.Context())
+		if err != nil {
+			s.logger.Warn("Failed to get the Docker daemon info", zap.Error(err))
+		} else {
+			response.Docker = &api.DockerDaemonResponse{
+				Host:          info.Host,
+				APIVersion:    info.APIVersion,
+				ServerVersion: info.ServerVersion,
+				OS:            info.OS,
+				Arch:          info.Arch,
+			}
+		}
+	}
+
+	if s.config.StatusReporter != nil {
+		status := s.config.StatusReporter.Status(c.Context())
+		response.Discovery = &api.DiscoveryStatusResponse{
+			DaemonReachable: status.DaemonReachable,
+			InstanceCount:   status.InstanceCount,
+			LastError:       status.LastError,
+		}
+		if !status.LastDiscovery.IsZero() {
+			response.Discovery.LastDiscovery = &status.LastDiscovery
+		}
+		if !status.LastErrorAt.IsZero() {
+			response.Discovery.LastErrorAt = &status.LastErrorAt
+		}
+	}
+
+	if s.config.SkipReporter != nil {
+		for _, skipped := range s.config.SkipReporter.SkippedInstances() {
+			response.Skipped = append(response.Skipped, api.SkippedInstanceResponse{
+				ContainerId: skipped.ContainerId,
+				Reason:      skipped.Reason,
+			})
+		}
+	}
+
+	status := fiber.StatusOK
+	if !response.Ready {
+		status = fiber.StatusServiceUnavailable
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+// watchHandler streams the changes of an object as Server-Sent Events
+//
+//	@Summary		Watch an object
+//	@Description	Stream the changes of the object, one Server-Sent Event per change, until the client disconnects. Overwrites are reported as "created". If watching fails, an "error" event is sent before the stream ends. Only served if the object notifications are enabled.
+//	@Tags			objects
+//	@Produce		text/event-stream
+//	@Param			id			path		string			true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			X-Namespace	header		string			false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200			{object}	api.ObjectEvent	"data: <event>"
+//	@Router			/object/{id}/watch [post]
+func (s *Server) watchHandler(c *fiber.Ctx) error {
+	objectId := c.Params("id")
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	// The watch outlives the handler, it is cancelled when the client disconnects
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := s.objects(c).WatchObject(ctx, objectId)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		// Comments keep the connection alive and detect the disconnected clients between the events
+		keepAlive := time.NewTicker(watchKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					if err := <-errs; err != nil {
+						s.logger.Error("Failed to watch object", zap.String("objectId", objectId), zap.Error(err))
+						_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", "Failed to watch object")
+					}
+
+					_ = w.Flush()
+					return
+				}
+
+				data, err := json.Marshal(api.ObjectEvent{Event: event.Type, ObjectId: event.ObjectId})
+				if err != nil {
+					s.logger.Error("Failed to encode object event", zap.Error(err))
+					continue
+				}
+
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+			case <-keepAlive.C:
+				_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+			}
+
+			// Flushing fails once the client disconnects
+			if err := w.Flush(); err != nil {
+				s.logger.Debug("Client disconnected from the object watch", zap.Error(err))
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// deleteByPrefixHandler deletes the objects starting with the prefix from all instances
+//
+//	@Summary		Delete objects by prefix
+//	@Description	Delete all objects starting with the prefix from all instances. An empty prefix deletes all objects of the namespace and must be confirmed with all=true. Nothing is deleted if more objects match than the bulk delete limit. Requires the admin API key.
+//	@Tags			objects
+//	@Produce		json
+//	@Param			X-API-Key	header		string	true	"Admin API key"
+//	@Param			prefix		query		string	false	"Prefix of the objects (alphanumeric, optionally with slashes)"
+//	@Param			all			query		bool	false	"Confirm deleting all objects, when the prefix is empty"
+//	@Param			X-Namespace	header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200			{object}	api.BulkDeleteResponse
+//	@Failure		400			{object}	api.ErrorResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Failure		422			{object}	api.ErrorResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Failure		502			{object}	api.ErrorResponse
+//	@Failure		503			{object}	api.ErrorResponse
+//	@Header			503			{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/objects [delete]
+func (s *Server) deleteByPrefixHandler(c *fiber.Ctx) error {
+	prefix := c.Query("prefix")
+	switch {
+	case prefix == "" && !c.QueryBool("all"):
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "An empty prefix deletes all objects, confirm with all=true"})
+	case prefix != "" && !middleware.IsValidObjectPrefix(prefix):
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Invalid prefix"})
+	}
+
+	deleted, err := s.objects(c).DeleteObjectsByPrefix(c.Context(), prefix)
+	switch {
+	case err == nil:
+		return c.Status(fiber.StatusOK).JSON(api.BulkDeleteResponse{Prefix: prefix, Deleted: deleted})
+	case errors.Is(err, gateway.ErrBulkDeleteLimitExceeded):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(api.ErrorResponse{Code: api.ErrorCodeBulkLimitExceeded, Message: err.Error()})
+	default:
+		return s.sendError(c, err, "Failed to delete objects")
+	}
+}
+
+// countHandler counts the objects on the S3 instances
+//
+//	@Summary		Count objects
+//	@Description	Get the number of objects on all S3 instances, optionally per instance. Cheaper than listing the objects. The counts may be up to 30 seconds old.
+//	@Tags			objects
+//	@Produce		json
+//	@Param			perInstance	query		bool	false	"Include the number of objects per instance (not available in a namespace)"
+//	@Param			X-Namespace	header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200			{object}	api.ObjectCountResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Failure		502			{object}	api.ErrorResponse
+//	@Failure		503			{object}	api.ErrorResponse
+//	@Header			503			{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/objects/count [get]
+func (s *Server) countHandler(c *fiber.Ctx) error {
+	count, err := s.objects(c).CountObjects(c.Context())
+	switch {
+	case err == nil:
+		response := api.ObjectCountResponse{Total: count.Total}
+		if c.QueryBool("perInstance") {
+			response.Instances = map[string]int{}
+			for instanceNum, n := range count.Instances {
+				response.Instances[strconv.Itoa(instanceNum)] = n
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(response)
+	default:
+		return s.sendError(c, err, "Failed to count objects")
+	}
+}
+
+// healthzHandler returns the health of the S3 instances, as last checked by the background health monitor
+//
+//	@Summary		Instance health
+//	@Description	Get the health of the S3 instances, keyed by the instance number. Served without checking the instances. Returns 503 if all checked instances are unhealthy.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	map[string]bool
+//	@Failure		503	{object}	map[string]bool
+//	@Router			/healthz [get]
+func (s *Server) healthzHandler(c *fiber.Ctx) error {
+	health := map[string]bool{}
+	anyHealthy := false
+	if s.config.HealthReporter != nil {
+		for instanceNum, healthy := range s.config.HealthReporter.InstanceHealth() {
+			health[strconv.Itoa(instanceNum)] = healthy
+			anyHealthy = anyHealthy || healthy
 		}
 	}
 
-	s.app.Get("/objects", timeout.NewWithContext(listHandler, time.Second*30))
+	status := fiber.StatusOK
+	if len(health) > 0 && !anyHealthy {
+		status = fiber.StatusServiceUnavailable
+	}
+
+	return c.Status(status).JSON(health)
+}
+
+// streamHandler streams all object ids from the S3 instances as Server-Sent Events
+//
+//	@Summary		Stream objects
+//	@Description	Stream all object ids from the S3 instances, one Server-Sent Event per object id. If listing fails, an "error" event is sent before the stream ends.
+//	@Tags			objects
+//	@Produce		text/event-stream
+//	@Param			X-Namespace	header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200			{string}	string	"data: <id>"
+//	@Router			/objects/stream [get]
+func (s *Server) streamHandler(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	// The stream outlives the handler, it is cancelled when the client disconnects
+	ctx, cancel := context.WithCancel(context.Background())
+	objectIds, errs := s.objects(c).StreamObjects(ctx)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		for objectId := range objectIds {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", objectId)
+
+			// Buffered writes are sent in batches, flushing fails once the client disconnects
+			if w.Buffered() < w.Size()/2 {
+				continue
+			}
+
+			if err := w.Flush(); err != nil {
+				s.logger.Debug("Client disconnected from the object stream", zap.Error(err))
+				return
+			}
+		}
+
+		if err := <-errs; err != nil {
+			s.logger.Error("Failed to stream objects", zap.Error(err))
+			_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", "Failed to list objects")
+		}
+
+		_ = w.Flush()
+	})
+
+	return nil
+}
+
+// instancesHandler lists the discovered S3 instances
+//
+//	@Summary		List the instances
+//	@Description	List the discovered S3 instances with their health and Minio version. Requires the admin API key.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			X-API-Key	header		string	true	"Admin API key"
+//	@Success		200			{array}		api.InstanceResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Router			/admin/instances [get]
+func (s *Server) instancesHandler(c *fiber.Ctx) error {
+	instances, err := s.gatewayService.Instances(c.Context())
+	if err != nil {
+		s.logger.Error("Failed to discover instances", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(api.ErrorResponse{Code: api.ErrorCodeInternalError, Message: "Failed to discover instances"})
+	}
+
+	response := make([]api.InstanceResponse, 0, len(instances))
+	for _, instance := range instances {
+		instanceResponse := api.InstanceResponse{
+			Instance: instance.InstanceNum,
+			Hostname: instance.Hostname,
+			Port:     instance.Port,
+			Version:  instance.Version,
+		}
+		if !instance.LastChecked.IsZero() {
+			healthy := instance.Healthy
+			instanceResponse.Healthy = &healthy
+		}
+
+		response = append(response, instanceResponse)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// clientCreationRateHandler returns the number of S3 clients created in the last second
+//
+//	@Summary		Get the client creation rate
+//	@Description	Get the number of S3 clients created in the last second. With the clients pooled, a sustained rate means some code path bypasses the pool. Requires the admin API key.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			X-API-Key	header		string	true	"Admin API key"
+//	@Success		200			{object}	api.ClientCreationRateResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Router			/admin/metrics/client-creation-rate [get]
+func (s *Server) clientCreationRateHandler(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(api.ClientCreationRateResponse{PerSecond: s.config.ClientCreationReporter.ClientCreationRate()})
+}
+
+// getLogLevelHandler returns the level of the global logger
+//
+//	@Summary		Get the log level
+//	@Description	Get the level of the global logger. Requires the admin API key.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			X-API-Key	header		string	true	"Admin API key"
+//	@Success		200			{object}	api.LogLevelResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Router			/admin/log-level [get]
+func (s *Server) getLogLevelHandler(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(api.LogLevelResponse{Level: s.logLevel.Level().String()})
+}
+
+// setLogLevelHandler changes the level of the global logger, without a restart
+//
+//	@Summary		Set the log level
+//	@Description	Change the level of the global logger (debug, info, warn or error). Requires the admin API key.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-API-Key	header		string				true	"Admin API key"
+//	@Param			request		body		api.LogLevelRequest	true	"Log level"
+//	@Success		200			{object}	api.LogLevelResponse
+//	@Failure		400			{object}	api.ErrorResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Router			/admin/log-level [put]
+func (s *Server) setLogLevelHandler(c *fiber.Ctx) error {
+	request := api.LogLevelRequest{}
+	err := c.BodyParser(&request)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Invalid log level request"})
+	}
+
+	level, err := zapcore.ParseLevel(request.Level)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: err.Error()})
+	}
+
+	s.logLevel.SetLevel(level)
+	s.logger.Warn("Log level changed", zap.String("level", level.String()))
+
+	return c.Status(fiber.StatusOK).JSON(api.LogLevelResponse{Level: level.String()})
+}
+
+// bulkDeleteHandler deletes all objects starting with a prefix
+//
+//	@Summary		Delete objects by prefix
+//	@Description	Delete all objects starting with the prefix from all instances. Nothing is deleted if more objects match than the bulk delete limit. Requires the admin API key.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-API-Key	header		string					true	"Admin API key"
+//	@Param			dry-run		query		bool					false	"Only count the matching objects"
+//	@Param			request		body		api.BulkDeleteRequest	true	"Prefix of the objects"
+//	@Success		200			{object}	api.BulkDeleteResponse
+//	@Failure		400			{object}	api.ErrorResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Failure		422			{object}	api.ErrorResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Failure		502			{object}	api.ErrorResponse
+//	@Failure		503			{object}	api.ErrorResponse
+//	@Router			/admin/bulk-delete-by-prefix [post]
+func (s *Server) bulkDeleteHandler(c *fiber.Ctx) error {
+	request := api.BulkDeleteRequest{}
+	err := c.BodyParser(&request)
+	if err != nil || !middleware.IsValidObjectPrefix(request.Prefix) {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Invalid bulk delete request"})
+	}
+
+	dryRun := c.QueryBool("dry-run")
+
+	var deleted int
+	if dryRun {
+		deleted, err = s.gatewayService.CountObjectsByPrefix(c.Context(), request.Prefix)
+	} else {
+		deleted, err = s.gatewayService.DeleteObjectsByPrefix(c.Context(), request.Prefix)
+	}
+
+	switch {
+	case err == nil:
+		return c.Status(fiber.StatusOK).JSON(api.BulkDeleteResponse{Prefix: request.Prefix, Deleted: deleted, DryRun: dryRun})
+	case errors.Is(err, gateway.ErrBulkDeleteLimitExceeded):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(api.ErrorResponse{Code: api.ErrorCodeBulkLimitExceeded, Message: err.Error()})
+	default:
+		return s.sendError(c, err, "Failed to delete objects")
+	}
+}
+
+// migrateHandler moves an object from the instance it is sharded to, to another instance
+//
+//	@Summary		Migrate an object
+//	@Description	Move an object to another instance, e.g. when retiring an instance. Requires the admin API key.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-API-Key	header		string				true	"Admin API key"
+//	@Param			dry-run		query		bool				false	"Only validate the connectivity and the object existence"
+//	@Param			request		body		api.MigrateRequest	true	"Object and target instance"
+//	@Success		200			{object}	api.MigrateResponse
+//	@Failure		400			{object}	api.ErrorResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Failure		404			{object}	api.ErrorResponse
+//	@Failure		409			{object}	api.ErrorResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Failure		502			{object}	api.ErrorResponse
+//	@Failure		503			{object}	api.ErrorResponse
+//	@Header			503			{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/objects/migrate [post]
+func (s *Server) migrateHandler(c *fiber.Ctx) error {
+	request := api.MigrateRequest{}
+	err := c.BodyParser(&request)
+	if err != nil || !middleware.IsValidObjectId(request.ObjectId) {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Invalid migration request"})
+	}
+
+	dryRun := c.QueryBool("dry-run")
+	var result *gateway.MigrationResult
+	if dryRun {
+		result, err = s.gatewayService.CheckMigration(c.Context(), request.ObjectId, request.TargetInstance)
+	} else {
+		result, err = s.gatewayService.MigrateObject(s.requestContext(c), request.ObjectId, request.TargetInstance)
+	}
+
+	switch {
+	case err == nil:
+		return c.Status(fiber.StatusOK).JSON(api.MigrateResponse{
+			ObjectId:       request.ObjectId,
+			SourceInstance: result.SourceInstance,
+			TargetInstance: result.TargetInstance,
+			DryRun:         dryRun,
+		})
+	case errors.Is(err, gateway.ErrInstanceNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(api.ErrorResponse{Code: api.ErrorCodeInstanceNotFound, Message: "Target instance not found"})
+	case errors.Is(err, gateway.ErrObjectAlreadyExists):
+		return c.Status(fiber.StatusConflict).JSON(api.ErrorResponse{Code: api.ErrorCodeObjectAlreadyExists, Message: "Object already exists in the target instance"})
+	default:
+		return s.sendError(c, err, "Failed to migrate object")
+	}
+}
+
+// exportHandler starts exporting an object to an external S3-compatible target
+//
+//	@Summary		Export an object
+//	@Description	Start copying an object to an external S3-compatible target. The target endpoint must be one of the allowed export endpoints. Poll the returned job for the result. Requires the admin API key.
+//	@Tags			objects
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-API-Key	header		string				true	"Admin API key"
+//	@Param			id			path		string				true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			request		body		api.ExportRequest	true	"Export target"
+//	@Param			X-Namespace	header		string				false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		202			{object}	api.ExportJobResponse
+//	@Failure		400			{object}	api.ErrorResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Failure		403			{object}	api.ErrorResponse
+//	@Failure		500			{object}	api.ErrorResponse
+//	@Failure		502			{object}	api.ErrorResponse
+//	@Failure		503			{object}	api.ErrorResponse
+//	@Header			503			{integer}	Retry-After	"Seconds to wait before retrying, when no instances are available"
+//	@Router			/object/{id}/export [post]
+func (s *Server) exportHandler(c *fiber.Ctx) error {
+	objectId := c.Params("id")
+
+	request := api.ExportRequest{}
+	err := c.BodyParser(&request)
+	if err != nil || request.Bucket == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Invalid export request"})
+	}
+
+	target := gateway.ExportTarget{
+		Endpoint:  request.Endpoint,
+		Bucket:    request.Bucket,
+		AccessKey: request.AccessKey,
+		SecretKey: request.SecretKey,
+	}
+
+	jobId, err := s.objects(c).ExportObject(c.Context(), objectId, target)
+	switch {
+	case err == nil:
+		return c.Status(fiber.StatusAccepted).JSON(api.ExportJobResponse{JobId: jobId, ObjectId: objectId, Status: gateway.ExportStatusRunning})
+	case errors.Is(err, gateway.ErrInvalidExportTarget):
+		return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{Code: api.ErrorCodeInvalidRequest, Message: "Invalid target endpoint, expected an HTTP or HTTPS URL without a path"})
+	case errors.Is(err, gateway.ErrExportTargetNotAllowed):
+		s.logger.Warn("Export target not allowed", zap.Error(err))
+		return c.Status(fiber.StatusForbidden).JSON(api.ErrorResponse{Code: api.ErrorCodeExportTargetNotAllowed, Message: "The target endpoint is not an allowed export endpoint"})
+	default:
+		return s.sendError(c, err, "Failed to export object")
+	}
+}
+
+// exportStatusHandler returns the status of an export job
+//
+//	@Summary		Get an export job
+//	@Description	Get the status of an object export job. The finished jobs are kept for an hour. Requires the admin API key.
+//	@Tags			objects
+//	@Produce		json
+//	@Param			X-API-Key	header		string	true	"Admin API key"
+//	@Param			id			path		string	true	"Object ID (alphanumeric, up to 32 characters)"
+//	@Param			jobId		path		string	true	"Export job ID"
+//	@Param			X-Namespace	header		string	false	"Namespace of the object keys, isolating them from the other namespaces"
+//	@Success		200			{object}	api.ExportJobResponse
+//	@Failure		401			{object}	api.ErrorResponse
+//	@Failure		404			{object}	api.ErrorResponse
+//	@Router			/object/{id}/export/{jobId} [get]
+func (s *Server) exportStatusHandler(c *fiber.Ctx) error {
+	job, err := s.objects(c).GetExportJob(c.Context(), c.Params("id"), c.Params("jobId"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(api.ErrorResponse{Code: api.ErrorCodeExportJobNotFound, Message: "Export job not found"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(api.ExportJobResponse{
+		JobId:    job.Id,
+		ObjectId: job.ObjectId,
+		Status:   job.Status,
+		Error:    job.Error,
+	})
 }
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testAdminAPIKey = "admin-key"
+
+// newTestServer creates a server with the gateway routes, storing the objects on n in-memory instances numbered
+// from 1. The clients are keyed by the instance number.
+func newTestServer(t testing.TB, n int, config Config, opts ...gateway.Option) (*Server, map[int]*s3test.Client) {
+	t.Helper()
+
+	clients := map[int]*s3test.Client{}
+	for i := 1; i <= n; i++ {
+		clients[i] = s3test.NewClient()
+	}
+
+	factory := gateway.WithClientFactory(func(instance discovery.S3Instance) (s3.Client, error) {
+		return clients[instance.InstanceNum], nil
+	})
+	service := gateway.NewServiceV1WithOptions(discoverytest.NewService(discoverytest.Instances(n)...), s3.Options{}, append([]gateway.Option{factory}, opts...)...)
+	t.Cleanup(func() { _ = service.Close() })
+
+	if config.AdminAPIKey == "" {
+		config.AdminAPIKey = testAdminAPIKey
+	}
+
+	server := NewServer(zap.NewNop(), service, config)
+	server.gatewayRoutes()
+	return server, clients
+}
+
+// listen serves the router on a local port and returns its address
+func listen(t testing.TB, server *Server) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.app.Listener(listener) }()
+	t.Cleanup(func() { _ = server.app.Shutdown() })
+	return listener.Addr().String()
+}
+
+// do sends the request to the server and returns the response with its body
+func do(t *testing.T, server *Server, req *http.Request) (*http.Response, string) {
+	t.Helper()
+
+	res, err := server.app.Test(req, -1)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(body)
+}
+
+// newRequest creates a request with the headers, given as name-value pairs
+func newRequest(method, target string, body io.Reader, headers ...string) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	return req
+}
+
+// newUploadRequest creates an upload request of the file, with the headers given as name-value pairs
+func newUploadRequest(t testing.TB, objectId string, data []byte, headers ...string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "file.txt")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return newRequest(http.MethodPut, "/object/"+objectId, body, append([]string{fiber.HeaderContentType, writer.FormDataContentType()}, headers...)...)
+}
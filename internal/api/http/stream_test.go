@@ -0,0 +1,71 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvents reads the Server-Sent Events of the response, calling the function with the data of each event
+func readEvents(t *testing.T, res *http.Response, fn func(event, data string)) {
+	t.Helper()
+
+	event := ""
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			fn(event, strings.TrimPrefix(line, "data: "))
+			event = ""
+		}
+	}
+
+	require.NoError(t, scanner.Err())
+}
+
+func TestServer_StreamObjects(t *testing.T) {
+	server, clients := newTestServer(t, 4, Config{})
+	for i := 0; i < 10000; i++ {
+		clients[i%4+1].Put(fmt.Sprintf("object%05d", i), []byte("data"))
+	}
+
+	res, err := http.Get("http://" + listen(t, server) + "/objects/stream")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	received := map[string]bool{}
+	readEvents(t, res, func(event, data string) {
+		assert.Empty(t, event)
+		received[data] = true
+	})
+	assert.Len(t, received, 10000)
+}
+
+func TestServer_StreamObjectsError(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+	clients[1].Put("object", []byte("data"))
+	clients[2].Fail(errors.New("connection refused"))
+
+	res, err := http.Get("http://" + listen(t, server) + "/objects/stream")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var events []string
+	readEvents(t, res, func(event, _ string) {
+		events = append(events, event)
+	})
+	require.NotEmpty(t, events)
+	assert.Equal(t, "error", events[len(events)-1])
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testCertificate is a self-signed certificate, also used as the CA of the certificates it signs
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	tls         tls.Certificate
+	certFile    string
+	keyFile     string
+}
+
+// newTestCertificate creates a certificate for localhost, signed by the parent or self-signed if nil,
+// and writes it with its key to the directory
+func newTestCertificate(t *testing.T, dir, name string, parent *testCertificate) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	c := &testCertificate{
+		certificate: certificate,
+		key:         key,
+		tls:         pair,
+		certFile:    filepath.Join(dir, name+".crt"),
+		keyFile:     filepath.Join(dir, name+".key"),
+	}
+	require.NoError(t, os.WriteFile(c.certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(c.keyFile, keyPEM, 0o600))
+	return c
+}
+
+// pool returns a certificate pool trusting the certificate
+func (c *testCertificate) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(c.certificate)
+	return pool
+}
+
+// runServer runs the server with the TLS configuration on a free local port and returns its address
+func runServer(t *testing.T, tlsConfig TLSConfig) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	service := gateway.NewServiceV1WithOptions(discoverytest.NewService(discoverytest.Instances(1)...), s3.Options{})
+	t.Cleanup(func() { _ = service.Close() })
+	server := NewServer(zap.NewNop(), service, Config{AdminAPIKey: testAdminAPIKey})
+	go server.Run(address, tlsConfig)
+	t.Cleanup(func() { _ = server.Shutdown(time.Second) })
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp4", address)
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return address
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCertificate := newTestCertificate(t, dir, "server", nil)
+	address := runServer(t, TLSConfig{CertFile: serverCertificate.certFile, KeyFile: serverCertificate.keyFile})
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: serverCertificate.pool()}}}
+	res, err := client.Get("https://" + address + "/healthz")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	require.NotNil(t, res.TLS)
+	assert.True(t, res.TLS.HandshakeComplete)
+	assert.Equal(t, "server", res.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+func TestServer_PlaintextWithoutCertificate(t *testing.T) {
+	address := runServer(t, TLSConfig{})
+
+	res, err := http.Get("http://" + address + "/healthz")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Nil(t, res.TLS)
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	serverCertificate := newTestCertificate(t, dir, "server", ca)
+	clientCertificate := newTestCertificate(t, dir, "client", ca)
+	untrustedCertificate := newTestCertificate(t, dir, "untrusted", nil)
+	address := runServer(t, TLSConfig{CertFile: serverCertificate.certFile, KeyFile: serverCertificate.keyFile, ClientCAFile: ca.certFile})
+
+	handshake := func(certificates ...tls.Certificate) error {
+		conn, err := tls.Dial("tcp4", address, &tls.Config{RootCAs: ca.pool(), Certificates: certificates})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		// TLS 1.3 reports the rejected client certificate on the first read
+		_, err = conn.Write([]byte("GET /healthz HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		if err != nil {
+			return err
+		}
+		_, err = conn.Read(make([]byte, 1))
+		return err
+	}
+
+	assert.NoError(t, handshake(clientCertificate.tls))
+	assert.Error(t, handshake())
+	assert.Error(t, handshake(untrustedCertificate.tls))
+}
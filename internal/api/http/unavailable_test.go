@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_NoInstancesAvailable(t *testing.T) {
+	// The discovery found no instances
+	server, _ := newTestServer(t, 0, Config{})
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{name: "upload", request: newUploadRequest(t, "object", []byte("data"))},
+		{name: "download", request: newRequest(http.MethodGet, "/object/object", nil)},
+		{name: "delete", request: newRequest(http.MethodDelete, "/object/object", nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := do(t, server, tt.request)
+			assertErrorResponse(t, res, body, fiber.StatusServiceUnavailable, api.ErrorCodeInstanceUnavailable)
+			assert.Equal(t, retryAfterSeconds, res.Header.Get(fiber.HeaderRetryAfter))
+		})
+	}
+
+	t.Run("head", func(t *testing.T) {
+		res, _ := do(t, server, newRequest(http.MethodHead, "/object/object", nil))
+		assert.Equal(t, fiber.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, retryAfterSeconds, res.Header.Get(fiber.HeaderRetryAfter))
+	})
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadHandler_KeepsObjectIds(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{})
+
+	// The object ID of the first upload is not overwritten by the following request reusing the buffer
+	for _, objectId := range []string{"first", "other"} {
+		res, _ := do(t, server, newUploadRequest(t, objectId, []byte(objectId)))
+		assert.Equal(t, fiber.StatusCreated, res.StatusCode)
+	}
+
+	assert.Equal(t, []string{"first", "other"}, clients[1].Keys())
+	assert.Equal(t, []byte("first"), clients[1].Object("first").Data)
+}
+
+func TestUploadHandler_QuotaExceeded(t *testing.T) {
+	enforcer := gateway.NewInMemoryQuotaEnforcer(map[string]gateway.Quota{s3.BucketName: {MaxBytes: 10}})
+	server, clients := newTestServer(t, 1, Config{}, gateway.WithQuotaEnforcer(enforcer))
+
+	res, _ := do(t, server, newUploadRequest(t, "small", []byte("12345")))
+	assert.Equal(t, fiber.StatusCreated, res.StatusCode)
+
+	res, body := do(t, server, newUploadRequest(t, "large", []byte("123456789")))
+	assert.Equal(t, fiber.StatusInsufficientStorage, res.StatusCode)
+	assert.Contains(t, body, string(api.ErrorCodeQuotaExceeded))
+	assert.Contains(t, body, "quota")
+	assert.Equal(t, []string{"small"}, clients[1].Keys())
+}
+
+func TestUploadHandler_ExpireSeconds(t *testing.T) {
+	server, clients := newTestServer(t, 1, Config{})
+
+	before := time.Now()
+	res, _ := do(t, server, newUploadRequest(t, "temporary", []byte("data"), expireSecondsHeader, "60"))
+	assert.Equal(t, fiber.StatusCreated, res.StatusCode)
+	assert.WithinRange(t, clients[1].Object("temporary").ExpiresAt, before.Add(time.Minute), time.Now().Add(time.Minute))
+
+	for _, expireSeconds := range []string{"0", "-5", "soon"} {
+		res, body := do(t, server, newUploadRequest(t, "invalid", []byte("data"), expireSecondsHeader, expireSeconds))
+		assert.Equal(t, fiber.StatusBadRequest, res.StatusCode, expireSeconds)
+		assert.Contains(t, body, expireSecondsHeader)
+	}
+	assert.Equal(t, []string{"temporary"}, clients[1].Keys())
+}
+
+func TestUploadHandler_ContentTypeLists(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		status int
+	}{
+		{name: "disabled by default", status: fiber.StatusCreated},
+		{name: "allowed", config: Config{UploadContentTypeAllowlist: []string{"application/*"}}, status: fiber.StatusCreated},
+		{name: "not allowed", config: Config{UploadContentTypeAllowlist: []string{"image/*"}}, status: fiber.StatusUnsupportedMediaType},
+		{name: "denied", config: Config{UploadContentTypeDenylist: []string{"application/octet-stream"}}, status: fiber.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clients := newTestServer(t, 1, tt.config)
+
+			// The uploaded file is an application/octet-stream
+			res, body := do(t, server, newUploadRequest(t, "object", []byte("data")))
+			assert.Equal(t, tt.status, res.StatusCode)
+			if tt.status != fiber.StatusCreated {
+				assert.Contains(t, body, string(api.ErrorCodeUnsupportedMediaType))
+				assert.Empty(t, clients[1].Keys())
+			}
+		})
+	}
+}
+
+func TestUploadHandler_ContentMD5(t *testing.T) {
+	data := []byte("transported data")
+	digest := md5.Sum(data)
+	otherDigest := md5.Sum([]byte("other data"))
+
+	tests := []struct {
+		name       string
+		headers    []string
+		status     int
+		code       api.ErrorCode
+		validated  string
+		objectKeys []string
+	}{
+		{name: "match", headers: []string{contentMD5Header, base64.StdEncoding.EncodeToString(digest[:])}, status: fiber.StatusCreated, validated: "true", objectKeys: []string{"object"}},
+		{name: "mismatch", headers: []string{contentMD5Header, base64.StdEncoding.EncodeToString(otherDigest[:])}, status: fiber.StatusBadRequest, code: api.ErrorCodeChecksumMismatch},
+		{name: "invalid", headers: []string{contentMD5Header, "not-a-digest"}, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+		{name: "absent", status: fiber.StatusCreated, objectKeys: []string{"object"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clients := newTestServer(t, 1, Config{})
+
+			res, body := do(t, server, newUploadRequest(t, "object", data, tt.headers...))
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Equal(t, tt.validated, res.Header.Get(contentMD5ValidatedHeader))
+			if tt.code != "" {
+				assert.Contains(t, body, string(tt.code))
+			}
+
+			// The mismatching object is not stored
+			if tt.objectKeys == nil {
+				assert.Empty(t, clients[1].Keys())
+			} else {
+				assert.Equal(t, tt.objectKeys, clients[1].Keys())
+				assert.Equal(t, data, clients[1].Object("object").Data)
+			}
+		})
+	}
+}
+
+func TestUploadHandler_Response(t *testing.T) {
+	server, clients := newTestServer(t, 2, Config{})
+
+	data := []byte("object data")
+	res, body := do(t, server, newUploadRequest(t, "object", data))
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+	assert.Equal(t, "/object/object", res.Header.Get(fiber.HeaderLocation))
+	assert.Equal(t, fiber.MIMEApplicationJSON, res.Header.Get(fiber.HeaderContentType))
+
+	// The instance storing the object is reported
+	instance := 1
+	if clients[instance].Object("object") == nil {
+		instance = 2
+	}
+	digest := md5.Sum(data)
+	assert.JSONEq(t, fmt.Sprintf(`{"id":"object","instance":%d,"etag":%q,"size":%d}`, instance, hex.EncodeToString(digest[:]), len(data)), body)
+}
+
+func TestUploadHandler_ObjectSize(t *testing.T) {
+	server, _ := newTestServer(t, 1, Config{})
+
+	for _, size := range []int{0, 1, 1000, 256 * 1024} {
+		t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) {
+			res, body := do(t, server, newUploadRequest(t, "object", bytes.Repeat([]byte("a"), size)))
+			require.Equal(t, fiber.StatusCreated, res.StatusCode, body)
+			assert.Equal(t, strconv.Itoa(size), res.Header.Get(objectSizeHeader))
+		})
+	}
+}
+
+func TestUploadHandler_MaxObjectSize(t *testing.T) {
+	// A limit above the 4 MB default body limit of Fiber
+	const limit = 5 << 20
+	server, clients := newTestServer(t, 1, Config{MaxObjectSize: limit}, gateway.WithMaxObjectSize(limit))
+
+	res, _ := do(t, server, newUploadRequest(t, "object", bytes.Repeat([]byte("a"), limit)))
+	assert.Equal(t, fiber.StatusCreated, res.StatusCode)
+	assert.Len(t, clients[1].Object("object").Data, limit)
+
+	// The body over the limit is rejected by the gateway, not cut off by Fiber
+	res, body := do(t, server, newUploadRequest(t, "large", bytes.Repeat([]byte("a"), limit+1)))
+	assertErrorResponse(t, res, body, fiber.StatusRequestEntityTooLarge, api.ErrorCodeObjectTooLarge)
+	assert.Nil(t, clients[1].Object("large"))
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/gateway"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// watchingService serves the object watches from the mock notification channels
+type watchingService struct {
+	gateway.Service
+	events   chan s3.ObjectEvent
+	errs     chan error
+	contexts chan context.Context
+}
+
+func newWatchingService() *watchingService {
+	return &watchingService{events: make(chan s3.ObjectEvent, 10), errs: make(chan error, 1), contexts: make(chan context.Context, 1)}
+}
+
+func (s *watchingService) WatchObject(ctx context.Context, _ string) (<-chan s3.ObjectEvent, <-chan error) {
+	s.contexts <- ctx
+	return s.events, s.errs
+}
+
+func newWatchServer(service gateway.Service, enabled bool) *Server {
+	server := NewServer(zap.NewNop(), service, Config{AdminAPIKey: testAdminAPIKey, ObjectNotificationsEnabled: enabled})
+	server.gatewayRoutes()
+	return server
+}
+
+func TestServer_WatchObject(t *testing.T) {
+	service := newWatchingService()
+	server := newWatchServer(service, true)
+
+	service.events <- s3.ObjectEvent{Type: s3.ObjectEventCreated, ObjectId: "object"}
+	close(service.events)
+	close(service.errs)
+
+	res, body := do(t, server, newRequest(http.MethodPost, "/object/object/watch", nil))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	assert.Equal(t, "data: {\"event\":\"created\",\"object_id\":\"object\"}\n\n", body)
+}
+
+func TestServer_WatchObjectError(t *testing.T) {
+	service := newWatchingService()
+	server := newWatchServer(service, true)
+
+	close(service.events)
+	service.errs <- errors.New("notifications not configured")
+	close(service.errs)
+
+	res, body := do(t, server, newRequest(http.MethodPost, "/object/object/watch", nil))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "event: error\ndata: Failed to watch object\n\n", body)
+}
+
+func TestServer_WatchObjectDisconnect(t *testing.T) {
+	previous := watchKeepAliveInterval
+	watchKeepAliveInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchKeepAliveInterval = previous })
+
+	service := newWatchingService()
+	address := listen(t, newWatchServer(service, true))
+
+	res, err := http.Post("http://"+address+"/object/object/watch", "", nil)
+	require.NoError(t, err)
+
+	service.events <- s3.ObjectEvent{Type: s3.ObjectEventDeleted, ObjectId: "object"}
+	// Skip the keep-alive comments
+	reader := bufio.NewReader(res.Body)
+	line := ""
+	for !strings.HasPrefix(line, "data:") {
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "data: {\"event\":\"deleted\",\"object_id\":\"object\"}\n", line)
+
+	// The watch is cancelled once the client disconnects
+	ctx := <-service.contexts
+	require.NoError(t, res.Body.Close())
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not cancelled after the client disconnected")
+	}
+}
+
+func TestServer_WatchObjectDisabled(t *testing.T) {
+	server := newWatchServer(newWatchingService(), false)
+
+	res, _ := do(t, server, newRequest(http.MethodPost, "/object/object/watch", nil))
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Backend is a named discovery service
+type Backend struct {
+	Name    string
+	Service Service
+}
+
+// CompositeService queries an ordered list of discovery backends and returns the result of the first backend that
+// discovers any instance. The backends that fail or discover no instances are skipped.
+type CompositeService struct {
+	backends []Backend
+	logger   *zap.Logger
+
+	mu           sync.RWMutex
+	servedBy     string
+	readyBackend string
+}
+
+// NewCompositeService creates a new instance of the CompositeService, preferring the backends in the given order
+func NewCompositeService(backends ...Backend) *CompositeService {
+	return &CompositeService{
+		backends: backends,
+		logger:   zap.L().Named("composite-discovery"),
+	}
+}
+
+// DiscoverS3Instances returns the instances of the first backend returning a non-empty result
+func (c *CompositeService) DiscoverS3Instances(ctx context.Context) ([]S3Instance, error) {
+	var lastErr error
+	for _, backend := range c.backends {
+		instances, err := backend.Service.DiscoverS3Instances(ctx)
+		switch {
+		case err != nil:
+			c.logger.Warn("Discovery backend failed, falling back", zap.String("backend", backend.Name), zap.Error(err))
+			lastErr = errors.Wrapf(err, "discovery backend %s failed", backend.Name)
+			continue
+		case len(instances) == 0:
+			c.logger.Warn("Discovery backend found no instances, falling back", zap.String("backend", backend.Name))
+			continue
+		}
+
+		c.mu.Lock()
+		if c.servedBy != backend.Name {
+			c.logger.Info("Discovery served by backend", zap.String("backend", backend.Name))
+		}
+		c.servedBy = backend.Name
+		c.mu.Unlock()
+
+		return instances, nil
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	return []S3Instance{}, nil
+}
+
+// Watch emits the instance set of the first backend with a non-empty instance set, whenever any backend changes
+func (c *CompositeService) Watch(ctx context.Context) (<-chan []S3Instance, error) {
+	updates := make([]<-chan []S3Instance, len(c.backends))
+	for i, backend := range c.backends {
+		backendUpdates, err := backend.Service.Watch(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to watch discovery backend %s", backend.Name)
+		}
+
+		updates[i] = backendUpdates
+	}
+
+	type update struct {
+		backend   int
+		instances []S3Instance
+	}
+
+	merged := make(chan update)
+	var wg sync.WaitGroup
+	for i, backendUpdates := range updates {
+		i, backendUpdates := i, backendUpdates
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for instances := range backendUpdates {
+				select {
+				case merged <- update{backend: i, instances: instances}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	n := &notifier{}
+	subscriber := n.subscribe(ctx)
+
+	go func() {
+		latest := make([][]S3Instance, len(c.backends))
+		for u := range merged {
+			latest[u.backend] = u.instances
+
+			for _, instances := range latest {
+				if len(instances) > 0 {
+					n.publish(instances)
+					break
+				}
+			}
+		}
+	}()
+
+	return subscriber, nil
+}
+
+// Ready checks if any of the backends is ready
+func (c *CompositeService) Ready(ctx context.Context) bool {
+	for _, backend := range c.backends {
+		if !backend.Service.Ready(ctx) {
+			continue
+		}
+
+		c.mu.Lock()
+		if c.readyBackend != backend.Name {
+			c.logger.Info("Discovery backend is ready", zap.String("backend", backend.Name))
+		}
+		c.readyBackend = backend.Name
+		c.mu.Unlock()
+
+		return true
+	}
+
+	c.logger.Warn("No discovery backend is ready")
+	return false
+}
+
+// ServedBy returns the name of the backend that served the last discovery
+func (c *CompositeService) ServedBy() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.servedBy
+}
+
+// ReadyBackend returns the name of the first backend found ready by the last readiness check
+func (c *CompositeService) ReadyBackend() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.readyBackend
+}
+
+// Close closes the backends holding connections, e.g. to the Docker daemon
+func (c *CompositeService) Close() error {
+	var errs []error
+	for _, backend := range c.backends {
+		if closer, ok := backend.Service.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+
+	return stderrors.Join(errs...)
+}
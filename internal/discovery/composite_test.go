@@ -0,0 +1,125 @@
+package discovery_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCompositeService combines a docker and a static backend, preferring the docker one
+func newCompositeService() (*discovery.CompositeService, *discoverytest.Service, *discoverytest.Service) {
+	primary := discoverytest.NewService(discoverytest.Instances(2)...)
+	fallback := discoverytest.NewService(discoverytest.Instance(7))
+	composite := discovery.NewCompositeService(
+		discovery.Backend{Name: "docker", Service: primary},
+		discovery.Backend{Name: "static", Service: fallback},
+	)
+
+	return composite, primary, fallback
+}
+
+func TestCompositeService_PrimarySuccess(t *testing.T) {
+	composite, _, _ := newCompositeService()
+
+	instances, err := composite.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, discoverytest.Instances(2), instances)
+	assert.Equal(t, "docker", composite.ServedBy())
+
+	assert.True(t, composite.Ready(context.Background()))
+	assert.Equal(t, "docker", composite.ReadyBackend())
+}
+
+func TestCompositeService_PrimaryEmptyFallback(t *testing.T) {
+	composite, primary, _ := newCompositeService()
+	primary.SetInstances()
+
+	instances, err := composite.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []discovery.S3Instance{discoverytest.Instance(7)}, instances)
+	assert.Equal(t, "static", composite.ServedBy())
+
+	assert.True(t, composite.Ready(context.Background()))
+	assert.Equal(t, "static", composite.ReadyBackend())
+
+	// The primary is preferred again once it recovers
+	primary.SetInstances(discoverytest.Instances(2)...)
+	instances, err = composite.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+	assert.Equal(t, "docker", composite.ServedBy())
+}
+
+func TestCompositeService_PrimaryFailedFallback(t *testing.T) {
+	composite, primary, _ := newCompositeService()
+	primary.Fail(errors.New("docker daemon unreachable"))
+
+	instances, err := composite.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []discovery.S3Instance{discoverytest.Instance(7)}, instances)
+	assert.Equal(t, "static", composite.ServedBy())
+	assert.True(t, composite.Ready(context.Background()))
+}
+
+func TestCompositeService_AllFailed(t *testing.T) {
+	composite, primary, fallback := newCompositeService()
+	primary.Fail(errors.New("docker daemon unreachable"))
+	fallback.Fail(errors.New("config file missing"))
+
+	_, err := composite.DiscoverS3Instances(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "discovery backend static failed")
+	assert.False(t, composite.Ready(context.Background()))
+}
+
+func TestCompositeService_AllEmpty(t *testing.T) {
+	composite, primary, fallback := newCompositeService()
+	primary.SetInstances()
+	fallback.SetInstances()
+
+	instances, err := composite.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+	assert.False(t, composite.Ready(context.Background()))
+}
+
+func TestCompositeService_Watch(t *testing.T) {
+	composite, primary, _ := newCompositeService()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := composite.Watch(ctx)
+	require.NoError(t, err)
+
+	next := func() []discovery.S3Instance {
+		t.Helper()
+		select {
+		case instances := <-changes:
+			return instances
+		case <-time.After(5 * time.Second):
+			t.Fatal("no instance set was emitted")
+			return nil
+		}
+	}
+
+	// The fallback may be emitted before the primary catches up
+	instances := next()
+	if len(instances) != 2 {
+		instances = next()
+	}
+	assert.Equal(t, discoverytest.Instances(2), instances)
+
+	// The primary lost all instances - the fallback instances are emitted
+	primary.SetInstances()
+	assert.Equal(t, []discovery.S3Instance{discoverytest.Instance(7)}, next())
+
+	primary.SetInstances(discoverytest.Instance(1))
+	assert.Equal(t, []discovery.S3Instance{discoverytest.Instance(1)}, next())
+}
@@ -0,0 +1,135 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGetContainerDetails_Credentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       []string
+		accessKey string
+		secretKey string
+	}{
+		{
+			name:      "deprecated variables",
+			env:       []string{"MINIO_ACCESS_KEY=old-access", "MINIO_SECRET_KEY=old-secret"},
+			accessKey: "old-access",
+			secretKey: "old-secret",
+		},
+		{
+			name:      "root variables",
+			env:       []string{"MINIO_ROOT_USER=new-access", "MINIO_ROOT_PASSWORD=new-secret"},
+			accessKey: "new-access",
+			secretKey: "new-secret",
+		},
+		{
+			name:      "root variables take precedence",
+			env:       []string{"MINIO_ACCESS_KEY=old-access", "MINIO_SECRET_KEY=old-secret", "MINIO_ROOT_USER=new-access", "MINIO_ROOT_PASSWORD=new-secret"},
+			accessKey: "new-access",
+			secretKey: "new-secret",
+		},
+		{
+			name:      "incomplete root variables fall back",
+			env:       []string{"MINIO_ACCESS_KEY=old-access", "MINIO_SECRET_KEY=old-secret", "MINIO_ROOT_USER=new-access"},
+			accessKey: "old-access",
+			secretKey: "old-secret",
+		},
+		{
+			name: "neither",
+			env:  []string{"MINIO_BROWSER=off"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+			daemon.setEnv("c1", tt.env...)
+			service := NewServiceV1(daemon.client(), Options{})
+
+			instance, err := service.getContainerDetails(context.Background(), "c1")
+			if tt.accessKey == "" {
+				assert.ErrorIs(t, err, ErrIncompleteInstance)
+				assert.ErrorContains(t, err, "access key")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.accessKey, instance.AccessKey)
+			assert.Equal(t, tt.secretKey, instance.SecretKey)
+		})
+	}
+}
+
+func TestDiscoverS3Instances_SkipsMissingCredentials(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	daemon.setEnv("c2")
+	core, logs := observer.New(zapcore.WarnLevel)
+	service := NewServiceV1(daemon.client(), Options{})
+	service.logger = zap.New(core)
+
+	// The instance without the credentials is skipped instead of discovered with blank keys
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "c1", instances[0].ContainerId)
+
+	skipped := service.SkippedInstances()
+	require.Len(t, skipped, 1)
+	assert.Equal(t, "c2", skipped[0].ContainerId)
+	assert.Contains(t, skipped[0].Reason, "access key")
+
+	warnings := logs.FilterMessage("Skipping incomplete S3 instance").All()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "c2", warnings[0].ContextMap()["containerId"])
+	assert.Contains(t, warnings[0].ContextMap()["reason"], "missing access key, secret key")
+}
+
+func TestDiscoverS3Instances_StrictCredentials(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	daemon.setEnv("c2")
+	service := NewServiceV1(daemon.client(), Options{StrictCredentials: true})
+
+	_, err := service.DiscoverS3Instances(context.Background())
+	assert.ErrorIs(t, err, ErrIncompleteInstance)
+	assert.ErrorContains(t, err, "container c2")
+	assert.ErrorContains(t, err, "missing access key, secret key")
+
+	// Once the configuration is complete, the discovery passes again
+	daemon.setEnv("c2", "MINIO_ROOT_USER=access", "MINIO_ROOT_PASSWORD=secret")
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, instanceNums(instances))
+	assert.Empty(t, service.SkippedInstances())
+}
+
+func TestDiscoverS3Instances_SkippedReset(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	daemon.setEnv("c2")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	_, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, service.SkippedInstances(), 1)
+
+	// The skipped instances are of the last discovery
+	daemon.setEnv("c2", "MINIO_ROOT_USER=access", "MINIO_ROOT_PASSWORD=secret")
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, instanceNums(instances))
+	assert.Empty(t, service.SkippedInstances())
+}
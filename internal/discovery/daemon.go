@@ -0,0 +1,61 @@
+package discovery
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DaemonInfo describes the Docker daemon the instances are discovered from
+type DaemonInfo struct {
+	Host string
+	// APIVersion is the API version negotiated with the daemon (or configured)
+	APIVersion    string
+	ServerVersion string
+	OS            string
+	Arch          string
+}
+
+// DaemonReporter reports the daemon the instances are discovered from
+type DaemonReporter interface {
+	DaemonInfo(ctx context.Context) (*DaemonInfo, error)
+}
+
+// DaemonInfo returns the version and the platform of the Docker daemon. The result is cached for a few seconds, so
+// frequent readiness probes don't hit the daemon.
+func (s *ServiceV1) DaemonInfo(ctx context.Context) (*DaemonInfo, error) {
+	if s.dockerClient == nil {
+		return nil, errors.New("no Docker client configured")
+	}
+
+	s.daemonMu.Lock()
+	defer s.daemonMu.Unlock()
+
+	if !s.daemonAt.IsZero() && time.Since(s.daemonAt) < statusCacheTTL {
+		return s.daemonInfo, s.daemonErr
+	}
+
+	s.daemonInfo, s.daemonErr = s.fetchDaemonInfo(ctx)
+	s.daemonAt = time.Now()
+	return s.daemonInfo, s.daemonErr
+}
+
+// fetchDaemonInfo requests the version of the Docker daemon
+func (s *ServiceV1) fetchDaemonInfo(ctx context.Context) (*DaemonInfo, error) {
+	versionCtx, cancel := s.dockerContext(ctx)
+	defer cancel()
+
+	version, err := s.dockerClient.ServerVersion(versionCtx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the Docker daemon version")
+	}
+
+	return &DaemonInfo{
+		Host:          s.dockerClient.DaemonHost(),
+		APIVersion:    s.dockerClient.ClientVersion(),
+		ServerVersion: version.Version,
+		OS:            version.Os,
+		Arch:          version.Arch,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemonInfo(t *testing.T) {
+	daemon := newFakeDocker(t)
+	client := daemon.client()
+
+	info, err := NewServiceV1(client, Options{}).DaemonInfo(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &DaemonInfo{
+		Host:          client.DaemonHost(),
+		APIVersion:    client.ClientVersion(),
+		ServerVersion: "26.0.0",
+		OS:            "linux",
+		Arch:          "amd64",
+	}, info)
+}
+
+func TestDaemonInfo_Unreachable(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.fail(http.StatusInternalServerError)
+
+	_, err := NewServiceV1(daemon.client(), Options{}).DaemonInfo(context.Background())
+	assert.ErrorContains(t, err, "failed to get the Docker daemon version")
+}
+
+func TestDaemonInfo_NoClient(t *testing.T) {
+	_, err := NewServiceV1(nil, Options{}).DaemonInfo(context.Background())
+	assert.ErrorContains(t, err, "no Docker client configured")
+}
+
+func TestDaemonInfo_Cached(t *testing.T) {
+	daemon := newFakeDocker(t)
+	service := NewServiceV1(daemon.client(), Options{})
+
+	info, err := service.DaemonInfo(context.Background())
+	require.NoError(t, err)
+
+	// The readiness probes reuse the recent version instead of hitting the daemon
+	daemon.fail(http.StatusInternalServerError)
+	cached, err := service.DaemonInfo(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, info, cached)
+
+	// Once expired, the daemon is asked again
+	service.daemonAt = time.Now().Add(-statusCacheTTL)
+	_, err = service.DaemonInfo(context.Background())
+	assert.ErrorContains(t, err, "failed to get the Docker daemon version")
+}
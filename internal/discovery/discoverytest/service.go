@@ -0,0 +1,113 @@
+// Package discoverytest provides a discovery.Service serving a fixed set of instances, for the tests
+package discoverytest
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+)
+
+// Service serves the instances it is given. The set can be replaced while in use.
+type Service struct {
+	mu        sync.Mutex
+	instances []discovery.S3Instance
+	err       error
+	watchers  []chan []discovery.S3Instance
+}
+
+// NewService creates a Service serving the instances
+func NewService(instances ...discovery.S3Instance) *Service {
+	return &Service{instances: instances}
+}
+
+// Instances creates n instances numbered from 1, each with its own host
+func Instances(n int) []discovery.S3Instance {
+	instances := make([]discovery.S3Instance, 0, n)
+	for i := 1; i <= n; i++ {
+		instances = append(instances, Instance(i))
+	}
+
+	return instances
+}
+
+// Instance creates an instance with the number, its host and container named after it
+func Instance(instanceNum int) discovery.S3Instance {
+	name := "minio-" + strconv.Itoa(instanceNum)
+	return discovery.S3Instance{
+		ContainerId:      name,
+		InstanceNum:      instanceNum,
+		Hostname:         name,
+		Port:             "9000",
+		AccessKey:        "access",
+		SecretKey:        "secret",
+		WeightedCapacity: 1,
+	}
+}
+
+// SetInstances replaces the served instances and notifies the watchers
+func (s *Service) SetInstances(instances ...discovery.S3Instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.instances = instances
+	for _, watcher := range s.watchers {
+		select {
+		case watcher <- append([]discovery.S3Instance{}, instances...):
+		default:
+		}
+	}
+}
+
+// Fail makes the discovery return the error, until called with nil
+func (s *Service) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *Service) DiscoverS3Instances(ctx context.Context) ([]discovery.S3Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return append([]discovery.S3Instance{}, s.instances...), nil
+}
+
+// Watch emits the current instances and each replaced set, until the context is cancelled
+func (s *Service) Watch(ctx context.Context) (<-chan []discovery.S3Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watcher := make(chan []discovery.S3Instance, 16)
+	watcher <- append([]discovery.S3Instance{}, s.instances...)
+	s.watchers = append(s.watchers, watcher)
+
+	out := make(chan []discovery.S3Instance)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case instances := <-watcher:
+				select {
+				case out <- instances:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *Service) Ready(ctx context.Context) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err == nil && len(s.instances) > 0
+}
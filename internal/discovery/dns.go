@@ -0,0 +1,243 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Resolver resolves the S3 instance addresses. It is satisfied by net.Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// DNSOptions configure the DNS discovery service
+type DNSOptions struct {
+	// Hostname resolving to the S3 instances, e.g. a headless service name
+	Hostname string
+	// UseSRV resolves the Hostname as an SRV record, which also carries the ports
+	UseSRV bool
+	// Port the S3 instances listen on, used with A/AAAA records
+	Port string
+	// Shared credentials of the S3 instances
+	AccessKey string
+	SecretKey string
+	// Debounce is the number of consecutive resolutions an address has to appear in (or be missing from),
+	// before it is added to (or removed from) the instance set
+	Debounce int
+}
+
+// DNSService discovers the S3 instances by resolving a hostname (A/AAAA or SRV records), e.g. in Swarm or Kubernetes.
+// The instances are keyed by their address, so the sharding of an instance doesn't change when other instances are
+// added or removed.
+type DNSService struct {
+	resolver Resolver
+	options  DNSOptions
+	logger   *zap.Logger
+
+	mu sync.RWMutex
+	// Addresses in the instance set
+	addresses []string
+	// Number of consecutive resolutions each address changed its presence in
+	pending  map[string]int
+	resolved bool
+	lastErr  error
+	notifier notifier
+}
+
+// NewDNSService creates a new instance of the DNSService
+func NewDNSService(resolver Resolver, options DNSOptions) *DNSService {
+	if options.Debounce < 1 {
+		options.Debounce = 1
+	}
+
+	return &DNSService{
+		resolver: resolver,
+		options:  options,
+		logger:   zap.L().Named("dns-discovery"),
+		pending:  map[string]int{},
+	}
+}
+
+// Run resolves the hostname every interval, until the context is cancelled
+func (s *DNSService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Resolve(ctx)
+		if err != nil {
+			s.logger.Error("Failed to resolve S3 instances", zap.Error(err))
+		}
+
+		instances, err := s.DiscoverS3Instances(ctx)
+		if err == nil {
+			s.notifier.publish(instances)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resolve resolves the hostname and updates the instance set. The first successful resolution is applied immediately,
+// later changes only after they persist for the configured number of resolutions, so flapping records are ignored.
+func (s *DNSService) Resolve(ctx context.Context) error {
+	resolved, err := s.lookup(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastErr = err
+	if err != nil {
+		return err
+	}
+
+	if !s.resolved {
+		s.resolved = true
+		s.addresses = resolved
+		return nil
+	}
+
+	current := map[string]bool{}
+	for _, address := range s.addresses {
+		current[address] = true
+	}
+
+	latest := map[string]bool{}
+	for _, address := range resolved {
+		latest[address] = true
+	}
+
+	// Count the resolutions the added and removed addresses persisted in - reset the ones that flapped back
+	changed := map[string]bool{}
+	for address := range latest {
+		if !current[address] {
+			changed[address] = true
+		}
+	}
+	for address := range current {
+		if !latest[address] {
+			changed[address] = true
+		}
+	}
+
+	for address := range s.pending {
+		if !changed[address] {
+			delete(s.pending, address)
+		}
+	}
+
+	for address := range changed {
+		s.pending[address]++
+		if s.pending[address] < s.options.Debounce {
+			continue
+		}
+
+		delete(s.pending, address)
+		if latest[address] {
+			s.logger.Info("S3 instance added", zap.String("address", address))
+			current[address] = true
+		} else {
+			s.logger.Info("S3 instance removed", zap.String("address", address))
+			delete(current, address)
+		}
+	}
+
+	addresses := make([]string, 0, len(current))
+	for address := range current {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	s.addresses = addresses
+
+	return nil
+}
+
+// lookup resolves the hostname to a sorted list of host:port addresses
+func (s *DNSService) lookup(ctx context.Context) ([]string, error) {
+	addresses := []string{}
+
+	if s.options.UseSRV {
+		_, records, err := s.resolver.LookupSRV(ctx, "", "", s.options.Hostname)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to resolve SRV records")
+		}
+
+		for _, record := range records {
+			addresses = append(addresses, net.JoinHostPort(record.Target, strconv.Itoa(int(record.Port))))
+		}
+	} else {
+		hosts, err := s.resolver.LookupHost(ctx, s.options.Hostname)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to resolve hostname")
+		}
+
+		for _, host := range hosts {
+			addresses = append(addresses, net.JoinHostPort(host, s.options.Port))
+		}
+	}
+
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// Watch emits the instance set whenever a resolution run by Run changes it
+func (s *DNSService) Watch(ctx context.Context) (<-chan []S3Instance, error) {
+	return s.notifier.subscribe(ctx), nil
+}
+
+// DiscoverS3Instances returns the S3 instances from the last resolution
+func (s *DNSService) DiscoverS3Instances(ctx context.Context) ([]S3Instance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.resolved {
+		if s.lastErr != nil {
+			return nil, errors.Wrap(s.lastErr, "S3 instances were not resolved yet")
+		}
+
+		return nil, errors.New("S3 instances were not resolved yet")
+	}
+
+	instances := make([]S3Instance, 0, len(s.addresses))
+	for _, address := range s.addresses {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid resolved address")
+		}
+
+		instances = append(instances, S3Instance{
+			InstanceNum:      instanceNumberFromKey(address),
+			InstanceKey:      address,
+			Replica:          1,
+			WeightedCapacity: 1,
+			AccessKey:        s.options.AccessKey,
+			SecretKey:        s.options.SecretKey,
+			IpAddress:        host,
+			Hostname:         host,
+			Port:             port,
+			InternalPort:     port,
+		})
+	}
+
+	return instances, nil
+}
+
+// Ready checks if the service is ready (if the last resolution succeeded and returned any instance)
+func (s *DNSService) Ready(ctx context.Context) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.resolved && s.lastErr == nil && len(s.addresses) > 0
+}
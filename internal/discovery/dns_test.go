@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolves the hostname to the set hosts
+type fakeResolver struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (r *fakeResolver) set(hosts ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts = hosts
+}
+
+func (r *fakeResolver) LookupHost(context.Context, string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.hosts...), nil
+}
+
+func (r *fakeResolver) LookupSRV(context.Context, string, string, string) (string, []*net.SRV, error) {
+	return "", nil, &net.DNSError{Err: "no SRV records", IsNotFound: true}
+}
+
+func TestDNSService_StableInstanceNumbers(t *testing.T) {
+	resolver := &fakeResolver{}
+	resolver.set("10.0.0.1", "10.0.0.2", "10.0.0.3")
+	service := NewDNSService(resolver, DNSOptions{Hostname: "minio", Port: "9000"})
+	ctx := context.Background()
+
+	require.NoError(t, service.Resolve(ctx))
+	instances, err := service.DiscoverS3Instances(ctx)
+	require.NoError(t, err)
+	require.Len(t, instances, 3)
+
+	numbers := map[string]int{}
+	for _, instance := range instances {
+		assert.Equal(t, net.JoinHostPort(instance.Hostname, instance.Port), instance.InstanceKey)
+		numbers[instance.InstanceKey] = instance.InstanceNum
+	}
+	assert.Len(t, numbers, 3)
+
+	// Removing the first address keeps the identity of the others
+	resolver.set("10.0.0.2", "10.0.0.3", "10.0.0.4")
+	require.NoError(t, service.Resolve(ctx))
+	instances, err = service.DiscoverS3Instances(ctx)
+	require.NoError(t, err)
+	require.Len(t, instances, 3)
+
+	for _, instance := range instances {
+		if number, ok := numbers[instance.InstanceKey]; ok {
+			assert.Equal(t, number, instance.InstanceNum, instance.InstanceKey)
+			continue
+		}
+
+		assert.Equal(t, "10.0.0.4:9000", instance.InstanceKey)
+		assert.Equal(t, instanceNumberFromKey("10.0.0.4:9000"), instance.InstanceNum)
+	}
+}
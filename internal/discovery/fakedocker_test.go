@@ -0,0 +1,318 @@
+package discovery
+
+import (
+	"archive/tar"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	docker "github.com/docker/docker/client"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDocker is a minimal Docker daemon, serving the container listing and inspection used by the discovery
+type fakeDocker struct {
+	t      testing.TB
+	server *httptest.Server
+
+	mu         sync.Mutex
+	containers map[string]types.ContainerJSON
+	// status fails the requests with the status, if set
+	status int
+	// inspections counts the inspections by the container ID
+	inspections map[string]int
+	// inspectionDelay delays the responses of the inspections, without blocking the other requests
+	inspectionDelay time.Duration
+	// inspecting is the number of the inspections in progress, maxInspecting the highest one seen
+	inspecting, maxInspecting int
+	// failedInspections fails the inspections of the containers with the status
+	failedInspections map[string]int
+	// files are the files of the containers by the container ID and the path, served by the archive API
+	files map[string]map[string]string
+}
+
+func newFakeDocker(t testing.TB) *fakeDocker {
+	f := &fakeDocker{t: t, containers: map[string]types.ContainerJSON{}, inspections: map[string]int{}, failedInspections: map[string]int{}, files: map[string]map[string]string{}}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client creates a Docker client of the daemon
+func (f *fakeDocker) client() *docker.Client {
+	client, err := docker.NewClientWithOpts(docker.WithHost("tcp://"+f.server.Listener.Addr().String()), docker.WithVersion("1.43"), docker.WithHTTPClient(f.server.Client()))
+	require.NoError(f.t, err)
+	f.t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// addMinio adds a running, healthy Minio container with the name and the IP address
+func (f *fakeDocker) addMinio(id, name, ipAddress string) {
+	f.add(types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:   id,
+			Name: "/" + name,
+			State: &types.ContainerState{
+				Status:    containerStateRunning,
+				Running:   true,
+				StartedAt: time.Unix(0, 0).UTC().Format(time.RFC3339Nano),
+				Health:    &types.Health{Status: types.Healthy},
+			},
+		},
+		Config: &container.Config{
+			Hostname: id,
+			Image:    "minio/minio:RELEASE.2024-01-01T00-00-00Z",
+			Env:      []string{"MINIO_ROOT_USER=access", "MINIO_ROOT_PASSWORD=secret"},
+			Labels:   map[string]string{},
+		},
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{"bridge": {IPAddress: ipAddress}},
+		},
+	})
+}
+
+// serveOn makes the container dialled on the host and port, e.g. of a test server
+func (f *fakeDocker) serveOn(id, host, port string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.containers[id]
+	c.Config.Hostname = host
+	c.Config.Labels[portLabel] = port
+	f.containers[id] = c
+}
+
+// update changes the container
+func (f *fakeDocker) update(id string, fn func(c *types.ContainerJSON)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.containers[id]
+	fn(&c)
+	f.containers[id] = c
+}
+
+// setNetworks replaces the network settings of the container
+func (f *fakeDocker) setNetworks(id string, settings *types.NetworkSettings) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.containers[id]
+	c.NetworkSettings = settings
+	f.containers[id] = c
+}
+
+// setEnv replaces the environment of the container
+func (f *fakeDocker) setEnv(id string, env ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.containers[id]
+	c.Config.Env = env
+	f.containers[id] = c
+}
+
+func (f *fakeDocker) add(c types.ContainerJSON) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers[c.ID] = c
+}
+
+func (f *fakeDocker) remove(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.containers, id)
+}
+
+// fail fails the following requests with the status, zero stops failing them
+func (f *fakeDocker) fail(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+// addFile adds the file to the container filesystem
+func (f *fakeDocker) addFile(id, path, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.files[id] == nil {
+		f.files[id] = map[string]string{}
+	}
+	f.files[id][path] = content
+}
+
+// failInspection fails the inspections of the container with the status
+func (f *fakeDocker) failInspection(id string, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failedInspections[id] = status
+}
+
+// delayInspections delays every inspection, so the concurrent inspections overlap
+func (f *fakeDocker) delayInspections(delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inspectionDelay = delay
+}
+
+// maxConcurrentInspections returns the highest number of the inspections in progress at once
+func (f *fakeDocker) maxConcurrentInspections() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.maxInspecting
+}
+
+func (f *fakeDocker) inspectionCount(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inspections[id]
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := regexp.MustCompile(`^/v[\d.]+`).ReplaceAllString(r.URL.Path, "")
+	if f.status != 0 {
+		f.error(w, f.status, "daemon failure")
+		return
+	}
+
+	switch {
+	case path == "/_ping":
+		w.WriteHeader(http.StatusOK)
+	case path == "/version":
+		f.json(w, types.Version{Version: "26.0.0", APIVersion: "1.45", Os: "linux", Arch: "amd64"})
+	case path == "/containers/json":
+		f.list(w, r)
+	case strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/archive"):
+		f.archive(w, strings.TrimSuffix(strings.TrimPrefix(path, "/containers/"), "/archive"), r.URL.Query().Get("path"))
+	case strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/json"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/containers/"), "/json")
+		f.inspections[id]++
+		f.waitInspection()
+
+		if status, ok := f.failedInspections[id]; ok {
+			f.error(w, status, "inspection failure")
+			return
+		}
+
+		c, ok := f.containers[id]
+		if !ok {
+			f.error(w, http.StatusNotFound, "No such container: "+id)
+			return
+		}
+		f.json(w, c)
+	default:
+		f.error(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// archive serves the container file as a tar archive, with its stat in the header
+func (f *fakeDocker) archive(w http.ResponseWriter, id, path string) {
+	content, ok := f.files[id][path]
+	if !ok {
+		f.error(w, http.StatusNotFound, "Could not find the file "+path+" in container "+id)
+		return
+	}
+
+	stat, err := json.Marshal(types.ContainerPathStat{Name: filepath.Base(path), Size: int64(len(content)), Mode: 0o400})
+	require.NoError(f.t, err)
+	w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+	w.Header().Set("Content-Type", "application/x-tar")
+
+	archive := tar.NewWriter(w)
+	require.NoError(f.t, archive.WriteHeader(&tar.Header{Name: filepath.Base(path), Mode: 0o400, Size: int64(len(content))}))
+	_, err = archive.Write([]byte(content))
+	require.NoError(f.t, err)
+	require.NoError(f.t, archive.Close())
+}
+
+// waitInspection releases the lock for the inspection delay, counting the overlapping inspections.
+// Must be called with the lock held.
+func (f *fakeDocker) waitInspection() {
+	if f.inspectionDelay == 0 {
+		return
+	}
+
+	f.inspecting++
+	f.maxInspecting = max(f.maxInspecting, f.inspecting)
+	delay := f.inspectionDelay
+	f.mu.Unlock()
+
+	time.Sleep(delay)
+
+	f.mu.Lock()
+	f.inspecting--
+}
+
+// list lists the containers matching the name and label filters
+func (f *fakeDocker) list(w http.ResponseWriter, r *http.Request) {
+	args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+	require.NoError(f.t, err)
+
+	ids := make([]string, 0, len(f.containers))
+	for id := range f.containers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	listed := []types.Container{}
+	for _, id := range ids {
+		c := f.containers[id]
+		name := strings.TrimPrefix(c.Name, "/")
+		if names := args.Get("name"); len(names) > 0 && !matchesAny(names, name) {
+			continue
+		}
+		if labels := args.Get("label"); len(labels) > 0 && !hasLabels(labels, c.Config.Labels) {
+			continue
+		}
+
+		listed = append(listed, types.Container{ID: c.ID, Names: []string{c.Name}, State: c.State.Status, Labels: c.Config.Labels})
+	}
+
+	f.json(w, listed)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if regexp.MustCompile(pattern).MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLabels(selectors []string, labels map[string]string) bool {
+	for _, selector := range selectors {
+		key, value, hasValue := strings.Cut(selector, "=")
+		if labelValue, ok := labels[key]; !ok || (hasValue && labelValue != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeDocker) json(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(f.t, json.NewEncoder(w).Encode(v))
+}
+
+func (f *fakeDocker) error(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,207 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"go.uber.org/zap"
+)
+
+const (
+	healthCheckTimeout = time.Second * 2
+	// discoveryProbeTimeout bounds the liveness probe done while discovering, so an unresponsive instance doesn't
+	// delay the discovery
+	discoveryProbeTimeout = time.Second
+)
+
+// discoveryProbeClient probes the liveness of the instances while discovering
+var discoveryProbeClient = &http.Client{Timeout: discoveryProbeTimeout}
+
+// HealthReporter reports the health of the S3 instances, keyed by the instance number
+type HealthReporter interface {
+	InstanceHealth() map[int]bool
+}
+
+// BackgroundHealthMonitor polls the liveness endpoint of every discovered instance and keeps its last health state,
+// so the health can be read without any network calls.
+type BackgroundHealthMonitor struct {
+	discover   func(ctx context.Context) ([]S3Instance, error)
+	interval   time.Duration
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	mu     sync.RWMutex
+	health map[int]*atomic.Bool
+}
+
+// NewBackgroundHealthMonitor creates a new instance of the BackgroundHealthMonitor, polling the instances returned by
+// the discover function every interval.
+func NewBackgroundHealthMonitor(discover func(ctx context.Context) ([]S3Instance, error), interval time.Duration) *BackgroundHealthMonitor {
+	return &BackgroundHealthMonitor{
+		discover:   discover,
+		interval:   interval,
+		httpClient: &http.Client{Timeout: healthCheckTimeout},
+		logger:     zap.L().Named("health-monitor"),
+		health:     map[int]*atomic.Bool{},
+	}
+}
+
+// Run checks the health of the instances every interval. Blocks until the context is cancelled.
+func (m *BackgroundHealthMonitor) Run(ctx context.Context) {
+	m.logger.Info("Monitoring the health of S3 instances", zap.Duration("interval", m.interval))
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check polls the liveness endpoint of all instances concurrently
+func (m *BackgroundHealthMonitor) check(ctx context.Context) {
+	instances, err := m.discover(ctx)
+	if err != nil {
+		m.logger.Warn("Failed to discover S3 instances for the health check", zap.Error(err))
+		return
+	}
+
+	// Forget the instances that are gone
+	current := map[int]bool{}
+	for _, instance := range instances {
+		current[instance.InstanceNum] = true
+	}
+
+	m.mu.Lock()
+	for instanceNum := range m.health {
+		if !current[instanceNum] {
+			delete(m.health, instanceNum)
+		}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, instance := range instances {
+		wg.Add(1)
+
+		go func(instance S3Instance) {
+			defer wg.Done()
+
+			healthy := isLive(ctx, m.httpClient, instance)
+			if m.state(instance.InstanceNum).Swap(healthy) != healthy {
+				m.logger.Info("S3 instance health changed", zap.Int("instance", instance.InstanceNum), zap.Bool("healthy", healthy))
+			}
+		}(instance)
+	}
+
+	wg.Wait()
+}
+
+// isLive calls the Minio liveness endpoint of the instance
+func isLive(ctx context.Context, httpClient *http.Client, instance S3Instance) bool {
+	response, err := probeLiveness(ctx, httpClient, instance, http.MethodGet)
+	if err != nil {
+		return false
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode == http.StatusOK
+}
+
+// probeLiveness calls the Minio liveness endpoint of the instance with the method
+func probeLiveness(ctx context.Context, httpClient *http.Client, instance S3Instance, method string) (*http.Response, error) {
+	scheme := "http"
+	if instance.Secure {
+		tlsConfig, err := instance.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		scheme = "https"
+		httpClient = &http.Client{Timeout: httpClient.Timeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+
+	url := fmt.Sprintf("%s://%s:%s/minio/health/live", scheme, instance.Hostname, instance.Port)
+	request, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpClient.Do(request)
+}
+
+// containerHealthy checks the health of the instance with the Docker health check of the container, if it defines one,
+// or by calling the Minio liveness endpoint otherwise. A starting container is not healthy yet.
+func containerHealthy(ctx context.Context, state *types.ContainerState, instance S3Instance) bool {
+	if state != nil && state.Health != nil && state.Health.Status != types.NoHealthcheck {
+		return state.Health.Status == types.Healthy
+	}
+
+	return isLive(ctx, discoveryProbeClient, instance)
+}
+
+// DiscoverHealthyS3Instances discovers the instances with the service and excludes the ones found unhealthy.
+// The instances whose health was not checked are included.
+func DiscoverHealthyS3Instances(ctx context.Context, service Service) ([]S3Instance, error) {
+	instances, err := service.DiscoverS3Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	healthy := []S3Instance{}
+	for _, instance := range instances {
+		if instance.Healthy || instance.LastChecked.IsZero() {
+			healthy = append(healthy, instance)
+		}
+	}
+
+	return healthy, nil
+}
+
+// state returns the health state of the instance, creating a healthy one for new instances
+func (m *BackgroundHealthMonitor) state(instanceNum int) *atomic.Bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.health[instanceNum]
+	if !ok {
+		state = &atomic.Bool{}
+		state.Store(true)
+		m.health[instanceNum] = state
+	}
+
+	return state
+}
+
+// Healthy returns the last health state of the instance. Instances that weren't checked yet are healthy.
+func (m *BackgroundHealthMonitor) Healthy(instanceNum int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.health[instanceNum]
+	return !ok || state.Load()
+}
+
+// InstanceHealth returns the last health state of all checked instances
+func (m *BackgroundHealthMonitor) InstanceHealth() map[int]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	health := make(map[int]bool, len(m.health))
+	for instanceNum, state := range m.health {
+		health[instanceNum] = state.Load()
+	}
+
+	return health
+}
@@ -0,0 +1,192 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMinio serves the Minio liveness endpoint, failing it while down
+type fakeMinio struct {
+	server *httptest.Server
+	down   atomic.Bool
+}
+
+func newFakeMinio(t testing.TB) *fakeMinio {
+	m := &fakeMinio{}
+	m.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.down.Load() || r.URL.Path != "/minio/health/live" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// address returns the host and the port of the server
+func (m *fakeMinio) address(t testing.TB) (string, string) {
+	host, port, err := net.SplitHostPort(m.server.Listener.Addr().String())
+	require.NoError(t, err)
+	return host, port
+}
+
+func instanceNums(instances []S3Instance) []int {
+	nums := make([]int, 0, len(instances))
+	for _, instance := range instances {
+		nums = append(nums, instance.InstanceNum)
+	}
+	return nums
+}
+
+func TestHealthMonitor_ExcludesDownInstance(t *testing.T) {
+	const interval = 100 * time.Millisecond
+
+	daemon := newFakeDocker(t)
+	minios := map[string]*fakeMinio{"c1": newFakeMinio(t), "c2": newFakeMinio(t)}
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	for id, minio := range minios {
+		host, port := minio.address(t)
+		daemon.serveOn(id, host, port)
+	}
+
+	service := NewServiceV1(daemon.client(), Options{FilterUnhealthy: true}, WithHealthMonitor(interval))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.MonitorHealth(ctx)
+
+	discovered := func() []int {
+		instances, err := service.DiscoverS3Instances(context.Background())
+		require.NoError(t, err)
+		return instanceNums(instances)
+	}
+	require.Eventually(t, func() bool { return len(service.InstanceHealth()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []int{1, 2}, discovered())
+
+	// The instance going down is excluded within two health check intervals
+	minios["c2"].down.Store(true)
+	assert.Eventually(t, func() bool {
+		nums := discovered()
+		return len(nums) == 1 && nums[0] == 1
+	}, 2*interval, 5*time.Millisecond)
+	assert.Equal(t, map[int]bool{1: true, 2: false}, service.InstanceHealth())
+
+	// And included again once it recovers
+	minios["c2"].down.Store(false)
+	assert.Eventually(t, func() bool { return len(discovered()) == 2 }, 2*interval, 5*time.Millisecond)
+}
+
+func TestHealthMonitor_WithoutFiltering(t *testing.T) {
+	daemon := newFakeDocker(t)
+	minio := newFakeMinio(t)
+	minio.down.Store(true)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	host, port := minio.address(t)
+	daemon.serveOn("c1", host, port)
+
+	service := NewServiceV1(daemon.client(), Options{}, WithHealthMonitor(time.Hour))
+	service.healthMonitor.check(context.Background())
+	require.Equal(t, map[int]bool{1: false}, service.InstanceHealth())
+
+	// The unhealthy instance is reported, but still discovered
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, instanceNums(instances))
+}
+
+func TestHealthMonitor_ForgetsRemovedInstance(t *testing.T) {
+	instances := []S3Instance{{InstanceNum: 1, Hostname: "127.0.0.1", Port: "1"}}
+	monitor := NewBackgroundHealthMonitor(func(context.Context) ([]S3Instance, error) {
+		return instances, nil
+	}, time.Hour)
+
+	monitor.check(context.Background())
+	assert.Equal(t, map[int]bool{1: false}, monitor.InstanceHealth())
+
+	instances = nil
+	monitor.check(context.Background())
+	assert.Empty(t, monitor.InstanceHealth())
+	assert.True(t, monitor.Healthy(1))
+}
+
+func TestDiscoverS3Instances_ContainerHealth(t *testing.T) {
+	live, down := newFakeMinio(t), newFakeMinio(t)
+	down.down.Store(true)
+
+	daemon := newFakeDocker(t)
+	setHealth := func(id, status string) {
+		daemon.update(id, func(c *types.ContainerJSON) {
+			if status == "" {
+				c.State.Health = nil
+				return
+			}
+			c.State.Health = &types.Health{Status: status}
+		})
+	}
+
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	setHealth("c2", types.Unhealthy)
+	daemon.addMinio("c3", "amazin-object-storage-node-3", "10.0.0.3")
+	setHealth("c3", types.Starting)
+
+	// Without a health check, the liveness endpoint is probed
+	daemon.addMinio("c4", "amazin-object-storage-node-4", "10.0.0.4")
+	setHealth("c4", "")
+	host, port := live.address(t)
+	daemon.serveOn("c4", host, port)
+	daemon.addMinio("c5", "amazin-object-storage-node-5", "10.0.0.5")
+	setHealth("c5", types.NoHealthcheck)
+	host, port = down.address(t)
+	daemon.serveOn("c5", host, port)
+
+	service := NewServiceV1(daemon.client(), Options{})
+	before := time.Now()
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, instances, 5)
+
+	health := map[int]bool{}
+	for _, instance := range instances {
+		health[instance.InstanceNum] = instance.Healthy
+		assert.False(t, instance.LastChecked.Before(before))
+	}
+	assert.Equal(t, map[int]bool{1: true, 2: false, 3: false, 4: true, 5: false}, health)
+
+	healthy, err := DiscoverHealthyS3Instances(context.Background(), service)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 4}, instanceNums(healthy))
+}
+
+// fixedService discovers the fixed instances
+type fixedService struct {
+	Service
+	instances []S3Instance
+}
+
+func (s fixedService) DiscoverS3Instances(context.Context) ([]S3Instance, error) {
+	return s.instances, nil
+}
+
+func TestDiscoverHealthyS3Instances_Unchecked(t *testing.T) {
+	service := fixedService{instances: []S3Instance{
+		{InstanceNum: 1},
+		{InstanceNum: 2, Healthy: true, LastChecked: time.Now()},
+		{InstanceNum: 3, Healthy: false, LastChecked: time.Now()},
+	}}
+
+	// The instances whose health was not checked are included
+	instances, err := DiscoverHealthyS3Instances(context.Background(), service)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, instanceNums(instances))
+}
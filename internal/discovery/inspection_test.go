@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverS3Instances_ParallelInspection(t *testing.T) {
+	daemon := newFakeDocker(t)
+	for i := 1; i <= 20; i++ {
+		daemon.addMinio(fmt.Sprintf("c%02d", i), fmt.Sprintf("amazin-object-storage-node-%d", i), fmt.Sprintf("10.0.0.%d", i))
+	}
+	daemon.delayInspections(50 * time.Millisecond)
+	service := NewServiceV1(daemon.client(), Options{})
+
+	start := time.Now()
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+
+	// The inspections overlap, up to the limit
+	assert.Equal(t, maxConcurrentInspections, daemon.maxConcurrentInspections())
+	assert.Less(t, time.Since(start), 20*50*time.Millisecond)
+
+	// The result is ordered by the instance number, regardless of the inspection order
+	require.Len(t, instances, 20)
+	for i, instance := range instances {
+		assert.Equal(t, i+1, instance.InstanceNum)
+	}
+}
+
+func TestDiscoverS3Instances_InspectionFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+	}{
+		{name: "skipped"},
+		{name: "strict", strict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+			daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+			daemon.addMinio("c3", "amazin-object-storage-node-3", "10.0.0.3")
+			daemon.failInspection("c2", http.StatusInternalServerError)
+			service := NewServiceV1(daemon.client(), Options{StrictInspection: tt.strict})
+
+			instances, err := service.DiscoverS3Instances(context.Background())
+			if tt.strict {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []int{1, 3}, instanceNums(instances))
+
+			skipped := service.SkippedInstances()
+			require.Len(t, skipped, 1)
+			assert.Equal(t, "c2", skipped[0].ContainerId)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package discovery
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+// ShardKey returns the key identifying the instance in the sharding - the instance key if set, the instance number
+// otherwise
+func (i S3Instance) ShardKey() string {
+	if i.InstanceKey != "" {
+		return i.InstanceKey
+	}
+
+	return strconv.Itoa(i.InstanceNum)
+}
+
+// instanceNumberFromKey derives the instance number of an instance identified only by its key. The numbers of
+// distinct keys are unlikely to collide, and the discovery logs the collisions.
+func instanceNumberFromKey(key string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() & 0x7fffffff)
+}
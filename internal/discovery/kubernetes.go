@@ -0,0 +1,248 @@
+package discovery
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const kubernetesResyncPeriod = time.Minute * 5
+
+// Pod names of a StatefulSet end with the ordinal, e.g. "minio-2"
+var statefulSetOrdinal = regexp.MustCompile(`-(\d+)$`)
+
+// KubernetesOptions configure the Kubernetes discovery service
+type KubernetesOptions struct {
+	Namespace string
+	// ServiceName is the Service whose endpoints are the S3 instances
+	ServiceName string
+	// InstanceLabel is the pod label holding the instance number. When empty, the StatefulSet pod ordinal is used.
+	InstanceLabel string
+	// SecretName is the Secret holding the credentials of the S3 instances
+	SecretName     string
+	AccessKeyField string
+	SecretKeyField string
+}
+
+// KubernetesService discovers the S3 instances from the ready endpoints of a Kubernetes Service.
+// StatefulSet pod ordinals start at 0, so the instance number is the ordinal + 1.
+type KubernetesService struct {
+	clientset kubernetes.Interface
+	options   KubernetesOptions
+	logger    *zap.Logger
+
+	mu        sync.RWMutex
+	instances []S3Instance
+	synced    bool
+	lastErr   error
+	notifier  notifier
+}
+
+// NewKubernetesService creates a new instance of the KubernetesService
+func NewKubernetesService(clientset kubernetes.Interface, options KubernetesOptions) *KubernetesService {
+	return &KubernetesService{
+		clientset: clientset,
+		options:   options,
+		logger:    zap.L().Named("kubernetes-discovery"),
+	}
+}
+
+// Run watches the EndpointSlices of the Service and refreshes the instances on every change.
+// Blocks until the context is cancelled.
+func (s *KubernetesService) Run(ctx context.Context) {
+	s.logger.Info("Watching the endpoints of the S3 service", zap.String("namespace", s.options.Namespace), zap.String("service", s.options.ServiceName))
+
+	factory := informers.NewSharedInformerFactoryWithOptions(s.clientset, kubernetesResyncPeriod,
+		informers.WithNamespace(s.options.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = discoveryv1.LabelServiceName + "=" + s.options.ServiceName
+		}),
+	)
+
+	refresh := func(interface{}) {
+		err := s.refresh(ctx)
+		if err != nil {
+			s.logger.Error("Failed to refresh S3 instances", zap.Error(err))
+		}
+	}
+
+	informer := factory.Discovery().V1().EndpointSlices().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    refresh,
+		UpdateFunc: func(_, newObj interface{}) { refresh(newObj) },
+		DeleteFunc: refresh,
+	})
+	if err != nil {
+		s.logger.Error("Failed to watch the endpoints", zap.Error(err))
+		return
+	}
+
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	<-ctx.Done()
+	factory.Shutdown()
+}
+
+// refresh rebuilds the instances from the ready endpoints of the Service
+func (s *KubernetesService) refresh(ctx context.Context) error {
+	instances, err := s.listS3Instances(ctx)
+
+	s.mu.Lock()
+	s.lastErr = err
+	if err == nil {
+		s.instances = instances
+		s.synced = true
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	s.notifier.publish(instances)
+	return nil
+}
+
+// Watch emits the instance set whenever the endpoints or the credentials change
+func (s *KubernetesService) Watch(ctx context.Context) (<-chan []S3Instance, error) {
+	return s.notifier.subscribe(ctx), nil
+}
+
+func (s *KubernetesService) listS3Instances(ctx context.Context) ([]S3Instance, error) {
+	s.logger.Info("Discovering S3 instances")
+
+	accessKey, secretKey, err := s.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices, err := s.clientset.DiscoveryV1().EndpointSlices(s.options.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + s.options.ServiceName,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list endpoint slices")
+	}
+
+	instances := []S3Instance{}
+	for _, slice := range slices.Items {
+		port := minioPort
+		if len(slice.Ports) > 0 && slice.Ports[0].Port != nil {
+			port = strconv.Itoa(int(*slice.Ports[0].Port))
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			// Only the ready endpoints serve requests
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+
+			if len(endpoint.Addresses) == 0 || endpoint.TargetRef == nil {
+				continue
+			}
+
+			instanceNum, err := s.instanceNumber(ctx, endpoint.TargetRef.Name)
+			if err != nil {
+				s.logger.Warn("Skipping an endpoint without an instance number", zap.String("pod", endpoint.TargetRef.Name), zap.Error(err))
+				continue
+			}
+
+			instances = append(instances, S3Instance{
+				ContainerId:      endpoint.TargetRef.Name,
+				InstanceNum:      instanceNum,
+				Replica:          1,
+				WeightedCapacity: 1,
+				AccessKey:        accessKey,
+				SecretKey:        secretKey,
+				IpAddress:        endpoint.Addresses[0],
+				Hostname:         endpoint.Addresses[0],
+				Port:             port,
+				InternalPort:     port,
+			})
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].InstanceNum < instances[j].InstanceNum })
+	return instances, nil
+}
+
+// instanceNumber determines the instance number of the pod, from the instance label or the StatefulSet ordinal
+func (s *KubernetesService) instanceNumber(ctx context.Context, podName string) (int, error) {
+	if s.options.InstanceLabel != "" {
+		pod, err := s.clientset.CoreV1().Pods(s.options.Namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get pod")
+		}
+
+		instanceNum, err := strconv.Atoi(pod.Labels[s.options.InstanceLabel])
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse instance ID from label %s", s.options.InstanceLabel)
+		}
+
+		return instanceNum, nil
+	}
+
+	matches := statefulSetOrdinal.FindStringSubmatch(podName)
+	if matches == nil {
+		return 0, errors.Errorf("pod name %s has no ordinal", podName)
+	}
+
+	ordinal, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse pod ordinal")
+	}
+
+	return ordinal + 1, nil
+}
+
+// credentials reads the access key and the secret key from the Secret
+func (s *KubernetesService) credentials(ctx context.Context) (string, string, error) {
+	secret, err := s.clientset.CoreV1().Secrets(s.options.Namespace).Get(ctx, s.options.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to get the credentials secret")
+	}
+
+	accessKey := string(secret.Data[s.options.AccessKeyField])
+	secretKey := string(secret.Data[s.options.SecretKeyField])
+	if accessKey == "" || secretKey == "" {
+		return "", "", errors.Wrapf(ErrMissingCredentials, "secret %s", s.options.SecretName)
+	}
+
+	return accessKey, secretKey, nil
+}
+
+// DiscoverS3Instances returns the S3 instances from the last refresh
+func (s *KubernetesService) DiscoverS3Instances(ctx context.Context) ([]S3Instance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.synced {
+		if s.lastErr != nil {
+			return nil, errors.Wrap(s.lastErr, "S3 instances were not discovered yet")
+		}
+
+		return nil, errors.New("S3 instances were not discovered yet")
+	}
+
+	instances := make([]S3Instance, len(s.instances))
+	copy(instances, s.instances)
+	return instances, nil
+}
+
+// Ready checks if the service is ready (if the last refresh succeeded and found any instance)
+func (s *KubernetesService) Ready(ctx context.Context) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.synced && s.lastErr == nil && len(s.instances) > 0
+}
@@ -0,0 +1,164 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var testKubernetesOptions = KubernetesOptions{
+	Namespace:      "storage",
+	ServiceName:    "minio",
+	SecretName:     "minio-credentials",
+	AccessKeyField: "accessKey",
+	SecretKeyField: "secretKey",
+}
+
+func newCredentialsSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "minio-credentials", Namespace: "storage"},
+		Data:       map[string][]byte{"accessKey": []byte("ring"), "secretKey": []byte("treepotato")},
+	}
+}
+
+// newEndpointSlice creates an EndpointSlice of the minio Service, serving on the port
+func newEndpointSlice(name string, port int32, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "storage",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "minio"},
+		},
+		Ports:     []discoveryv1.EndpointPort{{Port: &port}},
+		Endpoints: endpoints,
+	}
+}
+
+func newEndpoint(pod, address string, ready bool) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{
+		Addresses:  []string{address},
+		Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: pod, Namespace: "storage"},
+	}
+}
+
+// runKubernetesService runs the service against the fake clientset and subscribes to the instance changes
+func runKubernetesService(t *testing.T, options KubernetesOptions, objects ...runtime.Object) (*KubernetesService, *fake.Clientset, <-chan []S3Instance) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clientset := fake.NewSimpleClientset(objects...)
+	service := NewKubernetesService(clientset, options)
+	changes, err := service.Watch(ctx)
+	require.NoError(t, err)
+
+	go service.Run(ctx)
+	return service, clientset, changes
+}
+
+func nextInstances(t *testing.T, changes <-chan []S3Instance) []S3Instance {
+	t.Helper()
+
+	select {
+	case instances := <-changes:
+		return instances
+	case <-time.After(5 * time.Second):
+		t.Fatal("the instances were not refreshed")
+		return nil
+	}
+}
+
+func TestKubernetesService_StatefulSetOrdinals(t *testing.T) {
+	slice := newEndpointSlice("minio-abc", 9001,
+		newEndpoint("minio-1", "10.0.0.2", true),
+		newEndpoint("minio-0", "10.0.0.1", true),
+		newEndpoint("minio-2", "10.0.0.3", false),
+		newEndpoint("minio", "10.0.0.4", true),
+	)
+	service, _, changes := runKubernetesService(t, testKubernetesOptions, newCredentialsSecret(), slice)
+
+	instances := nextInstances(t, changes)
+	assert.Equal(t, []S3Instance{
+		{ContainerId: "minio-0", InstanceNum: 1, Replica: 1, WeightedCapacity: 1, AccessKey: "ring", SecretKey: "treepotato", IpAddress: "10.0.0.1", Hostname: "10.0.0.1", Port: "9001", InternalPort: "9001"},
+		{ContainerId: "minio-1", InstanceNum: 2, Replica: 1, WeightedCapacity: 1, AccessKey: "ring", SecretKey: "treepotato", IpAddress: "10.0.0.2", Hostname: "10.0.0.2", Port: "9001", InternalPort: "9001"},
+	}, instances)
+
+	discovered, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, instances, discovered)
+	assert.True(t, service.Ready(context.Background()))
+}
+
+func TestKubernetesService_EndpointChanges(t *testing.T) {
+	slice := newEndpointSlice("minio-abc", 9000, newEndpoint("minio-0", "10.0.0.1", true))
+	service, clientset, changes := runKubernetesService(t, testKubernetesOptions, newCredentialsSecret(), slice)
+	assert.Equal(t, []int{1}, instanceNums(nextInstances(t, changes)))
+
+	endpointSlices := clientset.DiscoveryV1().EndpointSlices("storage")
+
+	// A pod becomes ready
+	slice.Endpoints = append(slice.Endpoints, newEndpoint("minio-1", "10.0.0.2", true))
+	_, err := endpointSlices.Update(context.Background(), slice, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, instanceNums(nextInstances(t, changes)))
+
+	// A pod is no longer ready
+	ready := false
+	slice.Endpoints[0].Conditions.Ready = &ready
+	_, err = endpointSlices.Update(context.Background(), slice, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, instanceNums(nextInstances(t, changes)))
+
+	// The endpoints are removed
+	require.NoError(t, endpointSlices.Delete(context.Background(), slice.Name, metav1.DeleteOptions{}))
+	assert.Empty(t, nextInstances(t, changes))
+	assert.False(t, service.Ready(context.Background()))
+}
+
+func TestKubernetesService_InstanceLabel(t *testing.T) {
+	options := testKubernetesOptions
+	options.InstanceLabel = "storage/instance"
+
+	pod := func(name, instance string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "storage", Labels: map[string]string{"storage/instance": instance}}}
+	}
+
+	slice := newEndpointSlice("minio-abc", 9000,
+		newEndpoint("minio-x7k2p", "10.0.0.1", true),
+		newEndpoint("minio-q9d4m", "10.0.0.2", true),
+		newEndpoint("minio-unlabeled", "10.0.0.3", true),
+	)
+	_, _, changes := runKubernetesService(t, options, newCredentialsSecret(), slice,
+		pod("minio-x7k2p", "5"), pod("minio-q9d4m", "3"), pod("minio-unlabeled", ""))
+
+	instances := nextInstances(t, changes)
+	require.Len(t, instances, 2)
+	assert.Equal(t, 3, instances[0].InstanceNum)
+	assert.Equal(t, "minio-q9d4m", instances[0].ContainerId)
+	assert.Equal(t, 5, instances[1].InstanceNum)
+	assert.Equal(t, "minio-x7k2p", instances[1].ContainerId)
+}
+
+func TestKubernetesService_MissingCredentials(t *testing.T) {
+	secret := newCredentialsSecret()
+	delete(secret.Data, "secretKey")
+	slice := newEndpointSlice("minio-abc", 9000, newEndpoint("minio-0", "10.0.0.1", true))
+	service, _, _ := runKubernetesService(t, testKubernetesOptions, secret, slice)
+
+	require.Eventually(t, func() bool {
+		_, err := service.DiscoverS3Instances(context.Background())
+		return errors.Is(err, ErrMissingCredentials)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, service.Ready(context.Background()))
+}
@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/observability"
+	"go.uber.org/zap"
+)
+
+const metricsSubsystem = "discovery"
+
+var (
+	discoveredInstances = promauto.With(observability.Registry).NewGauge(prometheus.GaugeOpts{
+		Namespace: observability.MetricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "instances",
+		Help:      "Number of S3 instances found by the last discovery",
+	})
+	discoveryDuration = promauto.With(observability.Registry).NewHistogram(prometheus.HistogramOpts{
+		Namespace: observability.MetricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "duration_seconds",
+		Help:      "Duration of the discoveries listing the containers",
+		Buckets:   prometheus.DefBuckets,
+	})
+	discoverCallDuration = promauto.With(observability.Registry).NewHistogram(prometheus.HistogramOpts{
+		Namespace: observability.MetricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "discover_call_duration_seconds",
+		Help:      "Duration of the DiscoverS3Instances calls, including the ones served from memory",
+		Buckets:   prometheus.DefBuckets,
+	})
+	inspectDuration = promauto.With(observability.Registry).NewHistogram(prometheus.HistogramOpts{
+		Namespace: observability.MetricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "inspect_duration_seconds",
+		Help:      "Duration of inspecting a single container and resolving its details",
+		Buckets:   prometheus.DefBuckets,
+	})
+	parseFailures = promauto.With(observability.Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: observability.MetricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "parse_failures_total",
+		Help:      "Number of containers whose instance number could not be parsed",
+	})
+	credentialFailures = promauto.With(observability.Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: observability.MetricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "credential_failures_total",
+		Help:      "Number of containers whose credentials could not be extracted",
+	})
+	dockerErrors = promauto.With(observability.Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: observability.MetricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "docker_errors_total",
+		Help:      "Number of failed Docker API calls",
+	})
+)
+
+// recordDiscovery updates the instance gauge and warns when the number of instances changed since the last discovery
+func (s *ServiceV1) recordDiscovery(instances []S3Instance) {
+	discoveredInstances.Set(float64(len(instances)))
+
+	current := make([]int, 0, len(instances))
+	for _, instance := range instances {
+		current = append(current, instance.InstanceNum)
+	}
+	sort.Ints(current)
+
+	s.mu.Lock()
+	previous := s.discovered
+	s.discovered = current
+	s.lastDiscoveryAt = time.Now()
+	s.mu.Unlock()
+
+	if previous != nil && len(previous) != len(current) {
+		s.logger.Warn("Number of S3 instances changed", zap.Ints("before", previous), zap.Ints("after", current))
+	}
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/observability"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// scrape returns the value of the metric in the shared registry - the sample count of a histogram
+func scrape(t *testing.T, name string) float64 {
+	t.Helper()
+
+	families, err := observability.Registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		metric := family.GetMetric()[0]
+		switch family.GetType() {
+		case dto.MetricType_GAUGE:
+			return metric.GetGauge().GetValue()
+		case dto.MetricType_COUNTER:
+			return metric.GetCounter().GetValue()
+		case dto.MetricType_HISTOGRAM:
+			return float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+
+	t.Fatalf("metric %s not registered", name)
+	return 0
+}
+
+func TestServiceV1_Metrics(t *testing.T) {
+	const (
+		instancesMetric          = "object_storage_discovery_instances"
+		durationMetric           = "object_storage_discovery_duration_seconds"
+		parseFailuresMetric      = "object_storage_discovery_parse_failures_total"
+		credentialFailuresMetric = "object_storage_discovery_credential_failures_total"
+		dockerErrorsMetric       = "object_storage_discovery_docker_errors_total"
+	)
+
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	service := NewServiceV1(daemon.client(), Options{})
+	service.logger = zap.New(core)
+	ctx := context.Background()
+
+	durations := scrape(t, durationMetric)
+	parseFailures := scrape(t, parseFailuresMetric)
+	credentialFailures := scrape(t, credentialFailuresMetric)
+	dockerErrors := scrape(t, dockerErrorsMetric)
+
+	instances, err := service.listS3Instances(ctx)
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, 2.0, scrape(t, instancesMetric))
+	assert.Equal(t, durations+1, scrape(t, durationMetric))
+
+	// A container with an unparseable name and one without credentials are skipped and counted
+	daemon.addMinio("c3", "amazin-object-storage-node-x", "10.0.0.3")
+	daemon.addMinio("c4", "amazin-object-storage-node-4", "10.0.0.4")
+	daemon.setEnv("c4")
+
+	instances, err = service.listS3Instances(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, instanceNums(instances))
+	assert.Equal(t, 2.0, scrape(t, instancesMetric))
+	assert.Equal(t, durations+2, scrape(t, durationMetric))
+	assert.Equal(t, parseFailures+1, scrape(t, parseFailuresMetric))
+	assert.Equal(t, credentialFailures+1, scrape(t, credentialFailuresMetric))
+	assert.Zero(t, logs.FilterMessage("Number of S3 instances changed").Len())
+
+	// A removed instance is warned about with the sets before and after
+	daemon.remove("c2")
+	instances, err = service.listS3Instances(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, instanceNums(instances))
+	assert.Equal(t, 1.0, scrape(t, instancesMetric))
+
+	changes := logs.FilterMessage("Number of S3 instances changed").All()
+	require.Len(t, changes, 1)
+	fields := changes[0].ContextMap()
+	assert.Equal(t, []interface{}{1, 2}, fields["before"])
+	assert.Equal(t, []interface{}{1}, fields["after"])
+
+	// A failing daemon is counted
+	daemon.fail(http.StatusInternalServerError)
+	_, err = service.listS3Instances(ctx)
+	require.Error(t, err)
+	assert.Equal(t, dockerErrors+1, scrape(t, dockerErrorsMetric))
+	assert.Equal(t, 1.0, scrape(t, instancesMetric))
+}
@@ -1,16 +1,81 @@
 package discovery
 
+import "time"
+
+// Options configure the discovery service
+type Options struct {
+	// ContainerPrefix selects the S3 instance containers by name. Defaults to "amazin-object-storage-node-".
+	ContainerPrefix string
+	// MemberLabel additionally selects the S3 instance containers by a label, either "key" or "key=value"
+	MemberLabel string
+	// InstanceLabel is the label holding the instance number. Preferred over the number in the container name.
+	InstanceLabel string
+	// InstanceKeyLabel is the label holding an arbitrary instance key, used by the sharding instead of the instance
+	// number. Containers with the label don't need a number in their name.
+	InstanceKeyLabel string
+	// DialPublishedPort makes the clients dial the host-published port instead of the container's internal port.
+	// Required when the gateway runs outside the Docker network.
+	DialPublishedPort bool
+	// PublishedHost is the host the published ports are reachable on, used when the binding has no host IP
+	PublishedHost string
+	// StrictInspection fails the discovery if any container can't be inspected. Otherwise, the container is skipped.
+	StrictInspection bool
+	// StrictCredentials fails the discovery if any instance is missing the credentials or the address.
+	// Otherwise, the instance is skipped and reported by SkippedInstances.
+	StrictCredentials bool
+	// TLSCACert is the CA bundle used to verify the TLS instances. Empty uses the system roots.
+	TLSCACert string
+	// TLSInsecureSkipVerify disables the certificate verification of the TLS instances
+	TLSInsecureSkipVerify bool
+	// MaxRestartCount is the number of restarts after which a container is considered unhealthy, as it keeps crashing.
+	// Zero disables the check.
+	MaxRestartCount int
+	// FilterUnhealthy excludes the instances found unhealthy by the health monitor from the discovery
+	FilterUnhealthy bool
+	// MaxStaleness bounds the age of the last known instances served while the Docker daemon is unreachable.
+	// Defaults to a minute.
+	MaxStaleness time.Duration
+}
+
 type S3Instance struct {
 	// Id of the container running the S3 instance
 	ContainerId string
-	// Number of the S3 instance - beginning from 1
+	// Number of the S3 instance - beginning from 1. Derived from the InstanceKey, if the container has no number.
 	InstanceNum int
+	// InstanceKey identifies the instance in the sharding, if set. Independent of the container name.
+	InstanceKey string
+	// Compose replica number of the S3 instance container - beginning from 1
+	Replica int
+	// Start time of the container, used to prefer the most recent container when instance numbers collide
+	StartedAt time.Time
+	// Healthy is the health of the instance at LastChecked, from the container health check or the Minio liveness
+	// endpoint. A zero LastChecked means the health was not checked.
+	Healthy     bool
+	LastChecked time.Time
+	// Version is the Minio release of the instance, e.g. RELEASE.2024-03-15T01-07-19Z. Empty if unknown.
+	Version string
+	// Relative storage capacity of the instance used by the weighted sharding, from the minio.weight label - defaults to 1
+	WeightedCapacity int
 	// Access key for the S3 instance, extracted from the container env
 	AccessKey string
 	// Secret key for the S3 instance, extracted from the container env
 	SecretKey string
 	// Container Network settings
 	IpAddress string
-	Hostname  string
-	Port      string
+	// Hostname and Port the S3 clients dial
+	Hostname string
+	Port     string
+	// Minio API port inside the container
+	InternalPort string
+	// Host binding the Minio API port is published on, empty if not published
+	PublishedHost string
+	PublishedPort string
+	// Name of the Docker network the IP address was resolved from
+	Network string
+	// Secure dials the instance with TLS
+	Secure bool
+	// CACert is the CA bundle used to verify the certificate of the instance. Empty uses the system roots.
+	CACert string
+	// InsecureSkipVerify disables the certificate verification
+	InsecureSkipVerify bool
 }
@@ -0,0 +1,90 @@
+package discovery
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// notifier broadcasts the instance set to the subscribers whenever it changes. Identical consecutive snapshots are
+// sent only once. A slow subscriber receives only the latest snapshot.
+type notifier struct {
+	mu          sync.Mutex
+	subscribers map[chan []S3Instance]struct{}
+	last        []S3Instance
+	published   bool
+}
+
+// subscribe returns a channel receiving the instance set, starting with the current one if it is known.
+// The channel is closed when the context is cancelled.
+func (n *notifier) subscribe(ctx context.Context) <-chan []S3Instance {
+	subscriber := make(chan []S3Instance, 1)
+
+	n.mu.Lock()
+	if n.subscribers == nil {
+		n.subscribers = map[chan []S3Instance]struct{}{}
+	}
+	n.subscribers[subscriber] = struct{}{}
+	if n.published {
+		subscriber <- copyInstances(n.last)
+	}
+	n.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		n.mu.Lock()
+		delete(n.subscribers, subscriber)
+		close(subscriber)
+		n.mu.Unlock()
+	}()
+
+	return subscriber
+}
+
+// publish sends the instance set to the subscribers, if it differs from the previous one
+func (n *notifier) publish(instances []S3Instance) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.published && sameInstances(n.last, instances) {
+		return
+	}
+
+	n.last = copyInstances(instances)
+	n.published = true
+
+	for subscriber := range n.subscribers {
+		// Replace the snapshot the subscriber hasn't received yet
+		select {
+		case <-subscriber:
+		default:
+		}
+
+		subscriber <- copyInstances(instances)
+	}
+}
+
+// sameInstances compares the instance sets, ignoring when the health was checked
+func sameInstances(a, b []S3Instance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		x, y := a[i], b[i]
+		x.LastChecked, y.LastChecked = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(x, y) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func copyInstances(instances []S3Instance) []S3Instance {
+	copied := make([]S3Instance, len(instances))
+	copy(copied, instances)
+	return copied
+}
@@ -0,0 +1,134 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextSnapshot receives the next instance set from the watch channel
+func nextSnapshot(t *testing.T, updates <-chan []S3Instance) []S3Instance {
+	t.Helper()
+
+	select {
+	case instances, ok := <-updates:
+		require.True(t, ok, "watch channel closed")
+		return instances
+	case <-time.After(5 * time.Second):
+		t.Fatal("no instance set emitted")
+		return nil
+	}
+}
+
+// assertNoSnapshot asserts no instance set is emitted shortly
+func assertNoSnapshot(t *testing.T, updates <-chan []S3Instance) {
+	t.Helper()
+
+	select {
+	case instances := <-updates:
+		t.Fatalf("unexpected instance set emitted: %v", instances)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifier_Deduplicates(t *testing.T) {
+	var n notifier
+	updates := n.subscribe(context.Background())
+
+	first := []S3Instance{{ContainerId: "c1", InstanceNum: 1, LastChecked: time.Unix(1, 0)}}
+	n.publish(first)
+	assert.Equal(t, first, nextSnapshot(t, updates))
+
+	// Only the health check time differs
+	n.publish([]S3Instance{{ContainerId: "c1", InstanceNum: 1, LastChecked: time.Unix(2, 0)}})
+	assertNoSnapshot(t, updates)
+
+	second := []S3Instance{{ContainerId: "c1", InstanceNum: 1}, {ContainerId: "c2", InstanceNum: 2}}
+	n.publish(second)
+	assert.Equal(t, second, nextSnapshot(t, updates))
+}
+
+func TestNotifier_SlowSubscriberReceivesLatest(t *testing.T) {
+	var n notifier
+	updates := n.subscribe(context.Background())
+
+	n.publish([]S3Instance{{ContainerId: "c1", InstanceNum: 1}})
+	n.publish([]S3Instance{{ContainerId: "c2", InstanceNum: 2}})
+
+	assert.Equal(t, []S3Instance{{ContainerId: "c2", InstanceNum: 2}}, nextSnapshot(t, updates))
+	assertNoSnapshot(t, updates)
+}
+
+func TestNotifier_CurrentSetOnSubscribe(t *testing.T) {
+	var n notifier
+	n.publish([]S3Instance{{ContainerId: "c1", InstanceNum: 1}})
+
+	updates := n.subscribe(context.Background())
+	assert.Equal(t, []S3Instance{{ContainerId: "c1", InstanceNum: 1}}, nextSnapshot(t, updates))
+}
+
+func TestNotifier_ClosesOnCancel(t *testing.T) {
+	var n notifier
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := n.subscribe(ctx)
+
+	cancel()
+	select {
+	case _, ok := <-updates:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch channel not closed")
+	}
+
+	// Publishing after the subscriber is gone doesn't block
+	n.publish([]S3Instance{{ContainerId: "c1", InstanceNum: 1}})
+}
+
+func TestServiceV1_Watch(t *testing.T) {
+	shortenWatchBackoff(t, 10*time.Millisecond, 10*time.Millisecond)
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+
+	service, subscriptions := newWatchedService(t, daemon)
+	stream := receive(t, subscriptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := service.Watch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, instanceNums(nextSnapshot(t, updates)))
+
+	// Added
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	stream.messages <- events.Message{Action: events.ActionStart, Actor: events.Actor{ID: "c2", Attributes: map[string]string{"name": "amazin-object-storage-node-2"}}}
+	assert.ElementsMatch(t, []int{1, 2}, instanceNums(nextSnapshot(t, updates)))
+
+	// Restarted with the same configuration
+	stream.messages <- events.Message{Action: events.ActionStart, Actor: events.Actor{ID: "c2", Attributes: map[string]string{"name": "amazin-object-storage-node-2"}}}
+	assertNoSnapshot(t, updates)
+
+	// Credentials rotated
+	daemon.setEnv("c2", "MINIO_ROOT_USER=rotated", "MINIO_ROOT_PASSWORD=rotated-secret")
+	stream.messages <- events.Message{Action: events.ActionStart, Actor: events.Actor{ID: "c2", Attributes: map[string]string{"name": "amazin-object-storage-node-2"}}}
+	instances := nextSnapshot(t, updates)
+	require.Len(t, instances, 2)
+	for _, instance := range instances {
+		if instance.InstanceNum == 2 {
+			assert.Equal(t, "rotated", instance.AccessKey)
+			assert.Equal(t, "rotated-secret", instance.SecretKey)
+		}
+	}
+
+	// Removed
+	stream.messages <- events.Message{Action: events.ActionDie, Actor: events.Actor{ID: "c1", Attributes: map[string]string{"name": "amazin-object-storage-node-1"}}}
+	assert.Equal(t, []int{2}, instanceNums(nextSnapshot(t, updates)))
+
+	cancel()
+	require.Eventually(t, func() bool {
+		_, ok := <-updates
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,118 @@
+package discovery
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	docker "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"go.uber.org/zap"
+)
+
+const (
+	// Attempts to list the containers before the daemon is considered unreachable
+	daemonRetryAttempts = 3
+	daemonRetryBackoff  = time.Millisecond * 200
+	// How long the last known instances are served while the daemon is unreachable, unless configured
+	lastKnownMaxAge = time.Minute
+	// Bounds the listing shared by the concurrent discoveries, as it outlives the context of the first caller
+	sharedListTimeout = time.Second * 30
+)
+
+// isTransientDaemonError checks if the error is caused by the Docker daemon being temporarily unreachable
+func isTransientDaemonError(err error) bool {
+	return docker.IsErrConnectionFailed(err) || errdefs.IsUnavailable(err)
+}
+
+// listS3InstancesWithRetry lists the instances, retrying with a jittered backoff while the Docker daemon is
+// unreachable. If the daemon stays unreachable, the last known instances are served up to the MaxStaleness.
+// Concurrent calls share a single listing, each waiting at most until its own context is done.
+func (s *ServiceV1) listS3InstancesWithRetry(ctx context.Context) ([]S3Instance, error) {
+	result := s.listGroup.DoChan("list", func() (any, error) {
+		// The listing is shared, so it must not be cancelled with the caller which started it
+		listCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedListTimeout)
+		defer cancel()
+
+		return s.retryListS3Instances(listCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		// Each caller gets its own copy of the shared instances
+		instances := res.Val.([]S3Instance)
+		return append([]S3Instance{}, instances...), nil
+	}
+}
+
+func (s *ServiceV1) retryListS3Instances(ctx context.Context) ([]S3Instance, error) {
+	backoff := daemonRetryBackoff
+
+	var err error
+	for attempt := 1; attempt <= daemonRetryAttempts; attempt++ {
+		var instances []S3Instance
+		instances, err = s.listS3Instances(ctx)
+		if err == nil {
+			s.rememberInstances(instances)
+			return instances, nil
+		}
+
+		if !isTransientDaemonError(err) || attempt == daemonRetryAttempts {
+			break
+		}
+
+		// Jitter the backoff, so the gateways don't retry in lockstep after a daemon restart
+		sleep := backoff + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		s.logger.Warn("Docker daemon unreachable, retrying", zap.Int("attempt", attempt), zap.Duration("backoff", sleep), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sleep):
+		}
+		backoff *= 2
+	}
+
+	if instances, age, ok := s.lastKnownInstances(); ok && isTransientDaemonError(err) {
+		s.logger.Warn("Docker daemon unreachable, serving stale instances", zap.Duration("age", age), zap.Error(err))
+		return instances, nil
+	}
+
+	s.recordDiscoveryError(err)
+	return nil, err
+}
+
+// rememberInstances stores the last successfully discovered instances
+func (s *ServiceV1) rememberInstances(instances []S3Instance) {
+	s.mu.Lock()
+	s.lastKnown = instances
+	s.lastKnownAt = time.Now()
+	s.mu.Unlock()
+
+	s.notifier.publish(instances)
+}
+
+// lastKnownInstances returns the last successfully discovered instances and their age, if they are recent enough
+func (s *ServiceV1) lastKnownInstances() ([]S3Instance, time.Duration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	maxAge := s.options.MaxStaleness
+	if maxAge <= 0 {
+		maxAge = lastKnownMaxAge
+	}
+
+	age := time.Since(s.lastKnownAt)
+	if s.lastKnownAt.IsZero() || age > maxAge {
+		return nil, 0, false
+	}
+
+	instances := make([]S3Instance, len(s.lastKnown))
+	copy(instances, s.lastKnown)
+	return instances, age, true
+}
@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expirePing makes the next readiness check ping the daemon again, instead of reusing the last result
+func expirePing(service *ServiceV1) {
+	service.pingMu.Lock()
+	defer service.pingMu.Unlock()
+	service.pingAt = time.Time{}
+}
+
+func TestReady_TransientPingFailure(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	// Without any known instances, an unreachable daemon is not ready
+	daemon.fail(http.StatusServiceUnavailable)
+	assert.False(t, service.Ready(context.Background()))
+
+	// The daemon recovers
+	daemon.fail(0)
+	expirePing(service)
+	assert.True(t, service.Ready(context.Background()))
+
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+
+	// The daemon is briefly unreachable - the last known instances are still served
+	daemon.fail(http.StatusServiceUnavailable)
+	expirePing(service)
+	assert.True(t, service.Ready(context.Background()))
+	assert.False(t, service.Status(context.Background()).DaemonReachable)
+
+	// The daemon recovers again
+	daemon.fail(0)
+	expirePing(service)
+	assert.True(t, service.Ready(context.Background()))
+	assert.True(t, service.Status(context.Background()).DaemonReachable)
+}
+
+func TestReady_PersistentDaemonFailure(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	_, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+
+	// A daemon failing the requests is not briefly unreachable, even with the known instances
+	daemon.fail(http.StatusInternalServerError)
+	expirePing(service)
+	assert.False(t, service.Ready(context.Background()))
+}
+
+func TestReady_NoClientConfigured(t *testing.T) {
+	service := NewServiceV1(nil, Options{})
+	assert.False(t, service.Ready(context.Background()))
+	assert.False(t, service.Status(context.Background()).DaemonReachable)
+}
+
+func TestDiscoverS3Instances_RetriesTransientFailure(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	// The daemon recovers during the retry backoff
+	daemon.fail(http.StatusServiceUnavailable)
+	time.AfterFunc(daemonRetryBackoff/2, func() { daemon.fail(0) })
+
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, instanceNums(instances))
+}
+
+func TestDiscoverS3Instances_ServesLastKnownDuringOutage(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	_, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+
+	daemon.fail(http.StatusServiceUnavailable)
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, instanceNums(instances))
+
+	// The instances added during the outage are discovered once the daemon recovers
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	daemon.fail(0)
+	instances, err = service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, instanceNums(instances))
+}
@@ -0,0 +1,130 @@
+package discovery
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+)
+
+const (
+	// portLabel overrides the internal Minio API port of the container
+	portLabel = "object-storage.port"
+	// minioApiPortEnv and minioAddressEnv configure the address Minio listens on
+	minioApiPortEnv = "MINIO_API_PORT="
+	minioAddressEnv = "MINIO_ADDRESS="
+	// minioAddressFlag configures the address Minio listens on, in the container command
+	minioAddressFlag = "--address"
+)
+
+// Sources of the internal Minio API port, in the order of precedence
+const (
+	portSourceLabel   = "label"
+	portSourceEnv     = "env"
+	portSourceCommand = "command"
+	portSourceExposed = "exposed"
+	portSourceDefault = "default"
+)
+
+// resolvePorts determines the internal Minio API port of the container, the source it was determined from and the host
+// binding it is published on.
+func resolvePorts(container types.ContainerJSON) (internalPort string, portSource string, publishedHost string, publishedPort string) {
+	internalPort, portSource = containerPort(container)
+
+	// Prefer the actual bindings of a running container over the configured ones
+	bindings := nat.PortMap{}
+	if container.HostConfig != nil {
+		bindings = container.HostConfig.PortBindings
+	}
+	if container.NetworkSettings != nil && len(container.NetworkSettings.Ports) > 0 {
+		bindings = container.NetworkSettings.Ports
+	}
+
+	for _, binding := range bindings[nat.Port(internalPort+"/tcp")] {
+		if binding.HostPort != "" {
+			return internalPort, portSource, binding.HostIP, binding.HostPort
+		}
+	}
+
+	return internalPort, portSource, "", ""
+}
+
+// containerPort determines the internal Minio API port of the container from, in order: the port label, the Minio
+// environment, the --address flag of the command, the exposed ports and the default Minio API port.
+// If the container exposes several ports, the default Minio API port is preferred, then the lowest exposed port.
+func containerPort(container types.ContainerJSON) (string, string) {
+	if container.Config != nil {
+		if port := container.Config.Labels[portLabel]; port != "" {
+			return port, portSourceLabel
+		}
+
+		for _, variable := range container.Config.Env {
+			if port, ok := strings.CutPrefix(variable, minioApiPortEnv); ok && port != "" {
+				return port, portSourceEnv
+			}
+
+			if address, ok := strings.CutPrefix(variable, minioAddressEnv); ok {
+				if port := addressPort(address); port != "" {
+					return port, portSourceEnv
+				}
+			}
+		}
+
+		if port := commandPort(append(append([]string{}, container.Config.Entrypoint...), container.Config.Cmd...)); port != "" {
+			return port, portSourceCommand
+		}
+	}
+
+	if port := commandPort(container.Args); port != "" {
+		return port, portSourceCommand
+	}
+
+	exposed := []nat.Port{}
+	if container.Config != nil {
+		for port := range container.Config.ExposedPorts {
+			if port.Proto() == "tcp" {
+				exposed = append(exposed, port)
+			}
+		}
+	}
+	sort.Slice(exposed, func(i, j int) bool { return exposed[i].Int() < exposed[j].Int() })
+
+	for _, port := range exposed {
+		if port.Port() == minioPort {
+			return minioPort, portSourceExposed
+		}
+	}
+
+	if len(exposed) > 0 {
+		return exposed[0].Port(), portSourceExposed
+	}
+
+	return minioPort, portSourceDefault
+}
+
+// commandPort returns the port of the --address flag in the command, e.g. "--address :9100" or "--address=:9100"
+func commandPort(command []string) string {
+	for i, arg := range command {
+		if address, ok := strings.CutPrefix(arg, minioAddressFlag+"="); ok {
+			return addressPort(address)
+		}
+
+		if arg == minioAddressFlag && i+1 < len(command) {
+			return addressPort(command[i+1])
+		}
+	}
+
+	return ""
+}
+
+// addressPort returns the port of a host:port address
+func addressPort(address string) string {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return ""
+	}
+
+	return port
+}
@@ -0,0 +1,281 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGetContainerDetails_PublishedPorts(t *testing.T) {
+	tests := []struct {
+		name          string
+		exposed       []nat.Port
+		ports         nat.PortMap
+		bindings      nat.PortMap
+		internalPort  string
+		publishedHost string
+		publishedPort string
+	}{
+		{
+			name:         "no publish",
+			exposed:      []nat.Port{"9000/tcp"},
+			internalPort: "9000",
+		},
+		{
+			name:          "remapped publish",
+			exposed:       []nat.Port{"9000/tcp"},
+			ports:         nat.PortMap{"9000/tcp": {{HostIP: "0.0.0.0", HostPort: "9100"}}},
+			internalPort:  "9000",
+			publishedHost: "0.0.0.0",
+			publishedPort: "9100",
+		},
+		{
+			name:          "configured binding of a stopped container",
+			exposed:       []nat.Port{"9000/tcp"},
+			bindings:      nat.PortMap{"9000/tcp": {{HostIP: "127.0.0.1", HostPort: "9200"}}},
+			internalPort:  "9000",
+			publishedHost: "127.0.0.1",
+			publishedPort: "9200",
+		},
+		{
+			name:    "multiple exposed ports",
+			exposed: []nat.Port{"9001/tcp", "9000/tcp", "53/udp"},
+			ports: nat.PortMap{
+				"9001/tcp": {{HostIP: "0.0.0.0", HostPort: "19001"}},
+				"9000/tcp": {{HostIP: "0.0.0.0", HostPort: "19000"}},
+			},
+			internalPort:  "9000",
+			publishedHost: "0.0.0.0",
+			publishedPort: "19000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+			daemon.update("c1", func(c *types.ContainerJSON) {
+				c.Config.ExposedPorts = nat.PortSet{}
+				for _, port := range tt.exposed {
+					c.Config.ExposedPorts[port] = struct{}{}
+				}
+				c.HostConfig = &container.HostConfig{PortBindings: tt.bindings}
+				c.NetworkSettings.Ports = tt.ports
+			})
+			service := NewServiceV1(daemon.client(), Options{})
+
+			instance, err := service.getContainerDetails(context.Background(), "c1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.internalPort, instance.InternalPort)
+			assert.Equal(t, tt.publishedHost, instance.PublishedHost)
+			assert.Equal(t, tt.publishedPort, instance.PublishedPort)
+
+			// The clients dial the container IP and the internal port by default
+			assert.Equal(t, "10.0.0.1", instance.IpAddress)
+			assert.Equal(t, tt.internalPort, instance.Port)
+		})
+	}
+}
+
+func TestGetContainerDetails_DialPublishedPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    nat.PortMap
+		hostname string
+		port     string
+		err      string
+	}{
+		{
+			name:     "any interface",
+			ports:    nat.PortMap{"9000/tcp": {{HostIP: "0.0.0.0", HostPort: "9100"}}},
+			hostname: "docker-host",
+			port:     "9100",
+		},
+		{
+			name:     "host IP",
+			ports:    nat.PortMap{"9000/tcp": {{HostIP: "192.168.1.10", HostPort: "9100"}}},
+			hostname: "192.168.1.10",
+			port:     "9100",
+		},
+		{
+			name: "not published",
+			err:  "does not publish the port 9000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+			daemon.update("c1", func(c *types.ContainerJSON) {
+				c.NetworkSettings.Ports = tt.ports
+			})
+			service := NewServiceV1(daemon.client(), Options{DialPublishedPort: true, PublishedHost: "docker-host"})
+
+			instance, err := service.getContainerDetails(context.Background(), "c1")
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.hostname, instance.Hostname)
+			assert.Equal(t, tt.port, instance.Port)
+			assert.Equal(t, "9000", instance.InternalPort)
+		})
+	}
+}
+
+func TestContainerPort(t *testing.T) {
+	tests := []struct {
+		name       string
+		labels     map[string]string
+		env        []string
+		entrypoint []string
+		cmd        []string
+		args       []string
+		exposed    []nat.Port
+		port       string
+		source     string
+	}{
+		{
+			name:   "default",
+			port:   "9000",
+			source: portSourceDefault,
+		},
+		{
+			name:   "label",
+			labels: map[string]string{portLabel: "9300"},
+			port:   "9300",
+			source: portSourceLabel,
+		},
+		{
+			name:   "API port variable",
+			env:    []string{"MINIO_API_PORT=9100"},
+			port:   "9100",
+			source: portSourceEnv,
+		},
+		{
+			name:   "address variable",
+			env:    []string{"MINIO_ADDRESS=:9101"},
+			port:   "9101",
+			source: portSourceEnv,
+		},
+		{
+			name:   "address flag",
+			cmd:    []string{"server", "/data", "--address", ":9102"},
+			port:   "9102",
+			source: portSourceCommand,
+		},
+		{
+			name:       "address flag with equals sign in the entrypoint",
+			entrypoint: []string{"minio", "server", "--address=0.0.0.0:9103"},
+			port:       "9103",
+			source:     portSourceCommand,
+		},
+		{
+			name:   "address flag in the args",
+			args:   []string{"server", "/data", "--address", ":9104"},
+			port:   "9104",
+			source: portSourceCommand,
+		},
+		{
+			name:    "exposed port",
+			exposed: []nat.Port{"9105/tcp", "53/udp"},
+			port:    "9105",
+			source:  portSourceExposed,
+		},
+		{
+			name:    "lowest exposed port",
+			exposed: []nat.Port{"9107/tcp", "9106/tcp"},
+			port:    "9106",
+			source:  portSourceExposed,
+		},
+		{
+			name:    "default exposed port preferred",
+			exposed: []nat.Port{"9001/tcp", "9000/tcp", "8000/tcp"},
+			port:    "9000",
+			source:  portSourceExposed,
+		},
+		{
+			name:    "label over the variable",
+			labels:  map[string]string{portLabel: "9300"},
+			env:     []string{"MINIO_API_PORT=9100"},
+			cmd:     []string{"server", "--address", ":9102"},
+			exposed: []nat.Port{"9105/tcp"},
+			port:    "9300",
+			source:  portSourceLabel,
+		},
+		{
+			name:    "variable over the flag",
+			env:     []string{"MINIO_API_PORT=9100"},
+			cmd:     []string{"server", "--address", ":9102"},
+			exposed: []nat.Port{"9105/tcp"},
+			port:    "9100",
+			source:  portSourceEnv,
+		},
+		{
+			name:    "flag over the exposed port",
+			cmd:     []string{"server", "--address", ":9102"},
+			exposed: []nat.Port{"9105/tcp"},
+			port:    "9102",
+			source:  portSourceCommand,
+		},
+		{
+			name:   "invalid address ignored",
+			env:    []string{"MINIO_ADDRESS=9101"},
+			cmd:    []string{"server", "--address", "invalid"},
+			port:   "9000",
+			source: portSourceDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{Args: tt.args},
+				Config: &container.Config{
+					Labels:       tt.labels,
+					Env:          tt.env,
+					Entrypoint:   tt.entrypoint,
+					Cmd:          tt.cmd,
+					ExposedPorts: nat.PortSet{},
+				},
+			}
+			for _, port := range tt.exposed {
+				c.Config.ExposedPorts[port] = struct{}{}
+			}
+
+			port, source := containerPort(c)
+			assert.Equal(t, tt.port, port)
+			assert.Equal(t, tt.source, source)
+		})
+	}
+}
+
+func TestGetContainerDetails_LogsPortSource(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.setEnv("c1", "MINIO_ROOT_USER=access", "MINIO_ROOT_PASSWORD=secret", "MINIO_API_PORT=9100")
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	service := NewServiceV1(daemon.client(), Options{})
+	service.logger = zap.New(core)
+
+	instance, err := service.getContainerDetails(context.Background(), "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "9100", instance.Port)
+
+	entries := logs.FilterMessage("Resolved the Minio API port").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, zapcore.DebugLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{"containerId": "c1", "port": "9100", "source": portSourceEnv}, entries[0].ContextMap())
+}
@@ -0,0 +1,74 @@
+package discovery
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Maximum size of a credentials file read from a container
+const maxCredentialFileSize = 4096
+
+// credentialFileVariables map the variables pointing to the credential files (Docker secrets or mounted env files)
+// to the variables holding the credentials
+var credentialFileVariables = map[string]string{
+	"MINIO_ROOT_USER_FILE=":     minioRootUser,
+	"MINIO_ROOT_PASSWORD_FILE=": minioRootPassword,
+	"MINIO_ACCESS_KEY_FILE=":    minioAccessKey,
+	"MINIO_SECRET_KEY_FILE=":    minioSecret,
+}
+
+// resolveCredentialFiles reads the credential files referenced by the _FILE variables from the container and returns
+// the environment with the credentials appended, so they take precedence over the plain variables. Files that can't
+// be read are skipped, falling back to the plain variables. The credentials are never logged.
+func (s *ServiceV1) resolveCredentialFiles(ctx context.Context, containerId string, env []string) []string {
+	resolved := env
+	for _, environmentVariable := range env {
+		for fileVariable, credentialVariable := range credentialFileVariables {
+			path, ok := strings.CutPrefix(environmentVariable, fileVariable)
+			if !ok || path == "" {
+				continue
+			}
+
+			value, err := s.readContainerFile(ctx, containerId, path)
+			if err != nil {
+				s.logger.Warn("Failed to read the credentials file", zap.String("containerId", containerId), zap.String("path", path), zap.Error(err))
+				continue
+			}
+
+			resolved = append(resolved, credentialVariable+value)
+		}
+	}
+
+	return resolved
+}
+
+// readContainerFile reads a small file from the container filesystem through the Docker API
+func (s *ServiceV1) readContainerFile(ctx context.Context, containerId, path string) (string, error) {
+	copyCtx, cancel := s.dockerContext(ctx)
+	defer cancel()
+
+	reader, _, err := s.dockerClient.CopyFromContainer(copyCtx, containerId, path)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to copy the file from the container")
+	}
+	defer reader.Close()
+
+	// The file is returned as a tar archive
+	archive := tar.NewReader(reader)
+	_, err = archive.Next()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the file archive")
+	}
+
+	content, err := io.ReadAll(io.LimitReader(archive, maxCredentialFileSize))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the file")
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,115 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGetContainerDetails_CredentialFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       []string
+		files     map[string]string
+		accessKey string
+		secretKey string
+	}{
+		{
+			name:      "variables only",
+			env:       []string{"MINIO_ROOT_USER=env-access", "MINIO_ROOT_PASSWORD=env-secret"},
+			accessKey: "env-access",
+			secretKey: "env-secret",
+		},
+		{
+			name:      "files only",
+			env:       []string{"MINIO_ROOT_USER_FILE=/run/secrets/minio_user", "MINIO_ROOT_PASSWORD_FILE=/run/secrets/minio_password"},
+			files:     map[string]string{"/run/secrets/minio_user": "file-access\n", "/run/secrets/minio_password": "file-secret\n"},
+			accessKey: "file-access",
+			secretKey: "file-secret",
+		},
+		{
+			name:      "deprecated files",
+			env:       []string{"MINIO_ACCESS_KEY_FILE=/run/secrets/access", "MINIO_SECRET_KEY_FILE=/run/secrets/secret"},
+			files:     map[string]string{"/run/secrets/access": "file-access", "/run/secrets/secret": "file-secret"},
+			accessKey: "file-access",
+			secretKey: "file-secret",
+		},
+		{
+			name:      "mixed",
+			env:       []string{"MINIO_ROOT_USER=env-access", "MINIO_ROOT_PASSWORD_FILE=/run/secrets/minio_password"},
+			files:     map[string]string{"/run/secrets/minio_password": "file-secret"},
+			accessKey: "env-access",
+			secretKey: "file-secret",
+		},
+		{
+			name:      "files take precedence",
+			env:       []string{"MINIO_ROOT_USER=env-access", "MINIO_ROOT_PASSWORD=env-secret", "MINIO_ROOT_USER_FILE=/run/secrets/minio_user"},
+			files:     map[string]string{"/run/secrets/minio_user": "file-access"},
+			accessKey: "file-access",
+			secretKey: "env-secret",
+		},
+		{
+			name:      "missing file falls back",
+			env:       []string{"MINIO_ROOT_USER=env-access", "MINIO_ROOT_PASSWORD=env-secret", "MINIO_ROOT_PASSWORD_FILE=/run/secrets/missing"},
+			accessKey: "env-access",
+			secretKey: "env-secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+			daemon.setEnv("c1", tt.env...)
+			for path, content := range tt.files {
+				daemon.addFile("c1", path, content)
+			}
+
+			core, logs := observer.New(zapcore.DebugLevel)
+			service := NewServiceV1(daemon.client(), Options{})
+			service.logger = zap.New(core)
+
+			instances, err := service.DiscoverS3Instances(context.Background())
+			require.NoError(t, err)
+			require.Len(t, instances, 1)
+			assert.Equal(t, tt.accessKey, instances[0].AccessKey)
+			assert.Equal(t, tt.secretKey, instances[0].SecretKey)
+
+			// The credentials are never logged
+			for _, entry := range logs.All() {
+				for _, value := range append([]any{entry.Message}, mapValues(entry.ContextMap())...) {
+					assert.NotContains(t, value, tt.accessKey)
+					assert.NotContains(t, value, tt.secretKey)
+				}
+			}
+		})
+	}
+}
+
+func TestGetContainerDetails_MissingCredentialFile(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.setEnv("c1", "MINIO_ROOT_USER_FILE=/run/secrets/minio_user", "MINIO_ROOT_PASSWORD_FILE=/run/secrets/minio_password")
+	daemon.addFile("c1", "/run/secrets/minio_user", "file-access")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	_, err := service.getContainerDetails(context.Background(), "c1")
+	assert.ErrorIs(t, err, ErrIncompleteInstance)
+}
+
+// mapValues returns the string values of the log fields
+func mapValues(fields map[string]any) []any {
+	values := []any{}
+	for _, value := range fields {
+		if s, ok := value.(string); ok {
+			values = append(values, s)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,91 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addLabeledMinio adds a Minio container with the labels
+func addLabeledMinio(daemon *fakeDocker, id, name string, labels map[string]string) {
+	daemon.addMinio(id, name, "10.0.0.1")
+	daemon.update(id, func(c *types.ContainerJSON) {
+		c.Config.Labels = labels
+	})
+}
+
+func TestDiscoverS3Instances_Selection(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		setup   func(daemon *fakeDocker)
+		want    map[string]int
+	}{
+		{
+			name:    "prefix only",
+			options: Options{ContainerPrefix: "storage-"},
+			setup: func(daemon *fakeDocker) {
+				daemon.addMinio("c1", "storage-1", "10.0.0.1")
+				daemon.addMinio("c2", "storage-2", "10.0.0.2")
+				daemon.addMinio("other", "amazin-object-node-3", "10.0.0.3")
+			},
+			want: map[string]int{"c1": 1, "c2": 2},
+		},
+		{
+			name:    "label only",
+			options: Options{ContainerPrefix: "storage-", MemberLabel: "object-storage.member=true", InstanceLabel: "object-storage.instance"},
+			setup: func(daemon *fakeDocker) {
+				addLabeledMinio(daemon, "c1", "minio-a", map[string]string{"object-storage.member": "true", "object-storage.instance": "7"})
+				addLabeledMinio(daemon, "c2", "minio-b", map[string]string{"object-storage.member": "true", "object-storage.instance": "8"})
+				addLabeledMinio(daemon, "other", "minio-c", map[string]string{"object-storage.member": "false", "object-storage.instance": "9"})
+			},
+			want: map[string]int{"c1": 7, "c2": 8},
+		},
+		{
+			name:    "mixed, preferring the instance label",
+			options: Options{ContainerPrefix: "storage-", MemberLabel: "object-storage.member", InstanceLabel: "object-storage.instance"},
+			setup: func(daemon *fakeDocker) {
+				daemon.addMinio("c1", "storage-1", "10.0.0.1")
+				addLabeledMinio(daemon, "c2", "storage-2", map[string]string{"object-storage.member": "true", "object-storage.instance": "5"})
+				addLabeledMinio(daemon, "c3", "minio-c", map[string]string{"object-storage.member": "true", "object-storage.instance": "3"})
+				daemon.addMinio("other", "postgres", "10.0.0.4")
+			},
+			want: map[string]int{"c1": 1, "c2": 5, "c3": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			tt.setup(daemon)
+			service := NewServiceV1(daemon.client(), tt.options)
+
+			instances, err := service.DiscoverS3Instances(context.Background())
+			require.NoError(t, err)
+
+			numbers := map[string]int{}
+			for _, instance := range instances {
+				numbers[instance.ContainerId] = instance.InstanceNum
+			}
+			assert.Equal(t, tt.want, numbers)
+
+			// The containers are filtered by the daemon, the others are never inspected
+			assert.Zero(t, daemon.inspectionCount("other"))
+		})
+	}
+}
+
+func TestDiscoverS3Instances_DefaultPrefix(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.addMinio("other", "storage-2", "10.0.0.2")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "c1", instances[0].ContainerId)
+}
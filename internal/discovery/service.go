@@ -2,13 +2,24 @@ package discovery
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	docker "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -16,116 +27,724 @@ const (
 	minioPort         = "9000"
 	minioAccessKey    = "MINIO_ACCESS_KEY="
 	minioSecret       = "MINIO_SECRET_KEY="
+	minioRootUser     = "MINIO_ROOT_USER="
+	minioRootPassword = "MINIO_ROOT_PASSWORD="
+	// Label docker compose sets to the replica number of a scaled service
+	composeReplicaLabel = "com.docker.compose.container-number"
+	// Label holding the relative storage capacity of the instance
+	weightLabel = "minio.weight"
+	// Maximum number of containers inspected at once
+	maxConcurrentInspections = 4
+	// State of the containers that can serve the requests
+	containerStateRunning = "running"
 )
 
+var ErrMissingCredentials = errors.New("missing S3 credentials")
+
 type Service interface {
 	DiscoverS3Instances(ctx context.Context) ([]S3Instance, error)
+	// Watch emits the full instance set whenever the membership or the credentials change, starting with the current
+	// set if it is known. The channel is closed when the context is cancelled.
+	Watch(ctx context.Context) (<-chan []S3Instance, error)
 	Ready(ctx context.Context) bool
 }
 
 type ServiceV1 struct {
 	dockerClient *docker.Client
+	events       eventSource
+	options      Options
 	logger       *zap.Logger
+
+	// Bounds each request to the Docker daemon, except the event stream. Zero means no bound.
+	dockerTimeout time.Duration
+
+	// Only the containers attached to the network are discovered, if set
+	network string
+
+	// Networks the gateway container is attached to, resolved once the daemon answers. Nil until resolved.
+	selfNetworksMu sync.Mutex
+	selfNetworks   map[string]struct{}
+
+	// In-memory instance set, keyed by the container ID. Maintained by Run.
+	mu        sync.RWMutex
+	instances map[string]S3Instance
+	watching  bool
+
+	// Last successfully discovered instances, served while the daemon is briefly unreachable
+	lastKnown   []S3Instance
+	lastKnownAt time.Time
+	// Concurrent discoveries share a single listing
+	listGroup singleflight.Group
+
+	healthMonitor *BackgroundHealthMonitor
+	notifier      notifier
+
+	// Instance numbers found by the last discovery, nil before the first one
+	discovered []int
+	// Containers skipped by the last discovery
+	skipped []SkippedInstance
+	// Time of the last successful discovery and the last error, reported by Status
+	lastDiscoveryAt time.Time
+	lastError       string
+	lastErrorAt     time.Time
+
+	// Minio versions of the containers, detected once per container
+	versionsMu sync.Mutex
+	versions   map[string]string
+
+	// Result of the last Docker ping, reused for statusCacheTTL
+	pingMu  sync.Mutex
+	pingErr error
+	pingAt  time.Time
+
+	// Result of the last Docker version request, reused for statusCacheTTL
+	daemonMu   sync.Mutex
+	daemonInfo *DaemonInfo
+	daemonErr  error
+	daemonAt   time.Time
 }
 
-func NewServiceV1(dockerClient *docker.Client) *ServiceV1 {
-	return &ServiceV1{
+// Option configures the ServiceV1
+type Option func(*ServiceV1)
+
+// WithHealthMonitor polls the health of the discovered instances in the background every interval.
+// The monitor is started by MonitorHealth.
+func WithHealthMonitor(interval time.Duration) Option {
+	return func(s *ServiceV1) {
+		s.healthMonitor = NewBackgroundHealthMonitor(s.discoverS3Instances, interval)
+	}
+}
+
+// WithDockerNetwork discovers only the containers attached to the Docker network and dials their IP address on it.
+// Prevents picking up the instances on unintended networks on multi-network hosts.
+func WithDockerNetwork(networkName string) Option {
+	return func(s *ServiceV1) {
+		s.network = networkName
+	}
+}
+
+// WithDockerTimeout bounds each request to the Docker daemon, so a hung daemon can't block the discovery. The event
+// stream stays open for as long as Run watches it.
+func WithDockerTimeout(timeout time.Duration) Option {
+	return func(s *ServiceV1) {
+		s.dockerTimeout = timeout
+	}
+}
+
+func NewServiceV1(dockerClient *docker.Client, options Options, opts ...Option) *ServiceV1 {
+	s := &ServiceV1{
 		logger:       zap.L().Named("discovery"),
 		dockerClient: dockerClient,
+		options:      options,
+		events:       dockerClient.Events,
+		instances:    map[string]S3Instance{},
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-// DiscoverS3Instances returns a list of available S3 instances from the Docker daemon, filtered by the prefix.
-// Possible improvement - implement a cache for the instances, so we don't have to query Docker every time.
+// dockerContext returns the context of a single request to the Docker daemon, bound by the Docker timeout
+func (s *ServiceV1) dockerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.dockerTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, s.dockerTimeout)
+}
+
+// Close releases the connections to the Docker daemon
+func (s *ServiceV1) Close() error {
+	if s.dockerClient == nil {
+		return nil
+	}
+
+	return s.dockerClient.Close()
+}
+
+// DiscoverS3Instances returns a list of available S3 instances from the Docker daemon, filtered by the prefix or label.
+// If the service is watching Docker events, the instances are served from memory. With FilterUnhealthy, the instances
+// found unhealthy by the health monitor are excluded.
 func (s *ServiceV1) DiscoverS3Instances(ctx context.Context) ([]S3Instance, error) {
+	start := time.Now()
+	defer func() { discoverCallDuration.Observe(time.Since(start).Seconds()) }()
+
+	instances, err := s.discoverS3Instances(ctx)
+	if err != nil || s.healthMonitor == nil || !s.options.FilterUnhealthy {
+		return instances, err
+	}
+
+	healthy := []S3Instance{}
+	for _, instance := range instances {
+		if s.healthMonitor.Healthy(instance.InstanceNum) {
+			healthy = append(healthy, instance)
+		}
+	}
+
+	return healthy, nil
+}
+
+// discoverS3Instances returns all S3 instances, regardless of their health
+func (s *ServiceV1) discoverS3Instances(ctx context.Context) ([]S3Instance, error) {
+	if instances, ok := s.cachedInstances(); ok {
+		return instances, nil
+	}
+
+	return s.listS3InstancesWithRetry(ctx)
+}
+
+// Watch emits the instance set whenever it changes. The changes are detected by Run, or by the discoveries otherwise.
+func (s *ServiceV1) Watch(ctx context.Context) (<-chan []S3Instance, error) {
+	return s.notifier.subscribe(ctx), nil
+}
+
+// MonitorHealth runs the health monitor, if configured. Blocks until the context is cancelled.
+func (s *ServiceV1) MonitorHealth(ctx context.Context) {
+	if s.healthMonitor == nil {
+		return
+	}
+
+	s.healthMonitor.Run(ctx)
+}
+
+// InstanceHealth returns the health of the instances checked by the health monitor
+func (s *ServiceV1) InstanceHealth() map[int]bool {
+	if s.healthMonitor == nil {
+		return map[int]bool{}
+	}
+
+	return s.healthMonitor.InstanceHealth()
+}
+
+// listS3Instances queries the Docker daemon for the running S3 instances.
+func (s *ServiceV1) listS3Instances(ctx context.Context) ([]S3Instance, error) {
 	s.logger.Info("Discovering S3 instances")
 
-	// Get the list of active containers - we will filter out the ones that are not S3 instances
-	containers, err := s.dockerClient.ContainerList(ctx, container.ListOptions{All: false})
+	start := time.Now()
+	defer func() { discoveryDuration.Observe(time.Since(start).Seconds()) }()
+
+	// Docker filters can't combine the name and the label with OR, so the containers are listed once per selector
+	selectors := []filters.Args{filters.NewArgs(filters.Arg("name", regexp.QuoteMeta(s.containerPrefix())))}
+	if s.options.MemberLabel != "" {
+		selectors = append(selectors, filters.NewArgs(filters.Arg("label", s.options.MemberLabel)))
+	}
+
+	if s.network != "" {
+		for _, selector := range selectors {
+			selector.Add("network", s.network)
+		}
+	}
+
+	containerIds := []string{}
+	seen := map[string]bool{}
+	excluded := []string{}
+	for _, selector := range selectors {
+		// Get the list of active S3 instance containers
+		listCtx, cancel := s.dockerContext(ctx)
+		containers, err := s.dockerClient.ContainerList(listCtx, container.ListOptions{All: false, Filters: selector})
+		cancel()
+		if err != nil {
+			dockerErrors.Inc()
+			return nil, errors.Wrap(err, "failed to list containers")
+		}
+
+		for _, c := range containers {
+			if seen[c.ID] {
+				continue
+			}
+			seen[c.ID] = true
+
+			// Paused and restarting containers are listed as well, but can't serve the requests
+			if c.State != containerStateRunning {
+				excluded = append(excluded, fmt.Sprintf("%s (%s)", c.ID, c.State))
+				continue
+			}
+
+			containerIds = append(containerIds, c.ID)
+		}
+	}
+
+	if len(excluded) > 0 {
+		s.logger.Info("Excluded S3 instance containers that are not running", zap.Strings("containers", excluded))
+	}
+
+	// Inspect the containers concurrently - each result keeps the position of its container
+	details := make([]*S3Instance, len(containerIds))
+	var (
+		skippedMu sync.Mutex
+		skipped   []SkippedInstance
+	)
+	skip := func(containerId string, err error) {
+		skippedMu.Lock()
+		skipped = append(skipped, SkippedInstance{ContainerId: containerId, Reason: err.Error()})
+		skippedMu.Unlock()
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(maxConcurrentInspections)
+
+	for i, containerId := range containerIds {
+		i, containerId := i, containerId
+		group.Go(func() error {
+			s.logger.Info("Found an S3 instance container", zap.String("containerId", containerId))
+
+			// Get the container details
+			instance, err := s.getContainerDetails(groupCtx, containerId)
+			switch {
+			case errors.Is(err, ErrIncompleteInstance) && s.options.StrictCredentials:
+				return errors.Wrapf(err, "container %s", containerId)
+			case errors.Is(err, ErrIncompleteInstance):
+				s.logger.Warn("Skipping incomplete S3 instance", zap.String("containerId", containerId), zap.String("reason", err.Error()))
+				skip(containerId, err)
+				return nil
+			case err != nil && s.options.StrictInspection:
+				return err
+			case err != nil:
+				s.logger.Warn("Skipping S3 instance that could not be inspected", zap.String("containerId", containerId), zap.Error(err))
+				skip(containerId, err)
+				return nil
+			}
+
+			details[i] = instance
+			// The credentials must not be logged
+			s.logger.Debug("Extracted container configuration",
+				zap.String("containerId", instance.ContainerId),
+				zap.Int("instance", instance.InstanceNum),
+				zap.String("hostname", instance.Hostname),
+				zap.String("port", instance.Port),
+			)
+			return nil
+		})
+	}
+
+	err := group.Wait()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to list containers")
+		return nil, err
 	}
+	s.forgetVersions(seen)
 
 	response := []S3Instance{}
+	for _, instance := range details {
+		if instance != nil {
+			response = append(response, *instance)
+		}
+	}
 
-	for _, c := range containers {
-		for _, name := range c.Names {
-			// Include only the containers that have the "amazin-object-storage-node-" in their name
-			if strings.Contains(name, s3ContainerPrefix) {
-				s.logger.Info("Found an S3 instance container", zap.String("containerId", c.ID), zap.String("name", name))
+	// Order by the instance number, so the result doesn't depend on the inspection order
+	sort.SliceStable(response, func(i, j int) bool {
+		if response[i].InstanceNum != response[j].InstanceNum {
+			return response[i].InstanceNum < response[j].InstanceNum
+		}
 
-				// Get the container details
-				details, err := s.getContainerDetails(ctx, c.ID)
-				if err != nil {
-					return nil, err
-				}
+		return response[i].Replica < response[j].Replica
+	})
 
-				response = append(response, *details)
-				s.logger.Debug("Extracted container configuration", zap.Any("details", *details))
-			}
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].ContainerId < skipped[j].ContainerId
+	})
+	s.mu.Lock()
+	s.skipped = skipped
+	s.mu.Unlock()
+
+	instances := s.dedupeInstances(response)
+	s.recordDiscovery(instances)
+	return instances, nil
+}
+
+// dedupeInstances keeps a single container per shard key and orders the instances by the number. Replicas of a
+// compose-scaled node or a stale container next to a restarted one share the shard key, and using more than one of
+// them would break the sharding. The most recently started container is preferred, then the lowest replica.
+func (s *ServiceV1) dedupeInstances(instances []S3Instance) []S3Instance {
+	deduped := []S3Instance{}
+	indexes := map[string]int{}
+
+	for _, instance := range instances {
+		index, ok := indexes[instance.ShardKey()]
+		if !ok {
+			indexes[instance.ShardKey()] = len(deduped)
+			deduped = append(deduped, instance)
+			continue
+		}
+
+		current := deduped[index]
+		preferred := instance.StartedAt.After(current.StartedAt) ||
+			(instance.StartedAt.Equal(current.StartedAt) && instance.Replica < current.Replica)
+
+		kept, dropped := current, instance
+		if preferred {
+			kept, dropped = instance, current
+			deduped[index] = instance
+		}
+
+		s.logger.Warn("Multiple containers share an instance",
+			zap.String("shardKey", instance.ShardKey()),
+			zap.String("keptContainerId", kept.ContainerId),
+			zap.String("droppedContainerId", dropped.ContainerId),
+		)
+	}
+
+	sort.Slice(deduped, func(i, j int) bool {
+		if deduped[i].InstanceNum != deduped[j].InstanceNum {
+			return deduped[i].InstanceNum < deduped[j].InstanceNum
+		}
+
+		return deduped[i].ShardKey() < deduped[j].ShardKey()
+	})
+
+	// Distinct instances can still share a number, e.g. the containers named alike but labelled with different keys,
+	// or a number derived from a key colliding with another. Both are kept, as the sharding tells them apart, but
+	// the pinning and the migration address the instances by the number.
+	for i := 1; i < len(deduped); i++ {
+		if deduped[i].InstanceNum == deduped[i-1].InstanceNum {
+			s.logger.Warn("Distinct instances share an instance number",
+				zap.Int("instance", deduped[i].InstanceNum),
+				zap.String("shardKey", deduped[i-1].ShardKey()),
+				zap.String("otherShardKey", deduped[i].ShardKey()),
+			)
 		}
 	}
 
-	return response, nil
+	return deduped
+}
+
+// isS3Container checks if the container is an S3 instance, either by the member label or by the name prefix.
+func (s *ServiceV1) isS3Container(name string, labels map[string]string) bool {
+	if s.options.MemberLabel != "" {
+		key, value, hasValue := strings.Cut(s.options.MemberLabel, "=")
+		if labelValue, ok := labels[key]; ok && (!hasValue || labelValue == value) {
+			return true
+		}
+	}
+
+	return strings.Contains(name, s.containerPrefix())
+}
+
+// containerPrefix returns the configured container name prefix, defaulting to "amazin-object-storage-node-"
+func (s *ServiceV1) containerPrefix() string {
+	if s.options.ContainerPrefix == "" {
+		return s3ContainerPrefix
+	}
+
+	return s.options.ContainerPrefix
 }
 
 // getContainerDetails returns the details of a container
 func (s *ServiceV1) getContainerDetails(ctx context.Context, containerId string) (*S3Instance, error) {
 	s.logger.Info("Inspecting container", zap.String("containerId", containerId))
 
-	inspectedContainer, err := s.dockerClient.ContainerInspect(ctx, containerId)
+	start := time.Now()
+	defer func() { inspectDuration.Observe(time.Since(start).Seconds()) }()
+
+	inspectCtx, cancel := s.dockerContext(ctx)
+	defer cancel()
+
+	inspectedContainer, err := s.dockerClient.ContainerInspect(inspectCtx, containerId)
 	if err != nil {
+		dockerErrors.Inc()
 		return nil, errors.Wrap(err, "failed to inspect container")
 	}
 
-	// Example: "/deployment-amazin-object-storage-node-2-1"
-	// We need to trim any /<> from the name and remove the trailing -1 if it exists
 	containerName := strings.Trim(inspectedContainer.Name, "/")
-	nameParts := strings.Split(containerName, "-")
-	prefix := s3ContainerPrefix
-	if nameParts[0] != "amazin" {
-		prefix = nameParts[0] + "-" + s3ContainerPrefix
+	labels := inspectedContainer.Config.Labels
+	instanceKey := ""
+	if s.options.InstanceKeyLabel != "" {
+		instanceKey = labels[s.options.InstanceKeyLabel]
+	}
+
+	instanceId, replica, err := s.instanceNumber(containerName, labels)
+	if err != nil && instanceKey != "" {
+		// The key identifies the instance, regardless of the container name
+		instanceId, replica, err = instanceNumberFromKey(instanceKey), composeReplica(labels), nil
+	}
+	if err != nil {
+		parseFailures.Inc()
+		return nil, err
+	}
+
+	// Extract the access key and secret key from the container environment or the referenced credential files
+	env := s.resolveCredentialFiles(ctx, containerId, inspectedContainer.Config.Env)
+	// Missing credentials are reported by the validation below
+	s3AccessKey, s3SecretKey, err := extractCredentials(env)
+	if err != nil {
+		credentialFailures.Inc()
+	}
+
+	network, ipAddress := s.resolveIpAddress(ctx, inspectedContainer.NetworkSettings)
+	internalPort, portSource, publishedHost, publishedPort := resolvePorts(inspectedContainer)
+	s.logger.Debug("Resolved the Minio API port", zap.String("containerId", containerId), zap.String("port", internalPort), zap.String("source", portSource))
+
+	instance := &S3Instance{
+		ContainerId:      containerId,
+		InstanceNum:      instanceId,
+		InstanceKey:      instanceKey,
+		Replica:          replica,
+		WeightedCapacity: s.instanceWeight(containerName, inspectedContainer.Config.Labels),
+		IpAddress:        ipAddress,
+		Network:          network,
+		Hostname:         inspectedContainer.Config.Hostname,
+		AccessKey:        s3AccessKey,
+		SecretKey:        s3SecretKey,
+		InternalPort:     internalPort,
+		PublishedHost:    publishedHost,
+		PublishedPort:    publishedPort,
+		StartedAt:        startedAt(inspectedContainer.State),
+		Secure:           isTLSContainer(inspectedContainer.Config.Labels, inspectedContainer.Config.Env),
+		// By default, the upload/download will occur in the same docker network
+		Port: internalPort,
+	}
+
+	if instance.Secure {
+		instance.CACert = s.options.TLSCACert
+		instance.InsecureSkipVerify = s.options.TLSInsecureSkipVerify
+	}
+
+	// Dial the published port when the gateway runs outside the Docker network
+	if s.options.DialPublishedPort {
+		if publishedPort == "" {
+			return nil, errors.Errorf("container %s does not publish the port %s", containerName, internalPort)
+		}
+
+		instance.Hostname = s.options.PublishedHost
+		if publishedHost != "" && publishedHost != "0.0.0.0" && publishedHost != "::" {
+			instance.Hostname = publishedHost
+		}
+		instance.Port = publishedPort
+	}
+
+	err = validateInstance(*instance)
+	if err != nil {
+		return nil, err
+	}
+
+	instance.Healthy = containerHealthy(ctx, inspectedContainer.State, *instance)
+	instance.LastChecked = time.Now()
+	if !instance.Healthy {
+		s.logger.Warn("S3 instance is unhealthy", zap.String("containerId", containerId), zap.Int("instance", instanceId))
+	}
+
+	instance.Version = s.minioVersion(ctx, inspectedContainer.Config.Image, *instance)
+
+	// A container that keeps restarting is unhealthy, even if it is currently up
+	if s.options.MaxRestartCount > 0 && inspectedContainer.RestartCount > s.options.MaxRestartCount {
+		instance.Healthy = false
+		s.logger.Warn("S3 instance keeps restarting", zap.String("containerId", containerId), zap.Int("instance", instanceId), zap.Int("restarts", inspectedContainer.RestartCount))
+	}
+
+	return instance, nil
+}
+
+// instanceNumber determines the number of the S3 instance and its compose replica number.
+// The instance label takes precedence over the container name.
+func (s *ServiceV1) instanceNumber(containerName string, labels map[string]string) (int, int, error) {
+	if instanceLabel, ok := labels[s.options.InstanceLabel]; ok && s.options.InstanceLabel != "" {
+		instanceId, err := strconv.Atoi(instanceLabel)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "failed to parse instance ID from label %s", s.options.InstanceLabel)
+		}
+
+		return instanceId, composeReplica(labels), nil
+	}
+
+	return parseContainerName(containerName, s.containerPrefix())
+}
+
+// composeReplica returns the compose replica number of the container, 1 if it is not a compose replica
+func composeReplica(labels map[string]string) int {
+	replica, err := strconv.Atoi(labels[composeReplicaLabel])
+	if err != nil {
+		return 1
+	}
+
+	return replica
+}
+
+// instanceWeight returns the weight from the minio.weight label, defaulting to 1 when it is missing or invalid
+func (s *ServiceV1) instanceWeight(containerName string, labels map[string]string) int {
+	weightString, ok := labels[weightLabel]
+	if !ok {
+		return 1
+	}
+
+	weight, err := strconv.Atoi(weightString)
+	if err != nil || weight < 1 {
+		s.logger.Warn("Invalid instance weight, defaulting to 1", zap.String("name", containerName), zap.String("weight", weightString))
+		return 1
+	}
+
+	return weight
+}
+
+// parseContainerName extracts the node number and the compose replica number from the container name.
+// The name may start with a compose project prefix and end with a replica suffix, separated by "-" (compose v2) or "_" (compose v1).
+// Examples: "amazin-object-storage-node-1", "deployment-amazin-object-storage-node-2-1", "deployment_amazin-object-storage-node-12_3".
+// Names without a replica suffix are replica 1.
+func parseContainerName(containerName, prefix string) (int, int, error) {
+	pattern := regexp.MustCompile(regexp.QuoteMeta(prefix) + `(\d+)(?:[-_](\d+))?$`)
+
+	matches := pattern.FindStringSubmatch(containerName)
+	if matches == nil {
+		return 0, 0, errors.Errorf("failed to parse instance ID from container name %s", containerName)
+	}
+
+	instanceId, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to parse instance ID")
+	}
+
+	replica := 1
+	if matches[2] != "" {
+		replica, err = strconv.Atoi(matches[2])
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to parse replica number")
+		}
+	}
+
+	return instanceId, replica, nil
+}
+
+// resolveIpAddress picks the IP address of the container. Containers attached only to user-defined networks
+// have no IP address on the default bridge, so the networks are searched as well. A network shared with the gateway
+// container is preferred, falling back to the first network with an IP address, and to the default bridge.
+// With the network configured, only the address on that network is used.
+func (s *ServiceV1) resolveIpAddress(ctx context.Context, settings *types.NetworkSettings) (string, string) {
+	if settings == nil {
+		return "", ""
+	}
+
+	if s.network != "" {
+		if endpoint, ok := settings.Networks[s.network]; ok && endpoint != nil {
+			return s.network, endpoint.IPAddress
+		}
+
+		return s.network, ""
+	}
+
+	selfNetworks := s.gatewayNetworks(ctx)
+
+	// Sort the network names, so the fallback is deterministic
+	names := make([]string, 0, len(settings.Networks))
+	for name, endpoint := range settings.Networks {
+		if endpoint != nil && endpoint.IPAddress != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, shared := selfNetworks[name]; shared {
+			return name, settings.Networks[name].IPAddress
+		}
+	}
+
+	if len(names) > 0 {
+		return names[0], settings.Networks[names[0]].IPAddress
+	}
+
+	return "bridge", settings.IPAddress
+}
+
+// gatewayNetworks returns the networks the gateway container is attached to. Docker sets the container hostname
+// to the container ID, so the gateway can inspect itself. When not running in a container, no networks are returned.
+// The networks are resolved once the daemon answers - a failed inspection is retried on the next call.
+func (s *ServiceV1) gatewayNetworks(ctx context.Context) map[string]struct{} {
+	s.selfNetworksMu.Lock()
+	defer s.selfNetworksMu.Unlock()
+
+	if s.selfNetworks != nil {
+		return s.selfNetworks
 	}
 
-	// Extract the instance ID from the container name - the number after the prefix
-	// Example: "/amazin-object-storage-node-1"
-	// Example: "/amazin-object-storage-node"
-	instanceIdString := strings.TrimSuffix(strings.TrimPrefix(containerName, prefix), "-1")
-	instanceId, err := strconv.Atoi(instanceIdString)
+	hostname, err := os.Hostname()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse instance ID")
+		s.logger.Warn("Unable to determine the gateway hostname", zap.Error(err))
+		return nil
+	}
+
+	inspectCtx, cancel := s.dockerContext(ctx)
+	defer cancel()
+
+	self, err := s.dockerClient.ContainerInspect(inspectCtx, hostname)
+	switch {
+	case errdefs.IsNotFound(err):
+		s.logger.Debug("Gateway is not running in a Docker container", zap.Error(err))
+		s.selfNetworks = map[string]struct{}{}
+		return s.selfNetworks
+	case err != nil:
+		s.logger.Warn("Unable to inspect the gateway container, retrying on the next discovery", zap.Error(err))
+		return nil
 	}
 
-	// Extract the access key and secret key from the container environment
-	s3AccessKey, s3SecretKey := "", ""
-	for _, environmentVariable := range inspectedContainer.Config.Env {
-		if strings.HasPrefix(environmentVariable, minioAccessKey) {
-			s3AccessKey = strings.Split(environmentVariable, "=")[1]
-		} else if strings.HasPrefix(environmentVariable, minioSecret) {
-			s3SecretKey = strings.Split(environmentVariable, "=")[1]
+	s.selfNetworks = map[string]struct{}{}
+	if self.NetworkSettings != nil {
+		for name := range self.NetworkSettings.Networks {
+			s.selfNetworks[name] = struct{}{}
 		}
 	}
 
-	return &S3Instance{
-		ContainerId: containerId,
-		InstanceNum: instanceId,
-		IpAddress:   inspectedContainer.NetworkSettings.IPAddress,
-		Hostname:    inspectedContainer.Config.Hostname,
-		AccessKey:   s3AccessKey,
-		SecretKey:   s3SecretKey,
-		// We can assume that the port is always 9000, since the upload/download will occur in the same docker network
-		Port: minioPort,
-	}, nil
+	return s.selfNetworks
+}
+
+// extractCredentials extracts the access key and secret key from the container environment.
+// MINIO_ROOT_USER/MINIO_ROOT_PASSWORD take precedence over the deprecated MINIO_ACCESS_KEY/MINIO_SECRET_KEY.
+func extractCredentials(env []string) (string, string, error) {
+	accessKey, secretKey, rootUser, rootPassword := "", "", "", ""
+	for _, environmentVariable := range env {
+		switch {
+		case strings.HasPrefix(environmentVariable, minioRootUser):
+			rootUser = strings.TrimPrefix(environmentVariable, minioRootUser)
+		case strings.HasPrefix(environmentVariable, minioRootPassword):
+			rootPassword = strings.TrimPrefix(environmentVariable, minioRootPassword)
+		case strings.HasPrefix(environmentVariable, minioAccessKey):
+			accessKey = strings.TrimPrefix(environmentVariable, minioAccessKey)
+		case strings.HasPrefix(environmentVariable, minioSecret):
+			secretKey = strings.TrimPrefix(environmentVariable, minioSecret)
+		}
+	}
+
+	if rootUser != "" && rootPassword != "" {
+		return rootUser, rootPassword, nil
+	}
+
+	if accessKey != "" && secretKey != "" {
+		return accessKey, secretKey, nil
+	}
+
+	return "", "", ErrMissingCredentials
 }
 
 // Ready checks if the service is ready (if Docker client is connected)
 func (s *ServiceV1) Ready(ctx context.Context) bool {
 	s.logger.Debug("Checking if the service is ready")
-	if s.dockerClient == nil {
-		return false
-	}
 
 	// Try to ping docker
-	_, err := s.dockerClient.Ping(ctx)
-	return err == nil
+	err := s.pingDaemon(ctx)
+	if err == nil {
+		return true
+	}
+
+	// The last known instances are still served while the daemon is briefly unreachable
+	if _, _, ok := s.lastKnownInstances(); ok && isTransientDaemonError(err) {
+		s.logger.Warn("Docker daemon briefly unreachable, serving the last known instances", zap.Error(err))
+		return true
+	}
+
+	s.logger.Warn("Docker daemon unreachable", zap.Error(err))
+	return false
+}
+
+// startedAt parses the start time of the container. Zero if the container state is unknown.
+func startedAt(state *types.ContainerState) time.Time {
+	if state == nil {
+		return time.Time{}
+	}
+
+	started, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return started
 }
@@ -0,0 +1,305 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGatewayNetworks_RetriesFailedInspection(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	daemon := newFakeDocker(t)
+	daemon.add(types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: hostname, Name: "/gateway"},
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{"storage": {IPAddress: "10.0.0.2"}},
+		},
+	})
+	service := NewServiceV1(daemon.client(), Options{})
+	ctx := context.Background()
+
+	// The daemon failing is not cached
+	daemon.fail(http.StatusInternalServerError)
+	assert.Empty(t, service.gatewayNetworks(ctx))
+
+	daemon.fail(0)
+	assert.Equal(t, map[string]struct{}{"storage": {}}, service.gatewayNetworks(ctx))
+
+	// Once resolved, the networks are not inspected again
+	assert.Equal(t, map[string]struct{}{"storage": {}}, service.gatewayNetworks(ctx))
+	assert.Equal(t, 1, daemon.inspectionCount(hostname))
+}
+
+func TestGatewayNetworks_NotInContainer(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	daemon := newFakeDocker(t)
+	service := NewServiceV1(daemon.client(), Options{})
+
+	// A missing container is an answer - the gateway is not running in a container
+	assert.Empty(t, service.gatewayNetworks(context.Background()))
+	assert.Empty(t, service.gatewayNetworks(context.Background()))
+	assert.Equal(t, 1, daemon.inspectionCount(hostname))
+}
+
+func TestDedupeInstances(t *testing.T) {
+	started := time.Unix(1000, 0)
+	instance := func(containerId string, number, replica int, startedAt time.Time) S3Instance {
+		return S3Instance{ContainerId: containerId, InstanceNum: number, Replica: replica, StartedAt: startedAt}
+	}
+	containerIds := func(instances []S3Instance) []string {
+		ids := []string{}
+		for _, instance := range instances {
+			ids = append(ids, instance.ContainerId)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name      string
+		instances []S3Instance
+		want      []string
+	}{
+		{
+			name:      "gaps are ordered by the number",
+			instances: []S3Instance{instance("five", 5, 1, started), instance("one", 1, 1, started), instance("three", 3, 1, started)},
+			want:      []string{"one", "three", "five"},
+		},
+		{
+			name:      "most recently started container is kept",
+			instances: []S3Instance{instance("restarted", 3, 1, started.Add(time.Minute)), instance("one", 1, 1, started), instance("stale", 3, 1, started)},
+			want:      []string{"one", "restarted"},
+		},
+		{
+			name:      "lowest replica is kept when started together",
+			instances: []S3Instance{instance("replica-2", 1, 2, started), instance("replica-1", 1, 1, started)},
+			want:      []string{"replica-1"},
+		},
+		{
+			name: "containers of the same key are deduplicated",
+			instances: []S3Instance{
+				{ContainerId: "stale", InstanceNum: 2, InstanceKey: "zone-a", StartedAt: started},
+				{ContainerId: "restarted", InstanceNum: 2, InstanceKey: "zone-a", StartedAt: started.Add(time.Minute)},
+			},
+			want: []string{"restarted"},
+		},
+		{
+			name: "containers of different keys sharing a number are kept",
+			instances: []S3Instance{
+				{ContainerId: "zone-b", InstanceNum: 2, InstanceKey: "zone-b", StartedAt: started},
+				{ContainerId: "zone-a", InstanceNum: 2, InstanceKey: "zone-a", StartedAt: started},
+			},
+			want: []string{"zone-a", "zone-b"},
+		},
+	}
+
+	service := NewServiceV1(nil, Options{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, containerIds(service.dedupeInstances(tt.instances)))
+		})
+	}
+}
+
+func TestDedupeInstances_NumberCollision(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	service := NewServiceV1(nil, Options{})
+	service.logger = zap.New(core)
+
+	// The number derived from the key of a label-only instance collides with the number of a named instance
+	labelled := S3Instance{ContainerId: "labelled", InstanceNum: instanceNumberFromKey("zone-a"), InstanceKey: "zone-a"}
+	named := S3Instance{ContainerId: "named", InstanceNum: labelled.InstanceNum}
+
+	instances := service.dedupeInstances([]S3Instance{labelled, named})
+	assert.Len(t, instances, 2)
+
+	warnings := logs.FilterMessage("Distinct instances share an instance number").All()
+	require.Len(t, warnings, 1)
+	assert.EqualValues(t, labelled.InstanceNum, warnings[0].ContextMap()["instance"])
+	assert.Equal(t, named.ShardKey(), warnings[0].ContextMap()["shardKey"])
+	assert.Equal(t, "zone-a", warnings[0].ContextMap()["otherShardKey"])
+}
+
+func TestGetContainerDetails_Network(t *testing.T) {
+	endpoints := func(ipAddresses map[string]string) map[string]*network.EndpointSettings {
+		networks := map[string]*network.EndpointSettings{}
+		for name, ipAddress := range ipAddresses {
+			networks[name] = &network.EndpointSettings{IPAddress: ipAddress}
+		}
+		return networks
+	}
+
+	tests := []struct {
+		name            string
+		settings        *types.NetworkSettings
+		gatewayNetworks map[string]string
+		opts            []Option
+		network         string
+		ipAddress       string
+	}{
+		{
+			name:      "default bridge",
+			settings:  &types.NetworkSettings{DefaultNetworkSettings: types.DefaultNetworkSettings{IPAddress: "172.17.0.2"}},
+			network:   "bridge",
+			ipAddress: "172.17.0.2",
+		},
+		{
+			name:      "single custom network",
+			settings:  &types.NetworkSettings{Networks: endpoints(map[string]string{"storage": "10.0.0.5"})},
+			network:   "storage",
+			ipAddress: "10.0.0.5",
+		},
+		{
+			name:            "multiple networks prefer the gateway network",
+			settings:        &types.NetworkSettings{Networks: endpoints(map[string]string{"a-backup": "10.1.0.5", "storage": "10.0.0.5"})},
+			gatewayNetworks: map[string]string{"storage": "10.0.0.2"},
+			network:         "storage",
+			ipAddress:       "10.0.0.5",
+		},
+		{
+			name:      "multiple networks fall back to the first with an IP address",
+			settings:  &types.NetworkSettings{Networks: endpoints(map[string]string{"a-detached": "", "b-storage": "10.0.0.5", "c-backup": "10.1.0.5"})},
+			network:   "b-storage",
+			ipAddress: "10.0.0.5",
+		},
+		{
+			name:      "configured network",
+			settings:  &types.NetworkSettings{Networks: endpoints(map[string]string{"a-backup": "10.1.0.5", "storage": "10.0.0.5"})},
+			opts:      []Option{WithDockerNetwork("storage")},
+			network:   "storage",
+			ipAddress: "10.0.0.5",
+		},
+	}
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			if tt.gatewayNetworks != nil {
+				daemon.add(types.ContainerJSON{
+					ContainerJSONBase: &types.ContainerJSONBase{ID: hostname, Name: "/gateway"},
+					NetworkSettings:   &types.NetworkSettings{Networks: endpoints(tt.gatewayNetworks)},
+				})
+			}
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "")
+			daemon.setNetworks("c1", tt.settings)
+			service := NewServiceV1(daemon.client(), Options{}, tt.opts...)
+
+			instance, err := service.getContainerDetails(context.Background(), "c1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.network, instance.Network)
+			assert.Equal(t, tt.ipAddress, instance.IpAddress)
+		})
+	}
+}
+
+func TestGetContainerDetails_DockerTimeout(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("minio1", "amazin-object-storage-node-1", "172.17.0.2")
+	daemon.delayInspections(time.Second)
+	service := NewServiceV1(daemon.client(), Options{}, WithDockerTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	_, err := service.getContainerDetails(context.Background(), "minio1")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestParseContainerName(t *testing.T) {
+	const prefix = "amazin-object-storage-node-"
+
+	tests := []struct {
+		name     string
+		instance int
+		replica  int
+		err      bool
+	}{
+		{name: "amazin-object-storage-node-1", instance: 1, replica: 1},
+		{name: "amazin-object-storage-node-12", instance: 12, replica: 1},
+		{name: "amazin-object-storage-node-2-1", instance: 2, replica: 1},
+		{name: "amazin-object-storage-node-2-3", instance: 2, replica: 3},
+		{name: "deployment-amazin-object-storage-node-2-3", instance: 2, replica: 3},
+		{name: "deployment-amazin-object-storage-node-7", instance: 7, replica: 1},
+		{name: "deployment_amazin-object-storage-node-12_3", instance: 12, replica: 3},
+		{name: "my-project-2-amazin-object-storage-node-105-11", instance: 105, replica: 11},
+		{name: "amazin-object-storage-node-007", instance: 7, replica: 1},
+		{name: "amazin-object-storage-node-", err: true},
+		{name: "amazin-object-storage-node-abc", err: true},
+		{name: "amazin-object-storage-node-1-abc", err: true},
+		{name: "amazin-object-storage-node-1-2-3", err: true},
+		{name: "minio-1", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, replica, err := parseContainerName(tt.name, prefix)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.instance, instance)
+			assert.Equal(t, tt.replica, replica)
+		})
+	}
+}
+
+func TestDiscoverS3Instances_ComposeReplicas(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "deployment-amazin-object-storage-node-1-1", "10.0.0.1")
+	daemon.addMinio("c2", "deployment-amazin-object-storage-node-1-2", "10.0.0.2")
+	daemon.addMinio("c3", "deployment-amazin-object-storage-node-2-1", "10.0.0.3")
+	service := NewServiceV1(daemon.client(), Options{})
+
+	// The replicas of a node are one instance, the lowest replica is kept
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, "c1", instances[0].ContainerId)
+	assert.Equal(t, "c3", instances[1].ContainerId)
+}
+
+func TestGetContainerDetails_Weight(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		weight int
+	}{
+		{name: "no label", labels: map[string]string{}, weight: 1},
+		{name: "weight 2", labels: map[string]string{weightLabel: "2"}, weight: 2},
+		{name: "zero", labels: map[string]string{weightLabel: "0"}, weight: 1},
+		{name: "invalid", labels: map[string]string{weightLabel: "large"}, weight: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+			daemon.update("c1", func(c *types.ContainerJSON) {
+				c.Config.Labels = tt.labels
+			})
+			service := NewServiceV1(daemon.client(), Options{})
+
+			instance, err := service.getContainerDetails(context.Background(), "c1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.weight, instance.WeightedCapacity)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package discovery
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrIncompleteInstance is returned for an instance missing the configuration needed to connect to it
+var ErrIncompleteInstance = errors.New("incomplete S3 instance")
+
+// SkippedInstance is a container left out of the last discovery
+type SkippedInstance struct {
+	ContainerId string
+	Reason      string
+}
+
+// SkipReporter reports the containers skipped by the last discovery
+type SkipReporter interface {
+	SkippedInstances() []SkippedInstance
+}
+
+// SkippedInstances returns the containers skipped by the last discovery
+func (s *ServiceV1) SkippedInstances() []SkippedInstance {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]SkippedInstance(nil), s.skipped...)
+}
+
+// validateInstance checks the instance has the credentials and the address needed to create a client
+func validateInstance(instance S3Instance) error {
+	var missing []string
+	if instance.AccessKey == "" {
+		missing = append(missing, "access key")
+	}
+
+	if instance.SecretKey == "" {
+		missing = append(missing, "secret key")
+	}
+
+	if instance.Hostname == "" {
+		missing = append(missing, "hostname")
+	}
+
+	if instance.Port == "" {
+		missing = append(missing, "port")
+	}
+
+	if len(missing) > 0 {
+		return errors.Wrapf(ErrIncompleteInstance, "missing %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	staticInstancesKey = "instances"
+	staticDialTimeout  = time.Second * 2
+)
+
+// StaticInstance is an S3 instance listed in the configuration
+type StaticInstance struct {
+	Endpoint    string `mapstructure:"endpoint"`
+	AccessKey   string `mapstructure:"accessKey"`
+	SecretKey   string `mapstructure:"secretKey"`
+	InstanceNum int    `mapstructure:"instanceNum"`
+	// Secure dials the instance with TLS, optionally verified with the CA bundle at CACert
+	Secure             bool   `mapstructure:"secure"`
+	CACert             string `mapstructure:"caCert"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
+}
+
+// StaticService discovers the S3 instances listed in the configuration file, for setups without access to the Docker socket:
+//
+//	instances:
+//	  - endpoint: minio-1:9000
+//	    accessKey: ring
+//	    secretKey: treepotato
+//	    instanceNum: 1
+//	  - endpoint: minio-2:9000
+//	    accessKey: ring
+//	    secretKey: treepotato
+//	    instanceNum: 2
+//	    secure: true
+//	    caCert: /etc/ssl/minio-ca.pem
+type StaticService struct {
+	config *viper.Viper
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	instances []S3Instance
+	notifier  notifier
+}
+
+// NewStaticService creates a new instance of the StaticService, loading the instances from the configuration
+func NewStaticService(config *viper.Viper) (*StaticService, error) {
+	s := &StaticService{
+		config: config,
+		logger: zap.L().Named("static-discovery"),
+	}
+
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// WatchConfig reloads the instances when the configuration file changes
+func (s *StaticService) WatchConfig() {
+	s.config.OnConfigChange(func(event fsnotify.Event) {
+		s.logger.Info("Configuration changed, reloading S3 instances", zap.String("file", event.Name))
+
+		err := s.load()
+		if err != nil {
+			s.logger.Error("Failed to reload S3 instances, keeping the previous ones", zap.Error(err))
+		}
+	})
+	s.config.WatchConfig()
+}
+
+// load parses the instances from the configuration
+func (s *StaticService) load() error {
+	staticInstances := []StaticInstance{}
+	err := s.config.UnmarshalKey(staticInstancesKey, &staticInstances)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse static S3 instances")
+	}
+
+	instances := make([]S3Instance, 0, len(staticInstances))
+	for _, staticInstance := range staticInstances {
+		host, port, err := net.SplitHostPort(staticInstance.Endpoint)
+		if err != nil {
+			return errors.Wrapf(err, "invalid endpoint of instance %d", staticInstance.InstanceNum)
+		}
+
+		if staticInstance.AccessKey == "" || staticInstance.SecretKey == "" {
+			return errors.Wrapf(ErrMissingCredentials, "instance %d", staticInstance.InstanceNum)
+		}
+
+		instances = append(instances, S3Instance{
+			InstanceNum:        staticInstance.InstanceNum,
+			Replica:            1,
+			WeightedCapacity:   1,
+			AccessKey:          staticInstance.AccessKey,
+			SecretKey:          staticInstance.SecretKey,
+			Hostname:           host,
+			Port:               port,
+			InternalPort:       port,
+			Secure:             staticInstance.Secure,
+			CACert:             staticInstance.CACert,
+			InsecureSkipVerify: staticInstance.InsecureSkipVerify,
+		})
+	}
+
+	s.mu.Lock()
+	s.instances = instances
+	s.mu.Unlock()
+	s.notifier.publish(instances)
+
+	s.logger.Info("Loaded static S3 instances", zap.Int("count", len(instances)))
+	return nil
+}
+
+// DiscoverS3Instances returns the S3 instances from the configuration
+func (s *StaticService) DiscoverS3Instances(ctx context.Context) ([]S3Instance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	instances := make([]S3Instance, len(s.instances))
+	copy(instances, s.instances)
+	return instances, nil
+}
+
+// Watch emits the instance set whenever the configuration changes
+func (s *StaticService) Watch(ctx context.Context) (<-chan []S3Instance, error) {
+	return s.notifier.subscribe(ctx), nil
+}
+
+// Ready checks if the service is ready (if any instance is configured and at least one of them accepts connections)
+func (s *StaticService) Ready(ctx context.Context) bool {
+	s.logger.Debug("Checking if the service is ready")
+
+	instances, _ := s.DiscoverS3Instances(ctx)
+
+	dialer := net.Dialer{Timeout: staticDialTimeout}
+	for _, instance := range instances {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(instance.Hostname, instance.Port))
+		if err != nil {
+			s.logger.Debug("S3 instance is unreachable", zap.Int("instance", instance.InstanceNum), zap.Error(err))
+			continue
+		}
+
+		_ = conn.Close()
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const staticConfig = `instances:
+  - endpoint: minio-1:9000
+    accessKey: ring
+    secretKey: treepotato
+    instanceNum: 1
+  - endpoint: 10.0.0.2:9100
+    accessKey: ring2
+    secretKey: treepotato2
+    instanceNum: 2
+    secure: true
+    caCert: /etc/ssl/minio-ca.pem
+`
+
+// newStaticConfig writes the configuration file and reads it into a new viper instance
+func newStaticConfig(t *testing.T, content string) (*viper.Viper, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "instances.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	config := viper.New()
+	config.SetConfigFile(path)
+	require.NoError(t, config.ReadInConfig())
+	return config, path
+}
+
+func TestStaticService_Parse(t *testing.T) {
+	config, _ := newStaticConfig(t, staticConfig)
+	service, err := NewStaticService(config)
+	require.NoError(t, err)
+
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []S3Instance{
+		{InstanceNum: 1, Replica: 1, WeightedCapacity: 1, AccessKey: "ring", SecretKey: "treepotato", Hostname: "minio-1", Port: "9000", InternalPort: "9000"},
+		{InstanceNum: 2, Replica: 1, WeightedCapacity: 1, AccessKey: "ring2", SecretKey: "treepotato2", Hostname: "10.0.0.2", Port: "9100", InternalPort: "9100", Secure: true, CACert: "/etc/ssl/minio-ca.pem"},
+	}, instances)
+}
+
+func TestStaticService_ParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "endpoint without port",
+			content: "instances:\n  - endpoint: minio-1\n    accessKey: ring\n    secretKey: treepotato\n    instanceNum: 1\n",
+			err:     "invalid endpoint of instance 1",
+		},
+		{
+			name:    "missing credentials",
+			content: "instances:\n  - endpoint: minio-1:9000\n    accessKey: ring\n    instanceNum: 1\n",
+			err:     ErrMissingCredentials.Error(),
+		},
+		{
+			name:    "invalid instances",
+			content: "instances: minio-1:9000\n",
+			err:     "failed to parse static S3 instances",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _ := newStaticConfig(t, tt.content)
+			_, err := NewStaticService(config)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestStaticService_Reload(t *testing.T) {
+	config, path := newStaticConfig(t, staticConfig)
+	service, err := NewStaticService(config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := service.Watch(ctx)
+	require.NoError(t, err)
+	service.WatchConfig()
+
+	// The subscription starts with the loaded instances
+	require.Len(t, <-changes, 2)
+
+	require.NoError(t, os.WriteFile(path, []byte("instances:\n  - endpoint: minio-3:9000\n    accessKey: ring\n    secretKey: treepotato\n    instanceNum: 3\n"), 0o600))
+	select {
+	case instances := <-changes:
+		require.Len(t, instances, 1)
+		assert.Equal(t, 3, instances[0].InstanceNum)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the instances were not reloaded")
+	}
+
+	// An invalid configuration keeps the previous instances
+	require.NoError(t, os.WriteFile(path, []byte("instances:\n  - endpoint: minio-4\n    instanceNum: 4\n"), 0o600))
+	time.Sleep(200 * time.Millisecond)
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, 3, instances[0].InstanceNum)
+}
+
+func TestStaticService_Ready(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	closed, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddress := closed.Addr().String()
+	require.NoError(t, closed.Close())
+
+	instance := func(instanceNum int, endpoint string) string {
+		return "  - endpoint: " + endpoint + "\n    accessKey: ring\n    secretKey: treepotato\n    instanceNum: " + strconv.Itoa(instanceNum) + "\n"
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		ready   bool
+	}{
+		{name: "no instances", content: "instances: []\n"},
+		{name: "unreachable instance", content: "instances:\n" + instance(1, closedAddress)},
+		{name: "one reachable instance", content: "instances:\n" + instance(1, closedAddress) + instance(2, listener.Addr().String()), ready: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _ := newStaticConfig(t, tt.content)
+			service, err := NewStaticService(config)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.ready, service.Ready(context.Background()))
+		})
+	}
+}
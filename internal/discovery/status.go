@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// statusCacheTTL is how long the results of the Docker ping and version are reused, so frequent probes don't hit the daemon
+const statusCacheTTL = time.Second * 5
+
+// DiscoveryStatus describes the state of the discovery
+type DiscoveryStatus struct {
+	DaemonReachable bool
+	// LastDiscovery is the time of the last successful discovery, zero before the first one
+	LastDiscovery time.Time
+	InstanceCount int
+	// LastError is the last error of the Docker ping or the discovery, empty if none occurred
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// StatusReporter reports the state of the discovery
+type StatusReporter interface {
+	Status(ctx context.Context) DiscoveryStatus
+}
+
+// Status returns the state of the discovery. The daemon reachability is cached for a few seconds.
+func (s *ServiceV1) Status(ctx context.Context) DiscoveryStatus {
+	pingErr := s.pingDaemon(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return DiscoveryStatus{
+		DaemonReachable: pingErr == nil,
+		LastDiscovery:   s.lastDiscoveryAt,
+		InstanceCount:   len(s.discovered),
+		LastError:       s.lastError,
+		LastErrorAt:     s.lastErrorAt,
+	}
+}
+
+// pingDaemon pings the Docker daemon, reusing the result of a recent ping
+func (s *ServiceV1) pingDaemon(ctx context.Context) error {
+	s.pingMu.Lock()
+	defer s.pingMu.Unlock()
+
+	if !s.pingAt.IsZero() && time.Since(s.pingAt) < statusCacheTTL {
+		return s.pingErr
+	}
+
+	pingCtx, cancel := s.dockerContext(ctx)
+	defer cancel()
+
+	var err error
+	if s.dockerClient == nil {
+		err = errors.New("no Docker client configured")
+	} else if _, pingErr := s.dockerClient.Ping(pingCtx); pingErr != nil {
+		dockerErrors.Inc()
+		err = errors.Wrap(pingErr, "failed to ping the Docker daemon")
+	}
+
+	s.pingErr = err
+	s.pingAt = time.Now()
+	if err != nil {
+		s.recordDiscoveryError(err)
+	}
+
+	return err
+}
+
+// recordDiscoveryError stores the error for the status
+func (s *ServiceV1) recordDiscoveryError(err error) {
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+	s.mu.Unlock()
+}
@@ -0,0 +1,79 @@
+package discovery
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Label marking the S3 instance container as serving TLS
+	tlsLabel = "object-storage.tls"
+	// Minio serves TLS when the certificates directory is configured
+	minioCertsDir = "MINIO_CERTS_DIR="
+)
+
+// CA bundles are read once per path
+var caPools sync.Map
+
+// TLSConfig returns the TLS configuration used to dial the instance, or nil if the instance doesn't serve TLS
+func (i S3Instance) TLSConfig() (*tls.Config, error) {
+	if !i.Secure {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: i.InsecureSkipVerify,
+	}
+
+	if i.CACert != "" {
+		pool, err := caPool(i.CACert)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// caPool loads the CA bundle from the file
+func caPool(path string) (*x509.CertPool, error) {
+	if pool, ok := caPools.Load(path); ok {
+		return pool.(*x509.CertPool), nil
+	}
+
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the CA bundle")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.Errorf("no certificates found in the CA bundle %s", path)
+	}
+
+	caPools.Store(path, pool)
+	return pool, nil
+}
+
+// isTLSContainer checks if the container serves TLS, either by the TLS label or the Minio certificates directory
+func isTLSContainer(labels map[string]string, env []string) bool {
+	if labels[tlsLabel] == "true" {
+		return true
+	}
+
+	for _, environmentVariable := range env {
+		if strings.HasPrefix(environmentVariable, minioCertsDir) {
+			return true
+		}
+	}
+
+	return false
+}
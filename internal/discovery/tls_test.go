@@ -0,0 +1,68 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContainerDetails_TLS(t *testing.T) {
+	options := Options{TLSCACert: "/etc/ssl/minio-ca.pem", TLSInsecureSkipVerify: true}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		env    []string
+		secure bool
+	}{
+		{
+			name:   "plain",
+			secure: false,
+		},
+		{
+			name:   "label",
+			labels: map[string]string{tlsLabel: "true"},
+			secure: true,
+		},
+		{
+			name:   "label disabled",
+			labels: map[string]string{tlsLabel: "false"},
+			secure: false,
+		},
+		{
+			name:   "certificates directory",
+			env:    []string{"MINIO_CERTS_DIR=/certs"},
+			secure: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := newFakeDocker(t)
+			daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+			daemon.update("c1", func(c *types.ContainerJSON) {
+				for key, value := range tt.labels {
+					c.Config.Labels[key] = value
+				}
+				c.Config.Env = append(c.Config.Env, tt.env...)
+			})
+
+			instances, err := NewServiceV1(daemon.client(), options).DiscoverS3Instances(context.Background())
+			require.NoError(t, err)
+			require.Len(t, instances, 1)
+
+			instance := instances[0]
+			assert.Equal(t, tt.secure, instance.Secure)
+			if tt.secure {
+				assert.Equal(t, options.TLSCACert, instance.CACert)
+				assert.True(t, instance.InsecureSkipVerify)
+			} else {
+				assert.Empty(t, instance.CACert)
+				assert.False(t, instance.InsecureSkipVerify)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package discovery
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Validator checks the configuration of a service before it serves the requests
+type Validator interface {
+	// Validate returns all configuration errors found, nil if the configuration is valid
+	Validate(ctx context.Context) []error
+}
+
+// Validate checks that the Docker daemon is reachable and at least one S3 instance can be discovered
+func (s *ServiceV1) Validate(ctx context.Context) []error {
+	var errs []error
+
+	if strings.TrimSpace(s.containerPrefix()) == "" {
+		errs = append(errs, errors.New("the container prefix must not be blank"))
+	}
+
+	err := s.pingDaemon(ctx)
+	if err != nil {
+		// Nothing can be discovered without the daemon
+		return append(errs, err)
+	}
+
+	instances, err := s.discoverS3Instances(ctx)
+	switch {
+	case err != nil:
+		errs = append(errs, errors.Wrap(err, "failed to discover S3 instances"))
+	case len(instances) == 0:
+		errs = append(errs, errors.Errorf("no S3 instances found with the container prefix %q or the label %q", s.containerPrefix(), s.options.MemberLabel))
+	}
+
+	return errs
+}
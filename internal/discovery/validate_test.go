@@ -0,0 +1,67 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+
+	service := NewServiceV1(daemon.client(), Options{})
+	assert.Empty(t, service.Validate(context.Background()))
+}
+
+func TestValidate_BlankContainerPrefix(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+
+	service := NewServiceV1(daemon.client(), Options{ContainerPrefix: "  "})
+	errs := service.Validate(context.Background())
+	require.NotEmpty(t, errs)
+	assert.ErrorContains(t, errs[0], "container prefix must not be blank")
+}
+
+func TestValidate_DaemonUnreachable(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.fail(http.StatusInternalServerError)
+
+	// Nothing else is checked without the daemon
+	service := NewServiceV1(daemon.client(), Options{})
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "failed to ping the Docker daemon")
+}
+
+func TestValidate_NoDockerClient(t *testing.T) {
+	service := &ServiceV1{options: Options{}}
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "no Docker client configured")
+}
+
+func TestValidate_NoInstances(t *testing.T) {
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "other-container-1", "10.0.0.1")
+
+	service := NewServiceV1(daemon.client(), Options{})
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "no S3 instances found")
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	daemon := newFakeDocker(t)
+
+	service := NewServiceV1(daemon.client(), Options{ContainerPrefix: " "})
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 2)
+	assert.ErrorContains(t, errs[0], "container prefix must not be blank")
+	assert.ErrorContains(t, errs[1], "no S3 instances found")
+}
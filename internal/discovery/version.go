@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// minioReleasePrefix prefixes the Minio release versions, e.g. RELEASE.2024-03-15T01-07-19Z
+	minioReleasePrefix = "RELEASE."
+	minioReleaseLayout = "2006-01-02T15-04-05Z"
+)
+
+// Feature is a feature of the S3 instances that depends on the Minio version
+type Feature string
+
+const (
+	// FeatureBucketNotifications is the Minio extension listening to the bucket notifications
+	FeatureBucketNotifications Feature = "bucket-notifications"
+	// FeatureObjectTagging is the S3 object tagging API
+	FeatureObjectTagging Feature = "object-tagging"
+)
+
+// featureReleases are the first Minio releases supporting the features
+var featureReleases = map[Feature]time.Time{
+	FeatureBucketNotifications: time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+	FeatureObjectTagging:       time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// ReleaseTime parses the time of the Minio release from the version. Returns false if the version is unknown.
+func (i S3Instance) ReleaseTime() (time.Time, bool) {
+	release, ok := strings.CutPrefix(i.Version, minioReleasePrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	releaseTime, err := time.Parse(minioReleaseLayout, release)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return releaseTime, true
+}
+
+// Supports checks if the Minio version of the instance supports the feature. Instances of an unknown version are
+// assumed to support all features.
+func (i S3Instance) Supports(feature Feature) bool {
+	releaseTime, ok := i.ReleaseTime()
+	if !ok {
+		return true
+	}
+
+	return !releaseTime.Before(featureReleases[feature])
+}
+
+// minioVersion detects the Minio version of the container from the image tag, or from the Server header of the
+// liveness endpoint. The version is cached per container, so it is detected once. Empty if the version is unknown.
+func (s *ServiceV1) minioVersion(ctx context.Context, image string, instance S3Instance) string {
+	s.versionsMu.Lock()
+	version, ok := s.versions[instance.ContainerId]
+	s.versionsMu.Unlock()
+	if ok {
+		return version
+	}
+
+	version = imageVersion(image)
+	if version == "" {
+		version = serverVersion(ctx, instance)
+	}
+
+	s.versionsMu.Lock()
+	if s.versions == nil {
+		s.versions = map[string]string{}
+	}
+	s.versions[instance.ContainerId] = version
+	s.versionsMu.Unlock()
+
+	return version
+}
+
+// forgetVersions drops the cached versions of the containers that are gone
+func (s *ServiceV1) forgetVersions(containers map[string]bool) {
+	s.versionsMu.Lock()
+	defer s.versionsMu.Unlock()
+
+	for containerId := range s.versions {
+		if !containers[containerId] {
+			delete(s.versions, containerId)
+		}
+	}
+}
+
+// imageVersion returns the release tag of a Minio image, e.g. minio/minio:RELEASE.2024-03-15T01-07-19Z
+func imageVersion(image string) string {
+	index := strings.LastIndex(image, ":")
+	if index < 0 || !strings.HasPrefix(image[index+1:], minioReleasePrefix) {
+		return ""
+	}
+
+	return image[index+1:]
+}
+
+// serverVersion returns the release from the Server header of the instance, e.g. MinIO/RELEASE.2019-08-21T19-40-07Z.
+// Recent Minio releases don't include the release in the header.
+func serverVersion(ctx context.Context, instance S3Instance) string {
+	response, err := probeLiveness(ctx, discoveryProbeClient, instance, http.MethodHead)
+	if err != nil {
+		return ""
+	}
+	defer response.Body.Close()
+
+	_, release, ok := strings.Cut(response.Header.Get("Server"), "/")
+	if !ok || !strings.HasPrefix(release, minioReleasePrefix) {
+		return ""
+	}
+
+	return release
+}
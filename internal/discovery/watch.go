@@ -0,0 +1,196 @@
+package discovery
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"go.uber.org/zap"
+)
+
+// Backoff of the reconnects to the event stream, variables so the tests can shorten it
+var (
+	minWatchBackoff = time.Second
+	maxWatchBackoff = time.Second * 30
+)
+
+// eventSource returns a stream of Docker events and a stream of errors. It matches the signature of the Docker client
+// Events method, so the event stream can be replaced with a synthetic one.
+type eventSource func(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+
+// Run subscribes to the Docker events API and keeps an in-memory set of S3 instances up to date.
+// Once the instance set is reconciled, DiscoverS3Instances serves the instances from memory while the events keep
+// it up to date. When the reconciliation fails or the event stream drops, the instances are listed from the Docker
+// daemon again until the stream is reconnected with backoff. The instance set is fully reconciled against the
+// container list every reconcileInterval as a safety net. Run blocks until the context is cancelled.
+func (s *ServiceV1) Run(ctx context.Context, reconcileInterval time.Duration) {
+	s.logger.Info("Watching Docker events for S3 instances", zap.Duration("reconcileInterval", reconcileInterval))
+	defer s.setWatching(false)
+
+	go s.reconcileLoop(ctx, reconcileInterval)
+
+	backoff := minWatchBackoff
+	for {
+		// Populate the instance set before serving from memory, catching up on the events missed while disconnected
+		err := s.reconcile(ctx)
+		if err == nil {
+			s.setWatching(true)
+
+			var received bool
+			received, err = s.consumeEvents(ctx)
+			s.setWatching(false)
+
+			// The events flowed again, so the stream is healthy - the next drop is retried quickly
+			if received {
+				backoff = minWatchBackoff
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.logger.Warn("Docker event stream interrupted, reconnecting", zap.Error(err), zap.Duration("backoff", backoff))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxWatchBackoff)
+	}
+}
+
+// setWatching marks the in-memory instance set as kept up to date by Run, publishing it once it is
+func (s *ServiceV1) setWatching(watching bool) {
+	s.mu.Lock()
+	s.watching = watching
+	s.mu.Unlock()
+
+	if watching {
+		s.publishInstances()
+	}
+}
+
+// consumeEvents reads the container events until the stream returns an error or the context is cancelled.
+// Returns whether any event was received.
+func (s *ServiceV1) consumeEvents(ctx context.Context) (bool, error) {
+	eventFilters := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("event", string(events.ActionStart)),
+		filters.Arg("event", string(events.ActionStop)),
+		filters.Arg("event", string(events.ActionDie)),
+		filters.Arg("event", string(events.ActionPause)),
+		filters.Arg("event", string(events.ActionUnPause)),
+	)
+
+	received := false
+	messages, errs := s.events(ctx, types.EventsOptions{Filters: eventFilters})
+	for {
+		select {
+		case <-ctx.Done():
+			return received, ctx.Err()
+		case err := <-errs:
+			return received, err
+		case message := <-messages:
+			received = true
+			s.handleEvent(ctx, message)
+		}
+	}
+}
+
+// handleEvent updates the instance set based on a single container event.
+func (s *ServiceV1) handleEvent(ctx context.Context, message events.Message) {
+	// Include only the S3 instance containers - event attributes hold the container name and labels
+	name := message.Actor.Attributes["name"]
+	if !s.isS3Container(name, message.Actor.Attributes) {
+		return
+	}
+
+	logger := s.logger.With(zap.String("containerId", message.Actor.ID), zap.String("name", name), zap.String("action", string(message.Action)))
+
+	switch message.Action {
+	case events.ActionStart, events.ActionUnPause:
+		details, err := s.getContainerDetails(ctx, message.Actor.ID)
+		if err != nil {
+			logger.Error("Failed to get details of a started S3 instance", zap.Error(err))
+			return
+		}
+
+		s.mu.Lock()
+		s.instances[message.Actor.ID] = *details
+		s.mu.Unlock()
+		logger.Info("S3 instance added")
+		s.publishInstances()
+	case events.ActionStop, events.ActionDie, events.ActionPause:
+		s.mu.Lock()
+		delete(s.instances, message.Actor.ID)
+		s.mu.Unlock()
+		logger.Info("S3 instance removed")
+		s.publishInstances()
+	}
+}
+
+// reconcileLoop periodically reconciles the instance set until the context is cancelled.
+func (s *ServiceV1) reconcileLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = s.reconcile(ctx)
+		}
+	}
+}
+
+// reconcile replaces the in-memory instance set with the instances listed by the Docker daemon.
+func (s *ServiceV1) reconcile(ctx context.Context) error {
+	s.logger.Debug("Reconciling S3 instances")
+
+	instances, err := s.listS3Instances(ctx)
+	if err != nil {
+		s.logger.Error("Failed to reconcile S3 instances", zap.Error(err))
+		s.recordDiscoveryError(err)
+		return err
+	}
+
+	instanceSet := make(map[string]S3Instance, len(instances))
+	for _, instance := range instances {
+		instanceSet[instance.ContainerId] = instance
+	}
+
+	s.mu.Lock()
+	s.instances = instanceSet
+	s.mu.Unlock()
+	s.publishInstances()
+	return nil
+}
+
+// publishInstances notifies the watchers about the in-memory instance set
+func (s *ServiceV1) publishInstances() {
+	instances, ok := s.cachedInstances()
+	if ok {
+		s.notifier.publish(instances)
+	}
+}
+
+// cachedInstances returns the in-memory instance set and whether it is being kept up to date by Run.
+func (s *ServiceV1) cachedInstances() ([]S3Instance, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.watching {
+		return nil, false
+	}
+
+	response := make([]S3Instance, 0, len(s.instances))
+	for _, instance := range s.instances {
+		response = append(response, instance)
+	}
+
+	return s.dedupeInstances(response), true
+}
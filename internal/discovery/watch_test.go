@@ -0,0 +1,159 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventStream is a subscription to the synthetic events
+type eventStream struct {
+	messages chan events.Message
+	errs     chan error
+}
+
+// fakeEvents returns an event source whose subscriptions are sent to the returned channel
+func fakeEvents() (eventSource, <-chan eventStream) {
+	subscriptions := make(chan eventStream, 100)
+	source := func(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+		stream := eventStream{messages: make(chan events.Message), errs: make(chan error, 1)}
+		subscriptions <- stream
+		return stream.messages, stream.errs
+	}
+
+	return source, subscriptions
+}
+
+// shortenWatchBackoff shortens the reconnect backoff for the test
+func shortenWatchBackoff(t *testing.T, minBackoff, maxBackoff time.Duration) {
+	previousMin, previousMax := minWatchBackoff, maxWatchBackoff
+	minWatchBackoff, maxWatchBackoff = minBackoff, maxBackoff
+	t.Cleanup(func() { minWatchBackoff, maxWatchBackoff = previousMin, previousMax })
+}
+
+// newWatchedService starts watching the events of the daemon, returning the event subscriptions
+func newWatchedService(t *testing.T, daemon *fakeDocker) (*ServiceV1, <-chan eventStream) {
+	service := NewServiceV1(daemon.client(), Options{})
+	source, subscriptions := fakeEvents()
+	service.events = source
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.Run(ctx, time.Hour)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	return service, subscriptions
+}
+
+func receive(t *testing.T, subscriptions <-chan eventStream) eventStream {
+	t.Helper()
+
+	select {
+	case stream := <-subscriptions:
+		return stream
+	case <-time.After(5 * time.Second):
+		t.Fatal("no subscription to the events")
+		return eventStream{}
+	}
+}
+
+func watching(service *ServiceV1) bool {
+	_, ok := service.cachedInstances()
+	return ok
+}
+
+func TestRun_WatchesOnlyAfterReconcile(t *testing.T) {
+	shortenWatchBackoff(t, 10*time.Millisecond, 10*time.Millisecond)
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+	daemon.fail(http.StatusInternalServerError)
+
+	service, subscriptions := newWatchedService(t, daemon)
+
+	// The failed reconciliation doesn't serve the empty instance set from memory
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, watching(service))
+	assert.Empty(t, subscriptions)
+
+	daemon.fail(0)
+	receive(t, subscriptions)
+	instances, ok := service.cachedInstances()
+	require.True(t, ok)
+	require.Len(t, instances, 1)
+	assert.Equal(t, 1, instances[0].InstanceNum)
+}
+
+func TestRun_EventsUpdateInstances(t *testing.T) {
+	shortenWatchBackoff(t, 10*time.Millisecond, 10*time.Millisecond)
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+
+	service, subscriptions := newWatchedService(t, daemon)
+	stream := receive(t, subscriptions)
+
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	stream.messages <- events.Message{Action: events.ActionStart, Actor: events.Actor{ID: "c2", Attributes: map[string]string{"name": "amazin-object-storage-node-2"}}}
+	stream.messages <- events.Message{Action: events.ActionDie, Actor: events.Actor{ID: "c1", Attributes: map[string]string{"name": "amazin-object-storage-node-1"}}}
+
+	require.Eventually(t, func() bool {
+		instances, _ := service.DiscoverS3Instances(context.Background())
+		return len(instances) == 1 && instances[0].InstanceNum == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The instances are served from memory, not listed again
+	assert.Equal(t, 1, daemon.inspectionCount("c1"))
+}
+
+func TestRun_StopsWatchingWhileReconnecting(t *testing.T) {
+	shortenWatchBackoff(t, time.Hour, time.Hour)
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+
+	service, subscriptions := newWatchedService(t, daemon)
+	stream := receive(t, subscriptions)
+	require.True(t, watching(service))
+
+	// The events missed while disconnected would make the memory stale
+	stream.errs <- errors.New("stream dropped")
+	require.Eventually(t, func() bool { return !watching(service) }, 5*time.Second, 10*time.Millisecond)
+
+	daemon.addMinio("c2", "amazin-object-storage-node-2", "10.0.0.2")
+	instances, err := service.DiscoverS3Instances(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+}
+
+func TestRun_ResetsBackoffOnceEventsFlow(t *testing.T) {
+	const minBackoff = 20 * time.Millisecond
+	shortenWatchBackoff(t, minBackoff, time.Hour)
+	daemon := newFakeDocker(t)
+	daemon.addMinio("c1", "amazin-object-storage-node-1", "10.0.0.1")
+
+	_, subscriptions := newWatchedService(t, daemon)
+
+	// Drop the stream without any events, growing the backoff to 16 * minBackoff
+	for i := 0; i < 4; i++ {
+		receive(t, subscriptions).errs <- errors.New("stream dropped")
+	}
+
+	stream := receive(t, subscriptions)
+	stream.messages <- events.Message{Action: events.ActionStart, Actor: events.Actor{ID: "c1", Attributes: map[string]string{"name": "amazin-object-storage-node-1"}}}
+	dropped := time.Now()
+	stream.errs <- errors.New("stream dropped")
+
+	receive(t, subscriptions)
+	assert.Less(t, time.Since(dropped), 8*minBackoff)
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditEvent describes an operation modifying an object
+type AuditEvent struct {
+	Operation   string
+	ObjectId    string
+	InstanceNum int
+	// ClientIP is the IP of the client requesting the operation, empty for the operations run by the gateway itself
+	ClientIP string
+	// Subject is the authenticated subject of the client, empty if the client was not authenticated with a JWT
+	Subject string
+	Time    time.Time
+	Err     error
+}
+
+// Requester describes the client requesting the operations, recorded in their audit events
+type Requester struct {
+	ClientIP string
+	Subject  string
+}
+
+// requesterKey is the context key of the Requester
+type requesterKey struct{}
+
+// WithRequester returns a context carrying the requester of the operations run with it
+func WithRequester(ctx context.Context, requester Requester) context.Context {
+	return context.WithValue(ctx, requesterKey{}, requester)
+}
+
+// requesterFrom returns the requester carried by the context, if any
+func requesterFrom(ctx context.Context) Requester {
+	requester, _ := ctx.Value(requesterKey{}).(Requester)
+	return requester
+}
+
+// AuditLogger records the operations modifying the objects
+type AuditLogger interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// nopAuditLogger discards the audit events
+type nopAuditLogger struct{}
+
+func (nopAuditLogger) Audit(ctx context.Context, event AuditEvent) {}
+
+// ZapAuditLogger writes the audit events to a zap logger
+type ZapAuditLogger struct {
+	logger *zap.Logger
+}
+
+// NewZapAuditLogger creates a new instance of the ZapAuditLogger
+func NewZapAuditLogger(logger *zap.Logger) *ZapAuditLogger {
+	return &ZapAuditLogger{logger: logger.Named("audit")}
+}
+
+func (z *ZapAuditLogger) Audit(ctx context.Context, event AuditEvent) {
+	z.logger.Info("Object operation",
+		zap.String("operation", event.Operation),
+		zap.String("objectId", event.ObjectId),
+		zap.Int("instance", event.InstanceNum),
+		zap.String("clientIP", event.ClientIP),
+		zap.String("subject", event.Subject),
+		zap.Time("time", event.Time),
+		zap.Error(event.Err),
+	)
+}
+
+// audit records an operation on the object
+func (s *ServiceV1) audit(ctx context.Context, operation, objectId string, instanceNum int, err error) {
+	requester := requesterFrom(ctx)
+	s.auditLogger.Audit(ctx, AuditEvent{
+		Operation:   operation,
+		ObjectId:    objectId,
+		InstanceNum: instanceNum,
+		ClientIP:    requester.ClientIP,
+		Subject:     requester.Subject,
+		Time:        time.Now(),
+		Err:         err,
+	})
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recordingAuditLogger records the audit events
+type recordingAuditLogger struct {
+	events []AuditEvent
+}
+
+func (r *recordingAuditLogger) Audit(_ context.Context, event AuditEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestAudit_Requester(t *testing.T) {
+	auditLogger := &recordingAuditLogger{}
+	service, _, _ := newTestService(t, 1, WithAuditLogger(auditLogger))
+
+	ctx := WithRequester(context.Background(), Requester{ClientIP: "203.0.113.7", Subject: "client"})
+	_, err := service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	// The operations run without a requester are recorded without a client IP
+	require.NoError(t, service.DeleteObject(context.Background(), "object"))
+
+	require.Len(t, auditLogger.events, 2)
+	assert.Equal(t, "put", auditLogger.events[0].Operation)
+	assert.Equal(t, "203.0.113.7", auditLogger.events[0].ClientIP)
+	assert.Equal(t, "client", auditLogger.events[0].Subject)
+	assert.Equal(t, "delete", auditLogger.events[1].Operation)
+	assert.Empty(t, auditLogger.events[1].ClientIP)
+	assert.Empty(t, auditLogger.events[1].Subject)
+}
+
+func TestZapAuditLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	auditLogger := NewZapAuditLogger(zap.New(core))
+
+	auditLogger.Audit(context.Background(), AuditEvent{Operation: "put", ObjectId: "object", InstanceNum: 1, ClientIP: "203.0.113.7", Subject: "client"})
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "put", fields["operation"])
+	assert.Equal(t, "203.0.113.7", fields["clientIP"])
+	assert.Equal(t, "client", fields["subject"])
+}
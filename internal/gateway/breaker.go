@@ -0,0 +1,382 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// circuitState is the state of the circuit breaker of an instance
+type circuitState int
+
+const (
+	// circuitClosed passes the requests to the instance
+	circuitClosed circuitState = iota
+	// circuitOpen rejects the requests to the failing instance, until the open duration elapses
+	circuitOpen
+	// circuitHalfOpen passes a single probe request, closing the circuit if it succeeds
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// WithCircuitBreaker rejects the requests to an instance after failureThreshold consecutive failures, until
+// the openDuration elapses and a probe request succeeds. The rejected reads fail over to the replicas of the object,
+// if replicated. Zero failureThreshold disables the circuit breaker.
+func WithCircuitBreaker(failureThreshold int, openDuration time.Duration) Option {
+	return func(s *ServiceV1) {
+		if failureThreshold > 0 {
+			s.breakers = &circuitBreakers{
+				failureThreshold: failureThreshold,
+				openDuration:     openDuration,
+				logger:           zap.L().Named("circuit-breaker"),
+				breakers:         map[int]*circuitBreaker{},
+			}
+		}
+	}
+}
+
+// circuitBreakers keeps the circuit breaker of each instance. The breaker is tied to the container of the instance,
+// so a recreated container starts with a closed circuit.
+type circuitBreakers struct {
+	failureThreshold int
+	openDuration     time.Duration
+	logger           *zap.Logger
+
+	mu       sync.Mutex
+	breakers map[int]*circuitBreaker
+}
+
+// breaker returns the circuit breaker of the instance, replacing the breaker of a previous container
+func (c *circuitBreakers) breaker(instance discovery.S3Instance) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	identity := instanceIdentity(instance)
+	breaker, ok := c.breakers[instance.InstanceNum]
+	if ok && breaker.identity == identity {
+		return breaker
+	}
+
+	breaker = &circuitBreaker{
+		identity:         identity,
+		instanceNum:      instance.InstanceNum,
+		failureThreshold: c.failureThreshold,
+		openDuration:     c.openDuration,
+		logger:           c.logger.With(zap.Int("instance", instance.InstanceNum)),
+	}
+	c.breakers[instance.InstanceNum] = breaker
+	recordCircuitState(instance.InstanceNum, circuitClosed)
+	return breaker
+}
+
+// prune forgets the circuit breakers of the removed instances
+func (c *circuitBreakers) prune(removed []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, instanceNum := range removed {
+		if _, ok := c.breakers[instanceNum]; ok {
+			delete(c.breakers, instanceNum)
+			forgetCircuitState(instanceNum)
+		}
+	}
+}
+
+// instanceIdentity identifies the container of the instance, or its address if the instance is not a container
+func instanceIdentity(instance discovery.S3Instance) string {
+	if instance.ContainerId != "" {
+		return instance.ContainerId
+	}
+
+	return instance.Hostname + ":" + instance.Port
+}
+
+// circuitBreaker tracks the consecutive failures of an instance
+type circuitBreaker struct {
+	identity         string
+	instanceNum      int
+	failureThreshold int
+	openDuration     time.Duration
+	logger           *zap.Logger
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probeAt  time.Time
+}
+
+// allow checks if a request may be sent to the instance. Once the open duration elapses, a single probe request is
+// allowed. Another probe is allowed if the previous one is not recorded within the open duration.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if now.Sub(b.openedAt) < b.openDuration {
+			return false
+		}
+
+		b.setState(circuitHalfOpen)
+		b.probeAt = now
+		return true
+	case circuitHalfOpen:
+		if now.Sub(b.probeAt) < b.openDuration {
+			return false
+		}
+
+		b.probeAt = now
+		return true
+	default:
+		return true
+	}
+}
+
+// rejects checks if the requests to the instance are rejected, without taking the probe
+func (b *circuitBreaker) rejects(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		return now.Sub(b.openedAt) < b.openDuration
+	case circuitHalfOpen:
+		return now.Sub(b.probeAt) < b.openDuration
+	default:
+		return false
+	}
+}
+
+// record updates the circuit with the result of a request. Any response of the instance, including a missing object
+// or denied access, means the instance is up.
+func (b *circuitBreaker) record(err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		// The caller gave up, which says nothing about the instance
+	case isInstanceFailure(err):
+		b.failures++
+		if b.state == circuitHalfOpen || b.failures >= b.failureThreshold {
+			b.logger.Warn("Opening the circuit of the failing instance", zap.Int("failures", b.failures), zap.Error(err))
+			b.openedAt = now
+			b.failures = 0
+			b.setState(circuitOpen)
+		}
+	default:
+		b.failures = 0
+		if b.state != circuitClosed {
+			b.logger.Info("Closing the circuit of the recovered instance")
+			b.setState(circuitClosed)
+		}
+	}
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	b.state = state
+	recordCircuitState(b.instanceNum, state)
+}
+
+// isInstanceFailure checks if the error means the instance is failing, rather than rejecting the request
+func isInstanceFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, s3.ErrObjectNotFound),
+		errors.Is(err, s3.ErrBucketNotFound),
+		errors.Is(err, s3.ErrSizeMismatch),
+		errors.Is(err, s3.ErrAccessDenied),
+		errors.Is(err, s3.ErrInvalidCredentials):
+		return false
+	default:
+		return true
+	}
+}
+
+// readInstance returns the shard instance of the object, or the first replica whose circuit is not open if the
+// circuit of the shard instance is open
+func (s *ServiceV1) readInstance(ctx context.Context, objectId string) (*discovery.S3Instance, error) {
+	if s.breakers == nil || s.replicationFactor <= 1 {
+		return s.shardObjectToInstance(ctx, objectId)
+	}
+
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas, err := s.replicaInstances(objectId, instances, s.replicationFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, replica := range replicas {
+		if !s.breakers.breaker(replica).rejects(now) {
+			if replica.InstanceNum != replicas[0].InstanceNum {
+				s.logger.Debug("Reading the object from a replica", zap.String("objectId", objectId), zap.Int("instance", replica.InstanceNum))
+			}
+
+			return &replica, nil
+		}
+	}
+
+	// All circuits are open - the shard instance rejects the read
+	return &replicas[0], nil
+}
+
+// breakingClient rejects the requests to the instance with ErrInstanceUnavailable while its circuit is open.
+// The long-running streams and watches are not guarded.
+type breakingClient struct {
+	s3.Client
+	breaker *circuitBreaker
+}
+
+// guarded calls the function if the circuit allows it, recording the result
+func guarded[T any](c *breakingClient, fn func() (T, error)) (T, error) {
+	if !c.breaker.allow(time.Now()) {
+		var zero T
+		return zero, errors.Wrapf(ErrInstanceUnavailable, "circuit of instance %d is open", c.breaker.instanceNum)
+	}
+
+	result, err := fn()
+	c.breaker.record(err, time.Now())
+	return result, err
+}
+
+// guardedErr calls the function returning only an error if the circuit allows it, recording the result
+func guardedErr(c *breakingClient, fn func() error) error {
+	_, err := guarded(c, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+func (c *breakingClient) AddOrUpdateObject(ctx context.Context, objectId string, data io.Reader, options s3.PutOptions) (*s3.ObjectInfo, error) {
+	return guarded(c, func() (*s3.ObjectInfo, error) {
+		return c.Client.AddOrUpdateObject(ctx, objectId, data, options)
+	})
+}
+
+func (c *breakingClient) GetObject(ctx context.Context, objectId string) (io.Reader, error) {
+	return guarded(c, func() (io.Reader, error) {
+		return c.Client.GetObject(ctx, objectId)
+	})
+}
+
+func (c *breakingClient) GetObjects(ctx context.Context, filter s3.ListFilter) ([]string, error) {
+	return guarded(c, func() ([]string, error) {
+		return c.Client.GetObjects(ctx, filter)
+	})
+}
+
+func (c *breakingClient) GetObjectsSummary(ctx context.Context, filter s3.ListFilter) ([]s3.ObjectSummary, error) {
+	return guarded(c, func() ([]s3.ObjectSummary, error) {
+		return c.Client.GetObjectsSummary(ctx, filter)
+	})
+}
+
+func (c *breakingClient) ListObjectsPage(ctx context.Context, options s3.ListOptions) ([]string, string, error) {
+	var next string
+	objectIds, err := guarded(c, func() ([]string, error) {
+		objectIds, nextStartAfter, err := c.Client.ListObjectsPage(ctx, options)
+		next = nextStartAfter
+		return objectIds, err
+	})
+	return objectIds, next, err
+}
+
+func (c *breakingClient) CountObjects(ctx context.Context) (int, error) {
+	return guarded(c, func() (int, error) {
+		return c.Client.CountObjects(ctx)
+	})
+}
+
+func (c *breakingClient) StatObject(ctx context.Context, objectId string) (*s3.ObjectInfo, error) {
+	return guarded(c, func() (*s3.ObjectInfo, error) {
+		return c.Client.StatObject(ctx, objectId)
+	})
+}
+
+func (c *breakingClient) ObjectExists(ctx context.Context, objectId string) (bool, error) {
+	return guarded(c, func() (bool, error) {
+		return c.Client.ObjectExists(ctx, objectId)
+	})
+}
+
+func (c *breakingClient) DeleteObject(ctx context.Context, objectId string) error {
+	return guardedErr(c, func() error {
+		return c.Client.DeleteObject(ctx, objectId)
+	})
+}
+
+func (c *breakingClient) CopyObject(ctx context.Context, sourceId, targetId string, metadata map[string]string) error {
+	return guardedErr(c, func() error {
+		return c.Client.CopyObject(ctx, sourceId, targetId, metadata)
+	})
+}
+
+func (c *breakingClient) GetObjectVersion(ctx context.Context, objectId, versionId string) (io.Reader, error) {
+	return guarded(c, func() (io.Reader, error) {
+		return c.Client.GetObjectVersion(ctx, objectId, versionId)
+	})
+}
+
+func (c *breakingClient) StatObjectVersion(ctx context.Context, objectId, versionId string) (*s3.ObjectInfo, error) {
+	return guarded(c, func() (*s3.ObjectInfo, error) {
+		return c.Client.StatObjectVersion(ctx, objectId, versionId)
+	})
+}
+
+func (c *breakingClient) DeleteObjectVersion(ctx context.Context, objectId, versionId string) error {
+	return guardedErr(c, func() error {
+		return c.Client.DeleteObjectVersion(ctx, objectId, versionId)
+	})
+}
+
+func (c *breakingClient) ListObjectVersions(ctx context.Context, objectId string) ([]s3.ObjectVersion, error) {
+	return guarded(c, func() ([]s3.ObjectVersion, error) {
+		return c.Client.ListObjectVersions(ctx, objectId)
+	})
+}
+
+func (c *breakingClient) ListObjectsByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	return guarded(c, func() ([]string, error) {
+		return c.Client.ListObjectsByPrefix(ctx, prefix)
+	})
+}
+
+func (c *breakingClient) DeleteObjects(ctx context.Context, objectIds []string) (int, error) {
+	return guarded(c, func() (int, error) {
+		return c.Client.DeleteObjects(ctx, objectIds)
+	})
+}
+
+func (c *breakingClient) GetUsage(ctx context.Context) (s3.Usage, error) {
+	return guarded(c, func() (s3.Usage, error) {
+		return c.Client.GetUsage(ctx)
+	})
+}
+
+func (c *breakingClient) DeleteExpiredObjects(ctx context.Context, now time.Time) (int, error) {
+	return guarded(c, func() (int, error) {
+		return c.Client.DeleteExpiredObjects(ctx, now)
+	})
+}
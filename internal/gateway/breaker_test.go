@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var errInstanceDown = errors.New("connection refused")
+
+func newTestBreakers(failureThreshold int, openDuration time.Duration) *circuitBreakers {
+	return &circuitBreakers{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		logger:           zap.NewNop(),
+		breakers:         map[int]*circuitBreaker{},
+	}
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	breaker := newTestBreakers(3, time.Minute).breaker(discoverytest.Instance(1))
+	now := time.Now()
+
+	// Closed until the failure threshold
+	breaker.record(errInstanceDown, now)
+	breaker.record(errInstanceDown, now)
+	assert.Equal(t, circuitClosed, breaker.state)
+	assert.True(t, breaker.allow(now))
+
+	// A success resets the consecutive failures
+	breaker.record(nil, now)
+	breaker.record(errInstanceDown, now)
+	breaker.record(errInstanceDown, now)
+	assert.Equal(t, circuitClosed, breaker.state)
+
+	// Open after the threshold, rejecting until the open duration elapses
+	breaker.record(errInstanceDown, now)
+	assert.Equal(t, circuitOpen, breaker.state)
+	assert.False(t, breaker.allow(now.Add(time.Second)))
+	assert.True(t, breaker.rejects(now.Add(time.Second)))
+
+	// Half-open after the open duration, allowing a single probe
+	probeAt := now.Add(time.Minute)
+	assert.False(t, breaker.rejects(probeAt))
+	assert.True(t, breaker.allow(probeAt))
+	assert.Equal(t, circuitHalfOpen, breaker.state)
+	assert.False(t, breaker.allow(probeAt.Add(time.Second)))
+
+	// A failed probe opens the circuit again
+	breaker.record(errInstanceDown, probeAt)
+	assert.Equal(t, circuitOpen, breaker.state)
+	assert.False(t, breaker.allow(probeAt.Add(time.Second)))
+
+	// A successful probe closes it
+	probeAt = probeAt.Add(time.Minute)
+	require.True(t, breaker.allow(probeAt))
+	breaker.record(nil, probeAt)
+	assert.Equal(t, circuitClosed, breaker.state)
+	assert.True(t, breaker.allow(probeAt))
+}
+
+func TestCircuitBreaker_ProbeRearm(t *testing.T) {
+	breaker := newTestBreakers(1, time.Minute).breaker(discoverytest.Instance(1))
+	now := time.Now()
+
+	breaker.record(errInstanceDown, now)
+	probeAt := now.Add(time.Minute)
+	require.True(t, breaker.allow(probeAt))
+
+	// The probe is not recorded, e.g. its request hangs - another probe is allowed after the open duration
+	assert.False(t, breaker.allow(probeAt.Add(time.Minute-time.Second)))
+	assert.True(t, breaker.allow(probeAt.Add(time.Minute)))
+	assert.Equal(t, circuitHalfOpen, breaker.state)
+}
+
+func TestCircuitBreaker_IgnoresRejectedRequests(t *testing.T) {
+	breaker := newTestBreakers(1, time.Minute).breaker(discoverytest.Instance(1))
+	now := time.Now()
+
+	for _, err := range []error{
+		s3.ErrObjectNotFound,
+		s3.ErrAccessDenied,
+		errors.Wrap(s3.ErrBucketNotFound, "bucket"),
+		context.Canceled,
+	} {
+		breaker.record(err, now)
+		assert.Equal(t, circuitClosed, breaker.state, err.Error())
+	}
+}
+
+func TestCircuitBreakers_ResetOnContainerChange(t *testing.T) {
+	breakers := newTestBreakers(1, time.Minute)
+	instance := discoverytest.Instance(1)
+
+	breaker := breakers.breaker(instance)
+	breaker.record(errInstanceDown, time.Now())
+	require.Equal(t, circuitOpen, breaker.state)
+
+	// The same container keeps its open circuit
+	assert.Same(t, breaker, breakers.breaker(instance))
+
+	// A recreated container starts with a closed circuit
+	instance.ContainerId = "minio-1-recreated"
+	recreated := breakers.breaker(instance)
+	assert.NotSame(t, breaker, recreated)
+	assert.Equal(t, circuitClosed, recreated.state)
+	assert.True(t, recreated.allow(time.Now()))
+}
+
+func TestCircuitBreakers_PruneOnMembershipChange(t *testing.T) {
+	service, _, _ := newTestService(t, 3, WithCircuitBreaker(1, time.Minute))
+	for _, instance := range discoverytest.Instances(3) {
+		service.breakers.breaker(instance)
+	}
+
+	service.onInstanceSetChanged(context.Background(), instanceSetDiff(discoverytest.Instances(3), discoverytest.Instances(1)))
+
+	service.breakers.mu.Lock()
+	defer service.breakers.mu.Unlock()
+	assert.Len(t, service.breakers.breakers, 1)
+	assert.Contains(t, service.breakers.breakers, 1)
+}
+
+func TestReadInstance_FailsOverFromOpenCircuit(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithCircuitBreaker(1, time.Minute), WithReplicationFactor(2))
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	shard, err := service.shardObjectToInstance(context.Background(), "object")
+	require.NoError(t, err)
+
+	// The failure of the shard instance opens its circuit, so the read goes to the replica
+	clients[shard.InstanceNum].Fail(errInstanceDown)
+	_, err = service.GetObject(context.Background(), "object")
+	require.Error(t, err)
+
+	instance, err := service.readInstance(context.Background(), "object")
+	require.NoError(t, err)
+	assert.NotEqual(t, shard.InstanceNum, instance.InstanceNum)
+
+	// The open circuit rejects the requests without calling the instance
+	calls := clients[shard.InstanceNum].CallCount("StatObject")
+	client, err := service.pooledClient(*shard)
+	require.NoError(t, err)
+	_, err = client.StatObject(context.Background(), "object")
+	assert.ErrorIs(t, err, ErrInstanceUnavailable)
+	assert.Equal(t, calls, clients[shard.InstanceNum].CallCount("StatObject"))
+}
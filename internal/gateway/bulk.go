@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// ErrBulkDeleteLimitExceeded is returned when more objects match the prefix than are allowed to be deleted at once
+var ErrBulkDeleteLimitExceeded = errors.New("bulk delete limit exceeded")
+
+// CountObjectsByPrefix counts the objects starting with the prefix on all instances
+func (s *ServiceV1) CountObjectsByPrefix(ctx context.Context, prefix string) (int, error) {
+	matches, err := s.objectsByPrefix(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, objectIds := range matches {
+		count += len(objectIds)
+	}
+
+	return count, nil
+}
+
+// DeleteObjectsByPrefix deletes the objects starting with the prefix from all instances and returns the number of
+// deleted objects. Nothing is deleted if more objects match than the bulk delete limit.
+func (s *ServiceV1) DeleteObjectsByPrefix(ctx context.Context, prefix string) (int, error) {
+	logger := s.logger.With(zap.String("prefix", prefix))
+	logger.Info("Deleting objects by prefix")
+	defer s.readCache.RemovePrefix(prefix)
+
+	matches, err := s.objectsByPrefix(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, objectIds := range matches {
+		count += len(objectIds)
+	}
+
+	if s.maxBulkDeleteCount > 0 && count > s.maxBulkDeleteCount {
+		return 0, errors.Wrapf(ErrBulkDeleteLimitExceeded, "%d objects match the prefix, the limit is %d", count, s.maxBulkDeleteCount)
+	}
+
+	// The sizes of the deleted objects are not known, so the quota usage is recounted
+	defer s.recountQuotas(ctx)
+
+	deleted := 0
+	for client, objectIds := range matches {
+		if len(objectIds) == 0 {
+			continue
+		}
+
+		n, err := client.DeleteObjects(ctx, objectIds)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	logger.Info("Deleted objects by prefix", zap.Int("deleted", deleted))
+	return deleted, nil
+}
+
+// objectsByPrefix lists the objects starting with the prefix, grouped by the client of their instance
+func (s *ServiceV1) objectsByPrefix(ctx context.Context, prefix string) (map[s3.Client][]string, error) {
+	// Discover available S3 instances
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := map[s3.Client][]string{}
+	for _, instance := range instances {
+		// Minio client must be dynamically created, based on the S3 instance
+		client, err := s.clientFactory(instance)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+		}
+
+		objectIds, err := client.ListObjectsByPrefix(ctx, prefix)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to list objectIds for instance: %d", instance.InstanceNum))
+		}
+
+		matches[client] = objectIds
+	}
+
+	return matches, nil
+}
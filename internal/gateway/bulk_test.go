@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteObjectsByPrefix(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	clients[1].Put("photos/a", []byte("a"))
+	clients[1].Put("photos/b", []byte("b"))
+	clients[1].Put("docs/a", []byte("a"))
+	clients[2].Put("photos/c", []byte("c"))
+	clients[2].Put("photosx", []byte("x"))
+	clients[2].Put("docs/b", []byte("b"))
+
+	deleted, err := service.DeleteObjectsByPrefix(context.Background(), "photos/")
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, deleted)
+	assert.Equal(t, []string{"docs/a"}, clients[1].Keys())
+	assert.Equal(t, []string{"docs/b", "photosx"}, clients[2].Keys())
+}
+
+func TestDeleteObjectsByPrefix_All(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	clients[1].Put("a", []byte("a"))
+	clients[2].Put("b", []byte("b"))
+
+	deleted, err := service.DeleteObjectsByPrefix(context.Background(), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, deleted)
+	assert.Empty(t, clients[1].Keys())
+	assert.Empty(t, clients[2].Keys())
+}
+
+func TestDeleteObjectsByPrefix_LimitExceeded(t *testing.T) {
+	service, _, clients := newTestService(t, 2, WithMaxBulkDeleteCount(2))
+	clients[1].Put("photos/a", []byte("a"))
+	clients[1].Put("photos/b", []byte("b"))
+	clients[2].Put("photos/c", []byte("c"))
+
+	deleted, err := service.DeleteObjectsByPrefix(context.Background(), "photos/")
+	require.ErrorIs(t, err, ErrBulkDeleteLimitExceeded)
+
+	// Nothing is deleted when the limit is exceeded
+	assert.Zero(t, deleted)
+	assert.Len(t, clients[1].Keys(), 2)
+	assert.Len(t, clients[2].Keys(), 1)
+}
+
+func TestCountObjectsByPrefix(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	clients[1].Put("photos/a", []byte("a"))
+	clients[2].Put("photos/b", []byte("b"))
+	clients[2].Put("docs/a", []byte("a"))
+
+	count, err := service.CountObjectsByPrefix(context.Background(), "photos/")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, count)
+	assert.Len(t, clients[2].Keys(), 2)
+}
@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"mime/multipart"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+const (
+	// DefaultReadCacheMaxBytes is the total size of the cached objects, unless configured otherwise
+	DefaultReadCacheMaxBytes = 64 << 20
+	// Larger objects are streamed without being cached
+	maxSingleObjectBytes = 1 << 20
+)
+
+// cachingService caches the content of the downloaded objects in a ReadCache. The cached objects are invalidated
+// when they are modified through the service.
+type cachingService struct {
+	Service
+	ttl   time.Duration
+	cache *ReadCache
+}
+
+// CachingService caches the content of the small objects returned by GetObject for the ttl, up to
+// DefaultReadCacheMaxBytes in total. Returns the inner service unchanged if the ttl is not positive.
+func CachingService(inner Service, ttl time.Duration) Service {
+	return CachingServiceWithCache(inner, NewReadCache(DefaultReadCacheMaxBytes), ttl)
+}
+
+// CachingServiceWithCache caches the content of the small objects returned by GetObject in the cache for the ttl.
+// The objects modified behind the decorator, e.g. deleted by the expiry sweep, are served stale until they expire.
+// Returns the inner service unchanged if the cache holds no bytes or the ttl is not positive.
+func CachingServiceWithCache(inner Service, cache *ReadCache, ttl time.Duration) Service {
+	if cache == nil || cache.maxBytes <= 0 || ttl <= 0 {
+		return inner
+	}
+
+	return &cachingService{Service: inner, ttl: ttl, cache: cache}
+}
+
+func (c *cachingService) GetObject(ctx context.Context, objectId string) (io.Reader, error) {
+	if data, ok := c.cache.Get(objectId); ok {
+		return bytes.NewReader(data), nil
+	}
+
+	object, err := c.Service.GetObject(ctx, objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.cache.read(objectId, object, time.Now().Add(c.ttl))
+}
+
+func (c *cachingService) AddOrUpdateObject(ctx context.Context, objectId string, file multipart.File, options UploadOptions) (*UploadResult, error) {
+	defer c.cache.Remove(objectId)
+	return c.Service.AddOrUpdateObject(ctx, objectId, file, options)
+}
+
+func (c *cachingService) DeleteObject(ctx context.Context, objectId string) error {
+	defer c.cache.Remove(objectId)
+	return c.Service.DeleteObject(ctx, objectId)
+}
+
+// DeleteObjectVersion invalidates the object, as the deleted version may be the latest one
+func (c *cachingService) DeleteObjectVersion(ctx context.Context, objectId, versionId string) error {
+	defer c.cache.Remove(objectId)
+	return c.Service.DeleteObjectVersion(ctx, objectId, versionId)
+}
+
+func (c *cachingService) RenameObject(ctx context.Context, oldId, newId string) error {
+	defer c.cache.Remove(oldId)
+	defer c.cache.Remove(newId)
+	return c.Service.RenameObject(ctx, oldId, newId)
+}
+
+func (c *cachingService) DeleteObjectsByPrefix(ctx context.Context, prefix string) (int, error) {
+	defer c.cache.RemovePrefix(prefix)
+	return c.Service.DeleteObjectsByPrefix(ctx, prefix)
+}
+
+// MigrateObject invalidates the object, as the migration deletes it from the source instance
+func (c *cachingService) MigrateObject(ctx context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error) {
+	defer c.cache.Remove(objectId)
+	return c.Service.MigrateObject(ctx, objectId, targetInstanceNum)
+}
+
+// ReadCache is an LRU cache of the object contents bounded by their total size in bytes. The least recently used
+// objects are evicted once the size is exceeded. Removing from a nil ReadCache is a no-op.
+type ReadCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	entries  *simplelru.LRU[string, cacheEntry]
+}
+
+type cacheEntry struct {
+	data []byte
+	// Zero means the entry doesn't expire
+	expiresAt time.Time
+}
+
+// NewReadCache creates a ReadCache holding up to maxBytes of object data
+func NewReadCache(maxBytes int64) *ReadCache {
+	c := &ReadCache{maxBytes: maxBytes}
+	// The number of entries is bounded by their size, not count
+	c.entries, _ = simplelru.NewLRU[string, cacheEntry](math.MaxInt, func(_ string, entry cacheEntry) {
+		c.size -= int64(len(entry.data))
+	})
+
+	return c
+}
+
+// Get returns the data of the key, if it is cached and not expired
+func (c *ReadCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.entries.Remove(key)
+		return nil, false
+	}
+
+	return entry.data, true
+}
+
+// Add caches the data of the key until expiresAt, evicting the least recently used entries to make room for it.
+// Data larger than the cache is not cached.
+func (c *ReadCache) Add(key string, data []byte, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.Remove(key)
+	if int64(len(data)) > c.maxBytes {
+		return
+	}
+
+	for c.size+int64(len(data)) > c.maxBytes {
+		c.entries.RemoveOldest()
+	}
+
+	c.entries.Add(key, cacheEntry{data: data, expiresAt: expiresAt})
+	c.size += int64(len(data))
+}
+
+// Remove invalidates the key
+func (c *ReadCache) Remove(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.Remove(key)
+}
+
+// RemovePrefix invalidates the keys starting with the prefix
+func (c *ReadCache) RemovePrefix(prefix string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range c.entries.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			c.entries.Remove(key)
+		}
+	}
+}
+
+// Purge invalidates all keys
+func (c *ReadCache) Purge() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.Purge()
+}
+
+// read buffers the object and caches it until expiresAt, if it is not larger than maxSingleObjectBytes.
+// Returns a reader of the whole object, closing the object once the reader is closed.
+func (c *ReadCache) read(key string, object io.Reader, expiresAt time.Time) (io.Reader, error) {
+	// Read one byte past the limit to find out if the object fits into the cache
+	data, err := io.ReadAll(io.LimitReader(object, maxSingleObjectBytes+1))
+	if err != nil {
+		_ = closeObject(object)
+		return nil, err
+	}
+
+	if len(data) > maxSingleObjectBytes {
+		size, ok := s3.ObjectSize(object)
+		if !ok {
+			size = -1
+		}
+
+		return &streamedObject{Reader: io.MultiReader(bytes.NewReader(data), object), object: object, size: size}, nil
+	}
+
+	// The object is read whole
+	_ = closeObject(object)
+	c.Add(key, data, expiresAt)
+	return bytes.NewReader(data), nil
+}
+
+// streamedObject streams the rest of the object after its buffered start, closing the object with it
+type streamedObject struct {
+	io.Reader
+	object io.Reader
+	// size is the size of the whole object, -1 if unknown
+	size int64
+}
+
+// Size returns the size of the whole object, including the buffered start
+func (s *streamedObject) Size() int64 {
+	return s.size
+}
+
+func (s *streamedObject) Close() error {
+	return closeObject(s.object)
+}
+
+// closeObject closes the object, if it can be closed
+func closeObject(object io.Reader) error {
+	if closer, ok := object.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
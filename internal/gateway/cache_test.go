@@ -0,0 +1,279 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trackedObject is an object reader recording it was closed
+type trackedObject struct {
+	io.Reader
+	closed bool
+}
+
+func (o *trackedObject) Close() error {
+	o.closed = true
+	return nil
+}
+
+func TestReadCache_Read(t *testing.T) {
+	t.Run("small object is cached and closed", func(t *testing.T) {
+		cache := NewReadCache(DefaultReadCacheMaxBytes)
+		object := &trackedObject{Reader: bytes.NewReader([]byte("data"))}
+
+		reader, err := cache.read("object", object, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, object.closed)
+
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("data"), data)
+
+		cached, ok := cache.Get("object")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("data"), cached)
+	})
+
+	t.Run("large object is streamed and closed with the reader", func(t *testing.T) {
+		cache := NewReadCache(DefaultReadCacheMaxBytes)
+		content := bytes.Repeat([]byte("a"), maxSingleObjectBytes+10)
+		object := &trackedObject{Reader: bytes.NewReader(content)}
+
+		reader, err := cache.read("object", object, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.False(t, object.closed)
+
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+
+		closer, ok := reader.(io.Closer)
+		require.True(t, ok)
+		require.NoError(t, closer.Close())
+		assert.True(t, object.closed)
+
+		_, ok = cache.Get("object")
+		assert.False(t, ok)
+	})
+}
+
+func TestReadCache_Expires(t *testing.T) {
+	cache := NewReadCache(DefaultReadCacheMaxBytes)
+	cache.Add("expired", []byte("data"), time.Now().Add(-time.Second))
+	cache.Add("valid", []byte("data"), time.Now().Add(time.Minute))
+
+	_, ok := cache.Get("expired")
+	assert.False(t, ok)
+	_, ok = cache.Get("valid")
+	assert.True(t, ok)
+}
+
+func TestGetObject_ReadCacheTTL(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	service, _, clients := newTestService(t, 1, WithReadCache(DefaultReadCacheMaxBytes, ttl))
+	clients[1].Put("object", []byte("old"))
+
+	read := func() string {
+		reader, err := service.GetObject(context.Background(), "object")
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	assert.Equal(t, "old", read())
+
+	// Modified behind the gateway, the object is served stale until it expires
+	clients[1].Put("object", []byte("new"))
+	assert.Equal(t, "old", read())
+
+	time.Sleep(ttl * 2)
+	assert.Equal(t, "new", read())
+}
+
+// The service holds the only cache layer, so the objects removed by the service itself are never served from it
+func TestGetObject_ReadCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, service *ServiceV1)
+		// want is the content read afterward, empty if the object is gone
+		want string
+	}{
+		{name: "overwritten", modify: func(t *testing.T, service *ServiceV1) {
+			_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("new")), UploadOptions{})
+			require.NoError(t, err)
+		}, want: "new"},
+		{name: "deleted", modify: func(t *testing.T, service *ServiceV1) {
+			require.NoError(t, service.DeleteObject(context.Background(), "object"))
+		}},
+		{name: "deleted by prefix", modify: func(t *testing.T, service *ServiceV1) {
+			_, err := service.DeleteObjectsByPrefix(context.Background(), "obj")
+			require.NoError(t, err)
+		}},
+		{name: "expired", modify: func(t *testing.T, service *ServiceV1) {
+			time.Sleep(20 * time.Millisecond)
+			require.NoError(t, service.sweepExpiredObjects(context.Background()))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, clients := newTestService(t, 1, WithReadCache(DefaultReadCacheMaxBytes, time.Minute))
+			_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("old")), UploadOptions{ExpiresIn: 10 * time.Millisecond})
+			require.NoError(t, err)
+
+			reader, err := service.GetObject(context.Background(), "object")
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.Equal(t, "old", string(data))
+
+			tt.modify(t, service)
+
+			reader, err = service.GetObject(context.Background(), "object")
+			if tt.want == "" {
+				assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+				assert.Empty(t, clients[1].Keys())
+				return
+			}
+
+			require.NoError(t, err)
+			data, err = io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestCachingService(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		modify func(service Service) error
+	}{
+		{name: "uploaded", modify: func(service Service) error {
+			_, err := service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("new")), UploadOptions{})
+			return err
+		}},
+		{name: "deleted", modify: func(service Service) error {
+			return service.DeleteObject(ctx, "object")
+		}},
+		{name: "version deleted", modify: func(service Service) error {
+			return service.DeleteObjectVersion(ctx, "object", "1")
+		}},
+		{name: "renamed", modify: func(service Service) error {
+			return service.RenameObject(ctx, "object", "renamed")
+		}},
+		{name: "deleted by prefix", modify: func(service Service) error {
+			_, err := service.DeleteObjectsByPrefix(ctx, "obj")
+			return err
+		}},
+		{name: "migrated", modify: func(service Service) error {
+			_, err := service.MigrateObject(ctx, "object", 2)
+			return err
+		}},
+	}
+
+	read := func(t *testing.T, service Service) {
+		reader, err := service.GetObject(ctx, "object")
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &stubService{}
+			service := CachingService(inner, time.Minute)
+
+			// The second read is served from the cache
+			read(t, service)
+			read(t, service)
+			require.Len(t, inner.objectIds, 1)
+
+			require.NoError(t, tt.modify(service))
+
+			// The modified object is read from the inner service again
+			read(t, service)
+			assert.Len(t, inner.objectIds, 3)
+			assert.Equal(t, "object", inner.objectIds[2])
+		})
+	}
+}
+
+func TestCachingService_Expires(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	inner := &stubService{}
+	service := CachingService(inner, ttl)
+
+	for i := 0; i < 2; i++ {
+		_, err := service.GetObject(context.Background(), "object")
+		require.NoError(t, err)
+	}
+	assert.Len(t, inner.objectIds, 1)
+
+	time.Sleep(ttl * 2)
+	_, err := service.GetObject(context.Background(), "object")
+	require.NoError(t, err)
+	assert.Len(t, inner.objectIds, 2)
+}
+
+func TestCachingService_ErrorNotCached(t *testing.T) {
+	inner := &stubService{err: s3.ErrObjectNotFound}
+	service := CachingService(inner, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, err := service.GetObject(context.Background(), "object")
+		assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+	}
+	assert.Len(t, inner.objectIds, 2)
+}
+
+func TestCachingService_Disabled(t *testing.T) {
+	inner := &stubService{}
+
+	assert.Same(t, inner, CachingService(inner, 0))
+	assert.Same(t, inner, CachingServiceWithCache(inner, nil, time.Minute))
+	assert.Same(t, inner, CachingServiceWithCache(inner, NewReadCache(0), time.Minute))
+}
+
+func BenchmarkGetObject(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "uncached"},
+		{name: "cached", opts: []Option{WithReadCache(DefaultReadCacheMaxBytes, time.Minute)}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			service, _, clients := newTestService(b, 1, bm.opts...)
+			data := bytes.Repeat([]byte("a"), 64<<10)
+			clients[1].Put("object", data)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reader, err := service.GetObject(context.Background(), "object")
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				_, err = io.Copy(io.Discard, reader)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
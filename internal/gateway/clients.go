@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+// WithClientPoolLimits limits the number of pooled clients to maxSize and releases the clients not used for the idle
+// timeout. Zero keeps the defaults: no size limit and a 5 minute idle timeout. Ignored with WithClientPool.
+func WithClientPoolLimits(maxSize int, idleTimeout time.Duration) Option {
+	return func(s *ServiceV1) {
+		s.clientPoolMaxSize = maxSize
+		s.clientIdleTimeout = idleTimeout
+	}
+}
+
+// WithClientPool shares the pool of the S3 clients, e.g. with another service. The pool is closed by Close.
+// Without it, the service pools the clients created by its client factory.
+func WithClientPool(pool *s3.ClientPool) Option {
+	return func(s *ServiceV1) {
+		s.clients = pool
+	}
+}
+
+// pooledClient returns the pooled client of the instance, guarded by the circuit breaker of the instance if enabled
+func (s *ServiceV1) pooledClient(instance discovery.S3Instance) (s3.Client, error) {
+	client, err := s.clients.Client(instance)
+	if err != nil || s.breakers == nil {
+		return client, err
+	}
+
+	return &breakingClient{Client: client, breaker: s.breakers.breaker(instance)}, nil
+}
+
+// EvictIdleClients releases the pooled clients not used for the idle timeout, checking every half of it.
+// Blocks until the context is cancelled.
+func (s *ServiceV1) EvictIdleClients(ctx context.Context) {
+	s.clients.EvictIdleClients(ctx)
+}
@@ -0,0 +1,28 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceV1_CloseReleasesClients(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		_, err := service.AddOrUpdateObject(ctx, fmt.Sprintf("object%d", i), newTestFile([]byte("data")), UploadOptions{})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, service.Close())
+	for instanceNum, client := range clients {
+		assert.True(t, client.Closed(), "client of instance %d not closed", instanceNum)
+	}
+
+	// Closing again is a no-op
+	require.NoError(t, service.Close())
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// The counts are reused for this long, as counting lists all objects
+const countCacheTTL = 30 * time.Second
+
+// ObjectCount is the number of objects stored on the instances
+type ObjectCount struct {
+	Total int
+	// Instances is the number of objects per instance number
+	Instances map[int]int
+}
+
+// objectCountCache holds the last object count
+type objectCountCache struct {
+	mu        sync.Mutex
+	count     *ObjectCount
+	countedAt time.Time
+}
+
+// CountObjects counts the objects on all instances concurrently, without collecting their ids.
+// The count is cached for countCacheTTL.
+func (s *ServiceV1) CountObjects(ctx context.Context) (*ObjectCount, error) {
+	s.countCache.mu.Lock()
+	defer s.countCache.mu.Unlock()
+
+	if s.countCache.count != nil && time.Since(s.countCache.countedAt) < countCacheTTL {
+		return s.countCache.count, nil
+	}
+
+	count, err := s.countObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.countCache.count = count
+	s.countCache.countedAt = time.Now()
+	return count, nil
+}
+
+func (s *ServiceV1) countObjects(ctx context.Context) (*ObjectCount, error) {
+	s.logger.Info("Counting objects")
+
+	// Discover available S3 instances
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count := &ObjectCount{Instances: map[int]int{}}
+	countMutex := sync.Mutex{}
+
+	// Limit the number of instances queried at once
+	group, groupCtx := errgroup.WithContext(ctx)
+	if s.workerCount > 0 {
+		group.SetLimit(s.workerCount)
+	}
+
+	for _, instance := range instances {
+		instance := instance
+		group.Go(func() error {
+			// Minio client must be dynamically created, based on the S3 instance
+			client, err := s.clientFactory(instance)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+			}
+
+			n, err := client.CountObjects(groupCtx)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("unable to count objects for instance: %d", instance.InstanceNum))
+			}
+
+			countMutex.Lock()
+			count.Total += n
+			count.Instances[instance.InstanceNum] += n
+			countMutex.Unlock()
+			return nil
+		})
+	}
+
+	err = group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountObjects(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	for i := 0; i < 7; i++ {
+		clients[1].Put(fmt.Sprintf("a%d", i), []byte("a"))
+	}
+	for i := 0; i < 5; i++ {
+		clients[2].Put(fmt.Sprintf("b%d", i), []byte("b"))
+	}
+
+	count, err := service.CountObjects(context.Background())
+	require.NoError(t, err)
+
+	// The count matches the combined object sets of both instances
+	assert.Equal(t, len(clients[1].Keys())+len(clients[2].Keys()), count.Total)
+	assert.Equal(t, &ObjectCount{Total: 12, Instances: map[int]int{1: 7, 2: 5}}, count)
+
+	// Counting doesn't list the keys
+	assert.Zero(t, clients[1].CallCount("GetObjects"))
+	assert.Zero(t, clients[1].CallCount("StreamObjects"))
+}
+
+func TestCountObjects_InstanceFailure(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	clients[2].Fail(errors.New("connection refused"))
+
+	_, err := service.CountObjects(context.Background())
+	assert.ErrorContains(t, err, "instance: 2")
+}
+
+func TestCountObjects_Cached(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	clients[1].Put("a", []byte("a"))
+
+	count, err := service.CountObjects(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count.Total)
+
+	// The count is reused within the TTL
+	clients[2].Put("b", []byte("b"))
+	count, err = service.CountObjects(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count.Total)
+	assert.Equal(t, 1, clients[2].CallCount("CountObjects"))
+
+	// And recounted once it expires
+	service.countCache.countedAt = time.Now().Add(-countCacheTTL)
+	count, err = service.CountObjects(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &ObjectCount{Total: 2, Instances: map[int]int{1: 1, 2: 1}}, count)
+}
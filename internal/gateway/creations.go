@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// clientCreationSampleInterval is the interval the client creations are counted over
+const clientCreationSampleInterval = time.Second
+
+// ClientCreationReporter reports how many S3 clients were created in the last second
+type ClientCreationReporter interface {
+	ClientCreationRate() int64
+}
+
+// WithClientCreationRateWarn logs a warning when more clients than n are created in a second. With the clients pooled,
+// a high rate means some code path bypasses the pool. Zero disables the warning.
+func WithClientCreationRateWarn(n int64) Option {
+	return func(s *ServiceV1) {
+		s.clientCreationRateWarn = n
+	}
+}
+
+// ClientCreationRate returns the number of clients created in the last sampled second
+func (s *ServiceV1) ClientCreationRate() int64 {
+	return s.clientCreationRate.Load()
+}
+
+// MonitorClientCreations samples the client creations every second, warning when the rate exceeds the threshold.
+// Blocks until the context is cancelled.
+func (s *ServiceV1) MonitorClientCreations(ctx context.Context) {
+	ticker := time.NewTicker(clientCreationSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		created := s.clientCreations.Swap(0)
+		s.clientCreationRate.Store(created)
+
+		if s.clientCreationRateWarn > 0 && created > s.clientCreationRateWarn {
+			s.logger.Warn("High S3 client creation rate, the client pool may be bypassed",
+				zap.Int64("perSecond", created),
+				zap.Int64("threshold", s.clientCreationRateWarn),
+			)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// createClients creates a pooled client for each of the n instances, each one a new client
+func createClients(t *testing.T, service *ServiceV1, n int) {
+	t.Helper()
+
+	for _, instance := range discoverytest.Instances(n) {
+		_, err := service.clientFactory(instance)
+		require.NoError(t, err)
+	}
+}
+
+// monitorClientCreations runs the monitor of the service, returning its observed warnings
+func monitorClientCreations(t *testing.T, service *ServiceV1) *observer.ObservedLogs {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	service.logger = zap.New(core)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.MonitorClientCreations(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	return logs
+}
+
+func TestMonitorClientCreations_WarnsAboveThreshold(t *testing.T) {
+	service, _, _ := newTestService(t, 15, WithClientCreationRateWarn(10))
+	logs := monitorClientCreations(t, service)
+
+	// 15 creations within a second
+	createClients(t, service, 15)
+
+	require.Eventually(t, func() bool { return logs.Len() > 0 }, 3*clientCreationSampleInterval, 10*time.Millisecond)
+	warning := logs.All()[0]
+	assert.Equal(t, "High S3 client creation rate, the client pool may be bypassed", warning.Message)
+	assert.Equal(t, int64(15), warning.ContextMap()["perSecond"])
+	assert.Equal(t, int64(10), warning.ContextMap()["threshold"])
+	assert.Equal(t, int64(15), service.ClientCreationRate())
+}
+
+func TestMonitorClientCreations_PooledClients(t *testing.T) {
+	service, _, _ := newTestService(t, 5, WithClientCreationRateWarn(10))
+	logs := monitorClientCreations(t, service)
+
+	// The pooled clients are created once per instance
+	for i := 0; i < 5; i++ {
+		createClients(t, service, 5)
+	}
+
+	require.Eventually(t, func() bool { return service.ClientCreationRate() == 5 }, 3*clientCreationSampleInterval, 10*time.Millisecond)
+	assert.Zero(t, logs.Len())
+
+	// The counter is reset each second
+	require.Eventually(t, func() bool { return service.ClientCreationRate() == 0 }, 3*clientCreationSampleInterval, 10*time.Millisecond)
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SweepExpiredObjects deletes the expired objects from all instances every interval.
+// Blocks until the context is cancelled.
+func (s *ServiceV1) SweepExpiredObjects(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.sweepExpiredObjects(ctx)
+		if err != nil {
+			s.logger.Error("Failed to sweep expired objects", zap.Error(err))
+		}
+	}
+}
+
+func (s *ServiceV1) sweepExpiredObjects(ctx context.Context) error {
+	s.logger.Info("Sweeping expired objects")
+	// The expired objects are not known upfront
+	defer s.readCache.Purge()
+	defer s.recountQuotas(ctx)
+
+	// Discover available S3 instances
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, instance := range instances {
+		// Minio client must be dynamically created, based on the S3 instance
+		client, err := s.clientFactory(instance)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+		}
+
+		deleted, err := client.DeleteExpiredObjects(ctx, now)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("unable to delete expired objects for instance: %d", instance.InstanceNum))
+		}
+
+		s.logger.Info("Deleted expired objects", zap.Int("instance", instance.InstanceNum), zap.Int("deleted", deleted))
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddOrUpdateObject_ExpiresIn(t *testing.T) {
+	service, _, clients := newTestService(t, 1)
+
+	before := time.Now()
+	_, err := service.AddOrUpdateObject(context.Background(), "temporary", newTestFile([]byte("data")), UploadOptions{ExpiresIn: time.Minute})
+	require.NoError(t, err)
+	_, err = service.AddOrUpdateObject(context.Background(), "permanent", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	assert.WithinRange(t, clients[1].Object("temporary").ExpiresAt, before.Add(time.Minute), time.Now().Add(time.Minute))
+	assert.True(t, clients[1].Object("permanent").ExpiresAt.IsZero())
+}
+
+func TestSweepExpiredObjects(t *testing.T) {
+	service, _, clients := newTestService(t, 2, WithReadCache(1<<20, time.Hour))
+	for _, instanceNum := range []int{1, 2} {
+		for objectId, expiresAt := range map[string]time.Time{
+			"expired":   time.Now().Add(-time.Second),
+			"expiring":  time.Now().Add(time.Hour),
+			"permanent": {},
+		} {
+			_, err := clients[instanceNum].AddOrUpdateObject(context.Background(), objectId, bytes.NewReader([]byte("data")), s3.PutOptions{Size: 4, ExpiresAt: expiresAt})
+			require.NoError(t, err)
+		}
+	}
+	service.readCache.Add("expired", []byte("data"), time.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.SweepExpiredObjects(ctx, 10*time.Millisecond)
+	}()
+
+	require.Eventually(t, func() bool {
+		return clients[1].Object("expired") == nil && clients[2].Object("expired") == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-stopped
+
+	for _, instanceNum := range []int{1, 2} {
+		assert.Equal(t, []string{"expiring", "permanent"}, clients[instanceNum].Keys())
+	}
+
+	// The deleted objects are not served from the read cache
+	_, ok := service.readCache.Get("expired")
+	assert.False(t, ok)
+}
+
+func TestSweepExpiredObjects_InstanceFailure(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	clients[1].Fail(errors.New("connection refused"))
+
+	assert.ErrorContains(t, service.sweepExpiredObjects(context.Background()), "instance: 1")
+}
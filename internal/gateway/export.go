@@ -0,0 +1,214 @@
+package gateway
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var ErrExportJobNotFound = errors.New("export job not found")
+
+var (
+	// ErrInvalidExportTarget is returned when the export target endpoint is not an HTTP or HTTPS URL of a host
+	ErrInvalidExportTarget = errors.New("invalid export target")
+	// ErrExportTargetNotAllowed is returned when the export target endpoint is not in the allowed endpoints
+	ErrExportTargetNotAllowed = errors.New("export target not allowed")
+)
+
+const (
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+
+	exportTimeout = time.Hour
+	// exportJobTTL is how long the finished jobs are kept for polling
+	exportJobTTL = time.Hour
+)
+
+// WithExportTargets allows exporting the objects to the endpoints, given as HTTP or HTTPS URLs of a host, e.g.
+// https://s3.amazonaws.com. The objects can't be exported to any other endpoint, so the gateway can't be used to reach
+// the internal network. Exports are disabled without any allowed endpoints.
+func WithExportTargets(endpoints []string) Option {
+	return func(s *ServiceV1) {
+		s.exportTargets = map[string]struct{}{}
+		for _, endpoint := range endpoints {
+			origin, err := exportOrigin(endpoint)
+			if err != nil {
+				s.logger.Warn("Ignoring the invalid allowed export endpoint", zap.String("endpoint", endpoint), zap.Error(err))
+				continue
+			}
+
+			s.exportTargets[origin] = struct{}{}
+		}
+	}
+}
+
+// exportOrigin returns the scheme and the host of the endpoint. The endpoint can't have a path, as the objects are
+// exported to the bucket of the host.
+func exportOrigin(endpoint string) (string, error) {
+	target, err := url.Parse(endpoint)
+	switch {
+	case err != nil:
+		return "", errors.Wrap(ErrInvalidExportTarget, err.Error())
+	case target.Scheme != "http" && target.Scheme != "https", target.Host == "":
+		return "", errors.Wrap(ErrInvalidExportTarget, "expected an HTTP or HTTPS URL")
+	case target.User != nil, strings.Trim(target.Path, "/") != "", target.RawQuery != "", target.Fragment != "":
+		return "", errors.Wrap(ErrInvalidExportTarget, "expected a URL without credentials, path or query")
+	}
+
+	return target.Scheme + "://" + strings.ToLower(target.Host), nil
+}
+
+// allowedExportTarget checks the endpoint is allowed as an export target and returns its normalized form
+func (s *ServiceV1) allowedExportTarget(endpoint string) (string, error) {
+	origin, err := exportOrigin(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := s.exportTargets[origin]; !ok {
+		return "", errors.Wrapf(ErrExportTargetNotAllowed, "%s is not an allowed export endpoint", origin)
+	}
+
+	return origin, nil
+}
+
+// ExportTarget is an external S3-compatible storage the objects are exported to
+type ExportTarget struct {
+	Endpoint  string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// ExportJob is an asynchronous export of an object
+type ExportJob struct {
+	Id       string
+	ObjectId string
+	Status   string
+	Error    string
+	// FinishedAt is the time the job completed or failed, zero while running
+	FinishedAt time.Time
+}
+
+// exportJobs holds the export jobs in memory. The finished jobs are evicted after the exportJobTTL. The running jobs
+// are cancelled on close.
+type exportJobs struct {
+	mu     sync.Mutex
+	jobs   map[string]ExportJob
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (e *exportJobs) set(job ExportJob) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.jobs == nil {
+		e.jobs = map[string]ExportJob{}
+	}
+	e.jobs[job.Id] = job
+	e.evict(time.Now())
+}
+
+func (e *exportJobs) get(jobId string) (ExportJob, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.evict(time.Now())
+	job, ok := e.jobs[jobId]
+	return job, ok
+}
+
+// evict removes the jobs finished longer than the exportJobTTL ago
+func (e *exportJobs) evict(now time.Time) {
+	for id, job := range e.jobs {
+		if !job.FinishedAt.IsZero() && now.Sub(job.FinishedAt) > exportJobTTL {
+			delete(e.jobs, id)
+		}
+	}
+}
+
+// context returns the context of the running jobs, cancelled on close
+func (e *exportJobs) context() context.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.ctx == nil {
+		e.ctx, e.cancel = context.WithCancel(context.Background())
+	}
+	return e.ctx
+}
+
+// close cancels the running jobs
+func (e *exportJobs) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// ExportObject starts exporting the object to the target in the background and returns the job ID
+func (s *ServiceV1) ExportObject(ctx context.Context, objectId string, target ExportTarget) (string, error) {
+	logger := s.logger.With(zap.String("objectId", objectId))
+	logger.Info("Exporting object")
+
+	endpoint, err := s.allowedExportTarget(target.Endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	// Determine which instance to read from based on the objectId
+	instance, err := s.shardObjectToInstance(ctx, objectId)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to assign object to instance")
+	}
+
+	// Minio client must be dynamically created, based on the S3 instance
+	client, err := s.clientFactory(*instance)
+	if err != nil {
+		return "", err
+	}
+
+	job := ExportJob{Id: uuid.NewString(), ObjectId: objectId, Status: ExportStatusRunning}
+	s.exportJobs.set(job)
+
+	// The export outlives the request, until the service is closed
+	exportCtx, cancel := context.WithTimeout(s.exportJobs.context(), exportTimeout)
+	go func() {
+		defer cancel()
+
+		err := client.ExportObject(exportCtx, objectId, endpoint, target.Bucket, target.AccessKey, target.SecretKey)
+		if err != nil {
+			logger.Error("Failed to export object", zap.String("jobId", job.Id), zap.Error(err))
+			job.Status = ExportStatusFailed
+			job.Error = err.Error()
+		} else {
+			logger.Info("Exported object", zap.String("jobId", job.Id))
+			job.Status = ExportStatusCompleted
+		}
+
+		job.FinishedAt = time.Now()
+		s.exportJobs.set(job)
+	}()
+
+	return job.Id, nil
+}
+
+// GetExportJob returns the export job of the object
+func (s *ServiceV1) GetExportJob(ctx context.Context, objectId, jobId string) (*ExportJob, error) {
+	job, ok := s.exportJobs.get(jobId)
+	if !ok || job.ObjectId != objectId {
+		return nil, ErrExportJobNotFound
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"hash/crc64"
+	"hash/fnv"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/pkg/errors"
+)
+
+// Hasher hashes the object IDs for the sharding
+type Hasher interface {
+	Hash(id string) uint64
+}
+
+// FNVHasher hashes with FNV-64a. It is the default hasher.
+type FNVHasher struct{}
+
+func (FNVHasher) Hash(id string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(id))
+	return hash.Sum64()
+}
+
+// XXHasher hashes with xxHash64
+type XXHasher struct{}
+
+func (XXHasher) Hash(id string) uint64 {
+	return xxhash.Sum64String(id)
+}
+
+// CRCHasher hashes with CRC-64 (ECMA)
+type CRCHasher struct{}
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+func (CRCHasher) Hash(id string) uint64 {
+	return crc64.Checksum([]byte(id), crcTable)
+}
+
+// NewHasher returns the hasher with the given name: fnv, xxhash or crc. An empty name returns the FNVHasher.
+func NewHasher(name string) (Hasher, error) {
+	switch name {
+	case "", "fnv":
+		return FNVHasher{}, nil
+	case "xxhash":
+		return XXHasher{}, nil
+	case "crc":
+		return CRCHasher{}, nil
+	default:
+		return nil, errors.Errorf("unknown hash function: %s", name)
+	}
+}
+
+// hasherOrDefault returns the hasher, or the FNVHasher if it is not set
+func hasherOrDefault(hasher Hasher) Hasher {
+	if hasher == nil {
+		return FNVHasher{}
+	}
+
+	return hasher
+}
@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHasher(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Hasher
+	}{
+		{name: "", expected: FNVHasher{}},
+		{name: "fnv", expected: FNVHasher{}},
+		{name: "xxhash", expected: XXHasher{}},
+		{name: "crc", expected: CRCHasher{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewHasher(tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hasher)
+		})
+	}
+
+	_, err := NewHasher("md5")
+	assert.ErrorContains(t, err, "unknown hash function: md5")
+}
+
+func TestHasher_DefaultsToFNV(t *testing.T) {
+	instances := numberedInstances(1, 2, 3, 4, 5)
+
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("object%d", i)
+		expected, err := ModuloShardStrategy{Hasher: FNVHasher{}}.Shard(id, instances)
+		require.NoError(t, err)
+		actual, err := ModuloShardStrategy{}.Shard(id, instances)
+		require.NoError(t, err)
+		assert.Equal(t, expected.InstanceNum, actual.InstanceNum)
+	}
+}
+
+func TestHasher_Uniformity(t *testing.T) {
+	const keys = 100000
+
+	hashers := map[string]Hasher{"fnv": FNVHasher{}, "xxhash": XXHasher{}, "crc": CRCHasher{}}
+	for name, hasher := range hashers {
+		for _, n := range []int{2, 3, 5, 7} {
+			t.Run(fmt.Sprintf("%s/%d instances", name, n), func(t *testing.T) {
+				numbers := make([]int, 0, n)
+				for i := 1; i <= n; i++ {
+					numbers = append(numbers, i)
+				}
+				instances := numberedInstances(numbers...)
+
+				counts := map[int]int{}
+				for i := 0; i < keys; i++ {
+					instance, err := ModuloShardStrategy{Hasher: hasher}.Shard(fmt.Sprintf("object%d", i), instances)
+					require.NoError(t, err)
+					counts[instance.InstanceNum]++
+				}
+
+				// Every instance receives its share of the keys, within 5%
+				expected := float64(keys) / float64(n)
+				require.Len(t, counts, n)
+				for instanceNum, count := range counts {
+					assert.InEpsilon(t, expected, float64(count), 0.05, "instance %d", instanceNum)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/observability"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+// instrumentedService counts the operations of the inner service and measures their duration
+type instrumentedService struct {
+	Service
+	operations *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// InstrumentedService records the Prometheus metrics of the object operations in the registerer
+func InstrumentedService(inner Service, reg prometheus.Registerer) Service {
+	return &instrumentedService{
+		Service: inner,
+		operations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: observability.MetricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "operations_total",
+			Help:      "Number of the object operations by the result",
+		}, []string{"operation", "result"}),
+		duration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: observability.MetricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of the object operations",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+}
+
+// observe records an operation started at the given time
+func (i *instrumentedService) observe(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil && !errors.Is(err, ErrListTruncated) {
+		result = "error"
+	}
+
+	i.operations.WithLabelValues(operation, result).Inc()
+	i.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (i *instrumentedService) AddOrUpdateObject(ctx context.Context, objectId string, file multipart.File, options UploadOptions) (result *UploadResult, err error) {
+	defer func(start time.Time) { i.observe("put", start, err) }(time.Now())
+	return i.Service.AddOrUpdateObject(ctx, objectId, file, options)
+}
+
+func (i *instrumentedService) GetObject(ctx context.Context, objectId string) (object io.Reader, err error) {
+	defer func(start time.Time) { i.observe("get", start, err) }(time.Now())
+	return i.Service.GetObject(ctx, objectId)
+}
+
+func (i *instrumentedService) GetObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (object io.Reader, err error) {
+	defer func(start time.Time) { i.observe("get", start, err) }(time.Now())
+	return i.Service.GetObjectFromInstance(ctx, objectId, instanceNum)
+}
+
+func (i *instrumentedService) StatObject(ctx context.Context, objectId string) (info *s3.ObjectInfo, err error) {
+	defer func(start time.Time) { i.observe("stat", start, err) }(time.Now())
+	return i.Service.StatObject(ctx, objectId)
+}
+
+func (i *instrumentedService) StatObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (info *s3.ObjectInfo, err error) {
+	defer func(start time.Time) { i.observe("stat", start, err) }(time.Now())
+	return i.Service.StatObjectFromInstance(ctx, objectId, instanceNum)
+}
+
+func (i *instrumentedService) DeleteObject(ctx context.Context, objectId string) (err error) {
+	defer func(start time.Time) { i.observe("delete", start, err) }(time.Now())
+	return i.Service.DeleteObject(ctx, objectId)
+}
+
+func (i *instrumentedService) RenameObject(ctx context.Context, oldId, newId string) (err error) {
+	defer func(start time.Time) { i.observe("rename", start, err) }(time.Now())
+	return i.Service.RenameObject(ctx, oldId, newId)
+}
+
+func (i *instrumentedService) DeleteObjectsByPrefix(ctx context.Context, prefix string) (deleted int, err error) {
+	defer func(start time.Time) { i.observe("delete_by_prefix", start, err) }(time.Now())
+	return i.Service.DeleteObjectsByPrefix(ctx, prefix)
+}
+
+func (i *instrumentedService) CountObjectsByPrefix(ctx context.Context, prefix string) (count int, err error) {
+	defer func(start time.Time) { i.observe("count", start, err) }(time.Now())
+	return i.Service.CountObjectsByPrefix(ctx, prefix)
+}
+
+func (i *instrumentedService) GetObjects(ctx context.Context, filter s3.ListFilter) (objectIds []string, err error) {
+	defer func(start time.Time) { i.observe("list", start, err) }(time.Now())
+	return i.Service.GetObjects(ctx, filter)
+}
+
+func (i *instrumentedService) GetObjectsWithMetadata(ctx context.Context, filter s3.ListFilter) (summaries []s3.ObjectSummary, err error) {
+	defer func(start time.Time) { i.observe("list", start, err) }(time.Now())
+	return i.Service.GetObjectsWithMetadata(ctx, filter)
+}
+
+func (i *instrumentedService) GetObjectsAsync(ctx context.Context, filter s3.ListFilter) (objectIds []string, err error) {
+	defer func(start time.Time) { i.observe("list", start, err) }(time.Now())
+	return i.Service.GetObjectsAsync(ctx, filter)
+}
+
+func (i *instrumentedService) CountObjects(ctx context.Context) (count *ObjectCount, err error) {
+	defer func(start time.Time) { i.observe("count", start, err) }(time.Now())
+	return i.Service.CountObjects(ctx)
+}
+
+func (i *instrumentedService) MigrateObject(ctx context.Context, objectId string, targetInstanceNum int) (result *MigrationResult, err error) {
+	defer func(start time.Time) { i.observe("migrate", start, err) }(time.Now())
+	return i.Service.MigrateObject(ctx, objectId, targetInstanceNum)
+}
+
+func (i *instrumentedService) ExportObject(ctx context.Context, objectId string, target ExportTarget) (jobId string, err error) {
+	defer func(start time.Time) { i.observe("export", start, err) }(time.Now())
+	return i.Service.ExportObject(ctx, objectId, target)
+}
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedService(t *testing.T) {
+	inner := &stubService{keys: []string{"object"}}
+	service := InstrumentedService(inner, prometheus.NewRegistry()).(*instrumentedService)
+	ctx := context.Background()
+
+	_, err := service.GetObject(ctx, "object")
+	require.NoError(t, err)
+
+	inner.err = s3.ErrObjectNotFound
+	_, err = service.GetObject(ctx, "missing")
+	assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+
+	// A truncated listing is a successful one
+	inner.err = errors.WithStack(ErrListTruncated)
+	_, err = service.GetObjects(ctx, s3.ListFilter{})
+	assert.ErrorIs(t, err, ErrListTruncated)
+
+	assert.Equal(t, []string{"object", "missing"}, inner.objectIds)
+	assert.Equal(t, 1.0, testutil.ToFloat64(service.operations.WithLabelValues("get", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(service.operations.WithLabelValues("get", "error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(service.operations.WithLabelValues("list", "success")))
+	assert.Equal(t, 2, testutil.CollectAndCount(service.duration))
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrWriteInProgress is returned when the object is already being written and the writes are exclusive
+var ErrWriteInProgress = errors.New("write to the object already in progress")
+
+// WriteLocking controls the concurrent writes to the same object
+type WriteLocking string
+
+const (
+	// WriteLockingNone lets the concurrent writes race at the S3 instance, the last writer wins
+	WriteLockingNone WriteLocking = ""
+	// WriteLockingSerialize makes the concurrent writes wait for each other
+	WriteLockingSerialize WriteLocking = "serialize"
+	// WriteLockingExclusive rejects the writes to an object already being written with ErrWriteInProgress
+	WriteLockingExclusive WriteLocking = "exclusive"
+)
+
+// ParseWriteLocking parses the write locking mode
+func ParseWriteLocking(mode string) (WriteLocking, error) {
+	switch locking := WriteLocking(mode); locking {
+	case WriteLockingNone, WriteLockingSerialize, WriteLockingExclusive:
+		return locking, nil
+	default:
+		return "", errors.Errorf("unknown write locking mode %q", mode)
+	}
+}
+
+// WithWriteLocking locks the objects while they are written by this gateway. The writes of the other gateway
+// replicas are not locked.
+func WithWriteLocking(locking WriteLocking) Option {
+	return func(s *ServiceV1) {
+		s.writeLocking = locking
+	}
+}
+
+// keyedMutex locks the object keys independently. The locks of the keys no longer written are released.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	// held has a token while the key is locked
+	held chan struct{}
+	// refs counts the holder and the waiters, the lock is removed when it drops to zero
+	refs int
+}
+
+// lock locks the key until the returned function is called. Unless wait is set, it fails with ErrWriteInProgress
+// if the key is already locked, instead of waiting until the key is unlocked or the context is cancelled.
+func (m *keyedMutex) lock(ctx context.Context, key string, wait bool) (func(), error) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = map[string]*keyLock{}
+	}
+
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyLock{held: make(chan struct{}, 1)}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	select {
+	case l.held <- struct{}{}:
+		return func() {
+			<-l.held
+			m.release(key, l)
+		}, nil
+	default:
+	}
+
+	if !wait {
+		m.release(key, l)
+		return nil, ErrWriteInProgress
+	}
+
+	select {
+	case l.held <- struct{}{}:
+		return func() {
+			<-l.held
+			m.release(key, l)
+		}, nil
+	case <-ctx.Done():
+		m.release(key, l)
+		return nil, ctx.Err()
+	}
+}
+
+func (m *keyedMutex) release(key string, l *keyLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(m.locks, key)
+	}
+}
+
+// lockWrite locks the object for the write, according to the write locking mode
+func (s *ServiceV1) lockWrite(ctx context.Context, objectId string) (func(), error) {
+	switch s.writeLocking {
+	case WriteLockingSerialize:
+		return s.writeLocks.lock(ctx, objectId, true)
+	case WriteLockingExclusive:
+		return s.writeLocks.lock(ctx, objectId, false)
+	default:
+		return func() {}, nil
+	}
+}
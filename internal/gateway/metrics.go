@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/observability"
+)
+
+const metricsSubsystem = "gateway"
+
+// shardOperations counts the operations handled by each instance, showing the skew of the sharding
+var shardOperations = promauto.With(observability.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: observability.MetricsNamespace,
+	Subsystem: metricsSubsystem,
+	Name:      "shard_operations_total",
+	Help:      "Number of the reads and writes routed to each S3 instance",
+}, []string{"operation", "instance"})
+
+// circuitStates is the circuit breaker state of each instance: 0 closed, 1 open, 2 half-open
+var circuitStates = promauto.With(observability.Registry).NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: observability.MetricsNamespace,
+	Subsystem: metricsSubsystem,
+	Name:      "circuit_breaker_state",
+	Help:      "State of the circuit breaker of each S3 instance: 0 closed, 1 open, 2 half-open",
+}, []string{"instance"})
+
+// recordCircuitState sets the circuit breaker state of the instance
+func recordCircuitState(instanceNum int, state circuitState) {
+	circuitStates.WithLabelValues(strconv.Itoa(instanceNum)).Set(float64(state))
+}
+
+// forgetCircuitState removes the circuit breaker state of the removed instance
+func forgetCircuitState(instanceNum int) {
+	circuitStates.DeleteLabelValues(strconv.Itoa(instanceNum))
+}
+
+// recordShardOperation counts an operation routed to the instance
+func recordShardOperation(operation string, instanceNum int) {
+	shardOperations.WithLabelValues(operation, strconv.Itoa(instanceNum)).Inc()
+}
@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInstanceNotFound    = errors.New("instance not found")
+	ErrObjectAlreadyExists = errors.New("object already exists")
+	ErrChecksumMismatch    = errors.New("checksum mismatch")
+)
+
+// migration holds the clients of the source and target instances of an object migration
+type migration struct {
+	source    s3.Client
+	target    s3.Client
+	sourceNum int
+}
+
+// MigrationResult describes the instances an object is migrated between
+type MigrationResult struct {
+	SourceInstance int
+	TargetInstance int
+}
+
+// CheckMigration validates the connectivity to both instances and that the object can be migrated, without moving any data
+func (s *ServiceV1) CheckMigration(ctx context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error) {
+	m, err := s.prepareMigration(ctx, objectId, targetInstanceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MigrationResult{SourceInstance: m.sourceNum, TargetInstance: targetInstanceNum}, nil
+}
+
+// MigrateObject moves the object from the instance it is sharded to, to the target instance.
+func (s *ServiceV1) MigrateObject(ctx context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error) {
+	logger := s.logger.With(zap.String("objectId", objectId), zap.Int("targetInstance", targetInstanceNum))
+	logger.Info("Migrating object")
+
+	m, err := s.prepareMigration(ctx, objectId, targetInstanceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.moveObject(ctx, m, objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Migrated object", zap.Int("sourceInstance", m.sourceNum))
+	s.audit(ctx, "migrate", objectId, targetInstanceNum, nil)
+	return &MigrationResult{SourceInstance: m.sourceNum, TargetInstance: targetInstanceNum}, nil
+}
+
+// moveObject copies the object from the source to the target instance of the migration and deletes the source object.
+// The copy is verified with a SHA-256 checksum before the source object is deleted. If the checksums don't match,
+// the copy is removed from the target instance.
+func (s *ServiceV1) moveObject(ctx context.Context, m *migration, objectId string) error {
+	logger := s.logger.With(zap.String("objectId", objectId), zap.Int("sourceInstance", m.sourceNum))
+
+	// Stream the object from the source to the target, hashing it on the way
+	object, err := m.source.GetObject(ctx, objectId)
+	if err != nil {
+		return errors.Wrap(err, "failed to get object from the source instance")
+	}
+
+	if closer, ok := object.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	sourceHash := sha256.New()
+	_, err = m.target.AddOrUpdateObject(ctx, objectId, io.TeeReader(object, sourceHash), s3.PutOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to upload object to the target instance")
+	}
+
+	// Verify the copy, removing it if it doesn't match the source
+	targetChecksum, err := objectChecksum(ctx, m.target, objectId)
+	if err == nil && !bytes.Equal(sourceHash.Sum(nil), targetChecksum) {
+		err = ErrChecksumMismatch
+	}
+
+	if err != nil {
+		logger.Error("Failed to verify the migrated object, rolling back", zap.Error(err))
+		rollbackErr := m.target.DeleteObject(ctx, objectId)
+		if rollbackErr != nil {
+			logger.Error("Failed to roll back the migrated object", zap.Error(rollbackErr))
+		}
+
+		return err
+	}
+
+	err = m.source.DeleteObject(ctx, objectId)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete object from the source instance")
+	}
+
+	return nil
+}
+
+// prepareMigration creates the clients for the source and target instances and checks the object can be migrated
+func (s *ServiceV1) prepareMigration(ctx context.Context, objectId string, targetInstanceNum int) (*migration, error) {
+	sourceInstance, err := s.shardObjectToInstance(ctx, objectId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to assign object to instance")
+	}
+
+	if sourceInstance.InstanceNum == targetInstanceNum {
+		return nil, errors.Wrap(ErrObjectAlreadyExists, "object is already stored in the target instance")
+	}
+
+	targetInstance, err := s.findInstance(ctx, targetInstanceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &migration{sourceNum: sourceInstance.InstanceNum}
+
+	// Minio clients must be dynamically created, based on the S3 instance
+	m.source, err = s.clientFactory(*sourceInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	m.target, err = s.clientFactory(*targetInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceExists, err := m.source.ObjectExists(ctx, objectId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reach the source instance")
+	}
+
+	if !sourceExists {
+		return nil, s3.ErrObjectNotFound
+	}
+
+	targetExists, err := m.target.ObjectExists(ctx, objectId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reach the target instance")
+	}
+
+	if targetExists {
+		return nil, errors.Wrap(ErrObjectAlreadyExists, fmt.Sprintf("object exists in the target instance: %d", targetInstanceNum))
+	}
+
+	return m, nil
+}
+
+// findInstance returns the instance with the given number
+func (s *ServiceV1) findInstance(ctx context.Context, instanceNum int) (*discovery.S3Instance, error) {
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instance := range instances {
+		if instance.InstanceNum == instanceNum {
+			return &instance, nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrInstanceNotFound, fmt.Sprintf("instance: %d", instanceNum))
+}
+
+// objectChecksum computes the SHA-256 checksum of the object stored in the instance
+func objectChecksum(ctx context.Context, client s3.Client, objectId string) ([]byte, error) {
+	object, err := client.GetObject(ctx, objectId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read back the object")
+	}
+
+	if closer, ok := object.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, object)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read back the object")
+	}
+
+	return hash.Sum(nil), nil
+}
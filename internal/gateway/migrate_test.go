@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// migrationTarget returns the number of an instance the object is not sharded to
+func migrationTarget(t *testing.T, service *ServiceV1, objectId string, n int) (int, int) {
+	t.Helper()
+
+	source, err := service.shardObjectToInstance(context.Background(), objectId)
+	require.NoError(t, err)
+	return source.InstanceNum, source.InstanceNum%n + 1
+}
+
+func TestMigrateObject(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+	source, target := migrationTarget(t, service, "object", 3)
+
+	result, err := service.MigrateObject(context.Background(), "object", target)
+	require.NoError(t, err)
+	assert.Equal(t, &MigrationResult{SourceInstance: source, TargetInstance: target}, result)
+
+	assert.Nil(t, clients[source].Object("object"))
+	require.NotNil(t, clients[target].Object("object"))
+	assert.Equal(t, []byte("data"), clients[target].Object("object").Data)
+}
+
+func TestMigrateObject_ChecksumMismatch(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+	source, target := migrationTarget(t, service, "object", 2)
+
+	// The target reads back a different content than written
+	service.clientFactory = func(instance discovery.S3Instance) (s3.Client, error) {
+		if instance.InstanceNum == target {
+			return corruptingClient{Client: clients[target]}, nil
+		}
+		return clients[instance.InstanceNum], nil
+	}
+
+	_, err = service.MigrateObject(context.Background(), "object", target)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	// The copy is rolled back and the source object is kept
+	assert.Empty(t, clients[target].Keys())
+	require.NotNil(t, clients[source].Object("object"))
+	assert.Equal(t, []byte("data"), clients[source].Object("object").Data)
+}
+
+func TestMigrateObject_DestinationExists(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+	source, target := migrationTarget(t, service, "object", 3)
+	clients[target].Put("object", []byte("other"))
+
+	_, err = service.MigrateObject(context.Background(), "object", target)
+	assert.ErrorIs(t, err, ErrObjectAlreadyExists)
+
+	// Neither object is touched
+	assert.Equal(t, []byte("data"), clients[source].Object("object").Data)
+	assert.Equal(t, []byte("other"), clients[target].Object("object").Data)
+
+	// Migrating to the instance the object is already stored in conflicts as well
+	_, err = service.MigrateObject(context.Background(), "object", source)
+	assert.ErrorIs(t, err, ErrObjectAlreadyExists)
+}
+
+func TestMigrateObject_NotFound(t *testing.T) {
+	service, _, _ := newTestService(t, 3)
+	_, target := migrationTarget(t, service, "missing", 3)
+
+	_, err := service.MigrateObject(context.Background(), "missing", target)
+	assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+	_, err = service.MigrateObject(context.Background(), "missing", 7)
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+}
+
+func TestCheckMigration(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+	source, target := migrationTarget(t, service, "object", 3)
+
+	// The dry run doesn't move the object
+	result, err := service.CheckMigration(context.Background(), "object", target)
+	require.NoError(t, err)
+	assert.Equal(t, &MigrationResult{SourceInstance: source, TargetInstance: target}, result)
+	assert.NotNil(t, clients[source].Object("object"))
+	assert.Empty(t, clients[target].Keys())
+	assert.Zero(t, clients[target].CallCount("AddOrUpdateObject"))
+}
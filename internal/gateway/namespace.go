@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+// namespacedService prepends a prefix to the object keys, so multiple applications can share the gateway without
+// conflicting keys. The sharding hashes the prefixed key, so each namespace is distributed independently.
+type namespacedService struct {
+	Service
+	prefix string
+}
+
+// WithNamespace returns a service storing the objects under the key prefix. The prefix is stripped from the returned
+// object ids. Returns the service unchanged if the prefix is empty.
+func WithNamespace(service Service, prefix string) Service {
+	if prefix == "" {
+		return service
+	}
+
+	return &namespacedService{Service: service, prefix: prefix}
+}
+
+func (n *namespacedService) key(objectId string) string {
+	return n.prefix + objectId
+}
+
+// strip removes the prefix from the keys, dropping the keys outside the namespace
+func (n *namespacedService) strip(keys []string) []string {
+	objectIds := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if objectId, ok := strings.CutPrefix(key, n.prefix); ok {
+			objectIds = append(objectIds, objectId)
+		}
+	}
+
+	return objectIds
+}
+
+func (n *namespacedService) AddOrUpdateObject(ctx context.Context, objectId string, file multipart.File, options UploadOptions) (*UploadResult, error) {
+	return n.Service.AddOrUpdateObject(ctx, n.key(objectId), file, options)
+}
+
+func (n *namespacedService) GetObject(ctx context.Context, objectId string) (io.Reader, error) {
+	return n.Service.GetObject(ctx, n.key(objectId))
+}
+
+func (n *namespacedService) GetObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (io.Reader, error) {
+	return n.Service.GetObjectFromInstance(ctx, n.key(objectId), instanceNum)
+}
+
+func (n *namespacedService) StatObject(ctx context.Context, objectId string) (*s3.ObjectInfo, error) {
+	return n.Service.StatObject(ctx, n.key(objectId))
+}
+
+func (n *namespacedService) StatObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (*s3.ObjectInfo, error) {
+	return n.Service.StatObjectFromInstance(ctx, n.key(objectId), instanceNum)
+}
+
+func (n *namespacedService) DeleteObject(ctx context.Context, objectId string) error {
+	return n.Service.DeleteObject(ctx, n.key(objectId))
+}
+
+func (n *namespacedService) GetObjectVersion(ctx context.Context, objectId, versionId string) (io.Reader, error) {
+	return n.Service.GetObjectVersion(ctx, n.key(objectId), versionId)
+}
+
+func (n *namespacedService) StatObjectVersion(ctx context.Context, objectId, versionId string) (*s3.ObjectInfo, error) {
+	return n.Service.StatObjectVersion(ctx, n.key(objectId), versionId)
+}
+
+func (n *namespacedService) DeleteObjectVersion(ctx context.Context, objectId, versionId string) error {
+	return n.Service.DeleteObjectVersion(ctx, n.key(objectId), versionId)
+}
+
+func (n *namespacedService) ListObjectVersions(ctx context.Context, objectId string) ([]s3.ObjectVersion, error) {
+	return n.Service.ListObjectVersions(ctx, n.key(objectId))
+}
+
+func (n *namespacedService) RenameObject(ctx context.Context, oldId, newId string) error {
+	return n.Service.RenameObject(ctx, n.key(oldId), n.key(newId))
+}
+
+func (n *namespacedService) DeleteObjectsByPrefix(ctx context.Context, prefix string) (int, error) {
+	return n.Service.DeleteObjectsByPrefix(ctx, n.key(prefix))
+}
+
+func (n *namespacedService) CountObjectsByPrefix(ctx context.Context, prefix string) (int, error) {
+	return n.Service.CountObjectsByPrefix(ctx, n.key(prefix))
+}
+
+func (n *namespacedService) GetObjects(ctx context.Context, filter s3.ListFilter) ([]string, error) {
+	filter.Prefix = n.key(filter.Prefix)
+	keys, err := n.Service.GetObjects(ctx, filter)
+	if err != nil && !errors.Is(err, ErrListTruncated) {
+		return nil, err
+	}
+
+	return n.strip(keys), err
+}
+
+func (n *namespacedService) GetObjectsAsync(ctx context.Context, filter s3.ListFilter) ([]string, error) {
+	filter.Prefix = n.key(filter.Prefix)
+	keys, err := n.Service.GetObjectsAsync(ctx, filter)
+	if err != nil && !errors.Is(err, ErrListTruncated) {
+		return nil, err
+	}
+
+	return n.strip(keys), err
+}
+
+func (n *namespacedService) GetObjectsWithMetadata(ctx context.Context, filter s3.ListFilter) ([]s3.ObjectSummary, error) {
+	filter.Prefix = n.key(filter.Prefix)
+	summaries, err := n.Service.GetObjectsWithMetadata(ctx, filter)
+	if err != nil && !errors.Is(err, ErrListTruncated) {
+		return nil, err
+	}
+
+	for i := range summaries {
+		summaries[i].ObjectId = strings.TrimPrefix(summaries[i].ObjectId, n.prefix)
+	}
+
+	return summaries, err
+}
+
+// StreamObjects streams the objects of the namespace. The objects outside the namespace are skipped.
+func (n *namespacedService) StreamObjects(ctx context.Context) (<-chan string, <-chan error) {
+	keys, errs := n.Service.StreamObjects(ctx)
+
+	objectIds := make(chan string, cap(keys))
+	go func() {
+		defer close(objectIds)
+
+		for key := range keys {
+			objectId, ok := strings.CutPrefix(key, n.prefix)
+			if !ok {
+				continue
+			}
+
+			select {
+			case objectIds <- objectId:
+			case <-ctx.Done():
+				// Drain the stream, so it can be closed
+				for range keys {
+				}
+				return
+			}
+		}
+	}()
+
+	return objectIds, errs
+}
+
+// CountObjects counts the objects of the namespace. The count per instance is not available.
+func (n *namespacedService) CountObjects(ctx context.Context) (*ObjectCount, error) {
+	total, err := n.Service.CountObjectsByPrefix(ctx, n.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectCount{Total: total}, nil
+}
+
+func (n *namespacedService) WatchObject(ctx context.Context, objectId string) (<-chan s3.ObjectEvent, <-chan error) {
+	keyEvents, errs := n.Service.WatchObject(ctx, n.key(objectId))
+
+	events := make(chan s3.ObjectEvent)
+	go func() {
+		defer close(events)
+
+		for event := range keyEvents {
+			event.ObjectId = strings.TrimPrefix(event.ObjectId, n.prefix)
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				for range keyEvents {
+				}
+				return
+			}
+		}
+	}()
+
+	return events, errs
+}
+
+func (n *namespacedService) MigrateObject(ctx context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error) {
+	return n.Service.MigrateObject(ctx, n.key(objectId), targetInstanceNum)
+}
+
+func (n *namespacedService) CheckMigration(ctx context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error) {
+	return n.Service.CheckMigration(ctx, n.key(objectId), targetInstanceNum)
+}
+
+func (n *namespacedService) ExportObject(ctx context.Context, objectId string, target ExportTarget) (string, error) {
+	return n.Service.ExportObject(ctx, n.key(objectId), target)
+}
+
+func (n *namespacedService) GetExportJob(ctx context.Context, objectId, jobId string) (*ExportJob, error) {
+	job, err := n.Service.GetExportJob(ctx, n.key(objectId), jobId)
+	if err != nil {
+		return nil, err
+	}
+
+	stripped := *job
+	stripped.ObjectId = strings.TrimPrefix(job.ObjectId, n.prefix)
+	return &stripped, nil
+}
+
+func (n *namespacedService) shardObjectToInstance(ctx context.Context, objectId string) (*discovery.S3Instance, error) {
+	return n.Service.shardObjectToInstance(ctx, n.key(objectId))
+}
@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithNamespace(t *testing.T) {
+	inner := &stubService{keys: []string{"tenant-a", "tenant-b", "other-c"}}
+	service := WithNamespace(inner, "tenant-")
+	ctx := context.Background()
+
+	_, err := service.AddOrUpdateObject(ctx, "a", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+	_, err = service.GetObject(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteObject(ctx, "a"))
+	assert.Equal(t, []string{"tenant-a", "tenant-a", "tenant-a"}, inner.objectIds)
+
+	// The listing is scoped to the namespace and the prefix is stripped from the keys
+	objectIds, err := service.GetObjects(ctx, s3.ListFilter{Prefix: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, objectIds)
+	assert.Equal(t, "tenant-b", inner.filters[0].Prefix)
+}
+
+func TestWithNamespace_EmptyPrefix(t *testing.T) {
+	inner := &stubService{}
+	assert.Same(t, Service(inner), WithNamespace(inner, ""))
+}
+
+func TestWithNamespace_IsolatesNamespaces(t *testing.T) {
+	service, _, clients := newTestService(t, 1)
+	first, second := WithNamespace(service, "first-"), WithNamespace(service, "second-")
+	ctx := context.Background()
+
+	_, err := first.AddOrUpdateObject(ctx, "object", newTestFile([]byte("first")), UploadOptions{})
+	require.NoError(t, err)
+	_, err = second.AddOrUpdateObject(ctx, "object", newTestFile([]byte("second")), UploadOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"first-object", "second-object"}, clients[1].Keys())
+
+	object, err := first.GetObject(ctx, "object")
+	require.NoError(t, err)
+	data, err := io.ReadAll(object)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal([]byte("first"), data))
+
+	require.NoError(t, second.DeleteObject(ctx, "object"))
+	objectIds, err := first.GetObjects(ctx, s3.ListFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"object"}, objectIds)
+}
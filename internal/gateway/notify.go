@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// WatchObject streams the changes of the object from its instance. The events channel is closed once the context is
+// cancelled or the notifications fail. At most one error is sent to the error channel, which is closed after the
+// events channel.
+func (s *ServiceV1) WatchObject(ctx context.Context, objectId string) (<-chan s3.ObjectEvent, <-chan error) {
+	s.logger.Info("Watching object", zap.String("objectId", objectId))
+
+	events := make(chan s3.ObjectEvent, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(errChan)
+		defer close(events)
+
+		// Determine which instance the object is stored in based on the objectId
+		instance, err := s.shardObjectToInstance(ctx, objectId)
+		if err != nil {
+			errChan <- errors.Wrap(err, "failed to assign object to instance")
+			return
+		}
+
+		if !instance.Supports(discovery.FeatureBucketNotifications) {
+			errChan <- errors.Wrapf(ErrFeatureUnsupported, "instance %d runs %s", instance.InstanceNum, instance.Version)
+			return
+		}
+
+		// Minio client must be dynamically created, based on the S3 instance
+		client, err := s.clientFactory(*instance)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		err = client.WatchObject(ctx, objectId, events)
+		if err != nil && ctx.Err() == nil {
+			errChan <- errors.Wrap(err, "failed to watch object")
+		}
+	}()
+
+	return events, errChan
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+// Option configures the ServiceV1
+type Option func(*ServiceV1)
+
+// ClientFactory creates the S3 client of an instance
+type ClientFactory func(instance discovery.S3Instance) (s3.Client, error)
+
+// WithReplicationFactor sets the number of instances each object is stored on. Values below 2 disable replication.
+func WithReplicationFactor(n int) Option {
+	return func(s *ServiceV1) {
+		s.replicationFactor = n
+	}
+}
+
+// WithShardStrategy sets the strategy assigning the objects to the instances
+func WithShardStrategy(strategy ShardStrategy) Option {
+	return func(s *ServiceV1) {
+		if strategy != nil {
+			s.shardStrategy = strategy
+		}
+	}
+}
+
+// WithWorkerCount limits the number of instances queried concurrently. Zero queries all instances at once.
+func WithWorkerCount(n int) Option {
+	return func(s *ServiceV1) {
+		s.workerCount = n
+	}
+}
+
+// WithClientFactory replaces the factory creating the Minio clients
+func WithClientFactory(fn func(discovery.S3Instance) (s3.Client, error)) Option {
+	return func(s *ServiceV1) {
+		if fn != nil {
+			s.clientFactory = fn
+		}
+	}
+}
+
+// WithMaxObjectSize limits the size of the uploaded objects in bytes. Zero means no limit.
+func WithMaxObjectSize(bytes int64) Option {
+	return func(s *ServiceV1) {
+		s.maxObjectSize = bytes
+	}
+}
+
+// WithAuditLogger sets the logger recording the operations modifying the objects
+func WithAuditLogger(al AuditLogger) Option {
+	return func(s *ServiceV1) {
+		if al != nil {
+			s.auditLogger = al
+		}
+	}
+}
+
+// WithQuotaEnforcer sets the quota enforcer. Without it, the quotas are not enforced.
+func WithQuotaEnforcer(quotaEnforcer QuotaEnforcer) Option {
+	return func(s *ServiceV1) {
+		s.quotaEnforcer = quotaEnforcer
+	}
+}
+
+// WithReadCache caches the content of the small objects read by GetObject for the ttl, up to maxBytes in total.
+// The objects modified behind the gateway are served stale until they expire. Zero maxBytes or ttl disables the cache.
+// Unlike the CachingService decorator, the cache is invalidated by the background sweeps of the service as well.
+func WithReadCache(maxBytes int64, ttl time.Duration) Option {
+	return func(s *ServiceV1) {
+		if maxBytes > 0 && ttl > 0 {
+			s.readCache = NewReadCache(maxBytes)
+			s.readCacheTTL = ttl
+		}
+	}
+}
+
+// WithMaxListedObjects limits the number of objects returned by a listing, protecting the memory of the gateway.
+// Zero disables the limit.
+func WithMaxListedObjects(n int) Option {
+	return func(s *ServiceV1) {
+		s.maxListedObjects = n
+	}
+}
+
+// WithMaxBulkDeleteCount limits the number of objects deleted by a single bulk delete. Zero disables the limit.
+func WithMaxBulkDeleteCount(n int) Option {
+	return func(s *ServiceV1) {
+		s.maxBulkDeleteCount = n
+	}
+}
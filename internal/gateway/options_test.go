@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newOptionsService(t *testing.T, opts ...Option) *ServiceV1 {
+	t.Helper()
+
+	service := NewServiceV1WithOptions(discoverytest.NewService(discoverytest.Instances(1)...), s3.Options{}, opts...)
+	t.Cleanup(func() { _ = service.Close() })
+	return service
+}
+
+func TestNewServiceV1WithOptions_Defaults(t *testing.T) {
+	service := newOptionsService(t)
+
+	assert.Equal(t, s3.BucketName, service.s3Options.Bucket)
+	assert.Equal(t, ModuloShardStrategy{}, service.shardStrategy)
+	assert.Equal(t, 1, service.replicationFactor)
+	assert.Zero(t, service.workerCount)
+	assert.Equal(t, nopAuditLogger{}, service.auditLogger)
+	assert.Nil(t, service.quotaEnforcer)
+	assert.Nil(t, service.readCache)
+}
+
+func TestNewServiceV1(t *testing.T) {
+	quotaEnforcer := NewInMemoryQuotaEnforcer(map[string]Quota{})
+	strategy := &WeightedHashShardStrategy{}
+	service := NewServiceV1(discoverytest.NewService(), s3.Options{Bucket: "objects"}, quotaEnforcer, strategy)
+	t.Cleanup(func() { _ = service.Close() })
+
+	assert.Equal(t, "objects", service.s3Options.Bucket)
+	assert.Same(t, quotaEnforcer, service.quotaEnforcer)
+	assert.Same(t, strategy, service.shardStrategy)
+	assert.Equal(t, 1, service.replicationFactor)
+}
+
+func TestWithReplicationFactor(t *testing.T) {
+	assert.Equal(t, 3, newOptionsService(t, WithReplicationFactor(3)).replicationFactor)
+}
+
+func TestWithShardStrategy(t *testing.T) {
+	strategy := &WeightedHashShardStrategy{}
+	assert.Same(t, strategy, newOptionsService(t, WithShardStrategy(strategy)).shardStrategy)
+
+	// A nil strategy keeps the default
+	assert.Equal(t, ModuloShardStrategy{}, newOptionsService(t, WithShardStrategy(nil)).shardStrategy)
+}
+
+func TestWithWorkerCount(t *testing.T) {
+	assert.Equal(t, 4, newOptionsService(t, WithWorkerCount(4)).workerCount)
+}
+
+func TestWithClientFactory(t *testing.T) {
+	client := s3test.NewClient()
+	var created []int
+	service := newOptionsService(t, WithClientFactory(func(instance discovery.S3Instance) (s3.Client, error) {
+		created = append(created, instance.InstanceNum)
+		return client, nil
+	}))
+
+	// The factory is wrapped by the client pool
+	got, err := service.clientFactory(discoverytest.Instances(1)[0])
+	require.NoError(t, err)
+	assert.Same(t, client, got)
+	assert.Equal(t, []int{1}, created)
+
+	// A nil factory keeps the Minio clients
+	assert.NotNil(t, newOptionsService(t, WithClientFactory(nil)).clientFactory)
+}
+
+func TestWithMaxObjectSize(t *testing.T) {
+	assert.EqualValues(t, 1024, newOptionsService(t, WithMaxObjectSize(1024)).maxObjectSize)
+}
+
+func TestWithAuditLogger(t *testing.T) {
+	auditLogger := NewZapAuditLogger(zap.NewNop())
+	assert.Same(t, auditLogger, newOptionsService(t, WithAuditLogger(auditLogger)).auditLogger)
+
+	// A nil logger keeps discarding the events
+	assert.Equal(t, nopAuditLogger{}, newOptionsService(t, WithAuditLogger(nil)).auditLogger)
+}
+
+func TestWithQuotaEnforcer(t *testing.T) {
+	quotaEnforcer := NewInMemoryQuotaEnforcer(map[string]Quota{})
+	assert.Same(t, quotaEnforcer, newOptionsService(t, WithQuotaEnforcer(quotaEnforcer)).quotaEnforcer)
+}
+
+func TestWithReadCache(t *testing.T) {
+	service := newOptionsService(t, WithReadCache(1024, time.Minute))
+	assert.NotNil(t, service.readCache)
+	assert.Equal(t, time.Minute, service.readCacheTTL)
+
+	assert.Nil(t, newOptionsService(t, WithReadCache(0, time.Minute)).readCache)
+	assert.Nil(t, newOptionsService(t, WithReadCache(1024, 0)).readCache)
+}
+
+func TestWithMaxListedObjects(t *testing.T) {
+	assert.Equal(t, 100, newOptionsService(t, WithMaxListedObjects(100)).maxListedObjects)
+}
+
+func TestWithMaxBulkDeleteCount(t *testing.T) {
+	assert.Equal(t, 50, newOptionsService(t, WithMaxBulkDeleteCount(50)).maxBulkDeleteCount)
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// GetObjectFromInstance fetches an object from the given instance, bypassing the sharding.
+// Returns ErrInstanceNotFound if the instance was not discovered.
+func (s *ServiceV1) GetObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (io.Reader, error) {
+	s.logger.Info("Getting object from pinned S3 instance", zap.String("objectId", objectId), zap.Int("instance", instanceNum))
+
+	client, err := s.pinnedClient(ctx, instanceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := client.GetObject(ctx, objectId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get object from S3")
+	}
+
+	return obj, nil
+}
+
+// StatObjectFromInstance returns the metadata of an object stored in the given instance, bypassing the sharding.
+// Returns ErrInstanceNotFound if the instance was not discovered.
+func (s *ServiceV1) StatObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (*s3.ObjectInfo, error) {
+	client, err := s.pinnedClient(ctx, instanceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.StatObject(ctx, objectId)
+}
+
+// pinnedClient returns the client of the discovered instance with the given number
+func (s *ServiceV1) pinnedClient(ctx context.Context, instanceNum int) (s3.Client, error) {
+	instance, err := s.findInstance(ctx, instanceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	// Minio client must be dynamically created, based on the S3 instance
+	return s.clientFactory(*instance)
+}
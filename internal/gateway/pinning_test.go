@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetObjectFromInstance(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	clients[2].Put("object", []byte("data"))
+	ctx := context.Background()
+
+	reader, err := service.GetObjectFromInstance(ctx, "object", 2)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	info, err := service.StatObjectFromInstance(ctx, "object", 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, info.Size)
+
+	_, err = service.GetObjectFromInstance(ctx, "object", 4)
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+	_, err = service.StatObjectFromInstance(ctx, "object", 4)
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+}
+
+func TestAddOrUpdateObject_PinnedInstance(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	ctx := context.Background()
+
+	pin := 3
+	_, err := service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("data")), UploadOptions{PinnedInstance: &pin})
+	require.NoError(t, err)
+	assert.NotNil(t, clients[3].Object("object"))
+	assert.Nil(t, clients[1].Object("object"))
+	assert.Nil(t, clients[2].Object("object"))
+
+	missing := 4
+	_, err = service.AddOrUpdateObject(ctx, "other", newTestFile([]byte("data")), UploadOptions{PinnedInstance: &missing})
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+}
@@ -0,0 +1,313 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+const quotaRecalculationInterval = time.Hour
+
+// QuotaEnforcer limits the storage used by a namespace. The namespace of an object is its key prefix up to the last
+// slash, e.g. the X-Namespace of the request, or the bucket for the objects stored without a prefix.
+//
+// The uploads don't only check the quota: two concurrent uploads could both pass the check and exceed the quota
+// together. Instead, they reserve the usage with ReserveUsage, which checks and records the change in a single step.
+type QuotaEnforcer interface {
+	// CheckQuota returns ErrQuotaExceeded if storing the additional bytes would exceed the quota of the namespace.
+	// Nothing is recorded.
+	CheckQuota(ctx context.Context, namespace string, additionalBytes int64) error
+	// ReserveUsage checks the usage change like CheckQuota and records it right away, so the concurrent writes count
+	// it, and returns the function reverting it if the write fails.
+	ReserveUsage(ctx context.Context, namespace string, change s3.Usage) (func(), error)
+	// RemoveUsage records the objects removed from the namespace
+	RemoveUsage(ctx context.Context, namespace string, removed s3.Usage)
+	// SetUsage replaces the recorded usage of all namespaces with the actual usage. The namespaces missing from the
+	// usage hold nothing.
+	SetUsage(ctx context.Context, usage map[string]s3.Usage)
+}
+
+// Quota is the storage limit of a namespace. Zero means unlimited.
+type Quota struct {
+	MaxBytes   int64 `yaml:"max_bytes"`
+	MaxObjects int64 `yaml:"max_objects"`
+}
+
+// InMemoryQuotaEnforcer tracks the usage of each namespace in memory
+type InMemoryQuotaEnforcer struct {
+	quotas map[string]Quota
+	// Usage per namespace, stored as *namespaceUsage
+	usage  sync.Map
+	logger *zap.Logger
+}
+
+type namespaceUsage struct {
+	mu    sync.Mutex
+	usage s3.Usage
+}
+
+// NewInMemoryQuotaEnforcer creates a new instance of the InMemoryQuotaEnforcer with the given quotas
+func NewInMemoryQuotaEnforcer(quotas map[string]Quota) *InMemoryQuotaEnforcer {
+	return &InMemoryQuotaEnforcer{
+		quotas: quotas,
+		logger: zap.L().Named("quota"),
+	}
+}
+
+// NewInMemoryQuotaEnforcerFromFile loads the quotas from a YAML file mapping the namespace to its quota:
+//
+//	spacelift-storage:
+//	  max_bytes: 1073741824
+//	  max_objects: 1000
+//	team-a:
+//	  max_bytes: 10485760
+func NewInMemoryQuotaEnforcerFromFile(path string) (*InMemoryQuotaEnforcer, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read quota file")
+	}
+
+	quotas := map[string]Quota{}
+	err = yaml.Unmarshal(content, &quotas)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse quota file")
+	}
+
+	return NewInMemoryQuotaEnforcer(quotas), nil
+}
+
+// CheckQuota returns ErrQuotaExceeded if storing the additional bytes would exceed the quota of the namespace
+func (q *InMemoryQuotaEnforcer) CheckQuota(ctx context.Context, namespace string, additionalBytes int64) error {
+	usage := q.namespaceUsage(namespace)
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	return q.check(namespace, usage.usage, s3.Usage{Bytes: additionalBytes})
+}
+
+// ReserveUsage checks the usage change against the quota and records it in a single step, so the concurrent writes
+// can't exceed the quota together. Shrinking the usage is always allowed.
+func (q *InMemoryQuotaEnforcer) ReserveUsage(ctx context.Context, namespace string, change s3.Usage) (func(), error) {
+	usage := q.namespaceUsage(namespace)
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+
+	err := q.check(namespace, usage.usage, change)
+	if err != nil {
+		return nil, err
+	}
+
+	usage.add(change)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			usage.mu.Lock()
+			defer usage.mu.Unlock()
+			usage.add(s3.Usage{Objects: -change.Objects, Bytes: -change.Bytes})
+		})
+	}, nil
+}
+
+// check returns ErrQuotaExceeded if the usage change would grow the usage of the namespace over its quota
+func (q *InMemoryQuotaEnforcer) check(namespace string, usage, change s3.Usage) error {
+	quota, ok := q.quotas[namespace]
+	if !ok {
+		return nil
+	}
+
+	bytes, objects := usage.Bytes+change.Bytes, usage.Objects+change.Objects
+	if quota.MaxBytes > 0 && change.Bytes > 0 && bytes > quota.MaxBytes {
+		return errors.Wrap(ErrQuotaExceeded, fmt.Sprintf("namespace %s would use %d of %d bytes", namespace, bytes, quota.MaxBytes))
+	}
+
+	if quota.MaxObjects > 0 && change.Objects > 0 && objects > quota.MaxObjects {
+		return errors.Wrap(ErrQuotaExceeded, fmt.Sprintf("namespace %s already holds %d of %d objects", namespace, usage.Objects, quota.MaxObjects))
+	}
+
+	return nil
+}
+
+// RemoveUsage records the objects removed from the namespace
+func (q *InMemoryQuotaEnforcer) RemoveUsage(ctx context.Context, namespace string, removed s3.Usage) {
+	usage := q.namespaceUsage(namespace)
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	usage.add(s3.Usage{Objects: -removed.Objects, Bytes: -removed.Bytes})
+}
+
+// Usage returns the recorded usage of the namespace
+func (q *InMemoryQuotaEnforcer) Usage(namespace string) s3.Usage {
+	usage := q.namespaceUsage(namespace)
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	return usage.usage
+}
+
+// SetUsage replaces the recorded usage of all namespaces with the actual usage
+func (q *InMemoryQuotaEnforcer) SetUsage(ctx context.Context, actual map[string]s3.Usage) {
+	q.logger.Debug("Setting namespace usage", zap.Any("usage", actual))
+
+	// The namespaces emptied since the last count are reset as well
+	q.usage.Range(func(namespace, usage any) bool {
+		namespaceUsage := usage.(*namespaceUsage)
+		namespaceUsage.mu.Lock()
+		namespaceUsage.usage = actual[namespace.(string)]
+		namespaceUsage.mu.Unlock()
+		return true
+	})
+
+	for namespace, namespaceActual := range actual {
+		usage := q.namespaceUsage(namespace)
+		usage.mu.Lock()
+		usage.usage = namespaceActual
+		usage.mu.Unlock()
+	}
+}
+
+// add changes the usage, never dropping below zero. Must be called with the mutex held.
+func (u *namespaceUsage) add(change s3.Usage) {
+	u.usage.Objects = max(u.usage.Objects+change.Objects, 0)
+	u.usage.Bytes = max(u.usage.Bytes+change.Bytes, 0)
+}
+
+func (q *InMemoryQuotaEnforcer) namespaceUsage(namespace string) *namespaceUsage {
+	usage, _ := q.usage.LoadOrStore(namespace, &namespaceUsage{})
+	return usage.(*namespaceUsage)
+}
+
+// RecalculateQuotas re-counts the actual storage used in all instances every hour, correcting the usage drift
+// caused by the objects written outside the gateway. Blocks until the context is cancelled.
+func (s *ServiceV1) RecalculateQuotas(ctx context.Context) {
+	if s.quotaEnforcer == nil {
+		return
+	}
+
+	ticker := time.NewTicker(quotaRecalculationInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.recalculateQuotas(ctx)
+		if err != nil {
+			s.logger.Error("Failed to recalculate quotas", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reserveQuota reserves the quota of storing the object of the size, replacing the existing object if there is one.
+// Returns the function releasing the reservation if the object isn't stored.
+func (s *ServiceV1) reserveQuota(ctx context.Context, client s3.Client, objectId string, size int64) (func(), error) {
+	if s.quotaEnforcer == nil {
+		return func() {}, nil
+	}
+
+	change := s3.Usage{Objects: 1, Bytes: size}
+	existing, err := client.StatObject(ctx, objectId)
+	switch {
+	case err == nil:
+		// The overwrite changes only the size
+		change = s3.Usage{Bytes: size - existing.Size}
+	case !errors.Is(err, s3.ErrObjectNotFound):
+		return nil, err
+	}
+
+	return s.quotaEnforcer.ReserveUsage(ctx, s.quotaNamespace(objectId), change)
+}
+
+// quotaNamespace returns the namespace of the object: its key prefix up to the last slash, or the bucket for the
+// objects stored without a prefix
+func (s *ServiceV1) quotaNamespace(objectId string) string {
+	if i := strings.LastIndex(objectId, "/"); i >= 0 {
+		return objectId[:i]
+	}
+
+	return s.s3Options.Bucket
+}
+
+// recountQuotas recounts the usage after the objects of unknown sizes were deleted, e.g. in bulk
+func (s *ServiceV1) recountQuotas(ctx context.Context) {
+	if s.quotaEnforcer == nil {
+		return
+	}
+
+	err := s.recalculateQuotas(ctx)
+	if err != nil {
+		s.logger.Error("Failed to recalculate quotas", zap.Error(err))
+	}
+}
+
+// recalculateQuotas sets the usage of each namespace to the storage used in all instances. Each replicated object is
+// counted once.
+func (s *ServiceV1) recalculateQuotas(ctx context.Context) error {
+	s.logger.Info("Recalculating quotas")
+
+	// Discover available S3 instances
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return err
+	}
+
+	sizes, err := s.objectSizes(ctx, instances)
+	if err != nil {
+		return err
+	}
+
+	usage := map[string]s3.Usage{}
+	for objectId, size := range sizes {
+		namespace := s.quotaNamespace(objectId)
+		namespaceUsage := usage[namespace]
+		namespaceUsage.Objects++
+		namespaceUsage.Bytes += size
+		usage[namespace] = namespaceUsage
+	}
+
+	s.quotaEnforcer.SetUsage(ctx, usage)
+	return nil
+}
+
+// objectSizes returns the size of each object once, however many replicas hold it. The size of an object is taken
+// from its shard instance, if the shard instance holds it.
+func (s *ServiceV1) objectSizes(ctx context.Context, instances []discovery.S3Instance) (map[string]int64, error) {
+	sizes := map[string]int64{}
+	for _, instance := range instances {
+		// Minio client must be dynamically created, based on the S3 instance
+		client, err := s.clientFactory(instance)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+		}
+
+		summaries, err := client.GetObjectsSummary(ctx, s3.ListFilter{})
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to get usage for instance: %d", instance.InstanceNum))
+		}
+
+		for _, summary := range summaries {
+			if _, counted := sizes[summary.ObjectId]; counted {
+				primary, err := s.shardStrategy.Shard(summary.ObjectId, instances)
+				if err != nil || primary.InstanceNum != instance.InstanceNum {
+					continue
+				}
+			}
+
+			sizes[summary.ObjectId] = summary.Size
+		}
+	}
+
+	return sizes, nil
+}
@@ -0,0 +1,253 @@
+package gateway
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNamespace is the default bucket, the quota namespace of the test services
+const testNamespace = s3.BucketName
+
+func TestInMemoryQuotaEnforcer_ConcurrentReservations(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{testNamespace: {MaxObjects: 10, MaxBytes: 1000}})
+
+	var reserved atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := enforcer.ReserveUsage(context.Background(), testNamespace, s3.Usage{Objects: 1, Bytes: 10})
+			if err == nil {
+				reserved.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, ErrQuotaExceeded)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 10, reserved.Load())
+	assert.Equal(t, s3.Usage{Objects: 10, Bytes: 100}, enforcer.Usage(testNamespace))
+}
+
+func TestInMemoryQuotaEnforcer_ReserveUsage(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{testNamespace: {MaxObjects: 2, MaxBytes: 100}})
+	ctx := context.Background()
+
+	release, err := enforcer.ReserveUsage(ctx, testNamespace, s3.Usage{Objects: 1, Bytes: 60})
+	require.NoError(t, err)
+
+	_, err = enforcer.ReserveUsage(ctx, testNamespace, s3.Usage{Objects: 1, Bytes: 60})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// The released reservation frees the quota, releasing it again is a no-op
+	release()
+	release()
+	assert.Equal(t, s3.Usage{}, enforcer.Usage(testNamespace))
+
+	_, err = enforcer.ReserveUsage(ctx, testNamespace, s3.Usage{Objects: 1, Bytes: 60})
+	require.NoError(t, err)
+
+	// Shrinking is allowed even over the quota
+	enforcer.SetUsage(ctx, map[string]s3.Usage{testNamespace: {Objects: 5, Bytes: 500}})
+	_, err = enforcer.ReserveUsage(ctx, testNamespace, s3.Usage{Bytes: -10})
+	require.NoError(t, err)
+
+	enforcer.RemoveUsage(ctx, testNamespace, s3.Usage{Objects: 1, Bytes: 90})
+	assert.Equal(t, s3.Usage{Objects: 4, Bytes: 400}, enforcer.Usage(testNamespace))
+}
+
+func TestInMemoryQuotaEnforcer_CheckQuota(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{testNamespace: {MaxBytes: 100}})
+	ctx := context.Background()
+	enforcer.SetUsage(ctx, map[string]s3.Usage{testNamespace: {Objects: 1, Bytes: 60}})
+
+	require.NoError(t, enforcer.CheckQuota(ctx, testNamespace, 40))
+	assert.ErrorIs(t, enforcer.CheckQuota(ctx, testNamespace, 41), ErrQuotaExceeded)
+	require.NoError(t, enforcer.CheckQuota(ctx, "unlimited", 1000))
+
+	// The check records nothing
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 60}, enforcer.Usage(testNamespace))
+}
+
+func TestInMemoryQuotaEnforcer_SetUsage(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{})
+	ctx := context.Background()
+	enforcer.SetUsage(ctx, map[string]s3.Usage{"team-a": {Objects: 1, Bytes: 10}, "team-b": {Objects: 2, Bytes: 20}})
+
+	// The namespaces missing from the count hold nothing anymore
+	enforcer.SetUsage(ctx, map[string]s3.Usage{"team-b": {Objects: 1, Bytes: 5}})
+	assert.Equal(t, s3.Usage{}, enforcer.Usage("team-a"))
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 5}, enforcer.Usage("team-b"))
+}
+
+func TestAddOrUpdateObject_Quota(t *testing.T) {
+	ctx := context.Background()
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{testNamespace: {MaxObjects: 2, MaxBytes: 20}})
+	service, _, clients := newTestService(t, 1, WithQuotaEnforcer(enforcer))
+
+	_, err := service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("12345678")), UploadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 8}, enforcer.Usage(testNamespace))
+
+	// The overwrite counts only the size difference, not another object
+	_, err = service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("1234567890123")), UploadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 13}, enforcer.Usage(testNamespace))
+
+	_, err = service.AddOrUpdateObject(ctx, "other", newTestFile([]byte("12345678")), UploadOptions{})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// The failed upload releases its reservation
+	clients[1].FailMethod("AddOrUpdateObject", errors.New("instance failure"))
+	_, err = service.AddOrUpdateObject(ctx, "other", newTestFile([]byte("1234567")), UploadOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 13}, enforcer.Usage(testNamespace))
+
+	// The deleted object frees the quota
+	require.NoError(t, service.DeleteObject(ctx, "object"))
+	assert.Equal(t, s3.Usage{}, enforcer.Usage(testNamespace))
+}
+
+func TestAddOrUpdateObject_NamespaceQuota(t *testing.T) {
+	ctx := context.Background()
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{"team-a": {MaxBytes: 10}, "team-b": {MaxBytes: 10}})
+	service, _, _ := newTestService(t, 1, WithQuotaEnforcer(enforcer))
+	teamA, teamB := WithNamespace(service, "team-a/"), WithNamespace(service, "team-b/")
+
+	_, err := teamA.AddOrUpdateObject(ctx, "object", newTestFile([]byte("12345678")), UploadOptions{})
+	require.NoError(t, err)
+
+	// The usage of one namespace doesn't count against the quota of another
+	_, err = teamB.AddOrUpdateObject(ctx, "object", newTestFile([]byte("12345678")), UploadOptions{})
+	require.NoError(t, err)
+	_, err = teamA.AddOrUpdateObject(ctx, "other", newTestFile([]byte("123")), UploadOptions{})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 8}, enforcer.Usage("team-a"))
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 8}, enforcer.Usage("team-b"))
+	assert.Equal(t, s3.Usage{}, enforcer.Usage(testNamespace))
+
+	require.NoError(t, teamA.DeleteObject(ctx, "object"))
+	assert.Equal(t, s3.Usage{}, enforcer.Usage("team-a"))
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 8}, enforcer.Usage("team-b"))
+}
+
+func TestDeleteObjectsByPrefix_RecountsQuota(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{})
+	service, _, clients := newTestService(t, 2, WithQuotaEnforcer(enforcer))
+	clients[1].Put("logs/a", []byte("aaa"))
+	clients[2].Put("logs/b", []byte("bb"))
+	clients[2].Put("keep", []byte("k"))
+	enforcer.SetUsage(context.Background(), map[string]s3.Usage{testNamespace: {Objects: 3, Bytes: 6}})
+
+	deleted, err := service.DeleteObjectsByPrefix(context.Background(), "logs/")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 1}, enforcer.Usage(testNamespace))
+}
+
+func TestRecalculateQuotas(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{testNamespace: {MaxObjects: 3}})
+	service, _, clients := newTestService(t, 2, WithQuotaEnforcer(enforcer))
+
+	// The objects written outside the gateway are not recorded
+	clients[1].Put("a", []byte("aaa"))
+	clients[2].Put("b", []byte("bb"))
+	clients[2].Put("c", []byte("c"))
+	enforcer.SetUsage(context.Background(), map[string]s3.Usage{testNamespace: {Objects: 1, Bytes: 1}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.RecalculateQuotas(ctx)
+	}()
+
+	// The usage is recounted right away, not only after the first interval
+	require.Eventually(t, func() bool {
+		return enforcer.Usage(testNamespace) == s3.Usage{Objects: 3, Bytes: 6}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err := service.AddOrUpdateObject(context.Background(), "d", newTestFile([]byte("d")), UploadOptions{})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	cancel()
+	<-stopped
+}
+
+func TestRecalculateQuotas_Namespaces(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{})
+	service, _, clients := newTestService(t, 2, WithQuotaEnforcer(enforcer))
+	clients[1].Put("team-a/x", []byte("aaa"))
+	clients[2].Put("team-a/y", []byte("a"))
+	clients[2].Put("team-b/x", []byte("bb"))
+	clients[1].Put("z", []byte("z"))
+
+	require.NoError(t, service.recalculateQuotas(context.Background()))
+	assert.Equal(t, s3.Usage{Objects: 2, Bytes: 4}, enforcer.Usage("team-a"))
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 2}, enforcer.Usage("team-b"))
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 1}, enforcer.Usage(testNamespace))
+}
+
+func TestRecalculateQuotas_InstanceFailure(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{})
+	service, _, clients := newTestService(t, 2, WithQuotaEnforcer(enforcer))
+	clients[1].Put("a", []byte("aaa"))
+	clients[2].Fail(errors.New("connection refused"))
+	enforcer.SetUsage(context.Background(), map[string]s3.Usage{testNamespace: {Objects: 5, Bytes: 50}})
+
+	// The partial count doesn't replace the recorded usage
+	assert.ErrorContains(t, service.recalculateQuotas(context.Background()), "instance: 2")
+	assert.Equal(t, s3.Usage{Objects: 5, Bytes: 50}, enforcer.Usage(testNamespace))
+}
+
+func TestRecalculateQuotas_Replicated(t *testing.T) {
+	enforcer := NewInMemoryQuotaEnforcer(map[string]Quota{testNamespace: {MaxObjects: 2}})
+	service, _, _ := newTestService(t, 3, WithQuotaEnforcer(enforcer), WithReplicationFactor(2))
+
+	_, err := service.AddOrUpdateObject(context.Background(), "a", newTestFile([]byte("aaa")), UploadOptions{})
+	require.NoError(t, err)
+
+	// Each object is counted once, not on each of its replicas
+	require.NoError(t, service.recalculateQuotas(context.Background()))
+	assert.Equal(t, s3.Usage{Objects: 1, Bytes: 3}, enforcer.Usage(testNamespace))
+
+	_, err = service.AddOrUpdateObject(context.Background(), "b", newTestFile([]byte("bb")), UploadOptions{})
+	assert.NoError(t, err)
+}
+
+func TestRecalculateQuotas_WithoutEnforcer(t *testing.T) {
+	service, _, clients := newTestService(t, 1)
+
+	// Returns right away instead of recounting every hour
+	service.RecalculateQuotas(context.Background())
+	assert.Zero(t, clients[1].CallCount("GetObjectsSummary"))
+}
+
+func TestNewInMemoryQuotaEnforcerFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotas.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("spacelift-storage:\n  max_bytes: 1024\n  max_objects: 10\n"), 0o600))
+
+	enforcer, err := NewInMemoryQuotaEnforcerFromFile(path)
+	require.NoError(t, err)
+
+	_, err = enforcer.ReserveUsage(context.Background(), "spacelift-storage", s3.Usage{Objects: 1, Bytes: 2048})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = enforcer.ReserveUsage(context.Background(), "other", s3.Usage{Objects: 1, Bytes: 2048})
+	assert.NoError(t, err)
+
+	_, err = NewInMemoryQuotaEnforcerFromFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read quota file")
+}
@@ -0,0 +1,249 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// rebalanceSampleSize is the number of objects per instance sampled to estimate the misplaced objects
+const rebalanceSampleSize = 100
+
+// InstanceSetChanged describes a change of the instance set
+type InstanceSetChanged struct {
+	// Added and Removed are the numbers of the instances that joined and left the set
+	Added   []int
+	Removed []int
+	// Instances is the new instance set
+	Instances []discovery.S3Instance
+}
+
+// InstanceSetHook is called when the instance membership changes
+type InstanceSetHook func(ctx context.Context, event InstanceSetChanged)
+
+// WithInstanceSetHook registers a hook called when the instance membership changes. Requires WatchInstances.
+func WithInstanceSetHook(hook InstanceSetHook) Option {
+	return func(s *ServiceV1) {
+		if hook != nil {
+			s.instanceSetHooks = append(s.instanceSetHooks, hook)
+		}
+	}
+}
+
+// WithAutoRebalance moves the misplaced objects to their shard instances after the instance membership changes.
+// The rebalance starts after the delay with up to a half of it added as jitter, so rolling restarts don't trigger
+// a rebalance for every instance. Zero disables the rebalancing.
+func WithAutoRebalance(delay time.Duration) Option {
+	return func(s *ServiceV1) {
+		s.rebalanceDelay = delay
+	}
+}
+
+// instanceSetDiff compares the instance numbers of the previous and the current instance set
+func instanceSetDiff(previous, current []discovery.S3Instance) InstanceSetChanged {
+	previousNums := map[int]bool{}
+	for _, instance := range previous {
+		previousNums[instance.InstanceNum] = true
+	}
+
+	currentNums := map[int]bool{}
+	event := InstanceSetChanged{Instances: current}
+	for _, instance := range current {
+		currentNums[instance.InstanceNum] = true
+		if !previousNums[instance.InstanceNum] {
+			event.Added = append(event.Added, instance.InstanceNum)
+		}
+	}
+
+	for _, instance := range previous {
+		if !currentNums[instance.InstanceNum] {
+			event.Removed = append(event.Removed, instance.InstanceNum)
+		}
+	}
+
+	sort.Ints(event.Added)
+	sort.Ints(event.Removed)
+	return event
+}
+
+// onInstanceSetChanged notifies the hooks and schedules the estimate of the misplaced objects and the rebalance.
+// A rebalance scheduled or running for a previous change is cancelled.
+func (s *ServiceV1) onInstanceSetChanged(ctx context.Context, event InstanceSetChanged) {
+	s.logger.Info("Instance membership changed", zap.Ints("added", event.Added), zap.Ints("removed", event.Removed))
+
+	if s.breakers != nil {
+		s.breakers.prune(event.Removed)
+	}
+
+	for _, hook := range s.instanceSetHooks {
+		hook(ctx, event)
+	}
+
+	if s.cancelRebalance != nil {
+		s.cancelRebalance()
+	}
+
+	rebalanceCtx, cancel := context.WithCancel(ctx)
+	s.cancelRebalance = cancel
+
+	go func() {
+		misplaced, err := s.estimateMisplacedObjects(rebalanceCtx, event.Instances)
+		if err != nil {
+			if rebalanceCtx.Err() == nil {
+				s.logger.Warn("Failed to estimate the misplaced objects", zap.Error(err))
+			}
+		} else {
+			s.logger.Info("Estimated the misplaced objects", zap.Int("misplaced", misplaced))
+		}
+
+		if s.rebalanceDelay <= 0 {
+			return
+		}
+
+		delay := s.rebalanceDelay + time.Duration(rand.Int63n(int64(s.rebalanceDelay/2)+1))
+		s.logger.Info("Scheduled the rebalance", zap.Duration("delay", delay))
+		select {
+		case <-rebalanceCtx.Done():
+			s.logger.Info("Rebalance cancelled before starting")
+			return
+		case <-time.After(delay):
+		}
+
+		moved, err := s.Rebalance(rebalanceCtx)
+		switch {
+		case rebalanceCtx.Err() != nil:
+			s.logger.Info("Rebalance cancelled", zap.Int("moved", moved))
+		case err != nil:
+			s.logger.Error("Failed to rebalance", zap.Int("moved", moved), zap.Error(err))
+		default:
+			s.logger.Info("Rebalanced objects", zap.Int("moved", moved))
+		}
+	}()
+}
+
+// estimateMisplacedObjects samples the objects of each instance and extrapolates the number of objects that are not
+// stored on their shard instance
+func (s *ServiceV1) estimateMisplacedObjects(ctx context.Context, instances []discovery.S3Instance) (int, error) {
+	estimate := 0.0
+	for _, instance := range instances {
+		client, err := s.clientFactory(instance)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+		}
+
+		objectIds, err := client.GetObjects(ctx, s3.ListFilter{})
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("unable to list objectIds for instance: %d", instance.InstanceNum))
+		}
+
+		if len(objectIds) == 0 {
+			continue
+		}
+
+		rand.Shuffle(len(objectIds), func(i, j int) { objectIds[i], objectIds[j] = objectIds[j], objectIds[i] })
+		sample := objectIds[:min(rebalanceSampleSize, len(objectIds))]
+
+		misplaced := 0
+		for _, objectId := range sample {
+			if !s.isPlaced(objectId, instance.InstanceNum, instances) {
+				misplaced++
+			}
+		}
+
+		estimate += float64(misplaced) / float64(len(sample)) * float64(len(objectIds))
+	}
+
+	return int(estimate), nil
+}
+
+// isPlaced checks if the object belongs on the instance - its shard instance or one of its replicas
+func (s *ServiceV1) isPlaced(objectId string, instanceNum int, instances []discovery.S3Instance) bool {
+	replicas, err := s.replicaInstances(objectId, instances, max(s.replicationFactor, 1))
+	if err != nil {
+		return true
+	}
+
+	for _, replica := range replicas {
+		if replica.InstanceNum == instanceNum {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rebalance moves the objects that are not stored on their shard instance (or one of its replicas) to the shard
+// instance and returns the number of moved objects. Objects already present on the shard instance are left in place.
+// A single object is always moved completely - the cancellation is checked between the objects.
+func (s *ServiceV1) Rebalance(ctx context.Context) (int, error) {
+	s.logger.Info("Rebalancing objects")
+
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	clients := map[int]s3.Client{}
+	for _, instance := range instances {
+		// Minio client must be dynamically created, based on the S3 instance
+		client, err := s.clientFactory(instance)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+		}
+		clients[instance.InstanceNum] = client
+	}
+
+	moved := 0
+	for _, instance := range instances {
+		objectIds, err := clients[instance.InstanceNum].GetObjects(ctx, s3.ListFilter{})
+		if err != nil {
+			return moved, errors.Wrap(err, fmt.Sprintf("unable to list objectIds for instance: %d", instance.InstanceNum))
+		}
+
+		for _, objectId := range objectIds {
+			if ctx.Err() != nil {
+				return moved, ctx.Err()
+			}
+
+			if s.isPlaced(objectId, instance.InstanceNum, instances) {
+				continue
+			}
+
+			target, err := s.shardStrategy.Shard(objectId, instances)
+			if err != nil {
+				return moved, err
+			}
+
+			logger := s.logger.With(zap.String("objectId", objectId), zap.Int("sourceInstance", instance.InstanceNum), zap.Int("targetInstance", target.InstanceNum))
+
+			exists, err := clients[target.InstanceNum].ObjectExists(ctx, objectId)
+			if err != nil {
+				return moved, errors.Wrap(err, "failed to reach the target instance")
+			}
+
+			if exists {
+				logger.Warn("Misplaced object already exists on its shard instance, leaving it in place")
+				continue
+			}
+
+			m := &migration{source: clients[instance.InstanceNum], target: clients[target.InstanceNum], sourceNum: instance.InstanceNum}
+			err = s.moveObject(context.WithoutCancel(ctx), m, objectId)
+			s.audit(ctx, "rebalance", objectId, target.InstanceNum, err)
+			if err != nil {
+				return moved, err
+			}
+
+			logger.Debug("Moved misplaced object")
+			moved++
+		}
+	}
+
+	return moved, nil
+}
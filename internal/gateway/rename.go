@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// RenamedFromMetadata is the user metadata of a renamed object holding its previous ID. If the object with the
+// previous ID still exists next to it, the rename was interrupted.
+const RenamedFromMetadata = "Renamed-From"
+
+// RenameObject renames the object by copying it to the new ID and deleting the original. The copy is marked with the
+// RenamedFromMetadata. If the original can't be deleted, the copy is removed.
+func (s *ServiceV1) RenameObject(ctx context.Context, oldId, newId string) error {
+	logger := s.logger.With(zap.String("objectId", oldId), zap.String("newId", newId))
+	logger.Info("Renaming object")
+	defer s.readCache.Remove(oldId)
+	defer s.readCache.Remove(newId)
+
+	// The object may move to another instance with its new ID
+	sourceInstance, err := s.shardObjectToInstance(ctx, oldId)
+	if err != nil {
+		return errors.Wrap(err, "failed to assign object to instance")
+	}
+
+	targetInstance, err := s.shardObjectToInstance(ctx, newId)
+	if err != nil {
+		return errors.Wrap(err, "failed to assign object to instance")
+	}
+
+	// Minio clients must be dynamically created, based on the S3 instance
+	source, err := s.clientFactory(*sourceInstance)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.clientFactory(*targetInstance)
+	if err != nil {
+		return err
+	}
+
+	exists, err := source.ObjectExists(ctx, oldId)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return s3.ErrObjectNotFound
+	}
+
+	exists, err = target.ObjectExists(ctx, newId)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.Wrap(ErrObjectAlreadyExists, "an object with the new ID already exists")
+	}
+
+	metadata := map[string]string{RenamedFromMetadata: oldId}
+	if sourceInstance.InstanceNum == targetInstance.InstanceNum {
+		err = source.CopyObject(ctx, oldId, newId, metadata)
+	} else {
+		err = streamObject(ctx, source, target, oldId, newId, metadata)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to copy the object to the new ID")
+	}
+
+	err = source.DeleteObject(ctx, oldId)
+	if err != nil {
+		logger.Error("Failed to delete the renamed object, rolling back", zap.Error(err))
+		rollbackErr := target.DeleteObject(ctx, newId)
+		if rollbackErr != nil {
+			logger.Error("Failed to roll back the renamed object", zap.Error(rollbackErr))
+		}
+
+		s.audit(ctx, "rename", oldId, targetInstance.InstanceNum, err)
+		return errors.Wrap(err, "failed to delete the object with the old ID")
+	}
+
+	// The replicas follow the shard instances of the IDs
+	s.replicate(ctx, "rename", newId, func(client s3.Client) error {
+		return streamObject(ctx, target, client, newId, newId, metadata)
+	})
+	s.replicate(ctx, "rename", oldId, func(client s3.Client) error {
+		return client.DeleteObject(ctx, oldId)
+	})
+
+	s.audit(ctx, "rename", oldId, targetInstance.InstanceNum, nil)
+	logger.Info("Renamed object")
+	return nil
+}
+
+// streamObject streams the object from the source instance to the target instance under the target ID
+func streamObject(ctx context.Context, source, target s3.Client, sourceId, targetId string, metadata map[string]string) error {
+	object, err := source.GetObject(ctx, sourceId)
+	if err != nil {
+		return err
+	}
+
+	if closer, ok := object.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	_, err = target.AddOrUpdateObject(ctx, targetId, object, s3.PutOptions{Metadata: metadata})
+	return err
+}
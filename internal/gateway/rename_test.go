@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renameTarget finds an objectId sharded to the same or to another instance than the objectId
+func renameTarget(t *testing.T, service *ServiceV1, objectId string, sameInstance bool) (string, int, int) {
+	t.Helper()
+
+	source, err := service.shardObjectToInstance(context.Background(), objectId)
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		newId := fmt.Sprintf("renamed%d", i)
+		target, err := service.shardObjectToInstance(context.Background(), newId)
+		require.NoError(t, err)
+
+		if (source.InstanceNum == target.InstanceNum) == sameInstance {
+			return newId, source.InstanceNum, target.InstanceNum
+		}
+	}
+
+	t.Fatal("no object ID found on the instance")
+	return "", 0, 0
+}
+
+func TestRenameObject(t *testing.T) {
+	for _, sameInstance := range []bool{true, false} {
+		t.Run(fmt.Sprintf("same instance %t", sameInstance), func(t *testing.T) {
+			service, _, clients := newTestService(t, 3)
+			_, err := service.AddOrUpdateObject(context.Background(), "draft", newTestFile([]byte("data")), UploadOptions{})
+			require.NoError(t, err)
+
+			newId, source, target := renameTarget(t, service, "draft", sameInstance)
+			require.NoError(t, service.RenameObject(context.Background(), "draft", newId))
+
+			assert.Nil(t, clients[source].Object("draft"))
+			renamed := clients[target].Object(newId)
+			require.NotNil(t, renamed)
+			assert.Equal(t, []byte("data"), renamed.Data)
+			assert.Equal(t, "draft", renamed.Metadata[RenamedFromMetadata])
+		})
+	}
+}
+
+func TestRenameObject_RollbackOnDeleteFailure(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	_, err := service.AddOrUpdateObject(context.Background(), "draft", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	newId, source, target := renameTarget(t, service, "draft", false)
+	clients[source].FailMethod("DeleteObject", errors.New("connection reset"))
+
+	err = service.RenameObject(context.Background(), "draft", newId)
+	assert.ErrorContains(t, err, "connection reset")
+
+	// The copy is removed, the original is kept
+	assert.Nil(t, clients[target].Object(newId))
+	require.NotNil(t, clients[source].Object("draft"))
+	assert.Equal(t, []byte("data"), clients[source].Object("draft").Data)
+}
+
+func TestRenameObject_Conflict(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	for _, objectId := range []string{"draft", "final"} {
+		_, err := service.AddOrUpdateObject(context.Background(), objectId, newTestFile([]byte(objectId)), UploadOptions{})
+		require.NoError(t, err)
+	}
+
+	err := service.RenameObject(context.Background(), "draft", "final")
+	assert.ErrorIs(t, err, ErrObjectAlreadyExists)
+
+	// Both objects are left untouched
+	for _, objectId := range []string{"draft", "final"} {
+		instance, err := service.shardObjectToInstance(context.Background(), objectId)
+		require.NoError(t, err)
+		assert.Equal(t, []byte(objectId), clients[instance.InstanceNum].Object(objectId).Data)
+	}
+}
+
+func TestRenameObject_NotFound(t *testing.T) {
+	service, _, _ := newTestService(t, 3)
+
+	err := service.RenameObject(context.Background(), "missing", "renamed")
+	assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+}
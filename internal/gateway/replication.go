@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// ReconcileReplicas periodically copies the objects to the replica instances they are missing from or hold a stale copy.
+// An object is expected on its shard instance and on the replicationFactor-1 instances following it.
+// Blocks until the context is cancelled.
+func (s *ServiceV1) ReconcileReplicas(ctx context.Context, interval time.Duration) {
+	replicationFactor := s.replicationFactor
+	if replicationFactor <= 1 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		repaired, err := s.reconcileReplicas(ctx, replicationFactor)
+		if err != nil {
+			s.logger.Error("Failed to reconcile replicas", zap.Error(err))
+		}
+
+		s.logger.Info("Reconciled replicas", zap.Int("repaired", repaired))
+	}
+}
+
+// reconcileReplicas copies the objects from their shard instance to the replicas missing them or holding a stale copy,
+// and returns the number of repaired replicas
+func (s *ServiceV1) reconcileReplicas(ctx context.Context, replicationFactor int) (int, error) {
+	s.logger.Info("Reconciling replicas")
+
+	// Discover available S3 instances
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Find out which instances hold each object and which content they hold
+	clients := map[int]s3.Client{}
+	holders := map[string]map[int]s3.ObjectSummary{}
+	for _, instance := range instances {
+		// Minio client must be dynamically created, based on the S3 instance
+		client, err := s.clientFactory(instance)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+		}
+		clients[instance.InstanceNum] = client
+
+		summaries, err := client.GetObjectsSummary(ctx, s3.ListFilter{})
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("unable to list objectIds for instance: %d", instance.InstanceNum))
+		}
+
+		for _, summary := range summaries {
+			if holders[summary.ObjectId] == nil {
+				holders[summary.ObjectId] = map[int]s3.ObjectSummary{}
+			}
+			holders[summary.ObjectId][instance.InstanceNum] = summary
+		}
+	}
+
+	repaired := 0
+	for objectId, holdingInstances := range holders {
+		replicas, err := s.replicaInstances(objectId, instances, replicationFactor)
+		if err != nil {
+			s.logger.Warn("Unable to determine the replicas of the object", zap.String("objectId", objectId), zap.Error(err))
+			continue
+		}
+
+		// The shard instance is the only source of the copies. Without it, the object was deleted, pinned to another
+		// instance or is being moved, and copying a replica could resurrect a deleted object.
+		primary := replicas[0].InstanceNum
+		source, ok := holdingInstances[primary]
+		if !ok {
+			continue
+		}
+
+		for _, replica := range replicas[1:] {
+			held, ok := holdingInstances[replica.InstanceNum]
+			if ok && held.ETag == source.ETag {
+				continue
+			}
+
+			err = copyObject(ctx, clients[primary], clients[replica.InstanceNum], objectId, source.Size)
+			if err != nil {
+				return repaired, errors.Wrap(err, fmt.Sprintf("unable to copy object %s to instance: %d", objectId, replica.InstanceNum))
+			}
+
+			s.logger.Info("Repaired replica", zap.String("objectId", objectId), zap.Int("instance", replica.InstanceNum), zap.Bool("stale", ok))
+			repaired++
+		}
+	}
+
+	return repaired, nil
+}
+
+// replicate applies the write to the replicas of the object, besides its shard instance. The failed writes are only
+// logged, the replicas are repaired by the reconciliation sweep.
+func (s *ServiceV1) replicate(ctx context.Context, operation, objectId string, write func(client s3.Client) error) {
+	if s.replicationFactor <= 1 {
+		return
+	}
+
+	// Discover available S3 instances
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to replicate the object", zap.String("operation", operation), zap.String("objectId", objectId), zap.Error(err))
+		return
+	}
+
+	replicas, err := s.replicaInstances(objectId, instances, s.replicationFactor)
+	if err != nil {
+		s.logger.Warn("Failed to replicate the object", zap.String("operation", operation), zap.String("objectId", objectId), zap.Error(err))
+		return
+	}
+
+	for _, replica := range replicas[1:] {
+		// Minio client must be dynamically created, based on the S3 instance
+		client, err := s.clientFactory(replica)
+		if err == nil {
+			err = write(client)
+		}
+
+		if err != nil {
+			s.logger.Warn("Failed to replicate the object",
+				zap.String("operation", operation),
+				zap.String("objectId", objectId),
+				zap.Int("instance", replica.InstanceNum),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// replicaInstances returns the shard instance of the object, followed by the next replicationFactor-1 instances
+// ordered by their number
+func (s *ServiceV1) replicaInstances(objectId string, instances []discovery.S3Instance, replicationFactor int) ([]discovery.S3Instance, error) {
+	primary, err := s.shardStrategy.Shard(objectId, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]discovery.S3Instance, len(instances))
+	copy(sorted, instances)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].InstanceNum < sorted[j].InstanceNum })
+
+	start := 0
+	for i, instance := range sorted {
+		if instance.InstanceNum == primary.InstanceNum {
+			start = i
+			break
+		}
+	}
+
+	replicas := []discovery.S3Instance{}
+	for i := 0; i < min(replicationFactor, len(sorted)); i++ {
+		replicas = append(replicas, sorted[(start+i)%len(sorted)])
+	}
+
+	return replicas, nil
+}
+
+// copyObject streams the object of the size from the source instance to the target instance
+func copyObject(ctx context.Context, source, target s3.Client, objectId string, size int64) error {
+	object, err := source.GetObject(ctx, objectId)
+	if err != nil {
+		return err
+	}
+
+	if closer, ok := object.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	_, err = target.AddOrUpdateObject(ctx, objectId, object, s3.PutOptions{Size: size})
+	return err
+}
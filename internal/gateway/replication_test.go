@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replicaHolders returns the numbers of the instances holding the object
+func replicaHolders(clients map[int]*s3test.Client, objectId string) []int {
+	holders := []int{}
+	for instanceNum := 1; instanceNum <= len(clients); instanceNum++ {
+		if clients[instanceNum].Object(objectId) != nil {
+			holders = append(holders, instanceNum)
+		}
+	}
+	return holders
+}
+
+func TestReconcileReplicas(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithReplicationFactor(2))
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	replicas, err := service.replicaInstances("object", discoverytest.Instances(3), 2)
+	require.NoError(t, err)
+	require.Len(t, replicas, 2)
+
+	// The replica instance was down during the write
+	missing := replicas[1].InstanceNum
+	require.NoError(t, clients[missing].DeleteObject(context.Background(), "object"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.ReconcileReplicas(ctx, 10*time.Millisecond)
+	}()
+
+	require.Eventually(t, func() bool {
+		return clients[missing].Object("object") != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The sweep stops on the cancellation
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reconciliation was not cancelled")
+	}
+
+	assert.Equal(t, []byte("data"), clients[missing].Object("object").Data)
+	assert.ElementsMatch(t, []int{replicas[0].InstanceNum, replicas[1].InstanceNum}, replicaHolders(clients, "object"))
+}
+
+func TestReconcileReplicas_RepairedCount(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithReplicationFactor(3))
+
+	// Each object is held by its shard instance only, missing from the other two
+	for i := 1; i <= 3; i++ {
+		objectId := fmt.Sprintf("object%d", i)
+		primary, err := service.shardStrategy.Shard(objectId, discoverytest.Instances(3))
+		require.NoError(t, err)
+		clients[primary.InstanceNum].Put(objectId, []byte("data"))
+	}
+
+	repaired, err := service.reconcileReplicas(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, 6, repaired)
+
+	// The consistent replicas are not copied again
+	repaired, err = service.reconcileReplicas(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Zero(t, repaired)
+}
+
+func TestReconcileReplicas_InstanceFailure(t *testing.T) {
+	service, _, clients := newTestService(t, 2, WithReplicationFactor(2))
+	clients[2].Fail(errors.New("connection refused"))
+
+	_, err := service.reconcileReplicas(context.Background(), 2)
+	assert.ErrorContains(t, err, "instance: 2")
+}
+
+func TestReconcileReplicas_WithoutReplication(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+
+	// Returns right away instead of reconciling every interval
+	service.ReconcileReplicas(context.Background(), time.Millisecond)
+	assert.Zero(t, clients[1].CallCount("GetObjects"))
+}
+
+func TestReconcileReplicas_StaleReplica(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithReplicationFactor(2))
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	replicas, err := service.replicaInstances("object", discoverytest.Instances(3), 2)
+	require.NoError(t, err)
+
+	// The replica missed an overwrite
+	clients[replicas[1].InstanceNum].Put("object", []byte("stale"))
+
+	repaired, err := service.reconcileReplicas(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+	assert.Equal(t, []byte("data"), clients[replicas[1].InstanceNum].Object("object").Data)
+}
+
+func TestReconcileReplicas_DeletedObject(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithReplicationFactor(2))
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	replicas, err := service.replicaInstances("object", discoverytest.Instances(3), 2)
+	require.NoError(t, err)
+	require.NotNil(t, clients[replicas[1].InstanceNum].Object("object"))
+
+	require.NoError(t, service.DeleteObject(context.Background(), "object"))
+	assert.Empty(t, replicaHolders(clients, "object"))
+
+	_, err = service.reconcileReplicas(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, replicaHolders(clients, "object"))
+}
+
+func TestReconcileReplicas_MissedDelete(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithReplicationFactor(2))
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	replicas, err := service.replicaInstances("object", discoverytest.Instances(3), 2)
+	require.NoError(t, err)
+
+	// The replica was down during the delete
+	clients[replicas[1].InstanceNum].FailMethod("DeleteObject", errors.New("connection refused"))
+	require.NoError(t, service.DeleteObject(context.Background(), "object"))
+	clients[replicas[1].InstanceNum].FailMethod("DeleteObject", nil)
+
+	// The leftover replica is not copied back to the shard instance
+	repaired, err := service.reconcileReplicas(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Zero(t, repaired)
+	assert.Nil(t, clients[replicas[0].InstanceNum].Object("object"))
+}
+
+func TestReplication_Overwrite(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithReplicationFactor(2))
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	_, err = service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("updated")), UploadOptions{})
+	require.NoError(t, err)
+
+	replicas, err := service.replicaInstances("object", discoverytest.Instances(3), 2)
+	require.NoError(t, err)
+	for _, replica := range replicas {
+		assert.Equal(t, []byte("updated"), clients[replica.InstanceNum].Object("object").Data)
+	}
+
+	// The replicas are consistent, nothing is repaired
+	repaired, err := service.reconcileReplicas(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Zero(t, repaired)
+}
+
+func TestReplication_Rename(t *testing.T) {
+	service, _, clients := newTestService(t, 3, WithReplicationFactor(2))
+	_, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile([]byte("data")), UploadOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, service.RenameObject(context.Background(), "object", "renamed"))
+
+	replicas, err := service.replicaInstances("renamed", discoverytest.Instances(3), 2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{replicas[0].InstanceNum, replicas[1].InstanceNum}, replicaHolders(clients, "renamed"))
+	assert.Empty(t, replicaHolders(clients, "object"))
+
+	_, err = service.reconcileReplicas(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, replicaHolders(clients, "object"))
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// Bounds the self-test of a single instance
+const selfTestTimeout = 10 * time.Second
+
+// SelfTestResult is the outcome of the self-test of an instance. Err is nil if the instance passed.
+type SelfTestResult struct {
+	Instance discovery.S3Instance
+	Err      error
+}
+
+// SelfTest writes a canary object to each instance, bypassing the sharding, reads it back, verifies its content and
+// deletes it. Catches the credential and network misconfiguration before serving traffic.
+func (s *ServiceV1) SelfTest(ctx context.Context) ([]SelfTestResult, error) {
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(instances) == 0 {
+		return nil, ErrNoInstancesAvailable
+	}
+
+	results := make([]SelfTestResult, 0, len(instances))
+	for _, instance := range instances {
+		results = append(results, SelfTestResult{Instance: instance, Err: s.selfTestInstance(ctx, instance)})
+	}
+
+	return results, nil
+}
+
+func (s *ServiceV1) selfTestInstance(ctx context.Context, instance discovery.S3Instance) error {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	client, err := s.clientFactory(instance)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the client")
+	}
+
+	objectId := fmt.Sprintf("selftest_%d", time.Now().UnixNano())
+	content := []byte("canary " + objectId)
+
+	_, err = client.AddOrUpdateObject(ctx, objectId, bytes.NewReader(content), s3.PutOptions{Size: int64(len(content))})
+	if err != nil {
+		return errors.Wrap(err, "failed to write the canary object")
+	}
+
+	// Remove the canary even if reading it back fails
+	defer func() {
+		err := client.DeleteObject(context.WithoutCancel(ctx), objectId)
+		if err != nil {
+			s.logger.Warn("Failed to delete the canary object", zap.Int("instance", instance.InstanceNum), zap.String("objectId", objectId), zap.Error(err))
+		}
+	}()
+
+	object, err := client.GetObject(ctx, objectId)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the canary object")
+	}
+
+	if closer, ok := object.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	read, err := io.ReadAll(object)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the canary object")
+	}
+
+	if !bytes.Equal(read, content) {
+		return errors.Wrap(ErrChecksumMismatch, "the canary object was read back with a different content")
+	}
+
+	return nil
+}
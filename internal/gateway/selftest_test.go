@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptingClient reads back a different content than written
+type corruptingClient struct {
+	*s3test.Client
+}
+
+func (c corruptingClient) GetObject(context.Context, string) (io.Reader, error) {
+	return bytes.NewReader([]byte("corrupted")), nil
+}
+
+func TestSelfTest(t *testing.T) {
+	service, _, clients := newTestService(t, 3)
+	clients[2].FailMethod("AddOrUpdateObject", s3.ErrAccessDenied)
+	clients[3].FailMethod("GetObject", errors.New("connection reset"))
+
+	results, err := service.SelfTest(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, 1, results[0].Instance.InstanceNum)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, s3.ErrAccessDenied)
+	assert.ErrorContains(t, results[2].Err, "failed to read the canary object")
+
+	// The canary objects are removed, even if reading them back failed
+	for _, client := range clients {
+		assert.Empty(t, client.Keys())
+	}
+	assert.Equal(t, 1, clients[3].CallCount("DeleteObject"))
+}
+
+func TestSelfTest_BypassesSharding(t *testing.T) {
+	service, _, clients := newTestService(t, 5)
+
+	results, err := service.SelfTest(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	for _, client := range clients {
+		assert.Equal(t, 1, client.CallCount("AddOrUpdateObject"))
+	}
+}
+
+func TestSelfTest_ContentMismatch(t *testing.T) {
+	client := corruptingClient{Client: s3test.NewClient()}
+	service := NewServiceV1WithOptions(discoverytest.NewService(discoverytest.Instances(1)...), s3.Options{},
+		WithClientFactory(func(discovery.S3Instance) (s3.Client, error) { return client, nil }))
+	t.Cleanup(func() { _ = service.Close() })
+
+	results, err := service.SelfTest(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Err, ErrChecksumMismatch)
+	assert.Empty(t, client.Keys())
+}
+
+func TestSelfTest_NoInstances(t *testing.T) {
+	service, _, _ := newTestService(t, 0)
+
+	_, err := service.SelfTest(context.Background())
+	assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+}
@@ -1,12 +1,14 @@
 package gateway
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"hash/fnv"
 	"io"
 	"mime/multipart"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spacelift-io/homework-object-storage/internal/discovery"
@@ -14,49 +16,289 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrObjectTooLarge is returned when the uploaded object exceeds the size limit
+var ErrObjectTooLarge = errors.New("object too large")
+
+// ErrFeatureUnsupported is returned when the Minio version of the instance doesn't support the feature
+var ErrFeatureUnsupported = errors.New("feature not supported by the S3 instance")
+
+// ErrListTruncated is returned along with the listed objects when more objects match than the listing limit
+var ErrListTruncated = errors.New("object listing truncated")
+
 // Service is the interface that provides the methods to interact with the S3 instances
 type Service interface {
-	AddOrUpdateObject(ctx context.Context, objectId string, file multipart.File) error
+	AddOrUpdateObject(ctx context.Context, objectId string, file multipart.File, options UploadOptions) (*UploadResult, error)
 	GetObject(ctx context.Context, objectId string) (io.Reader, error)
-	GetObjects(ctx context.Context) ([]string, error)
-	GetObjectsAsync(ctx context.Context) ([]string, error)
+	GetObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (io.Reader, error)
+	StatObject(ctx context.Context, objectId string) (*s3.ObjectInfo, error)
+	StatObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (*s3.ObjectInfo, error)
+	DeleteObject(ctx context.Context, objectId string) error
+	GetObjectVersion(ctx context.Context, objectId, versionId string) (io.Reader, error)
+	StatObjectVersion(ctx context.Context, objectId, versionId string) (*s3.ObjectInfo, error)
+	DeleteObjectVersion(ctx context.Context, objectId, versionId string) error
+	ListObjectVersions(ctx context.Context, objectId string) ([]s3.ObjectVersion, error)
+	RenameObject(ctx context.Context, oldId, newId string) error
+	DeleteObjectsByPrefix(ctx context.Context, prefix string) (int, error)
+	CountObjectsByPrefix(ctx context.Context, prefix string) (int, error)
+	GetObjects(ctx context.Context, filter s3.ListFilter) ([]string, error)
+	GetObjectsAsync(ctx context.Context, filter s3.ListFilter) ([]string, error)
+	GetObjectsWithMetadata(ctx context.Context, filter s3.ListFilter) ([]s3.ObjectSummary, error)
+	StreamObjects(ctx context.Context) (<-chan string, <-chan error)
+	CountObjects(ctx context.Context) (*ObjectCount, error)
+	WatchObject(ctx context.Context, objectId string) (<-chan s3.ObjectEvent, <-chan error)
+	MigrateObject(ctx context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error)
+	CheckMigration(ctx context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error)
+	ExportObject(ctx context.Context, objectId string, target ExportTarget) (string, error)
+	GetExportJob(ctx context.Context, objectId, jobId string) (*ExportJob, error)
+	Instances(ctx context.Context) ([]discovery.S3Instance, error)
 	Ready(ctx context.Context) bool
+	Close() error
 	shardObjectToInstance(ctx context.Context, objectId string) (*discovery.S3Instance, error)
 }
 
+// UploadOptions configure a single upload
+type UploadOptions struct {
+	// ExpiresIn deletes the object after the given duration. Zero means the object does not expire.
+	ExpiresIn time.Duration
+	// PinnedInstance stores the object on the instance with the given number, bypassing the sharding. Optional.
+	PinnedInstance *int
+}
+
+// UploadResult describes the stored object
+type UploadResult struct {
+	InstanceNum int
+	ETag        string
+	// Size is the number of bytes read from the uploaded file and stored
+	Size int64
+}
+
 // ServiceV1 is the implementation of the Service interface
 type ServiceV1 struct {
-	discoveryService discovery.Service
-	logger           *zap.Logger
+	discoveryService   discovery.Service
+	s3Options          s3.Options
+	quotaEnforcer      QuotaEnforcer
+	shardStrategy      ShardStrategy
+	replicationFactor  int
+	workerCount        int
+	maxObjectSize      int64
+	maxBulkDeleteCount int
+	maxListedObjects   int
+	clientFactory      ClientFactory
+	auditLogger        AuditLogger
+	clients            *s3.ClientPool
+	clientPoolMaxSize  int
+	clientIdleTimeout  time.Duration
+	breakers           *circuitBreakers
+	exportJobs         exportJobs
+	exportTargets      map[string]struct{}
+	readCache          *ReadCache
+	readCacheTTL       time.Duration
+	countCache         objectCountCache
+	writeLocking       WriteLocking
+	writeLocks         keyedMutex
+	logger             *zap.Logger
+
+	// Clients created since the last sample and in the last sampled second, run by MonitorClientCreations
+	clientCreations        atomic.Int64
+	clientCreationRate     atomic.Int64
+	clientCreationRateWarn int64
+
+	// Hooks and the rebalance triggered by the instance membership changes, run by WatchInstances
+	instanceSetHooks []InstanceSetHook
+	rebalanceDelay   time.Duration
+	cancelRebalance  context.CancelFunc
 }
 
-// NewServiceV1 creates a new instance of the ServiceV1
-func NewServiceV1(discoveryService discovery.Service) *ServiceV1 {
-	return &ServiceV1{
-		logger:           zap.L().Named("gateway"),
-		discoveryService: discoveryService,
+// NewServiceV1 creates a new instance of the ServiceV1. The quotaEnforcer is optional, the shardStrategy defaults to the ModuloShardStrategy.
+func NewServiceV1(discoveryService discovery.Service, s3Options s3.Options, quotaEnforcer QuotaEnforcer, shardStrategy ShardStrategy) *ServiceV1 {
+	return NewServiceV1WithOptions(discoveryService, s3Options, WithQuotaEnforcer(quotaEnforcer), WithShardStrategy(shardStrategy))
+}
+
+// NewServiceV1WithOptions creates a new instance of the ServiceV1 configured with the options.
+// By default, objects are not replicated, sharded with the ModuloShardStrategy and stored using pooled Minio clients.
+func NewServiceV1WithOptions(discoveryService discovery.Service, s3Options s3.Options, opts ...Option) *ServiceV1 {
+	if s3Options.Bucket == "" {
+		s3Options.Bucket = s3.BucketName
+	}
+
+	s := &ServiceV1{
+		logger:            zap.L().Named("gateway"),
+		discoveryService:  discoveryService,
+		s3Options:         s3Options,
+		shardStrategy:     ModuloShardStrategy{},
+		replicationFactor: 1,
+		auditLogger:       nopAuditLogger{},
+	}
+	s.clientFactory = func(instance discovery.S3Instance) (s3.Client, error) {
+		return s3.NewMinioClient(instance, s3Options)
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Count the clients actually created, so a bypassed pool shows up in the creation rate
+	if s.clients == nil {
+		createClient := s.clientFactory
+		s.clients = s3.NewClientPool(func(instance discovery.S3Instance) (s3.Client, error) {
+			s.clientCreations.Add(1)
+			return createClient(instance)
+		}, s.clientPoolMaxSize, s.clientIdleTimeout)
 	}
+	s.clientFactory = s.pooledClient
+
+	return s
+}
+
+// Close releases the connections to the S3 instances. It is idempotent and safe to call while requests are draining.
+func (s *ServiceV1) Close() error {
+	s.exportJobs.close()
+	err := s.clients.Close()
+	s3.CloseIdleConnections()
+	return err
 }
 
 // AddOrUpdateObject adds or updates an object in one of the available S3 instances
-func (s *ServiceV1) AddOrUpdateObject(ctx context.Context, objectId string, data multipart.File) error {
+func (s *ServiceV1) AddOrUpdateObject(ctx context.Context, objectId string, data multipart.File, options UploadOptions) (*UploadResult, error) {
 	logger := s.logger.With(zap.String("objectId", objectId))
 	logger.Info("Adding or updating object in S3")
 
-	// Determine which instance to write to based on the objectId
-	instance, err := s.shardObjectToInstance(ctx, objectId)
+	// The objectId is the full key, so the writes are locked across the namespaces correctly
+	unlock, err := s.lockWrite(ctx, objectId)
 	if err != nil {
-		return errors.Wrap(err, "failed to assign object to instance")
+		return nil, err
+	}
+	defer unlock()
+	defer s.readCache.Remove(objectId)
+
+	// Determine which instance to write to based on the objectId, unless the instance is pinned
+	var instance *discovery.S3Instance
+	if options.PinnedInstance != nil {
+		instance, err = s.findInstance(ctx, *options.PinnedInstance)
+	} else {
+		instance, err = s.shardObjectToInstance(ctx, objectId)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to assign object to instance")
 	}
+	recordShardOperation("write", instance.InstanceNum)
 
 	// Minio client must be dynamically created, based on the S3 instance
-	client, err := s3.NewMinioClient(*instance)
+	client, err := s.clientFactory(*instance)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// Make sure the object fits into the size limit and the quota
+	size, err := objectSize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.maxObjectSize > 0 && size > s.maxObjectSize {
+		return nil, errors.Wrapf(ErrObjectTooLarge, "object has %d of at most %d bytes", size, s.maxObjectSize)
+	}
+
+	releaseQuota, err := s.reserveQuota(ctx, client, objectId, size)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.Info("Adding object to S3 instance", zap.Int("instance", instance.InstanceNum))
-	return client.AddOrUpdateObject(ctx, objectId, data)
+	putOptions := s3.PutOptions{Size: size}
+	if options.ExpiresIn > 0 {
+		putOptions.ExpiresAt = time.Now().Add(options.ExpiresIn)
+	}
+
+	// The size reported by the file can't be trusted, so the body is read through a limit as well. Reading the byte
+	// over the limit fails the upload before it is committed.
+	body := io.Reader(data)
+	var limited *limitedReader
+	if s.maxObjectSize > 0 {
+		limited = newLimitedReader(data, s.maxObjectSize)
+		body = limited
+	}
+
+	// Count the bytes actually stored, so the clients can verify the upload.
+	// The upload is bound to the checked size - the client fails it before committing if the body differs.
+	counter := &countingWriter{w: io.Discard}
+	info, err := client.AddOrUpdateObject(ctx, objectId, io.TeeReader(body, counter), putOptions)
+	if limited != nil && limited.exceeded() {
+		err = errors.Wrapf(ErrObjectTooLarge, "object has more than %d bytes", s.maxObjectSize)
+	}
+
+	s.audit(ctx, "put", objectId, instance.InstanceNum, err)
+	if err != nil {
+		releaseQuota()
+		return nil, err
+	}
+
+	// The pinned objects bypass the sharding, so they are not replicated either
+	if options.PinnedInstance == nil {
+		s.replicate(ctx, "put", objectId, func(client s3.Client) error {
+			_, err := data.Seek(0, io.SeekStart)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.AddOrUpdateObject(ctx, objectId, data, putOptions)
+			return err
+		})
+	}
+
+	return &UploadResult{InstanceNum: instance.InstanceNum, ETag: info.ETag, Size: counter.n}, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+	w io.Writer
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// limitedReader reads at most max bytes through an io.LimitedReader of max+1 bytes. Reading the byte over the limit
+// fails with ErrObjectTooLarge, so the upload reading it is aborted.
+type limitedReader struct {
+	limited *io.LimitedReader
+	max     int64
+}
+
+func newLimitedReader(r io.Reader, max int64) *limitedReader {
+	return &limitedReader{limited: &io.LimitedReader{R: r, N: max + 1}, max: max}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.limited.Read(p)
+	if l.exceeded() {
+		return n, errors.Wrapf(ErrObjectTooLarge, "object has more than %d bytes", l.max)
+	}
+
+	return n, err
+}
+
+// exceeded reports whether the byte over the limit was read
+func (l *limitedReader) exceeded() bool {
+	return l.limited.N == 0
+}
+
+// objectSize determines the size of the uploaded file and rewinds it
+func objectSize(data multipart.File) (int64, error) {
+	size, err := data.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to determine object size")
+	}
+
+	_, err = data.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to determine object size")
+	}
+
+	return size, nil
 }
 
 // GetObject fetches an object from an instance of S3
@@ -64,14 +306,22 @@ func (s *ServiceV1) GetObject(ctx context.Context, objectId string) (io.Reader,
 	logger := s.logger.With(zap.String("objectId", objectId))
 	logger.Info("Getting object from S3")
 
-	// Determine which instance to read from based on the objectId
-	instance, err := s.shardObjectToInstance(ctx, objectId)
+	if s.readCache != nil {
+		if data, ok := s.readCache.Get(objectId); ok {
+			logger.Debug("Serving object from the read cache")
+			return bytes.NewReader(data), nil
+		}
+	}
+
+	// Determine which instance to read from based on the objectId, failing over to a replica if the instance is failing
+	instance, err := s.readInstance(ctx, objectId)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to assign object to instance")
 	}
+	recordShardOperation("read", instance.InstanceNum)
 
 	// Minio client must be dynamically created, based on the S3 instance
-	client, err := s3.NewMinioClient(*instance)
+	client, err := s.clientFactory(*instance)
 	if err != nil {
 		return nil, err
 	}
@@ -84,11 +334,77 @@ func (s *ServiceV1) GetObject(ctx context.Context, objectId string) (io.Reader,
 		return nil, errors.Wrap(err, "failed to get object from S3")
 	}
 
+	if s.readCache != nil {
+		return s.readCache.read(objectId, obj, time.Now().Add(s.readCacheTTL))
+	}
+
 	return obj, nil
 }
 
-// GetObjects get all objects (from all instances)
-func (s *ServiceV1) GetObjects(ctx context.Context) ([]string, error) {
+// StatObject fetches the metadata of an object from an instance of S3
+func (s *ServiceV1) StatObject(ctx context.Context, objectId string) (*s3.ObjectInfo, error) {
+	logger := s.logger.With(zap.String("objectId", objectId))
+	logger.Info("Getting object metadata from S3")
+
+	// Determine which instance to read from based on the objectId, failing over to a replica if the instance is failing
+	instance, err := s.readInstance(ctx, objectId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to assign object to instance")
+	}
+
+	// Minio client must be dynamically created, based on the S3 instance
+	client, err := s.clientFactory(*instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.StatObject(ctx, objectId)
+}
+
+// DeleteObject deletes an object from an instance of S3
+func (s *ServiceV1) DeleteObject(ctx context.Context, objectId string) error {
+	logger := s.logger.With(zap.String("objectId", objectId))
+	logger.Info("Deleting object from S3")
+	defer s.readCache.Remove(objectId)
+
+	// Determine which instance to delete from based on the objectId
+	instance, err := s.shardObjectToInstance(ctx, objectId)
+	if err != nil {
+		return errors.Wrap(err, "failed to assign object to instance")
+	}
+
+	// Minio client must be dynamically created, based on the S3 instance
+	client, err := s.clientFactory(*instance)
+	if err != nil {
+		return err
+	}
+
+	// Deleting a missing object succeeds in S3, so check the existence first. The size is released from the quota.
+	existing, err := client.StatObject(ctx, objectId)
+	if err != nil {
+		return err
+	}
+
+	err = client.DeleteObject(ctx, objectId)
+	s.audit(ctx, "delete", objectId, instance.InstanceNum, err)
+	if err != nil {
+		return err
+	}
+
+	if s.quotaEnforcer != nil {
+		s.quotaEnforcer.RemoveUsage(ctx, s.quotaNamespace(objectId), s3.Usage{Objects: 1, Bytes: existing.Size})
+	}
+
+	s.replicate(ctx, "delete", objectId, func(client s3.Client) error {
+		return client.DeleteObject(ctx, objectId)
+	})
+
+	return nil
+}
+
+// GetObjects get all objects matching the filter (from all instances). If more objects match than the listing limit,
+// the listing is truncated and returned with ErrListTruncated.
+func (s *ServiceV1) GetObjects(ctx context.Context, filter s3.ListFilter) ([]string, error) {
 	s.logger.Info("Get all objects")
 
 	// Discover available S3 instances
@@ -100,27 +416,41 @@ func (s *ServiceV1) GetObjects(ctx context.Context) ([]string, error) {
 	objectIds := []string{}
 
 	for _, instance := range instances {
+		if s.maxListedObjects > 0 {
+			// List one object past the limit to find out if the listing is truncated
+			filter.Limit = s.maxListedObjects - len(objectIds) + 1
+		}
+
 		// Minio client must be dynamically created, based on the S3 instance
-		client, err := s3.NewMinioClient(instance)
+		client, err := s.clientFactory(instance)
 		if err != nil {
 			return nil, errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
 		}
 
-		objects, err := client.GetObjects(ctx)
+		objects, err := client.GetObjects(ctx, filter)
 		if err != nil {
 			return nil, errors.Wrap(err, fmt.Sprintf("unable to list objectIds for instance: %d", instance.InstanceNum))
 		}
 
 		objectIds = append(objectIds, objects...)
+		if s.maxListedObjects > 0 && len(objectIds) > s.maxListedObjects {
+			break
+		}
 	}
 
-	return objectIds, nil
+	return truncateListing(s, objectIds)
 }
 
-// GetObjects get all objects from all instances asnychonously
-func (s *ServiceV1) GetObjectsAsync(ctx context.Context) ([]string, error) {
+// GetObjects get all objects matching the filter from all instances asnychonously. If more objects match than the
+// listing limit, the listing is truncated and returned with ErrListTruncated.
+func (s *ServiceV1) GetObjectsAsync(ctx context.Context, filter s3.ListFilter) ([]string, error) {
 	s.logger.Info("Get all objects")
 
+	if s.maxListedObjects > 0 {
+		// List one object past the limit to find out if the listing is truncated
+		filter.Limit = s.maxListedObjects + 1
+	}
+
 	// Discover available S3 instances
 	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
 	if err != nil {
@@ -135,20 +465,30 @@ func (s *ServiceV1) GetObjectsAsync(ctx context.Context) ([]string, error) {
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(instances))
 
+	// Limit the number of instances queried at once
+	workers := s.workerCount
+	if workers <= 0 {
+		workers = len(instances)
+	}
+	semaphore := make(chan struct{}, max(workers, 1))
+
 	for _, instance := range instances {
 		wg.Add(1)
 
 		go func(s3Instance discovery.S3Instance) {
 			defer wg.Done()
 
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
 			// Minio client must be dynamically created, based on the S3 instance
-			client, err := s3.NewMinioClient(s3Instance)
+			client, err := s.clientFactory(s3Instance)
 			if err != nil {
 				errChan <- errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", s3Instance.InstanceNum))
 				return
 			}
 
-			objects, err := client.GetObjects(ctx)
+			objects, err := client.GetObjects(ctx, filter)
 			if err != nil {
 				errChan <- errors.Wrap(err, fmt.Sprintf("unable to list objectIds for instance: %d", s3Instance.InstanceNum))
 				return
@@ -171,7 +511,91 @@ func (s *ServiceV1) GetObjectsAsync(ctx context.Context) ([]string, error) {
 		}
 	}
 
-	return objectIds, nil
+	return truncateListing(s, objectIds)
+}
+
+// truncateListing truncates the listed objects exceeding the listing limit of the service
+func truncateListing[T any](s *ServiceV1, objects []T) ([]T, error) {
+	if s.maxListedObjects <= 0 || len(objects) <= s.maxListedObjects {
+		return objects, nil
+	}
+
+	s.logger.Warn("Object listing truncated", zap.Int("limit", s.maxListedObjects))
+	return objects[:s.maxListedObjects], errors.Wrapf(ErrListTruncated, "more than %d objects match", s.maxListedObjects)
+}
+
+// StreamObjects streams the objectIds from all instances concurrently, without collecting them in memory.
+// The objectIds channel is closed once all instances are exhausted, an error occurs or the context is cancelled.
+// At most one error is sent to the error channel, which is closed after the objectIds channel.
+func (s *ServiceV1) StreamObjects(ctx context.Context) (<-chan string, <-chan error) {
+	s.logger.Info("Stream all objects")
+
+	objectIds := make(chan string, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(errChan)
+		defer close(objectIds)
+
+		// Discover available S3 instances
+		instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		// The first error stops the other instances
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var once sync.Once
+		fail := func(err error) {
+			once.Do(func() {
+				errChan <- err
+				cancel()
+			})
+		}
+
+		// Limit the number of instances queried at once
+		workers := s.workerCount
+		if workers <= 0 {
+			workers = len(instances)
+		}
+		semaphore := make(chan struct{}, max(workers, 1))
+
+		var wg sync.WaitGroup
+		for _, instance := range instances {
+			wg.Add(1)
+
+			go func(s3Instance discovery.S3Instance) {
+				defer wg.Done()
+
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				// Minio client must be dynamically created, based on the S3 instance
+				client, err := s.clientFactory(s3Instance)
+				if err != nil {
+					fail(errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", s3Instance.InstanceNum)))
+					return
+				}
+
+				err = client.StreamObjects(streamCtx, objectIds)
+				if err != nil && streamCtx.Err() == nil {
+					fail(errors.Wrap(err, fmt.Sprintf("unable to stream objectIds for instance: %d", s3Instance.InstanceNum)))
+				}
+			}(instance)
+		}
+
+		wg.Wait()
+	}()
+
+	return objectIds, errChan
+}
+
+// Instances returns the discovered S3 instances
+func (s *ServiceV1) Instances(ctx context.Context) ([]discovery.S3Instance, error) {
+	return s.discoveryService.DiscoverS3Instances(ctx)
 }
 
 // Ready checks if the service is ready (if the Minio client is online and the Docker client is connected)
@@ -194,28 +618,5 @@ func (s *ServiceV1) shardObjectToInstance(ctx context.Context, objectId string)
 		return nil, err
 	}
 
-	// If there are no instances available, return an error
-	if len(instances) == 0 {
-		return nil, errors.New("no instances available")
-	}
-
-	// Hash the objectId and use the modulo of the hash to determine the instance
-	// https://medium.com/@nynptel/what-is-modular-hashing-9c1fbbb3c611
-	objectIdHash := hashId(objectId)
-	instanceNum := objectIdHash % uint64(len(instances))
-
-	// Get the instance based on the instanceNum -> Fixed the function based on feedback
-	for _, instance := range instances {
-		if instance.InstanceNum == int(instanceNum) {
-			return &instance, nil
-		}
-	}
-
-	return nil, errors.New("instance not found or unavailable")
-}
-
-func hashId(id string) uint64 {
-	hash := fnv.New64a()
-	hash.Write([]byte(id))
-	return hash.Sum64()
+	return s.shardStrategy.Shard(objectId, instances)
 }
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"sync"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3/s3test"
+)
+
+// newTestService creates a service storing the objects on n in-memory instances, numbered from 1.
+// The clients are keyed by the instance number.
+func newTestService(t testing.TB, n int, opts ...Option) (*ServiceV1, *discoverytest.Service, map[int]*s3test.Client) {
+	t.Helper()
+
+	discoveryService := discoverytest.NewService(discoverytest.Instances(n)...)
+	clients := map[int]*s3test.Client{}
+	for i := 1; i <= n; i++ {
+		clients[i] = s3test.NewClient()
+	}
+
+	// The pool creates the clients of different instances concurrently, e.g. when rebalancing
+	var mu sync.Mutex
+	factory := WithClientFactory(func(instance discovery.S3Instance) (s3.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		client, ok := clients[instance.InstanceNum]
+		if !ok {
+			client = s3test.NewClient()
+			clients[instance.InstanceNum] = client
+		}
+
+		return client, nil
+	})
+
+	service := NewServiceV1WithOptions(discoveryService, s3.Options{}, append([]Option{factory}, opts...)...)
+	t.Cleanup(func() { _ = service.Close() })
+	return service, discoveryService, clients
+}
+
+// testFile is an uploaded file of the content
+type testFile struct {
+	*bytes.Reader
+}
+
+func newTestFile(data []byte) multipart.File {
+	return testFile{Reader: bytes.NewReader(data)}
+}
+
+func (testFile) Close() error {
+	return nil
+}
+
+// shrunkFile reports a smaller size than its content, as a file growing during the upload does
+type shrunkFile struct {
+	testFile
+	size int64
+}
+
+func (f shrunkFile) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		return f.size, nil
+	}
+	return f.testFile.Seek(offset, whence)
+}
+
+// stubService records the object ids and the list filters passed to it by the decorators. The methods not
+// implemented panic, as the embedded Service is nil.
+type stubService struct {
+	Service
+	// err is returned by all operations
+	err error
+	// keys are returned by the listings
+	keys      []string
+	objectIds []string
+	filters   []s3.ListFilter
+}
+
+func (s *stubService) AddOrUpdateObject(_ context.Context, objectId string, _ multipart.File, _ UploadOptions) (*UploadResult, error) {
+	s.objectIds = append(s.objectIds, objectId)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &UploadResult{InstanceNum: 1}, nil
+}
+
+func (s *stubService) GetObject(_ context.Context, objectId string) (io.Reader, error) {
+	s.objectIds = append(s.objectIds, objectId)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return bytes.NewReader([]byte("data")), nil
+}
+
+func (s *stubService) DeleteObject(_ context.Context, objectId string) error {
+	s.objectIds = append(s.objectIds, objectId)
+	return s.err
+}
+
+func (s *stubService) GetObjects(_ context.Context, filter s3.ListFilter) ([]string, error) {
+	s.filters = append(s.filters, filter)
+	return s.keys, s.err
+}
+
+func (s *stubService) DeleteObjectVersion(_ context.Context, objectId, _ string) error {
+	s.objectIds = append(s.objectIds, objectId)
+	return s.err
+}
+
+func (s *stubService) RenameObject(_ context.Context, oldId, _ string) error {
+	s.objectIds = append(s.objectIds, oldId)
+	return s.err
+}
+
+func (s *stubService) DeleteObjectsByPrefix(_ context.Context, prefix string) (int, error) {
+	s.objectIds = append(s.objectIds, prefix)
+	return 0, s.err
+}
+
+func (s *stubService) MigrateObject(_ context.Context, objectId string, targetInstanceNum int) (*MigrationResult, error) {
+	s.objectIds = append(s.objectIds, objectId)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &MigrationResult{SourceInstance: 1, TargetInstance: targetInstanceNum}, nil
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+)
+
+// virtualNodesPerWeight is the number of points each weight unit of an instance has on the hash ring.
+// A single point per unit would distribute the objects too unevenly.
+const virtualNodesPerWeight = 100
+
+var (
+	// ErrInstanceUnavailable is returned when the instance of the object is unavailable
+	ErrInstanceUnavailable = errors.New("instance unavailable")
+	// ErrNoInstancesAvailable is returned when no instances were discovered. The condition is usually transient.
+	ErrNoInstancesAvailable = errors.New("no instances available")
+)
+
+// ShardStrategy chooses the instance an object is stored in
+type ShardStrategy interface {
+	Shard(objectId string, instances []discovery.S3Instance) (*discovery.S3Instance, error)
+}
+
+// ModuloShardStrategy assigns the objects with a hash of the object ID modulo the number of instances.
+// The result indexes into the instances ordered by their number, so any numbering (1..n, 0..n-1, or with gaps) works.
+// Instances with an instance key are ordered by the key, after the instances without one.
+type ModuloShardStrategy struct {
+	// Hasher hashes the object IDs - defaults to the FNVHasher
+	Hasher Hasher
+}
+
+// Shard chooses the instance of the object from the given instances
+func (m ModuloShardStrategy) Shard(objectId string, instances []discovery.S3Instance) (*discovery.S3Instance, error) {
+	// If there are no instances available, return an error
+	if len(instances) == 0 {
+		return nil, ErrNoInstancesAvailable
+	}
+
+	// Index into the instances ordered by their key and number, so gaps in the numbering don't matter
+	sorted := make([]discovery.S3Instance, len(instances))
+	copy(sorted, instances)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].InstanceKey != sorted[j].InstanceKey {
+			return sorted[i].InstanceKey < sorted[j].InstanceKey
+		}
+
+		if sorted[i].InstanceNum != sorted[j].InstanceNum {
+			return sorted[i].InstanceNum < sorted[j].InstanceNum
+		}
+
+		// The duplicate numbers are deduplicated by the discovery, but the order must not depend on the input anyway
+		return sorted[i].ContainerId < sorted[j].ContainerId
+	})
+
+	// Hash the objectId and use the modulo of the hash to determine the instance
+	// https://medium.com/@nynptel/what-is-modular-hashing-9c1fbbb3c611
+	index := hasherOrDefault(m.Hasher).Hash(objectId) % uint64(len(sorted))
+	return &sorted[index], nil
+}
+
+// RebuildableShardStrategy is a ShardStrategy keeping a sharding state derived from the instance set
+type RebuildableShardStrategy interface {
+	ShardStrategy
+	// Rebuild recomputes the sharding state for the given instances
+	Rebuild(instances []discovery.S3Instance)
+}
+
+// WeightedHashShardStrategy assigns the objects using a weighted hash ring. An instance with the weight W has W virtual
+// slots on the ring, so it receives W times as many objects as an instance with the weight 1.
+// The ring is cached and rebuilt only when the instance numbers or weights change.
+type WeightedHashShardStrategy struct {
+	// Hasher hashes the object IDs and the virtual nodes - defaults to the FNVHasher
+	Hasher Hasher
+
+	mu sync.Mutex
+	// Shard keys and weights the ring was built for
+	key  string
+	ring []ringPoint
+}
+
+type ringPoint struct {
+	hash     uint64
+	shardKey string
+}
+
+// Shard chooses the instance of the object from the given instances
+func (s *WeightedHashShardStrategy) Shard(objectId string, instances []discovery.S3Instance) (*discovery.S3Instance, error) {
+	// If there are no instances available, return an error
+	if len(instances) == 0 {
+		return nil, ErrNoInstancesAvailable
+	}
+
+	ring := s.ringFor(instances)
+
+	// The object belongs to the first point clockwise from its hash
+	objectIdHash := ringHash(hasherOrDefault(s.Hasher), objectId)
+	index := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= objectIdHash })
+	if index == len(ring) {
+		index = 0
+	}
+
+	for i := range instances {
+		if instances[i].ShardKey() == ring[index].shardKey {
+			return &instances[i], nil
+		}
+	}
+
+	return nil, ErrNoInstancesAvailable
+}
+
+// Rebuild rebuilds the ring for the given instances
+func (s *WeightedHashShardStrategy) Rebuild(instances []discovery.S3Instance) {
+	s.ringFor(instances)
+}
+
+// ringFor returns the ring of the instances, rebuilding the cached one if the instances changed
+func (s *WeightedHashShardStrategy) ringFor(instances []discovery.S3Instance) []ringPoint {
+	key := ringKey(instances)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ring != nil && s.key == key {
+		return s.ring
+	}
+
+	// Build the ring - the points depend only on the shard keys, so the ring is the same for every gateway
+	hasher := hasherOrDefault(s.Hasher)
+	ring := []ringPoint{}
+	for _, instance := range instances {
+		shardKey := instance.ShardKey()
+		weight := max(instance.WeightedCapacity, 1)
+		for slot := 0; slot < weight*virtualNodesPerWeight; slot++ {
+			ring = append(ring, ringPoint{hash: ringHash(hasher, fmt.Sprintf("%s-%d", shardKey, slot)), shardKey: shardKey})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	s.key = key
+	s.ring = ring
+	return ring
+}
+
+// ringKey identifies the ring of the instances by their shard keys and weights
+func ringKey(instances []discovery.S3Instance) string {
+	parts := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		parts = append(parts, fmt.Sprintf("%s:%d", instance.ShardKey(), max(instance.WeightedCapacity, 1)))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// ringHash hashes the id for the hash ring. FNV alone clusters similar ids (such as the virtual node names),
+// so the result of the hasher is mixed with the splitmix64 finalizer to spread the points evenly.
+func ringHash(hasher Hasher, id string) uint64 {
+	hash := hasher.Hash(id)
+	hash ^= hash >> 30
+	hash *= 0xbf58476d1ce4e5b9
+	hash ^= hash >> 27
+	hash *= 0x94d049bb133111eb
+	hash ^= hash >> 31
+	return hash
+}
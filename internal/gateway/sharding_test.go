@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// numberedInstances creates the instances with the numbers, in the given order
+func numberedInstances(numbers ...int) []discovery.S3Instance {
+	instances := make([]discovery.S3Instance, 0, len(numbers))
+	for _, number := range numbers {
+		instances = append(instances, discoverytest.Instance(number))
+	}
+
+	return instances
+}
+
+// shardAll shards the objects object0..object(n-1), counting the objects of each instance number
+func shardAll(t *testing.T, strategy ShardStrategy, instances []discovery.S3Instance, n int) map[int]int {
+	t.Helper()
+
+	counts := map[int]int{}
+	for i := 0; i < n; i++ {
+		instance, err := strategy.Shard(fmt.Sprintf("object%d", i), instances)
+		require.NoError(t, err)
+		counts[instance.InstanceNum]++
+	}
+
+	return counts
+}
+
+func TestModuloShardStrategy_Gaps(t *testing.T) {
+	strategy := ModuloShardStrategy{}
+
+	// Each instance of the sparse numbering receives objects
+	counts := shardAll(t, strategy, numberedInstances(1, 3, 5), 3000)
+	assert.Len(t, counts, 3)
+	for _, number := range []int{1, 3, 5} {
+		assert.Greater(t, counts[number], 500, "instance %d", number)
+	}
+
+	// The placement doesn't depend on the order the instances are listed in
+	for i := 0; i < 100; i++ {
+		objectId := fmt.Sprintf("object%d", i)
+		sorted, err := strategy.Shard(objectId, numberedInstances(1, 3, 5))
+		require.NoError(t, err)
+		shuffled, err := strategy.Shard(objectId, numberedInstances(5, 1, 3))
+		require.NoError(t, err)
+		assert.Equal(t, sorted.InstanceNum, shuffled.InstanceNum)
+	}
+}
+
+func TestModuloShardStrategy_Duplicates(t *testing.T) {
+	strategy := ModuloShardStrategy{}
+
+	stale := discoverytest.Instance(3)
+	stale.ContainerId = "stale"
+	instances := append(numberedInstances(1, 3), stale)
+	reversed := []discovery.S3Instance{instances[2], instances[1], instances[0]}
+
+	// The duplicate numbers are placed in the same order, whatever the order of the input
+	for i := 0; i < 100; i++ {
+		objectId := fmt.Sprintf("object%d", i)
+		first, err := strategy.Shard(objectId, instances)
+		require.NoError(t, err)
+		second, err := strategy.Shard(objectId, reversed)
+		require.NoError(t, err)
+		assert.Equal(t, first.ContainerId, second.ContainerId)
+	}
+}
+
+func TestModuloShardStrategy_NoInstances(t *testing.T) {
+	_, err := ModuloShardStrategy{}.Shard("object", nil)
+	assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+}
+
+func TestModuloShardStrategy_Numberings(t *testing.T) {
+	numberings := map[string][]int{
+		"from one":  {1, 2, 3},
+		"from zero": {0, 1, 2},
+		"sparse":    {2, 5, 9},
+	}
+
+	// The object is placed by the position of the instance, whatever the numbering
+	positions := map[string]int{}
+	for name, numbers := range numberings {
+		t.Run(name, func(t *testing.T) {
+			counts := shardAll(t, ModuloShardStrategy{}, numberedInstances(numbers...), 3000)
+			assert.Len(t, counts, 3)
+
+			for i := 0; i < 100; i++ {
+				objectId := fmt.Sprintf("object%d", i)
+				instance, err := ModuloShardStrategy{}.Shard(objectId, numberedInstances(numbers...))
+				require.NoError(t, err)
+
+				position := 0
+				for position < len(numbers) && numbers[position] != instance.InstanceNum {
+					position++
+				}
+				if expected, ok := positions[objectId]; ok {
+					assert.Equal(t, expected, position, objectId)
+				}
+				positions[objectId] = position
+			}
+		})
+	}
+}
+
+func TestAddOrUpdateObject_SparseNumbering(t *testing.T) {
+	service, discoveryService, clients := newTestService(t, 0)
+	discoveryService.SetInstances(numberedInstances(2, 5, 9)...)
+
+	for i := 0; i < 30; i++ {
+		_, err := service.AddOrUpdateObject(context.Background(), fmt.Sprintf("object%d", i), newTestFile([]byte("data")), UploadOptions{})
+		require.NoError(t, err)
+	}
+
+	// Every instance is selected, none is missing
+	require.Len(t, clients, 3)
+	for _, number := range []int{2, 5, 9} {
+		assert.NotEmpty(t, clients[number].Keys(), "instance %d", number)
+	}
+}
+
+func TestWeightedHashShardStrategy_Weights(t *testing.T) {
+	instances := numberedInstances(1, 2)
+	instances[0].WeightedCapacity = 2
+	instances[1].WeightedCapacity = 1
+
+	// The instance with the weight 2 receives about twice as many objects
+	counts := shardAll(t, &WeightedHashShardStrategy{}, instances, 10000)
+	assert.Equal(t, 10000, counts[1]+counts[2])
+	assert.InDelta(t, 2.0, float64(counts[1])/float64(counts[2]), 0.2)
+}
+
+func TestWeightedHashShardStrategy_DefaultWeight(t *testing.T) {
+	instances := numberedInstances(1, 2, 3)
+	instances[0].WeightedCapacity = 2
+
+	// The instances without a weight count as the weight 1, so the instance with the weight 2 holds about a half
+	counts := shardAll(t, &WeightedHashShardStrategy{}, instances, 10000)
+	assert.Equal(t, 10000, counts[1]+counts[2]+counts[3])
+	assert.InDelta(t, 5000, counts[1], 500)
+}
+
+func TestWeightedHashShardStrategy_Stable(t *testing.T) {
+	strategy := &WeightedHashShardStrategy{}
+	instances := numberedInstances(1, 2, 3)
+
+	// The assignment doesn't depend on the order of the instances or on the strategy instance
+	before := shardAll(t, strategy, instances, 1000)
+	assert.Equal(t, before, shardAll(t, &WeightedHashShardStrategy{}, numberedInstances(3, 1, 2), 1000))
+
+	// Adding an instance moves only the objects assigned to it
+	grown := append(numberedInstances(1, 2, 3), discoverytest.Instance(4))
+	for i := 0; i < 1000; i++ {
+		objectId := fmt.Sprintf("object%d", i)
+		previous, err := strategy.Shard(objectId, instances)
+		require.NoError(t, err)
+		current, err := strategy.Shard(objectId, grown)
+		require.NoError(t, err)
+
+		if current.InstanceNum != 4 {
+			assert.Equal(t, previous.InstanceNum, current.InstanceNum, objectId)
+		}
+	}
+}
+
+func TestWeightedHashShardStrategy_NoInstances(t *testing.T) {
+	_, err := (&WeightedHashShardStrategy{}).Shard("object", nil)
+	assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+}
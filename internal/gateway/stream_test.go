@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamObjects(t *testing.T) {
+	service, _, clients := newTestService(t, 4)
+	for i := 0; i < 10000; i++ {
+		clients[i%4+1].Put(fmt.Sprintf("object%05d", i), []byte("data"))
+	}
+
+	objectIds, errs := service.StreamObjects(context.Background())
+
+	// The objects are counted as they arrive, the stream never holds more than its buffer
+	received := map[string]bool{}
+	for objectId := range objectIds {
+		assert.LessOrEqual(t, len(objectIds), cap(objectIds))
+		received[objectId] = true
+	}
+
+	require.NoError(t, <-errs)
+	assert.Len(t, received, 10000)
+}
+
+func TestStreamObjects_SlowConsumer(t *testing.T) {
+	service, _, clients := newTestService(t, 4)
+	for i := 0; i < 10000; i++ {
+		clients[i%4+1].Put(fmt.Sprintf("object%05d", i), []byte("data"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	objectIds, errs := service.StreamObjects(ctx)
+
+	// The instances block on the full buffer instead of collecting the objects
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, cap(objectIds), len(objectIds))
+
+	cancel()
+	for range objectIds {
+	}
+	assert.NoError(t, <-errs)
+}
+
+func TestStreamObjects_InstanceFailure(t *testing.T) {
+	service, _, clients := newTestService(t, 2)
+	clients[1].Put("object", []byte("data"))
+	clients[2].Fail(errors.New("connection refused"))
+
+	objectIds, errs := service.StreamObjects(context.Background())
+	for range objectIds {
+	}
+
+	assert.ErrorContains(t, <-errs, "instance: 2")
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"golang.org/x/sync/errgroup"
+)
+
+// GetObjectsWithMetadata lists the objects matching the filter with their metadata from all instances concurrently.
+// If more objects match than the listing limit, the listing is truncated and returned with ErrListTruncated.
+func (s *ServiceV1) GetObjectsWithMetadata(ctx context.Context, filter s3.ListFilter) ([]s3.ObjectSummary, error) {
+	s.logger.Info("Get all objects with metadata")
+
+	// Discover available S3 instances
+	instances, err := s.discoveryService.DiscoverS3Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.maxListedObjects > 0 {
+		// List one object past the limit to find out if the listing is truncated
+		filter.Limit = s.maxListedObjects + 1
+	}
+
+	summaries := []s3.ObjectSummary{}
+	summariesMutex := sync.Mutex{}
+
+	// Limit the number of instances queried at once
+	group, groupCtx := errgroup.WithContext(ctx)
+	if s.workerCount > 0 {
+		group.SetLimit(s.workerCount)
+	}
+
+	for _, instance := range instances {
+		instance := instance
+		group.Go(func() error {
+			// Minio client must be dynamically created, based on the S3 instance
+			client, err := s.clientFactory(instance)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("unable to create s3 client for instance: %d", instance.InstanceNum))
+			}
+
+			objects, err := client.GetObjectsSummary(groupCtx, filter)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("unable to list objects for instance: %d", instance.InstanceNum))
+			}
+
+			summariesMutex.Lock()
+			summaries = append(summaries, objects...)
+			summariesMutex.Unlock()
+			return nil
+		})
+	}
+
+	err = group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return truncateListing(s, summaries)
+}
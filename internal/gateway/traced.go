@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedService wraps the object operations of the inner service in spans
+type tracedService struct {
+	Service
+	tracer trace.Tracer
+}
+
+// TracedService starts a span for each object operation with the tracer
+func TracedService(inner Service, tracer trace.Tracer) Service {
+	return &tracedService{Service: inner, tracer: tracer}
+}
+
+// start starts the span of an operation
+func (t *tracedService) start(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "gateway."+operation, trace.WithAttributes(attributes...))
+}
+
+// endSpan records the error of the operation and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrListTruncated) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+func objectIdAttribute(objectId string) attribute.KeyValue {
+	return attribute.String("object.id", objectId)
+}
+
+func (t *tracedService) AddOrUpdateObject(ctx context.Context, objectId string, file multipart.File, options UploadOptions) (result *UploadResult, err error) {
+	ctx, span := t.start(ctx, "AddOrUpdateObject", objectIdAttribute(objectId))
+	defer func() { endSpan(span, err) }()
+	return t.Service.AddOrUpdateObject(ctx, objectId, file, options)
+}
+
+func (t *tracedService) GetObject(ctx context.Context, objectId string) (object io.Reader, err error) {
+	ctx, span := t.start(ctx, "GetObject", objectIdAttribute(objectId))
+	defer func() { endSpan(span, err) }()
+	return t.Service.GetObject(ctx, objectId)
+}
+
+func (t *tracedService) GetObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (object io.Reader, err error) {
+	ctx, span := t.start(ctx, "GetObjectFromInstance", objectIdAttribute(objectId), attribute.Int("instance", instanceNum))
+	defer func() { endSpan(span, err) }()
+	return t.Service.GetObjectFromInstance(ctx, objectId, instanceNum)
+}
+
+func (t *tracedService) StatObject(ctx context.Context, objectId string) (info *s3.ObjectInfo, err error) {
+	ctx, span := t.start(ctx, "StatObject", objectIdAttribute(objectId))
+	defer func() { endSpan(span, err) }()
+	return t.Service.StatObject(ctx, objectId)
+}
+
+func (t *tracedService) StatObjectFromInstance(ctx context.Context, objectId string, instanceNum int) (info *s3.ObjectInfo, err error) {
+	ctx, span := t.start(ctx, "StatObjectFromInstance", objectIdAttribute(objectId), attribute.Int("instance", instanceNum))
+	defer func() { endSpan(span, err) }()
+	return t.Service.StatObjectFromInstance(ctx, objectId, instanceNum)
+}
+
+func (t *tracedService) DeleteObject(ctx context.Context, objectId string) (err error) {
+	ctx, span := t.start(ctx, "DeleteObject", objectIdAttribute(objectId))
+	defer func() { endSpan(span, err) }()
+	return t.Service.DeleteObject(ctx, objectId)
+}
+
+func (t *tracedService) RenameObject(ctx context.Context, oldId, newId string) (err error) {
+	ctx, span := t.start(ctx, "RenameObject", objectIdAttribute(oldId), attribute.String("object.new_id", newId))
+	defer func() { endSpan(span, err) }()
+	return t.Service.RenameObject(ctx, oldId, newId)
+}
+
+func (t *tracedService) DeleteObjectsByPrefix(ctx context.Context, prefix string) (deleted int, err error) {
+	ctx, span := t.start(ctx, "DeleteObjectsByPrefix", attribute.String("object.prefix", prefix))
+	defer func() { endSpan(span, err) }()
+	return t.Service.DeleteObjectsByPrefix(ctx, prefix)
+}
+
+func (t *tracedService) CountObjectsByPrefix(ctx context.Context, prefix string) (count int, err error) {
+	ctx, span := t.start(ctx, "CountObjectsByPrefix", attribute.String("object.prefix", prefix))
+	defer func() { endSpan(span, err) }()
+	return t.Service.CountObjectsByPrefix(ctx, prefix)
+}
+
+func (t *tracedService) GetObjects(ctx context.Context, filter s3.ListFilter) (objectIds []string, err error) {
+	ctx, span := t.start(ctx, "GetObjects", attribute.String("object.prefix", filter.Prefix))
+	defer func() { endSpan(span, err) }()
+	return t.Service.GetObjects(ctx, filter)
+}
+
+func (t *tracedService) GetObjectsWithMetadata(ctx context.Context, filter s3.ListFilter) (summaries []s3.ObjectSummary, err error) {
+	ctx, span := t.start(ctx, "GetObjectsWithMetadata", attribute.String("object.prefix", filter.Prefix))
+	defer func() { endSpan(span, err) }()
+	return t.Service.GetObjectsWithMetadata(ctx, filter)
+}
+
+func (t *tracedService) GetObjectsAsync(ctx context.Context, filter s3.ListFilter) (objectIds []string, err error) {
+	ctx, span := t.start(ctx, "GetObjectsAsync", attribute.String("object.prefix", filter.Prefix))
+	defer func() { endSpan(span, err) }()
+	return t.Service.GetObjectsAsync(ctx, filter)
+}
+
+func (t *tracedService) CountObjects(ctx context.Context) (count *ObjectCount, err error) {
+	ctx, span := t.start(ctx, "CountObjects")
+	defer func() { endSpan(span, err) }()
+	return t.Service.CountObjects(ctx)
+}
+
+func (t *tracedService) MigrateObject(ctx context.Context, objectId string, targetInstanceNum int) (result *MigrationResult, err error) {
+	ctx, span := t.start(ctx, "MigrateObject", objectIdAttribute(objectId), attribute.Int("instance", targetInstanceNum))
+	defer func() { endSpan(span, err) }()
+	return t.Service.MigrateObject(ctx, objectId, targetInstanceNum)
+}
+
+func (t *tracedService) ExportObject(ctx context.Context, objectId string, target ExportTarget) (jobId string, err error) {
+	ctx, span := t.start(ctx, "ExportObject", objectIdAttribute(objectId))
+	defer func() { endSpan(span, err) }()
+	return t.Service.ExportObject(ctx, objectId, target)
+}
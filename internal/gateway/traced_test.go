@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedService(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	inner := &stubService{}
+	service := TracedService(inner, provider.Tracer("test"))
+	ctx := context.Background()
+
+	_, err := service.GetObject(ctx, "object")
+	require.NoError(t, err)
+
+	inner.err = s3.ErrObjectNotFound
+	err = service.DeleteObject(ctx, "missing")
+	assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+
+	// A truncated listing is not an error
+	inner.err = errors.WithStack(ErrListTruncated)
+	_, err = service.GetObjects(ctx, s3.ListFilter{})
+	assert.ErrorIs(t, err, ErrListTruncated)
+
+	assert.Equal(t, []string{"object", "missing"}, inner.objectIds)
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	assert.Equal(t, "gateway.GetObject", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("object.id", "object"))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, "gateway.DeleteObject", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Len(t, spans[1].Events(), 1)
+
+	assert.Equal(t, "gateway.GetObjects", spans[2].Name())
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+}
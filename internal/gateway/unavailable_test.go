@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardStrategies_NoInstancesAvailable(t *testing.T) {
+	for name, strategy := range map[string]ShardStrategy{
+		"modulo":   ModuloShardStrategy{},
+		"weighted": &WeightedHashShardStrategy{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := strategy.Shard("object", []discovery.S3Instance{})
+			assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+		})
+	}
+}
+
+func TestServiceV1_NoInstancesAvailable(t *testing.T) {
+	service, _, _ := newTestService(t, 0)
+	ctx := context.Background()
+
+	_, err := service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("data")), UploadOptions{})
+	assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+
+	_, err = service.GetObject(ctx, "object")
+	assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+
+	_, err = service.StatObject(ctx, "object")
+	assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+
+	err = service.DeleteObject(ctx, "object")
+	assert.ErrorIs(t, err, ErrNoInstancesAvailable)
+
+	// Nothing is listed from no instances
+	objects, err := service.GetObjects(ctx, s3.ListFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+func TestServiceV1_InstancesRecovered(t *testing.T) {
+	service, discoveryService, _ := newTestService(t, 0)
+	ctx := context.Background()
+
+	_, err := service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("data")), UploadOptions{})
+	require.ErrorIs(t, err, ErrNoInstancesAvailable)
+
+	// The condition is transient - the retried upload succeeds once an instance is discovered
+	discoveryService.SetInstances(discovery.S3Instance{InstanceNum: 1, ContainerId: "minio-1", WeightedCapacity: 1})
+	_, err = service.AddOrUpdateObject(ctx, "object", newTestFile([]byte("data")), UploadOptions{})
+	assert.NoError(t, err)
+}
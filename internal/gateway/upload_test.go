@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddOrUpdateObject_SizeLimit(t *testing.T) {
+	const limit = 16
+
+	tests := []struct {
+		name    string
+		size    int
+		wantErr error
+	}{
+		{name: "at the limit", size: limit},
+		{name: "1 byte over the limit", size: limit + 1, wantErr: ErrObjectTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, clients := newTestService(t, 1, WithMaxObjectSize(limit))
+			clients[1].Put("object", []byte("previous"))
+
+			data := bytes.Repeat([]byte("a"), tt.size)
+			result, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile(data), UploadOptions{})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				// The previous object is kept and the oversized one is never uploaded
+				assert.Equal(t, []byte("previous"), clients[1].Object("object").Data)
+				assert.Equal(t, 0, clients[1].CallCount("AddOrUpdateObject"))
+				assert.Equal(t, 0, clients[1].CallCount("DeleteObject"))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.EqualValues(t, tt.size, result.Size)
+			assert.Equal(t, data, clients[1].Object("object").Data)
+		})
+	}
+}
+
+func TestAddOrUpdateObject_BodyLargerThanReported(t *testing.T) {
+	const limit = 16
+
+	tests := []struct {
+		name    string
+		size    int
+		wantErr error
+	}{
+		// The file grows past the limit after its size is checked, as a chunked body larger than declared does
+		{name: "1 byte over the limit", size: limit + 1, wantErr: ErrObjectTooLarge},
+		{name: "within the limit", size: limit - 1, wantErr: s3.ErrSizeMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, clients := newTestService(t, 1, WithMaxObjectSize(limit))
+			clients[1].Put("object", []byte("previous"))
+
+			file := shrunkFile{testFile: testFile{Reader: bytes.NewReader(bytes.Repeat([]byte("a"), tt.size))}, size: limit - 2}
+			_, err := service.AddOrUpdateObject(context.Background(), "object", file, UploadOptions{})
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			// The upload fails before it is committed, so the previous object is kept
+			assert.Equal(t, []byte("previous"), clients[1].Object("object").Data)
+			assert.Equal(t, 0, clients[1].CallCount("DeleteObject"))
+		})
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	limited := newLimitedReader(bytes.NewReader(bytes.Repeat([]byte("a"), 17)), 16)
+
+	data, err := io.ReadAll(limited)
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+	assert.True(t, limited.exceeded())
+	assert.Len(t, data, 17)
+
+	limited = newLimitedReader(bytes.NewReader(bytes.Repeat([]byte("a"), 16)), 16)
+	data, err = io.ReadAll(limited)
+	require.NoError(t, err)
+	assert.False(t, limited.exceeded())
+	assert.Len(t, data, 16)
+}
+
+func TestAddOrUpdateObject_Size(t *testing.T) {
+	for _, size := range []int{0, 1, 1024, 1 << 20} {
+		t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) {
+			service, _, _ := newTestService(t, 1)
+
+			result, err := service.AddOrUpdateObject(context.Background(), "object", newTestFile(bytes.Repeat([]byte("a"), size)), UploadOptions{})
+			require.NoError(t, err)
+			assert.EqualValues(t, size, result.Size)
+		})
+	}
+}
+
+func TestCountingWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	counter := &countingWriter{w: buffer}
+
+	_, err := io.Copy(counter, bytes.NewReader([]byte("hello")))
+	require.NoError(t, err)
+	_, err = counter.Write([]byte(" world"))
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 11, counter.n)
+	assert.Equal(t, "hello world", buffer.String())
+}
@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+// Validate checks that the service is configured with a valid bucket name, discovery service and shard strategy
+func (s *ServiceV1) Validate(ctx context.Context) []error {
+	var errs []error
+
+	// Fail fast, as Minio rejects an invalid bucket name only on its first use
+	err := s3.ValidateBucketName(s.s3Options.Bucket)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.shardStrategy == nil {
+		errs = append(errs, errors.New("no shard strategy configured"))
+	}
+
+	if s.discoveryService == nil {
+		return append(errs, errors.New("no discovery service configured"))
+	}
+
+	if validator, ok := s.discoveryService.(discovery.Validator); ok {
+		for _, err := range validator.Validate(ctx) {
+			errs = append(errs, errors.Wrap(err, "invalid discovery"))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_BucketName(t *testing.T) {
+	discoveryService := discoverytest.NewService(discoverytest.Instances(1)...)
+
+	service := NewServiceV1WithOptions(discoveryService, s3.Options{Bucket: "Invalid_Bucket"})
+	t.Cleanup(func() { _ = service.Close() })
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], s3.ErrInvalidBucketName)
+
+	// The default bucket is valid
+	service = NewServiceV1WithOptions(discoveryService, s3.Options{})
+	t.Cleanup(func() { _ = service.Close() })
+	assert.Empty(t, service.Validate(context.Background()))
+}
+
+// invalidDiscovery is a discovery service failing its validation
+type invalidDiscovery struct {
+	*discoverytest.Service
+	errs []error
+}
+
+func (d invalidDiscovery) Validate(context.Context) []error {
+	return d.errs
+}
+
+func TestValidate_ShardStrategy(t *testing.T) {
+	service, _, _ := newTestService(t, 1)
+	service.shardStrategy = nil
+
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "no shard strategy configured")
+}
+
+func TestValidate_NoDiscoveryService(t *testing.T) {
+	service, _, _ := newTestService(t, 1)
+	service.discoveryService = nil
+
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "no discovery service configured")
+}
+
+func TestValidate_InvalidDiscovery(t *testing.T) {
+	discoveryService := invalidDiscovery{
+		Service: discoverytest.NewService(discoverytest.Instances(1)...),
+		errs:    []error{errors.New("failed to ping the Docker daemon"), errors.New("no S3 instances found")},
+	}
+
+	service := NewServiceV1WithOptions(discoveryService, s3.Options{Bucket: "Invalid_Bucket"})
+	t.Cleanup(func() { _ = service.Close() })
+
+	// All errors are reported at once
+	errs := service.Validate(context.Background())
+	require.Len(t, errs, 3)
+	assert.ErrorIs(t, errs[0], s3.ErrInvalidBucketName)
+	assert.EqualError(t, errs[1], "invalid discovery: failed to ping the Docker daemon")
+	assert.EqualError(t, errs[2], "invalid discovery: no S3 instances found")
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// GetObjectVersion fetches a version of the object from its instance. Requires a versioned bucket.
+func (s *ServiceV1) GetObjectVersion(ctx context.Context, objectId, versionId string) (io.Reader, error) {
+	s.logger.Info("Getting object version from S3", zap.String("objectId", objectId), zap.String("versionId", versionId))
+
+	_, client, err := s.shardedClient(ctx, objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetObjectVersion(ctx, objectId, versionId)
+}
+
+// StatObjectVersion fetches the metadata of a version of the object from its instance. Requires a versioned bucket.
+func (s *ServiceV1) StatObjectVersion(ctx context.Context, objectId, versionId string) (*s3.ObjectInfo, error) {
+	s.logger.Info("Getting object version metadata from S3", zap.String("objectId", objectId), zap.String("versionId", versionId))
+
+	_, client, err := s.shardedClient(ctx, objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.StatObjectVersion(ctx, objectId, versionId)
+}
+
+// DeleteObjectVersion permanently deletes a version of the object. Requires a versioned bucket.
+func (s *ServiceV1) DeleteObjectVersion(ctx context.Context, objectId, versionId string) error {
+	s.logger.Info("Deleting object version from S3", zap.String("objectId", objectId), zap.String("versionId", versionId))
+	// The deleted version may be the latest one
+	defer s.readCache.Remove(objectId)
+
+	instance, client, err := s.shardedClient(ctx, objectId)
+	if err != nil {
+		return err
+	}
+
+	err = client.DeleteObjectVersion(ctx, objectId, versionId)
+	s.audit(ctx, "delete-version", objectId, instance.InstanceNum, err)
+	return err
+}
+
+// ListObjectVersions lists the versions of the object, the latest first. Returns s3.ErrObjectNotFound if the object has
+// no versions.
+func (s *ServiceV1) ListObjectVersions(ctx context.Context, objectId string) ([]s3.ObjectVersion, error) {
+	s.logger.Info("Listing object versions", zap.String("objectId", objectId))
+
+	_, client, err := s.shardedClient(ctx, objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := client.ListObjectVersions(ctx, objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return nil, s3.ErrObjectNotFound
+	}
+
+	return versions, nil
+}
+
+// shardedClient returns the instance the object is sharded to and its client
+func (s *ServiceV1) shardedClient(ctx context.Context, objectId string) (*discovery.S3Instance, s3.Client, error) {
+	instance, err := s.shardObjectToInstance(ctx, objectId)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to assign object to instance")
+	}
+
+	// Minio client must be dynamically created, based on the S3 instance
+	client, err := s.clientFactory(*instance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return instance, client, nil
+}
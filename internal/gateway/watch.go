@@ -0,0 +1,38 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"go.uber.org/zap"
+)
+
+// WatchInstances subscribes to the instance set changes and rebuilds the sharding state, if the shard strategy keeps one.
+// Membership changes are passed to the instance set hooks and trigger the rebalance, if enabled.
+// Blocks until the context is cancelled.
+func (s *ServiceV1) WatchInstances(ctx context.Context) {
+	updates, err := s.discoveryService.Watch(ctx)
+	if err != nil {
+		s.logger.Error("Failed to watch the instance set", zap.Error(err))
+		return
+	}
+
+	strategy, rebuildable := s.shardStrategy.(RebuildableShardStrategy)
+	var previous []discovery.S3Instance
+	for instances := range updates {
+		s.logger.Info("Instance set changed", zap.Int("instances", len(instances)))
+
+		if rebuildable {
+			strategy.Rebuild(instances)
+		}
+
+		// The first set is the initial state, not a change
+		if previous != nil {
+			event := instanceSetDiff(previous, instances)
+			if len(event.Added) > 0 || len(event.Removed) > 0 {
+				s.onInstanceSetChanged(ctx, event)
+			}
+		}
+		previous = instances
+	}
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery/discoverytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchInstances_RebuildsRing(t *testing.T) {
+	strategy := &WeightedHashShardStrategy{}
+	service, discoveryService, _ := newTestService(t, 3, WithShardStrategy(strategy))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.WatchInstances(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	ringKeyOf := func() string {
+		strategy.mu.Lock()
+		defer strategy.mu.Unlock()
+		return strategy.key
+	}
+
+	// The ring is built for the initial set
+	require.Eventually(t, func() bool { return ringKeyOf() == "1:1,2:1,3:1" }, 5*time.Second, 10*time.Millisecond)
+
+	// Added
+	discoveryService.SetInstances(discoverytest.Instances(4)...)
+	require.Eventually(t, func() bool { return ringKeyOf() == "1:1,2:1,3:1,4:1" }, 5*time.Second, 10*time.Millisecond)
+
+	// Removed and reweighted
+	weighted := discoverytest.Instance(2)
+	weighted.WeightedCapacity = 3
+	discoveryService.SetInstances(discoverytest.Instance(1), weighted)
+	require.Eventually(t, func() bool { return ringKeyOf() == "1:1,2:3" }, 5*time.Second, 10*time.Millisecond)
+
+	// The rebuilt ring shards only to the remaining instances
+	instances := []int{}
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		instance, err := strategy.Shard(id, []discovery.S3Instance{discoverytest.Instance(1), weighted})
+		require.NoError(t, err)
+		instances = append(instances, instance.InstanceNum)
+	}
+	assert.Subset(t, []int{1, 2}, instances)
+}
+
+func TestWatchInstances_StopsOnCancel(t *testing.T) {
+	service, _, _ := newTestService(t, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.WatchInstances(ctx)
+	}()
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not stopped")
+	}
+}
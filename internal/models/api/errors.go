@@ -0,0 +1,55 @@
+package api
+
+// ErrorCode is a stable, machine-readable identifier of an error. Clients should branch on the code, not on the message.
+//
+//	Code                           Status  Meaning
+//	INVALID_REQUEST                400     The request body, header or parameter is malformed
+//	INVALID_OBJECT_ID              400     The object ID is not alphanumeric or is longer than 32 characters
+//	MISSING_FILE_FIELD             400     The upload form has no file in the field
+//	INVALID_FILENAME               400     The filename of the upload is empty or has no extension
+//	CHECKSUM_MISMATCH              400     The uploaded file does not match the Content-MD5 header
+//	UNAUTHORIZED                   401     The credentials are missing or invalid
+//	FORBIDDEN                      403     The credentials are not valid for this API
+//	EXPORT_TARGET_NOT_ALLOWED      403     The export target endpoint is not one of the allowed export endpoints
+//	OBJECT_NOT_FOUND               404     The object does not exist
+//	INSTANCE_NOT_FOUND             404     The requested S3 instance does not exist
+//	EXPORT_JOB_NOT_FOUND           404     The export job does not exist
+//	OBJECT_ALREADY_EXISTS          409     The object already exists in the target instance
+//	WRITE_IN_PROGRESS              409     The object is already being written by another request
+//	BULK_LIMIT_EXCEEDED            422     More objects match the bulk operation than are allowed at once
+//	MULTIPLE_FILES                 422     The upload form has more than one file in the field
+//	IDEMPOTENCY_KEY_REUSED         422     The Idempotency-Key was used for an upload of a different file
+//	OBJECT_TOO_LARGE               413     The uploaded object exceeds the size limit
+//	UNSUPPORTED_MEDIA_TYPE         415     The content type of the upload is not allowed
+//	INTERNAL_ERROR                 500     An unexpected error occurred
+//	INSTANCE_CREDENTIALS_REJECTED  502     The S3 instance rejected the credentials of the gateway, e.g. stale after a rotation
+//	INSTANCE_UNAVAILABLE           503     No S3 instance is available to serve the object
+//	TIMEOUT                        503     The request timed out
+//	QUOTA_EXCEEDED                 507     The upload does not fit into the quota
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest              ErrorCode = "INVALID_REQUEST"
+	ErrorCodeInvalidObjectId             ErrorCode = "INVALID_OBJECT_ID"
+	ErrorCodeMissingFileField            ErrorCode = "MISSING_FILE_FIELD"
+	ErrorCodeInvalidFilename             ErrorCode = "INVALID_FILENAME"
+	ErrorCodeMultipleFiles               ErrorCode = "MULTIPLE_FILES"
+	ErrorCodeIdempotencyKeyReused        ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	ErrorCodeChecksumMismatch            ErrorCode = "CHECKSUM_MISMATCH"
+	ErrorCodeUnauthorized                ErrorCode = "UNAUTHORIZED"
+	ErrorCodeForbidden                   ErrorCode = "FORBIDDEN"
+	ErrorCodeExportTargetNotAllowed      ErrorCode = "EXPORT_TARGET_NOT_ALLOWED"
+	ErrorCodeObjectNotFound              ErrorCode = "OBJECT_NOT_FOUND"
+	ErrorCodeInstanceNotFound            ErrorCode = "INSTANCE_NOT_FOUND"
+	ErrorCodeExportJobNotFound           ErrorCode = "EXPORT_JOB_NOT_FOUND"
+	ErrorCodeObjectAlreadyExists         ErrorCode = "OBJECT_ALREADY_EXISTS"
+	ErrorCodeWriteInProgress             ErrorCode = "WRITE_IN_PROGRESS"
+	ErrorCodeBulkLimitExceeded           ErrorCode = "BULK_LIMIT_EXCEEDED"
+	ErrorCodeObjectTooLarge              ErrorCode = "OBJECT_TOO_LARGE"
+	ErrorCodeUnsupportedMediaType        ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorCodeInternalError               ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeInstanceUnavailable         ErrorCode = "INSTANCE_UNAVAILABLE"
+	ErrorCodeInstanceCredentialsRejected ErrorCode = "INSTANCE_CREDENTIALS_REJECTED"
+	ErrorCodeTimeout                     ErrorCode = "TIMEOUT"
+	ErrorCodeQuotaExceeded               ErrorCode = "QUOTA_EXCEEDED"
+)
@@ -0,0 +1,25 @@
+package api
+
+type MigrateRequest struct {
+	ObjectId       string `json:"objectId"`
+	TargetInstance int    `json:"targetInstance"`
+}
+
+type ExportRequest struct {
+	Endpoint  string `json:"endpoint"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+type BulkDeleteRequest struct {
+	Prefix string `json:"prefix"`
+}
+
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+type RenameRequest struct {
+	NewId string `json:"new_id"`
+}
@@ -1,6 +1,119 @@
 package api
 
+import "time"
+
 type ErrorResponse struct {
-	InternalCode int    `json:"code"`
-	Message      string `json:"message"`
+	// Code is set on errors only
+	Code    ErrorCode `json:"error_code,omitempty"`
+	Message string    `json:"message"`
+	// Field is the invalid request field, if the error concerns one
+	Field string `json:"field,omitempty"`
+}
+
+type UploadResponse struct {
+	Id       string `json:"id"`
+	Instance int    `json:"instance"`
+	ETag     string `json:"etag"`
+	Size     int64  `json:"size"`
+}
+
+type MigrateResponse struct {
+	ObjectId       string `json:"objectId"`
+	SourceInstance int    `json:"sourceInstance"`
+	TargetInstance int    `json:"targetInstance"`
+	// DryRun is set if the object was only checked, not moved
+	DryRun bool `json:"dryRun"`
+}
+
+type ExportJobResponse struct {
+	JobId    string `json:"jobId"`
+	ObjectId string `json:"objectId"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Ready bool `json:"ready"`
+	// Docker is set when the instances are discovered from a Docker daemon
+	Docker *DockerDaemonResponse `json:"docker,omitempty"`
+	// Discovery is set when the discovery backend reports its state
+	Discovery *DiscoveryStatusResponse `json:"discovery,omitempty"`
+	// Skipped lists the containers left out of the last discovery
+	Skipped []SkippedInstanceResponse `json:"skipped,omitempty"`
+}
+
+type DiscoveryStatusResponse struct {
+	DaemonReachable bool       `json:"daemonReachable"`
+	LastDiscovery   *time.Time `json:"lastDiscovery,omitempty"`
+	InstanceCount   int        `json:"instanceCount"`
+	LastError       string     `json:"lastError,omitempty"`
+	LastErrorAt     *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+type SkippedInstanceResponse struct {
+	ContainerId string `json:"containerId"`
+	Reason      string `json:"reason"`
+}
+
+type DockerDaemonResponse struct {
+	Host          string `json:"host"`
+	APIVersion    string `json:"apiVersion"`
+	ServerVersion string `json:"serverVersion"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+}
+
+type ObjectSummaryResponse struct {
+	Id           string    `json:"id"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	ETag         string    `json:"etag"`
+}
+
+type ObjectVersionResponse struct {
+	VersionId    string    `json:"versionId"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	ETag         string    `json:"etag"`
+	IsLatest     bool      `json:"isLatest"`
+	// IsDeleteMarker is set for the versions recording a deletion
+	IsDeleteMarker bool `json:"isDeleteMarker"`
+}
+
+type ObjectEvent struct {
+	// Event is "created" (also on overwrites) or "deleted"
+	Event    string `json:"event"`
+	ObjectId string `json:"object_id"`
+}
+
+type InstanceResponse struct {
+	Instance int    `json:"instance"`
+	Hostname string `json:"hostname"`
+	Port     string `json:"port"`
+	// Healthy is omitted if the health was not checked
+	Healthy *bool `json:"healthy,omitempty"`
+	// Version is the Minio release, omitted if unknown
+	Version string `json:"version,omitempty"`
+}
+
+type ClientCreationRateResponse struct {
+	// PerSecond is the number of S3 clients created in the last second
+	PerSecond int64 `json:"perSecond"`
+}
+
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+type ObjectCountResponse struct {
+	Total int `json:"total"`
+	// Instances is the number of objects per instance number, if requested
+	Instances map[string]int `json:"instances,omitempty"`
+}
+
+type BulkDeleteResponse struct {
+	Prefix string `json:"prefix"`
+	// Deleted is the number of deleted objects, or the number of matching objects on a dry run
+	Deleted int  `json:"deleted"`
+	DryRun  bool `json:"dryRun"`
 }
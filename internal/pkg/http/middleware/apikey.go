@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+)
+
+// APIKeyHeader is the header holding the API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyMiddleware authenticates the requests with a static API key. If no API key is configured, all requests are rejected.
+func APIKeyMiddleware(apiKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		providedKey := c.Get(APIKeyHeader)
+
+		if apiKey == "" || subtle.ConstantTimeCompare([]byte(providedKey), []byte(apiKey)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeUnauthorized,
+				Message: "Invalid API key",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+)
+
+// ValidateFileContentType checks the content type of the uploaded file against the allowlist and the denylist.
+// Both lists accept exact MIME types and wildcards such as "image/*". An empty allowlist allows all types not denied.
+// Rejected files are answered with 415 Unsupported Media Type.
+func ValidateFileContentType(formField string, allowlist, denylist []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		file, err := c.FormFile(formField)
+		if err != nil {
+			return err
+		}
+
+		contentType, allowed := IsAllowedContentType(file.Header.Get(fiber.HeaderContentType), allowlist, denylist)
+		if !allowed {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeUnsupportedMediaType,
+				Message: "Unsupported content type " + contentType,
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// IsAllowedContentType checks the Content-Type header against the allowlist and the denylist, as
+// ValidateFileContentType does. Returns the media type without the parameters, which is
+// application/octet-stream if the header is missing or malformed.
+func IsAllowedContentType(header string, allowlist, denylist []string) (string, bool) {
+	// Parameters like the charset are not part of the type
+	contentType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		contentType = "application/octet-stream"
+	}
+
+	return contentType, !matchesMimeType(contentType, denylist) && (len(allowlist) == 0 || matchesMimeType(contentType, allowlist))
+}
+
+// matchesMimeType checks if the content type matches any of the MIME types
+func matchesMimeType(contentType string, mimeTypes []string) bool {
+	for _, mimeType := range mimeTypes {
+		mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+
+		if prefix, isWildcard := strings.CutSuffix(mimeType, "/*"); isWildcard {
+			if strings.HasPrefix(contentType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+
+		if contentType == mimeType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAllowedContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		allowlist   []string
+		denylist    []string
+		contentType string
+		allowed     bool
+	}{
+		{name: "no lists", header: "application/x-msdownload", contentType: "application/x-msdownload", allowed: true},
+		{name: "allowed", header: "image/png", allowlist: []string{"image/png", "text/plain"}, contentType: "image/png", allowed: true},
+		{name: "not allowed", header: "application/pdf", allowlist: []string{"image/png"}, contentType: "application/pdf"},
+		{name: "allowed wildcard", header: "image/jpeg", allowlist: []string{"image/*"}, contentType: "image/jpeg", allowed: true},
+		{name: "denied", header: "application/x-msdownload", denylist: []string{"application/x-msdownload"}, contentType: "application/x-msdownload"},
+		{name: "denied wildcard", header: "video/mp4", denylist: []string{"video/*"}, contentType: "video/mp4"},
+		{name: "denied over allowed", header: "image/svg+xml", allowlist: []string{"image/*"}, denylist: []string{"image/svg+xml"}, contentType: "image/svg+xml"},
+		{name: "parameters and case", header: "Text/Plain; charset=utf-8", allowlist: []string{" TEXT/PLAIN "}, contentType: "text/plain", allowed: true},
+		{name: "missing header", header: "", denylist: []string{"application/octet-stream"}, contentType: "application/octet-stream"},
+		{name: "malformed header", header: "not a type", allowlist: []string{"application/octet-stream"}, contentType: "application/octet-stream", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, allowed := IsAllowedContentType(tt.header, tt.allowlist, tt.denylist)
+			assert.Equal(t, tt.contentType, contentType)
+			assert.Equal(t, tt.allowed, allowed)
+		})
+	}
+}
+
+// newFileRequest creates a multipart request uploading a file of the content type
+func newFileRequest(t *testing.T, contentType string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="file"`)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	require.NoError(t, err)
+	_, err = part.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/", body)
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+func TestValidateFileContentType(t *testing.T) {
+	app := fiber.New()
+	app.Put("/", ValidateFileContentType("file", []string{"image/*", "text/plain"}, []string{"image/svg+xml"}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	for contentType, status := range map[string]int{
+		"image/png":                fiber.StatusCreated,
+		"text/plain":               fiber.StatusCreated,
+		"image/svg+xml":            fiber.StatusUnsupportedMediaType,
+		"application/x-msdownload": fiber.StatusUnsupportedMediaType,
+	} {
+		res, err := app.Test(newFileRequest(t, contentType))
+		require.NoError(t, err)
+		assert.Equal(t, status, res.StatusCode, contentType)
+	}
+}
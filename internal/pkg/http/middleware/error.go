@@ -20,7 +20,28 @@ func FiberErrorHandler() func(ctx *fiber.Ctx, err error) error {
 
 		// Return from handler
 		return ctx.Status(code).JSON(api.ErrorResponse{
+			Code:    errorCode(code),
 			Message: err.Error(),
 		})
 	}
 }
+
+// errorCode maps the status code of an unhandled error to an error code
+func errorCode(status int) api.ErrorCode {
+	switch status {
+	case fiber.StatusBadRequest:
+		return api.ErrorCodeInvalidRequest
+	case fiber.StatusRequestEntityTooLarge:
+		return api.ErrorCodeObjectTooLarge
+	case fiber.StatusUnauthorized:
+		return api.ErrorCodeUnauthorized
+	case fiber.StatusForbidden:
+		return api.ErrorCodeForbidden
+	case fiber.StatusUnsupportedMediaType:
+		return api.ErrorCodeUnsupportedMediaType
+	case fiber.StatusRequestTimeout, fiber.StatusServiceUnavailable:
+		return api.ErrorCodeTimeout
+	default:
+		return api.ErrorCodeInternalError
+	}
+}
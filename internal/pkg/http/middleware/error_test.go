@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiberErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   api.ErrorCode
+	}{
+		{name: "bad request", err: fiber.ErrBadRequest, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+		{name: "too large", err: fiber.ErrRequestEntityTooLarge, status: fiber.StatusRequestEntityTooLarge, code: api.ErrorCodeObjectTooLarge},
+		{name: "unauthorized", err: fiber.ErrUnauthorized, status: fiber.StatusUnauthorized, code: api.ErrorCodeUnauthorized},
+		{name: "forbidden", err: fiber.ErrForbidden, status: fiber.StatusForbidden, code: api.ErrorCodeForbidden},
+		{name: "unsupported media type", err: fiber.ErrUnsupportedMediaType, status: fiber.StatusUnsupportedMediaType, code: api.ErrorCodeUnsupportedMediaType},
+		{name: "request timeout", err: fiber.ErrRequestTimeout, status: fiber.StatusRequestTimeout, code: api.ErrorCodeTimeout},
+		{name: "service unavailable", err: fiber.ErrServiceUnavailable, status: fiber.StatusServiceUnavailable, code: api.ErrorCodeTimeout},
+		{name: "wrapped", err: errors.Wrap(fiber.ErrUnauthorized, "token expired"), status: fiber.StatusUnauthorized, code: api.ErrorCodeUnauthorized},
+		{name: "unexpected", err: errors.New("unexpected"), status: fiber.StatusInternalServerError, code: api.ErrorCodeInternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: FiberErrorHandler()})
+			app.Get("/", func(c *fiber.Ctx) error {
+				return tt.err
+			})
+
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			response := map[string]any{}
+			require.NoError(t, json.Unmarshal(body, &response))
+			assert.Equal(t, string(tt.code), response["error_code"])
+			assert.Equal(t, tt.err.Error(), response["message"])
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const jwksCacheTTL = time.Minute * 5
+
+// jwksRefetchInterval is the minimum age of the cached JWKS refetched for an unknown key ID, so the tokens with made up
+// key IDs can't make every request fetch the JWKS
+const jwksRefetchInterval = time.Second * 15
+
+// jwksFetchTimeout bounds the fetch of the JWKS, so a hung JWKS endpoint doesn't hang the authenticated requests
+const jwksFetchTimeout = time.Second * 10
+
+// jwksMethods are the signing methods of the JWKS public keys. The symmetric methods and "none" are rejected, so the
+// public key can't be used as an HMAC secret.
+var jwksMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// SubjectKey is the fiber.Ctx.Locals key the authenticated subject is stored under, recorded in the audit events
+const SubjectKey = "subject"
+
+type jwtConfig struct {
+	issuer   string
+	audience string
+}
+
+// JWTOption configures the JWT middleware
+type JWTOption func(*jwtConfig)
+
+// WithIssuer requires the token to be issued by the given issuer
+func WithIssuer(issuer string) JWTOption {
+	return func(c *jwtConfig) {
+		c.issuer = issuer
+	}
+}
+
+// WithAudience requires the token to be issued for the given audience
+func WithAudience(audience string) JWTOption {
+	return func(c *jwtConfig) {
+		c.audience = audience
+	}
+}
+
+// JWTMiddleware authenticates the requests with a bearer JWT, verified with the keys from the JWKS URL.
+// Invalid or expired tokens are rejected with 401, valid tokens without the required audience with 403.
+// The sub claim is stored in the context locals under SubjectKey.
+func JWTMiddleware(jwksURL string, options ...JWTOption) fiber.Handler {
+	config := jwtConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+
+	keys := newJWKSCache(jwksURL, jwksFetchTimeout)
+	logger := zap.L().Named("jwt")
+
+	parserOptions := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithValidMethods(jwksMethods)}
+	if config.issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(config.issuer))
+	}
+	parser := jwt.NewParser(parserOptions...)
+
+	return func(c *fiber.Ctx) error {
+		tokenString, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found || tokenString == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeUnauthorized,
+				Message: "Missing bearer token",
+			})
+		}
+
+		token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			keyId, _ := token.Header["kid"].(string)
+			return keys.key(c.Context(), keyId, token.Method.Alg())
+		})
+		if err != nil {
+			logger.Debug("Rejected token", zap.Error(err))
+			return c.Status(fiber.StatusUnauthorized).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeUnauthorized,
+				Message: "Invalid token",
+			})
+		}
+
+		if config.audience != "" {
+			audience, err := token.Claims.GetAudience()
+			if err != nil || !slices.Contains(audience, config.audience) {
+				return c.Status(fiber.StatusForbidden).JSON(api.ErrorResponse{
+					Code:    api.ErrorCodeForbidden,
+					Message: "Token is not valid for this audience",
+				})
+			}
+		}
+
+		subject, err := token.Claims.GetSubject()
+		if err == nil {
+			c.Locals(SubjectKey, subject)
+		}
+
+		return c.Next()
+	}
+}
+
+// jwksCache fetches the JWKS and caches it for jwksCacheTTL. An unknown key ID refetches the JWKS cached for more than
+// jwksRefetchInterval, as the keys may have been rotated since.
+type jwksCache struct {
+	url          string
+	fetchTimeout time.Duration
+	// fetches shares a fetch of the JWKS between the concurrent requests
+	fetches   singleflight.Group
+	mu        sync.Mutex
+	set       jwk.Set
+	fetchedAt time.Time
+}
+
+func newJWKSCache(url string, fetchTimeout time.Duration) *jwksCache {
+	return &jwksCache{url: url, fetchTimeout: fetchTimeout}
+}
+
+// key returns the raw public key with the given key ID, if the token signing method is the algorithm of the key.
+// The keys without an algorithm are checked by their type when verifying the signature.
+func (j *jwksCache) key(ctx context.Context, keyId, method string) (interface{}, error) {
+	set, err := j.keySet(ctx, jwksCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := set.LookupKeyID(keyId)
+	if !ok {
+		set, err = j.keySet(ctx, jwksRefetchInterval)
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok = set.LookupKeyID(keyId)
+		if !ok {
+			return nil, errors.Errorf("key %s not found in JWKS", keyId)
+		}
+	}
+
+	if algorithm := key.Algorithm().String(); algorithm != "" && algorithm != method {
+		return nil, errors.Errorf("key %s is for the %s algorithm, not %s", keyId, algorithm, method)
+	}
+
+	var raw interface{}
+	err = key.Raw(&raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get raw key")
+	}
+
+	return raw, nil
+}
+
+// keySet returns the cached JWKS, fetching it if it was cached for longer than the maximum age. The cache is not
+// locked during the fetch, so the requests verified with the cached keys don't wait for it.
+func (j *jwksCache) keySet(ctx context.Context, maxAge time.Duration) (jwk.Set, error) {
+	j.mu.Lock()
+	set, fetchedAt := j.set, j.fetchedAt
+	j.mu.Unlock()
+
+	if set != nil && time.Since(fetchedAt) < maxAge {
+		return set, nil
+	}
+
+	fetched, err, _ := j.fetches.Do(j.url, func() (interface{}, error) {
+		// The fetch is shared, so it is not canceled with the request starting it
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), j.fetchTimeout)
+		defer cancel()
+
+		set, err := jwk.Fetch(fetchCtx, j.url)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch JWKS")
+		}
+
+		j.mu.Lock()
+		j.set = set
+		j.fetchedAt = time.Now()
+		j.mu.Unlock()
+		return set, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fetched.(jwk.Set), nil
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newJWKS serves the public key of the private key as a JWKS with the key ID and the algorithm
+func newJWKS(t *testing.T, privateKey *rsa.PrivateKey, keyId string, algorithm jwa.SignatureAlgorithm) string {
+	t.Helper()
+
+	key, err := jwk.FromRaw(&privateKey.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, keyId))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, algorithm))
+
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(key))
+	body, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(JWTMiddleware(newJWKS(t, privateKey, "key", jwa.RS256), WithAudience("gateway")))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals(SubjectKey).(string))
+	})
+
+	claims := jwt.MapClaims{"sub": "client", "aud": "gateway", "exp": time.Now().Add(time.Minute).Unix()}
+	sign := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = "key"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	// The public key used as the HMAC secret, as an attacker knowing it would
+	publicKeyBytes, err := json.Marshal(privateKey.PublicKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "valid", token: sign(jwt.SigningMethodRS256, privateKey, claims), status: http.StatusOK},
+		{name: "other method than the key's", token: sign(jwt.SigningMethodRS512, privateKey, claims), status: http.StatusUnauthorized},
+		{name: "symmetric method", token: sign(jwt.SigningMethodHS256, publicKeyBytes, claims), status: http.StatusUnauthorized},
+		{name: "none method", token: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims), status: http.StatusUnauthorized},
+		{name: "expired", token: sign(jwt.SigningMethodRS256, privateKey, jwt.MapClaims{"sub": "client", "aud": "gateway", "exp": time.Now().Add(-time.Minute).Unix()}), status: http.StatusUnauthorized},
+		{name: "other audience", token: sign(jwt.SigningMethodRS256, privateKey, jwt.MapClaims{"sub": "client", "aud": "other", "exp": time.Now().Add(time.Minute).Unix()}), status: http.StatusForbidden},
+		{name: "missing", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tt.token)
+			}
+
+			res, err := app.Test(req, -1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.StatusCode)
+		})
+	}
+}
+
+// rotatingJWKS serves the public keys of the private keys in the JWKS, counting its fetches
+type rotatingJWKS struct {
+	mu      sync.Mutex
+	body    []byte
+	fetches int
+}
+
+func (r *rotatingJWKS) rotate(t *testing.T, keys map[string]*rsa.PrivateKey) {
+	t.Helper()
+
+	set := jwk.NewSet()
+	for keyId, privateKey := range keys {
+		key, err := jwk.FromRaw(&privateKey.PublicKey)
+		require.NoError(t, err)
+		require.NoError(t, key.Set(jwk.KeyIDKey, keyId))
+		require.NoError(t, set.AddKey(key))
+	}
+	body, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.body = body
+}
+
+func (r *rotatingJWKS) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetches++
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(r.body)
+}
+
+func TestJWKSCache_KeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := &rotatingJWKS{}
+	jwks.rotate(t, map[string]*rsa.PrivateKey{"old": oldKey})
+	server := httptest.NewServer(jwks)
+	t.Cleanup(server.Close)
+
+	keys := newJWKSCache(server.URL, jwksFetchTimeout)
+	ctx := context.Background()
+	_, err = keys.key(ctx, "old", "RS256")
+	require.NoError(t, err)
+
+	// The key is rotated while the JWKS is cached. The unknown key ID doesn't refetch the JWKS just fetched.
+	jwks.rotate(t, map[string]*rsa.PrivateKey{"new": newKey})
+	_, err = keys.key(ctx, "new", "RS256")
+	require.Error(t, err)
+	assert.Equal(t, 1, jwks.fetches)
+
+	// Once the refetch interval passed, the unknown key ID refetches the JWKS, before it expires
+	keys.fetchedAt = time.Now().Add(-jwksRefetchInterval)
+	key, err := keys.key(ctx, "new", "RS256")
+	require.NoError(t, err)
+	assert.Equal(t, &newKey.PublicKey, key)
+	assert.Equal(t, 2, jwks.fetches)
+
+	// The made up key IDs are rate limited as well
+	_, err = keys.key(ctx, "made-up", "RS256")
+	require.Error(t, err)
+	_, err = keys.key(ctx, "new", "RS256")
+	require.NoError(t, err)
+	assert.Equal(t, 2, jwks.fetches)
+}
+
+func TestJWKSCache_HungEndpoint(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := &rotatingJWKS{}
+	jwks.rotate(t, map[string]*rsa.PrivateKey{"key": privateKey})
+	hung := make(chan struct{})
+	fetching := make(chan struct{}, 1)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) == 1 {
+			jwks.ServeHTTP(w, r)
+			return
+		}
+
+		fetching <- struct{}{}
+		select {
+		case <-hung:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(hung) })
+
+	keys := newJWKSCache(server.URL, 500*time.Millisecond)
+	ctx := context.Background()
+	_, err = keys.key(ctx, "key", "RS256")
+	require.NoError(t, err)
+
+	// The refetch for an unknown key ID hangs, the requests verified with the cached key don't wait for it
+	keys.fetchedAt = time.Now().Add(-jwksRefetchInterval)
+	refetched := make(chan error, 1)
+	go func() {
+		_, err := keys.key(ctx, "rotated", "RS256")
+		refetched <- err
+	}()
+	<-fetching
+
+	start := time.Now()
+	_, err = keys.key(ctx, "key", "RS256")
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+
+	// The hung fetch is bounded by the timeout
+	select {
+	case err := <-refetched:
+		assert.ErrorContains(t, err, "failed to fetch JWKS")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fetch of the JWKS was not bounded")
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/contrib/fiberzap/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"go.uber.org/zap"
+)
+
+const requestStartKey = "requestStart"
+
+// RequestIDMiddleware reuses the X-Request-ID header of the request or generates a new one, and sets it on the response
+func RequestIDMiddleware() fiber.Handler {
+	return requestid.New(requestid.Config{
+		Header: fiber.HeaderXRequestID,
+	})
+}
+
+// RequestLogger logs every request with the request and response body sizes, the duration, the client IP and
+// the request ID. The RequestIDMiddleware and the TrustProxies middleware must run before the logger.
+func RequestLogger(logger *zap.Logger) fiber.Handler {
+	config := fiberzap.ConfigDefault
+	config.Logger = logger
+	config.Fields = []string{"requestId"}
+	for _, field := range fiberzap.ConfigDefault.Fields {
+		// The client IP is logged by requestLogFields, as the remote address may be a proxy
+		if field != "ip" {
+			config.Fields = append(config.Fields, field)
+		}
+	}
+	config.FieldsFunc = requestLogFields
+
+	logHandler := fiberzap.New(config)
+	return func(c *fiber.Ctx) error {
+		c.Locals(requestStartKey, time.Now())
+		return logHandler(c)
+	}
+}
+
+// requestLogFields returns the body sizes and the duration of the request
+func requestLogFields(c *fiber.Ctx) []zap.Field {
+	// Prefer the Content-Length header - the body of a streamed request is not buffered
+	requestBytes := int64(c.Request().Header.ContentLength())
+	if requestBytes < 0 {
+		requestBytes = int64(len(c.Request().Body()))
+	}
+
+	// Reading the body of a streamed response would consume it - use the Content-Length instead, which is -1 if unknown
+	responseBytes := int64(c.Response().Header.ContentLength())
+	if !c.Response().IsBodyStream() {
+		responseBytes = int64(len(c.Response().Body()))
+	}
+
+	fields := []zap.Field{
+		zap.String("ip", ClientIP(c)),
+		zap.Int64("request_body_bytes", requestBytes),
+		zap.Int64("response_body_bytes", responseBytes),
+	}
+
+	if start, ok := c.Locals(requestStartKey).(time.Time); ok {
+		fields = append(fields, zap.Int64("duration_ms", time.Since(start).Milliseconds()))
+	}
+
+	return fields
+}
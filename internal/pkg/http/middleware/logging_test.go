@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	app := fiber.New()
+	app.Use(RequestIDMiddleware(), RequestLogger(zap.New(core)))
+	app.Put("/", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusCreated).SendString("created")
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(make([]byte, 1234)))
+	req.Header.Set(fiber.HeaderXRequestID, "request-1")
+	res, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, res.StatusCode)
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.EqualValues(t, 1234, fields["request_body_bytes"])
+	assert.EqualValues(t, len("created"), fields["response_body_bytes"])
+	assert.Contains(t, fields, "duration_ms")
+	assert.Equal(t, "request-1", fields["requestId"])
+	assert.EqualValues(t, fiber.StatusCreated, fields["status"])
+}
+
+func TestRequestLogger_GeneratedRequestId(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	app := fiber.New()
+	app.Use(RequestIDMiddleware(), RequestLogger(zap.New(core)))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(t, err)
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.EqualValues(t, 0, fields["request_body_bytes"])
+	assert.NotEmpty(t, fields["requestId"])
+	assert.Equal(t, res.Header.Get(fiber.HeaderXRequestID), fields["requestId"])
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+)
+
+// ValidateMultipartFile checks the multipart form contains exactly one file in the field, with a filename that has
+// an extension. Only the part headers are parsed, the files are skipped without being buffered.
+// A missing file is answered with 400 Bad Request, multiple files with 422 Unprocessable Entity.
+func ValidateMultipartFile(fieldName string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		_, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+		if err != nil || params["boundary"] == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeInvalidRequest,
+				Message: "Missing multipart boundary",
+			})
+		}
+
+		filenames := []string{}
+		reader := multipart.NewReader(bytes.NewReader(c.Body()), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{
+					Code:    api.ErrorCodeInvalidRequest,
+					Message: "Malformed multipart form",
+				})
+			}
+
+			// Form values with the same name are not files
+			if part.FormName() == fieldName && part.FileName() != "" {
+				filenames = append(filenames, part.FileName())
+			}
+		}
+
+		switch {
+		case len(filenames) == 0:
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeMissingFileField,
+				Message: fmt.Sprintf("Missing file in the %s field", fieldName),
+				Field:   fieldName,
+			})
+		case len(filenames) > 1:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeMultipleFiles,
+				Message: fmt.Sprintf("Expected one file in the %s field, got %d", fieldName, len(filenames)),
+				Field:   fieldName,
+			})
+		}
+
+		// The extension must not be just the dot
+		if len(filepath.Ext(filenames[0])) < 2 {
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeInvalidFilename,
+				Message: "The filename must have an extension",
+				Field:   fieldName,
+			})
+		}
+
+		return c.Next()
+	}
+}
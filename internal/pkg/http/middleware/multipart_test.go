@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formPart is a part of the multipart form. The part is a file if the filename is set.
+type formPart struct {
+	name     string
+	filename string
+}
+
+// newFormRequest creates a multipart request with the parts
+func newFormRequest(t *testing.T, parts ...formPart) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, part := range parts {
+		var w io.Writer
+		var err error
+		if part.filename != "" {
+			w, err = writer.CreateFormFile(part.name, part.filename)
+		} else {
+			w, err = writer.CreateFormField(part.name)
+		}
+		require.NoError(t, err)
+		_, err = w.Write([]byte("data"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/", body)
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+func TestValidateMultipartFile(t *testing.T) {
+	noBoundary := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString("data"))
+	noBoundary.Header.Set(fiber.HeaderContentType, "multipart/form-data")
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   api.ErrorCode
+		field  string
+	}{
+		{name: "one file", req: newFormRequest(t, formPart{name: "file", filename: "file.txt"}), status: fiber.StatusCreated},
+		{name: "missing boundary", req: noBoundary, status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidRequest},
+		{name: "missing field", req: newFormRequest(t, formPart{name: "other", filename: "file.txt"}), status: fiber.StatusBadRequest, code: api.ErrorCodeMissingFileField, field: "file"},
+		{name: "value instead of file", req: newFormRequest(t, formPart{name: "file"}), status: fiber.StatusBadRequest, code: api.ErrorCodeMissingFileField, field: "file"},
+		{name: "multiple files", req: newFormRequest(t, formPart{name: "file", filename: "a.txt"}, formPart{name: "file", filename: "b.txt"}), status: fiber.StatusUnprocessableEntity, code: api.ErrorCodeMultipleFiles, field: "file"},
+		{name: "filename without extension", req: newFormRequest(t, formPart{name: "file", filename: "file"}), status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidFilename, field: "file"},
+		{name: "filename ending with the dot", req: newFormRequest(t, formPart{name: "file", filename: "file."}), status: fiber.StatusBadRequest, code: api.ErrorCodeInvalidFilename, field: "file"},
+	}
+
+	app := fiber.New()
+	app.Put("/", ValidateMultipartFile("file"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := app.Test(tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.StatusCode)
+			if tt.code == "" {
+				return
+			}
+
+			// The clients branch on the JSON keys, so they are asserted rather than the decoded response
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			response := map[string]any{}
+			require.NoError(t, json.Unmarshal(body, &response))
+			assert.Equal(t, string(tt.code), response["error_code"], string(body))
+			assert.NotEmpty(t, response["message"])
+			if tt.field != "" {
+				assert.Equal(t, tt.field, response["field"])
+			} else {
+				assert.NotContains(t, response, "field")
+			}
+		})
+	}
+}
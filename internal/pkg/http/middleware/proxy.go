@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// ClientIPKey is the key of the client IP in the request locals
+const ClientIPKey = "clientIP"
+
+// TrustProxies resolves the real client IP of the requests coming through the trusted proxies and stores it in the
+// request locals under the ClientIPKey. When the remote address is in one of the trusted CIDRs, the first public IP
+// from the X-Forwarded-For header is used, falling back to the X-Real-IP header. The headers of requests from
+// untrusted addresses are ignored, so they can't be spoofed. Invalid CIDRs are skipped.
+func TrustProxies(cidrs []string) fiber.Handler {
+	trusted := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			zap.L().Warn("Skipping invalid trusted proxy CIDR", zap.String("cidr", cidr), zap.Error(err))
+			continue
+		}
+
+		trusted = append(trusted, network)
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Locals(ClientIPKey, resolveClientIP(c, trusted))
+		return c.Next()
+	}
+}
+
+// ClientIP returns the client IP resolved by TrustProxies, or the remote address if it didn't run
+func ClientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(ClientIPKey).(string); ok && ip != "" {
+		return ip
+	}
+
+	return c.IP()
+}
+
+// resolveClientIP returns the client IP from the proxy headers if the remote address is a trusted proxy
+func resolveClientIP(c *fiber.Ctx, trusted []*net.IPNet) string {
+	remoteIP := c.Context().RemoteIP()
+	if !containsIP(trusted, remoteIP) {
+		return remoteIP.String()
+	}
+
+	for _, forwarded := range strings.Split(c.Get(fiber.HeaderXForwardedFor), ",") {
+		ip := net.ParseIP(strings.TrimSpace(forwarded))
+		if ip != nil && !ip.IsPrivate() && !ip.IsLoopback() {
+			return ip.String()
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(c.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	return remoteIP.String()
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The requests of fiber.App.Test come from 0.0.0.0
+const testRemoteCIDR = "0.0.0.0/32"
+
+func clientIPOf(t *testing.T, cidrs []string, headers map[string]string) string {
+	t.Helper()
+
+	app := fiber.New()
+	app.Use(TrustProxies(cidrs))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(ClientIP(c))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := app.Test(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestTrustProxies(t *testing.T) {
+	tests := []struct {
+		name     string
+		cidrs    []string
+		headers  map[string]string
+		clientIP string
+	}{
+		{name: "no headers", cidrs: []string{testRemoteCIDR}, clientIP: "0.0.0.0"},
+		{name: "forwarded", cidrs: []string{testRemoteCIDR}, headers: map[string]string{fiber.HeaderXForwardedFor: "203.0.113.7"}, clientIP: "203.0.113.7"},
+		{name: "first public forwarded", cidrs: []string{testRemoteCIDR}, headers: map[string]string{fiber.HeaderXForwardedFor: "10.1.2.3, 192.168.0.1, 198.51.100.4, 203.0.113.7"}, clientIP: "198.51.100.4"},
+		{name: "invalid forwarded", cidrs: []string{testRemoteCIDR}, headers: map[string]string{fiber.HeaderXForwardedFor: "unknown, 127.0.0.1"}, clientIP: "0.0.0.0"},
+		{name: "real IP fallback", cidrs: []string{testRemoteCIDR}, headers: map[string]string{fiber.HeaderXForwardedFor: "10.1.2.3", "X-Real-IP": "198.51.100.4"}, clientIP: "198.51.100.4"},
+		{name: "invalid CIDR skipped", cidrs: []string{"not a cidr", " " + testRemoteCIDR + " "}, headers: map[string]string{fiber.HeaderXForwardedFor: "203.0.113.7"}, clientIP: "203.0.113.7"},
+		{name: "spoofed forwarded from untrusted", cidrs: []string{"10.0.0.0/8"}, headers: map[string]string{fiber.HeaderXForwardedFor: "203.0.113.7"}, clientIP: "0.0.0.0"},
+		{name: "spoofed real IP from untrusted", cidrs: []string{"127.0.0.0/8", "10.0.0.0/8"}, headers: map[string]string{"X-Real-IP": "203.0.113.7"}, clientIP: "0.0.0.0"},
+		{name: "no trusted proxies", headers: map[string]string{fiber.HeaderXForwardedFor: "203.0.113.7", "X-Real-IP": "198.51.100.4"}, clientIP: "0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.clientIP, clientIPOf(t, tt.cidrs, tt.headers))
+		})
+	}
+}
+
+func TestClientIP_WithoutTrustProxies(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(ClientIP(c))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(fiber.HeaderXForwardedFor, "203.0.113.7")
+	res, err := app.Test(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "0.0.0.0", string(body))
+}
@@ -6,20 +6,41 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/spacelift-io/homework-object-storage/internal/models/api"
 )
 
-var alphanumeric = regexp.MustCompile("^[a-zA-Z0-9_]{1,32}$")
+var (
+	alphanumeric = regexp.MustCompile("^[a-zA-Z0-9_]{1,32}$")
+	objectPrefix = regexp.MustCompile("^[a-zA-Z0-9_/]{1,256}$")
+	listFilter   = regexp.MustCompile(`^[a-zA-Z0-9_\-./]*$`)
+)
+
+// maxListFilterLength is the maximum combined length of the list prefix and suffix
+const maxListFilterLength = 64
 
-func validateObjectId(id string) bool {
+// IsValidObjectId checks if the object ID is alphanumeric, up to 32 characters
+func IsValidObjectId(id string) bool {
 	return alphanumeric.MatchString(id)
 }
 
+// IsValidObjectPrefix checks if the prefix is alphanumeric, optionally with slashes, up to 256 characters
+func IsValidObjectPrefix(prefix string) bool {
+	return objectPrefix.MatchString(prefix)
+}
+
+// IsValidListFilter checks if the list prefix and suffix consist of alphanumerics, '_', '-', '.' and '/',
+// up to 64 characters combined
+func IsValidListFilter(prefix, suffix string) bool {
+	return len(prefix)+len(suffix) <= maxListFilterLength && listFilter.MatchString(prefix) && listFilter.MatchString(suffix)
+}
+
 func ValidateObjectId() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		objectId := c.Params("id")
 
-		if !validateObjectId(objectId) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Error{
+		if !IsValidObjectId(objectId) {
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeInvalidObjectId,
 				Message: "Invalid object ID",
 			})
 		}
@@ -35,7 +56,8 @@ func ValidateContentType(acceptedContentType string) fiber.Handler {
 
 		// Check if the Content-Type is valid
 		if !strings.Contains(contentType, acceptedContentType) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Error{
+			return c.Status(fiber.StatusBadRequest).JSON(api.ErrorResponse{
+				Code:    api.ErrorCodeInvalidRequest,
 				Message: fmt.Sprintf("Invalid Content-Type. Expected %s", acceptedContentType),
 			})
 		}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidListFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		suffix string
+		valid  bool
+	}{
+		{name: "empty", valid: true},
+		{name: "prefix", prefix: "team-a/reports_2024.", valid: true},
+		{name: "suffix", suffix: ".tar.gz", valid: true},
+		{name: "64 characters combined", prefix: strings.Repeat("a", 32), suffix: strings.Repeat("b", 32), valid: true},
+		{name: "65 characters combined", prefix: strings.Repeat("a", 33), suffix: strings.Repeat("b", 32), valid: false},
+		{name: "invalid prefix character", prefix: "team a", valid: false},
+		{name: "invalid suffix character", suffix: "*.txt", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.valid, IsValidListFilter(tt.prefix, tt.suffix))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package observability
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat detects a stuck process - the process is considered alive while the heartbeat is touched more often than
+// the threshold
+type Heartbeat struct {
+	threshold time.Duration
+	last      atomic.Int64
+}
+
+// NewHeartbeat creates a heartbeat that goes stale if not touched within the threshold
+func NewHeartbeat(threshold time.Duration) *Heartbeat {
+	h := &Heartbeat{threshold: threshold}
+	h.Beat()
+	return h
+}
+
+// Beat touches the heartbeat
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Alive returns false if the heartbeat was not touched within the threshold
+func (h *Heartbeat) Alive() bool {
+	return time.Since(time.Unix(0, h.last.Load())) <= h.threshold
+}
+
+// Run touches the heartbeat every interval until the context is cancelled. A deadlocked or starved scheduler stops
+// the beats, so the interval should be a fraction of the threshold.
+func (h *Heartbeat) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.Beat()
+		}
+	}
+}
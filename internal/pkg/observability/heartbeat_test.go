@@ -0,0 +1,41 @@
+package observability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat_Stale(t *testing.T) {
+	heartbeat := NewHeartbeat(20 * time.Millisecond)
+	assert.True(t, heartbeat.Alive())
+
+	// Not touched within the threshold
+	time.Sleep(40 * time.Millisecond)
+	assert.False(t, heartbeat.Alive())
+
+	heartbeat.Beat()
+	assert.True(t, heartbeat.Alive())
+}
+
+func TestHeartbeat_Run(t *testing.T) {
+	heartbeat := NewHeartbeat(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		heartbeat.Run(ctx, 5*time.Millisecond)
+	}()
+
+	// The beats keep the heartbeat alive past the threshold
+	time.Sleep(150 * time.Millisecond)
+	assert.True(t, heartbeat.Alive())
+
+	// A stopped loop lets it go stale
+	cancel()
+	<-stopped
+	assert.Eventually(t, func() bool { return !heartbeat.Alive() }, 5*time.Second, 10*time.Millisecond)
+}
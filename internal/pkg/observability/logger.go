@@ -7,7 +7,8 @@ import (
 )
 
 // NewLogger creates a new JSON logger with the given log level, writing logs to stdout and stderr.
-func NewLogger(logLevel string) *zap.Logger {
+// The level can be changed at runtime with the returned AtomicLevel.
+func NewLogger(logLevel string) (*zap.Logger, zap.AtomicLevel) {
 
 	level := zapcore.InfoLevel
 	switch logLevel {
@@ -19,14 +20,16 @@ func NewLogger(logLevel string) *zap.Logger {
 		level = zapcore.ErrorLevel
 	}
 
+	atomicLevel := zap.NewAtomicLevelAt(level)
+
 	stdout := zapcore.Lock(os.Stdout)
 	stderr := zapcore.Lock(os.Stderr)
 
 	stdoutLevelEnabler := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-		return l >= level && l < zapcore.ErrorLevel
+		return atomicLevel.Enabled(l) && l < zapcore.ErrorLevel
 	})
 	stderrLevelEnabler := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-		return l >= level && l >= zapcore.ErrorLevel
+		return atomicLevel.Enabled(l) && l >= zapcore.ErrorLevel
 	})
 
 	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
@@ -36,5 +39,5 @@ func NewLogger(logLevel string) *zap.Logger {
 		zapcore.NewCore(encoder, stderr, stderrLevelEnabler),
 	)
 
-	return zap.New(core)
+	return zap.New(core), atomicLevel
 }
@@ -0,0 +1,43 @@
+package observability
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewLogger_Level(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected zapcore.Level
+	}{
+		{level: "debug", expected: zapcore.DebugLevel},
+		{level: "info", expected: zapcore.InfoLevel},
+		{level: "warning", expected: zapcore.WarnLevel},
+		{level: "error", expected: zapcore.ErrorLevel},
+		{level: "unknown", expected: zapcore.InfoLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			logger, level := NewLogger(tt.level)
+			assert.Equal(t, tt.expected, level.Level())
+			assert.True(t, logger.Core().Enabled(tt.expected))
+			assert.False(t, logger.Core().Enabled(tt.expected-1))
+		})
+	}
+}
+
+func TestNewLogger_SetLevel(t *testing.T) {
+	logger, level := NewLogger("info")
+	assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	// The returned level changes the level of the logger
+	level.SetLevel(zapcore.DebugLevel)
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+	assert.True(t, logger.Named("child").Core().Enabled(zapcore.DebugLevel))
+
+	level.SetLevel(zapcore.ErrorLevel)
+	assert.False(t, logger.Core().Enabled(zapcore.WarnLevel))
+}
@@ -0,0 +1,24 @@
+package observability
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// NewPprofServer creates a server exposing the net/http/pprof routes under /debug/pprof/. It is kept separate from the
+// gateway server, as the profiles expose sensitive runtime data.
+func NewPprofServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+}
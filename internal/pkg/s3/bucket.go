@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
+)
+
+// ensuredBuckets records the buckets known to exist, keyed by the instance endpoint and the bucket.
+// Clients are created per request, so the cache is shared between clients of the same instance.
+var ensuredBuckets = &bucketCache{entries: map[string]*bucketEntry{}}
+
+type bucketCache struct {
+	mu      sync.Mutex
+	entries map[string]*bucketEntry
+}
+
+type bucketEntry struct {
+	// Serializes the first writes, so they don't race to create the bucket
+	mu      sync.Mutex
+	ensured bool
+}
+
+// entry returns the cache entry of the endpoint, creating it if it does not exist
+func (b *bucketCache) entry(endpoint string) *bucketEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.entries[endpoint]
+	if !ok {
+		entry = &bucketEntry{}
+		b.entries[endpoint] = entry
+	}
+
+	return entry
+}
+
+// invalidate forces the next write to the endpoint to check the bucket again
+func (b *bucketCache) invalidate(endpoint string) {
+	entry := b.entry(endpoint)
+	entry.mu.Lock()
+	entry.ensured = false
+	entry.mu.Unlock()
+}
+
+// bucketKey identifies the bucket of the client in the ensuredBuckets
+func (c *MinioClient) bucketKey() string {
+	return c.client.EndpointURL().Host + "/" + c.bucket
+}
+
+// ensureBucket makes sure the bucket exists on the S3 instance. The check runs only once per instance,
+// until the cache is invalidated.
+func (c *MinioClient) ensureBucket(ctx context.Context) error {
+	entry := ensuredBuckets.entry(c.bucketKey())
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.ensured {
+		return nil
+	}
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+
+	// Check if the bucket exists, if not create it
+	exists, err := c.client.BucketExists(ctx, c.bucket)
+	if err != nil {
+		return c.wrapError(err, "failed to check if bucket exists")
+	}
+
+	if !exists {
+		if !c.options.AutoCreateBucket {
+			return errors.Wrapf(ErrBucketNotFound, "bucket %s does not exist and automatic bucket creation is disabled", c.bucket)
+		}
+
+		err = c.client.MakeBucket(ctx, c.bucket, minio.MakeBucketOptions{Region: c.options.Region})
+		if err != nil {
+			return c.wrapError(err, "failed to create a new bucket")
+		}
+	}
+
+	if c.options.ExpirationDays > 0 {
+		err = c.setBucketExpiration(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	entry.ensured = true
+	return nil
+}
+
+// ErrInvalidBucketName is returned when the bucket name is not DNS-compliant
+var ErrInvalidBucketName = errors.New("invalid bucket name")
+
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// ValidateBucketName checks the bucket name follows the S3 naming rules: 3-63 lowercase letters, digits, dots and
+// hyphens, starting and ending with a letter or a digit, without consecutive dots and not formatted as an IP address.
+func ValidateBucketName(name string) error {
+	switch {
+	case len(name) < 3 || len(name) > 63:
+		return errors.Wrapf(ErrInvalidBucketName, "%q must have 3-63 characters", name)
+	case strings.ContainsAny(name, "_ABCDEFGHIJKLMNOPQRSTUVWXYZ"):
+		return errors.Wrapf(ErrInvalidBucketName, "%q must be lowercase, without underscores", name)
+	case !bucketNamePattern.MatchString(name):
+		return errors.Wrapf(ErrInvalidBucketName, "%q must consist of lowercase letters, digits, dots and hyphens, starting and ending with a letter or a digit", name)
+	case strings.Contains(name, ".."):
+		return errors.Wrapf(ErrInvalidBucketName, "%q must not contain consecutive dots", name)
+	case net.ParseIP(name) != nil:
+		return errors.Wrapf(ErrInvalidBucketName, "%q must not be formatted as an IP address", name)
+	}
+
+	return nil
+}
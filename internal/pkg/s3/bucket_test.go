@@ -0,0 +1,188 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestValidateBucketName(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		valid  bool
+	}{
+		{name: "default", bucket: BucketName, valid: true},
+		{name: "dots and digits", bucket: "objects.prod-2", valid: true},
+		{name: "shortest", bucket: "abc", valid: true},
+		{name: "longest", bucket: strings.Repeat("a", 63), valid: true},
+		{name: "too short", bucket: "ab"},
+		{name: "too long", bucket: strings.Repeat("a", 64)},
+		{name: "uppercase", bucket: "Objects"},
+		{name: "underscore", bucket: "my_objects"},
+		{name: "leading hyphen", bucket: "-objects"},
+		{name: "trailing dot", bucket: "objects."},
+		{name: "consecutive dots", bucket: "my..objects"},
+		{name: "IP address", bucket: "192.168.1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBucketName(tt.bucket)
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrInvalidBucketName)
+		})
+	}
+}
+
+func TestMinioClient_CustomBucket(t *testing.T) {
+	server := newFakeS3(t)
+	client := server.client(Options{Bucket: "objects-staging", AutoCreateBucket: true})
+	ctx := context.Background()
+
+	// The custom bucket is created on the first upload and used instead of the default one
+	_, err := client.AddOrUpdateObject(ctx, "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+
+	data, ok := server.object("objects-staging", "object")
+	require.True(t, ok)
+	assert.Equal(t, []byte("data"), data)
+	_, ok = server.object(BucketName, "object")
+	assert.False(t, ok)
+
+	object, err := client.GetObject(ctx, "object")
+	require.NoError(t, err)
+	read, err := io.ReadAll(object)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), read)
+
+	objectIds, err := client.GetObjects(ctx, ListFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"object"}, objectIds)
+}
+
+func TestMinioClient_Region(t *testing.T) {
+	server := newFakeS3(t)
+	client := server.client(Options{Region: "eu-central-1", AutoCreateBucket: true})
+
+	_, err := client.AddOrUpdateObject(context.Background(), "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+
+	// The bucket is created in the region and all requests are signed for it
+	assert.Equal(t, map[string]string{BucketName: "eu-central-1"}, server.locations)
+	assert.Equal(t, map[string]bool{"eu-central-1": true}, server.regions)
+}
+
+func TestMinioClient_DefaultRegion(t *testing.T) {
+	server := newFakeS3(t)
+	client := server.client(Options{AutoCreateBucket: true})
+
+	_, err := client.AddOrUpdateObject(context.Background(), "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{BucketName: ""}, server.locations)
+	assert.Equal(t, map[string]bool{"us-east-1": true}, server.regions)
+}
+
+func TestMinioClient_AutoCreateBucket(t *testing.T) {
+	tests := []struct {
+		name             string
+		autoCreateBucket bool
+		buckets          []string
+		err              error
+	}{
+		{name: "auto-create missing bucket", autoCreateBucket: true},
+		{name: "auto-create existing bucket", autoCreateBucket: true, buckets: []string{BucketName}},
+		{name: "fail-fast missing bucket", err: ErrBucketNotFound},
+		{name: "fail-fast existing bucket", buckets: []string{BucketName}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeS3(t, tt.buckets...)
+			client := server.client(Options{AutoCreateBucket: tt.autoCreateBucket})
+
+			_, err := client.AddOrUpdateObject(context.Background(), "object", strings.NewReader("data"), PutOptions{Size: 4})
+			_, stored := server.object(BucketName, "object")
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.ErrorContains(t, err, "automatic bucket creation is disabled")
+
+				// The bucket is neither created nor written to
+				assert.Empty(t, server.locations)
+				assert.False(t, stored)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, stored)
+		})
+	}
+}
+
+func TestMinioClient_EnsuresBucketOnce(t *testing.T) {
+	server := newFakeS3(t)
+	ctx := context.Background()
+
+	// The clients are created per request, the bucket is still checked only by the first write
+	for i := 0; i < 10; i++ {
+		client := server.client(Options{AutoCreateBucket: true})
+		_, err := client.AddOrUpdateObject(ctx, fmt.Sprintf("object%d", i), strings.NewReader("data"), PutOptions{Size: 4})
+		require.NoError(t, err)
+	}
+
+	checks, creations := server.bucketCalls()
+	assert.Equal(t, 1, checks)
+	assert.Equal(t, 1, creations)
+}
+
+func TestMinioClient_EnsuresBucketOnceConcurrently(t *testing.T) {
+	server := newFakeS3(t)
+	ctx := context.Background()
+
+	// The concurrent first writes don't race to create the bucket
+	group := errgroup.Group{}
+	for i := 0; i < 20; i++ {
+		objectId := fmt.Sprintf("object%d", i)
+		group.Go(func() error {
+			_, err := server.client(Options{AutoCreateBucket: true}).AddOrUpdateObject(ctx, objectId, strings.NewReader("data"), PutOptions{Size: 4})
+			return err
+		})
+	}
+	require.NoError(t, group.Wait())
+
+	checks, creations := server.bucketCalls()
+	assert.Equal(t, 1, checks)
+	assert.Equal(t, 1, creations)
+}
+
+func TestMinioClient_EnsuresRemovedBucketAgain(t *testing.T) {
+	server := newFakeS3(t)
+	client := server.client(Options{AutoCreateBucket: true})
+	ctx := context.Background()
+
+	_, err := client.AddOrUpdateObject(ctx, "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+
+	// The write to the removed bucket fails and invalidates the cache, the next write creates the bucket again
+	server.removeBucket(BucketName)
+	_, err = client.AddOrUpdateObject(ctx, "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.Error(t, err)
+
+	_, err = client.AddOrUpdateObject(ctx, "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+
+	checks, creations := server.bucketCalls()
+	assert.Equal(t, 2, checks)
+	assert.Equal(t, 2, creations)
+}
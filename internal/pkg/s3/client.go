@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -13,124 +16,611 @@ import (
 	"go.uber.org/zap"
 )
 
-var ErrObjectNotFound = errors.New("object not found")
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrBucketNotFound = errors.New("bucket not found")
+	// ErrSizeMismatch is returned when the uploaded data doesn't match its declared size
+	ErrSizeMismatch = errors.New("object size mismatch")
+	// ErrAccessDenied is returned when the credentials of the instance don't allow accessing the object
+	ErrAccessDenied = errors.New("access denied")
+	// ErrInvalidCredentials is returned when the instance rejects the credentials, e.g. discovered before a rotation
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
 
 const (
-	bucketName = "spacelift-storage"
+	// BucketName is the default bucket the objects are stored in
+	BucketName = "spacelift-storage"
 )
 
 type Client interface {
-	AddOrUpdateObject(ctx context.Context, objectId string, data io.Reader) error
+	AddOrUpdateObject(ctx context.Context, objectId string, data io.Reader, options PutOptions) (*ObjectInfo, error)
 	GetObject(ctx context.Context, objectId string) (io.Reader, error)
-	GetObjects(ctx context.Context) ([]string, error)
+	GetObjects(ctx context.Context, filter ListFilter) ([]string, error)
+	GetObjectsSummary(ctx context.Context, filter ListFilter) ([]ObjectSummary, error)
+	StreamObjects(ctx context.Context, objectIds chan<- string) error
+	ListObjectsPage(ctx context.Context, options ListOptions) ([]string, string, error)
+	CountObjects(ctx context.Context) (int, error)
+	WatchObject(ctx context.Context, objectId string, events chan<- ObjectEvent) error
+	StatObject(ctx context.Context, objectId string) (*ObjectInfo, error)
+	ObjectExists(ctx context.Context, objectId string) (bool, error)
+	DeleteObject(ctx context.Context, objectId string) error
+	CopyObject(ctx context.Context, sourceId, targetId string, metadata map[string]string) error
+	GetObjectVersion(ctx context.Context, objectId, versionId string) (io.Reader, error)
+	StatObjectVersion(ctx context.Context, objectId, versionId string) (*ObjectInfo, error)
+	DeleteObjectVersion(ctx context.Context, objectId, versionId string) error
+	ListObjectVersions(ctx context.Context, objectId string) ([]ObjectVersion, error)
+	ListObjectsByPrefix(ctx context.Context, prefix string) ([]string, error)
+	DeleteObjects(ctx context.Context, objectIds []string) (int, error)
+	ExportObject(ctx context.Context, objectId string, targetEndpoint, targetBucket, accessKey, secretKey string) error
+	GetUsage(ctx context.Context) (Usage, error)
+	DeleteExpiredObjects(ctx context.Context, now time.Time) (int, error)
+	Close() error
+}
+
+// ObjectInfo holds the metadata of an object
+type ObjectInfo struct {
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	// VersionId is set if the bucket is versioned
+	VersionId string
+	// ContentType and Metadata are set by StatObject only. Metadata is the user metadata of the object.
+	ContentType string
+	Metadata    map[string]string
+}
+
+// PutOptions configure a single upload
+type PutOptions struct {
+	// ExpiresAt marks the object to be deleted by the expiry sweep. Zero means the object does not expire.
+	ExpiresAt time.Time
+	// Metadata is stored as the user metadata of the object
+	Metadata map[string]string
+	// Size of the data, if known. Small objects of a known size are uploaded in a single request, instead of being
+	// streamed in buffered parts. Zero means the size is unknown.
+	Size int64
+}
+
+// ListFilter narrows the listed objects. Empty fields match all objects.
+type ListFilter struct {
+	// Prefix is matched by the S3 backend
+	Prefix string
+	// Suffix is matched after listing, as S3 does not support suffix search
+	Suffix string
+	// Limit stops the listing after this many matching objects. Zero means no limit.
+	Limit int
+}
+
+// Matches checks if the objectId matches the filter
+func (f ListFilter) Matches(objectId string) bool {
+	return strings.HasPrefix(objectId, f.Prefix) && strings.HasSuffix(objectId, f.Suffix)
+}
+
+// Usage is the storage used by the objects in the bucket
+type Usage struct {
+	Objects int64
+	Bytes   int64
+}
+
+// Options configure the Minio client
+type Options struct {
+	// Bucket the objects are stored in, e.g. per environment. Empty defaults to BucketName.
+	Bucket string
+	// Region of the S3 backend. Empty defaults to us-east-1.
+	Region string
+	// AutoCreateBucket creates the bucket on upload if it does not exist. When disabled, the bucket must be pre-provisioned.
+	AutoCreateBucket bool
+	// ExpirationDays sets a bucket lifecycle rule expiring all objects after the given days. Zero disables expiration.
+	ExpirationDays int
+	// Client configures the timeouts and the connections of the clients
+	Client ClientOptions
 }
 
 type MinioClient struct {
-	client *minio.Client
-	logger *zap.Logger
+	client  *minio.Client
+	bucket  string
+	options Options
+	logger  *zap.Logger
 }
 
 // NewMinioClient creates a new instance of the Minio client based on the S3 instance
-func NewMinioClient(instance discovery.S3Instance) (*MinioClient, error) {
-	minioClient, err := minio.New(fmt.Sprintf("%s:%s", instance.Hostname, instance.Port), &minio.Options{
+func NewMinioClient(instance discovery.S3Instance, options Options) (*MinioClient, error) {
+	// The bucket name is validated at startup, Minio rejects an invalid one only on its first use
+	bucket := options.Bucket
+	if bucket == "" {
+		bucket = BucketName
+	}
+
+	minioOptions := &minio.Options{
 		Creds:  credentials.NewStaticV4(instance.AccessKey, instance.SecretKey, ""),
-		Secure: false,
-	})
+		Secure: instance.Secure,
+		Region: options.Region,
+	}
+
+	// The transport is shared with the other clients, so the connections are reused
+	transport, err := sharedTransport(instance, options.Client)
+	if err != nil {
+		return nil, err
+	}
+	minioOptions.Transport = transport
+
+	minioClient, err := minio.New(fmt.Sprintf("%s:%s", instance.Hostname, instance.Port), minioOptions)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create Minio client")
 	}
 
 	return &MinioClient{
-		client: minioClient,
-		logger: zap.L().Named("minio-client"),
+		client:  minioClient,
+		bucket:  bucket,
+		options: options,
+		logger:  zap.L().Named("minio-client").With(zap.Int("instance", instance.InstanceNum)),
 	}, nil
 }
 
-// AddOrUpdateObject adds or updates an object in the S3 instance. If the object already exists, it will be overwritten and if the bucket does not exist, it will be created.
-func (c *MinioClient) AddOrUpdateObject(ctx context.Context, objectId string, data io.Reader) error {
+// wrapError wraps the error of the S3 instance with the message. The rejected credentials and the denied access are
+// mapped to ErrInvalidCredentials and ErrAccessDenied, and logged with the instance number.
+func (c *MinioClient) wrapError(err error, message string) error {
+	res := minio.ToErrorResponse(err)
+	switch {
+	case res.Code == "SignatureDoesNotMatch", res.Code == "InvalidAccessKeyId", res.Code == "InvalidToken", res.Code == "ExpiredToken":
+		c.logger.Error("S3 instance rejected the credentials", zap.String("code", res.Code), zap.Error(err))
+		return errors.Wrap(ErrInvalidCredentials, message+": "+res.Message)
+	case res.Code == "AccessDenied", res.StatusCode == http.StatusForbidden:
+		c.logger.Error("S3 instance denied the access", zap.String("code", res.Code), zap.Error(err))
+		return errors.Wrap(ErrAccessDenied, message+": "+res.Message)
+	default:
+		return errors.Wrap(err, message)
+	}
+}
+
+// Close releases the client. Its transport is shared with the clients of the other instances, so its idle connections
+// are left to the idle timeout of the transport instead of being closed, and closed on the shutdown by
+// CloseIdleConnections. Close is idempotent.
+func (c *MinioClient) Close() error {
+	return nil
+}
+
+// AddOrUpdateObject adds or updates an object in the S3 instance. If the object already exists, it will be overwritten.
+// If the bucket does not exist, it will be created, unless automatic bucket creation is disabled.
+func (c *MinioClient) AddOrUpdateObject(ctx context.Context, objectId string, data io.Reader, options PutOptions) (*ObjectInfo, error) {
 	c.logger.Info("Adding or updating object in S3", zap.String("objectId", objectId))
 
-	// Check if the bucket exists, if not create it
-	exists, err := c.client.BucketExists(ctx, bucketName)
+	// Make sure the bucket exists before writing to it
+	err := c.ensureBucket(ctx)
 	if err != nil {
-		return errors.Wrap(err, "failed to check if bucket exists")
+		return nil, err
+	}
+
+	putOptions := minio.PutObjectOptions{UserMetadata: map[string]string{}}
+	for key, value := range options.Metadata {
+		putOptions.UserMetadata[key] = value
 	}
-	if !exists {
 
-		// Check if bucket exists, if not create it
-		err = c.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
-		if err != nil {
-			return errors.Wrap(err, "failed to create a new bucket")
+	if !options.ExpiresAt.IsZero() {
+		for key, value := range expirationMetadata(options.ExpiresAt) {
+			putOptions.UserMetadata[key] = value
 		}
 	}
 
+	size := int64(-1)
+	if options.Size > 0 {
+		size = options.Size
+	}
+
+	// Minio reads only the declared size, so a longer body would be stored truncated - fail it before it's sent whole.
+	// The mismatch cancels the upload, so Minio doesn't retry it.
+	var body *sizedReader
+	if size >= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		body = &sizedReader{r: data, remaining: size, cancel: cancel}
+		data = body
+	}
+
 	// Put the object in the S3 instance
-	_, err = c.client.PutObject(ctx, bucketName, objectId, data, -1, minio.PutObjectOptions{})
-	if err != nil {
+	uploadInfo, err := c.client.PutObject(ctx, c.bucket, objectId, data, size, putOptions)
+	if body != nil && body.err != nil {
+		return nil, body.err
+	}
 
+	if err != nil {
 		res := minio.ToErrorResponse(err)
+		// The bucket was removed since it was ensured - check it again on the next write
+		if res.Code == "NoSuchBucket" {
+			ensuredBuckets.invalidate(c.bucketKey())
+		}
+
 		if res.StatusCode == http.StatusNotFound {
-			return ErrObjectNotFound
+			return nil, ErrObjectNotFound
 		}
+
+		return nil, c.wrapError(err, "failed to put object to S3")
 	}
 
-	return nil
+	return &ObjectInfo{Size: uploadInfo.Size, LastModified: uploadInfo.LastModified, ETag: uploadInfo.ETag}, nil
+}
+
+// sizedReader reads exactly the declared size of the data. The read completing the size checks the data ends there
+// first, and fails with ErrSizeMismatch without returning the last bytes - the upload is aborted short of its
+// Content-Length and never committed.
+type sizedReader struct {
+	r         io.Reader
+	remaining int64
+	cancel    context.CancelFunc
+	err       error
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	if s.remaining == 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > s.remaining {
+		p = p[:s.remaining]
+	}
+
+	n, err := s.r.Read(p)
+	s.remaining -= int64(n)
+
+	switch {
+	case s.remaining == 0:
+		if extra, _ := s.r.Read(make([]byte, 1)); extra > 0 {
+			s.fail("the object is longer than its declared size")
+			return 0, s.err
+		}
+		return n, nil
+	case err == io.EOF:
+		s.fail("the object is shorter than its declared size")
+		return n, s.err
+	}
+
+	return n, err
+}
+
+func (s *sizedReader) fail(message string) {
+	s.err = errors.Wrap(ErrSizeMismatch, message)
+	s.cancel()
 }
 
 // GetObject fetches an object from the S3 instance.
 func (c *MinioClient) GetObject(ctx context.Context, objectId string) (io.Reader, error) {
 	c.logger.Info("Getting the object from S3", zap.String("objectId", objectId))
+	return c.getObject(ctx, objectId, minio.GetObjectOptions{})
+}
 
-	// Get the object from the S3 instance
-	obj, err := c.client.GetObject(ctx, bucketName, objectId, minio.GetObjectOptions{})
+// getObject gets the object with the options, checking it exists. The object is requested eagerly, so its size is of
+// the same response as its body.
+func (c *MinioClient) getObject(ctx context.Context, objectId string, options minio.GetObjectOptions) (io.Reader, error) {
+	body, info, _, err := minio.Core{Client: c.client}.GetObject(ctx, c.bucket, objectId, options)
 	if err != nil {
 		res := minio.ToErrorResponse(err)
 		if res.StatusCode == http.StatusNotFound {
 			return nil, ErrObjectNotFound
 		}
 
-		return nil, errors.Wrap(err, "failed to get object from S3")
+		return nil, c.wrapError(err, "failed to get object from S3")
 	}
 
-	stat, err := obj.Stat()
+	return &sizedObject{ReadCloser: body, size: info.Size}, nil
+}
+
+// sizedObject reads the body of the object with the size from the same response, so the size matches the body even
+// if the object is overwritten after it was stat'ed separately
+type sizedObject struct {
+	io.ReadCloser
+	size int64
+}
+
+// Size returns the size of the object
+func (o *sizedObject) Size() int64 {
+	return o.size
+}
+
+// ObjectSize returns the size of the object read by the reader, if the reader knows it. The readers of the clients
+// and of the byte slices know the size of the whole object - the size is valid before the reader is read.
+func ObjectSize(object io.Reader) (int64, bool) {
+	sized, ok := object.(interface{ Size() int64 })
+	if !ok || sized.Size() < 0 {
+		return 0, false
+	}
+
+	return sized.Size(), true
+}
+
+// StatObject fetches the metadata of the object from the S3 instance
+func (c *MinioClient) StatObject(ctx context.Context, objectId string) (*ObjectInfo, error) {
+	c.logger.Info("Getting the object metadata from S3", zap.String("objectId", objectId))
+	return c.statObject(ctx, objectId, minio.StatObjectOptions{})
+}
+
+// statObject fetches the metadata of the object with the options
+func (c *MinioClient) statObject(ctx context.Context, objectId string, options minio.StatObjectOptions) (*ObjectInfo, error) {
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+
+	info, err := c.client.StatObject(ctx, c.bucket, objectId, options)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get object from S3")
+		res := minio.ToErrorResponse(err)
+		if res.StatusCode == http.StatusNotFound {
+			return nil, ErrObjectNotFound
+		}
+
+		return nil, c.wrapError(err, "failed to stat object in S3")
 	}
 
-	if stat.Err != nil {
-		return nil, stat.Err
+	return &ObjectInfo{
+		Size:         info.Size,
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		VersionId:    info.VersionID,
+		ContentType:  info.ContentType,
+		Metadata:     info.UserMetadata,
+	}, nil
+}
+
+// ObjectExists checks if the object exists in the S3 instance
+func (c *MinioClient) ObjectExists(ctx context.Context, objectId string) (bool, error) {
+	c.logger.Info("Checking if the object exists in S3", zap.String("objectId", objectId))
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+
+	_, err := c.client.StatObject(ctx, c.bucket, objectId, minio.StatObjectOptions{})
+	if err != nil {
+		res := minio.ToErrorResponse(err)
+		if res.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+
+		return false, c.wrapError(err, "failed to stat object in S3")
 	}
 
-	return obj, nil
+	return true, nil
 }
 
-// GetObjects Get all objectsIds from the S3 instance
-func (c *MinioClient) GetObjects(ctx context.Context) ([]string, error) {
-	c.logger.Info("Getting objects from s3 instance")
+// DeleteObject deletes the object from the S3 instance
+func (c *MinioClient) DeleteObject(ctx context.Context, objectId string) error {
+	c.logger.Info("Deleting the object from S3", zap.String("objectId", objectId))
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+
+	err := c.client.RemoveObject(ctx, c.bucket, objectId, minio.RemoveObjectOptions{})
+	if err != nil {
+		return c.wrapError(err, "failed to delete object from S3")
+	}
+
+	return nil
+}
 
-	objectChan := c.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{})
+// CopyObject copies the object within the S3 instance, keeping its user metadata and adding the given metadata
+func (c *MinioClient) CopyObject(ctx context.Context, sourceId, targetId string, metadata map[string]string) error {
+	c.logger.Info("Copying the object", zap.String("sourceId", sourceId), zap.String("targetId", targetId))
+
+	info, err := c.client.StatObject(ctx, c.bucket, sourceId, minio.StatObjectOptions{})
+	if err != nil {
+		res := minio.ToErrorResponse(err)
+		if res.StatusCode == http.StatusNotFound {
+			return ErrObjectNotFound
+		}
+
+		return c.wrapError(err, "failed to stat object in S3")
+	}
+
+	// Replacing the metadata drops the existing user metadata, so it is copied explicitly
+	userMetadata := map[string]string{}
+	for key, value := range info.UserMetadata {
+		userMetadata[key] = value
+	}
+
+	for key, value := range metadata {
+		userMetadata[key] = value
+	}
+
+	_, err = c.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: c.bucket, Object: targetId, UserMetadata: userMetadata, ReplaceMetadata: true},
+		minio.CopySrcOptions{Bucket: c.bucket, Object: sourceId},
+	)
+	if err != nil {
+		return c.wrapError(err, "failed to copy object in S3")
+	}
+
+	return nil
+}
+
+// ListObjectsByPrefix lists the objectIds starting with the prefix
+func (c *MinioClient) ListObjectsByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	c.logger.Info("Listing objects by prefix", zap.String("prefix", prefix))
 
 	objectIds := []string{}
+	for object := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, c.wrapError(object.Err, "failed to list objects")
+		}
+
+		objectIds = append(objectIds, object.Key)
+	}
+
+	return objectIds, nil
+}
+
+// DeleteObjects deletes the objects in a single batch and returns the number of deleted objects
+func (c *MinioClient) DeleteObjects(ctx context.Context, objectIds []string) (int, error) {
+	c.logger.Info("Deleting objects from S3", zap.Int("count", len(objectIds)))
+
+	objects := make(chan minio.ObjectInfo, len(objectIds))
+	for _, objectId := range objectIds {
+		objects <- minio.ObjectInfo{Key: objectId}
+	}
+	close(objects)
 
+	// A failed batch is reported without an object name, next to the error of each object in it, if any.
+	// The error returned by S3 is preferred over the one of reading its response.
+	failed := map[string]bool{}
+	batchFailed := false
+	var err error
+	for removeErr := range c.client.RemoveObjects(ctx, c.bucket, objects, minio.RemoveObjectsOptions{}) {
+		if removeErr.ObjectName == "" {
+			batchFailed = true
+		} else {
+			failed[removeErr.ObjectName] = true
+		}
+
+		if err == nil || minio.ToErrorResponse(err).Code == "" {
+			err = removeErr.Err
+		}
+	}
+
+	if err != nil {
+		deleted := len(objectIds) - len(failed)
+		if batchFailed {
+			deleted = 0
+		}
+
+		return deleted, c.wrapError(err, "failed to delete objects from S3")
+	}
+
+	return len(objectIds), nil
+}
+
+// ExportObject streams the object to an external S3-compatible target. The target endpoint must be an HTTP or HTTPS URL.
+func (c *MinioClient) ExportObject(ctx context.Context, objectId string, targetEndpoint, targetBucket, accessKey, secretKey string) error {
+	c.logger.Info("Exporting the object", zap.String("objectId", objectId), zap.String("targetEndpoint", targetEndpoint), zap.String("targetBucket", targetBucket))
+
+	endpoint, err := url.Parse(targetEndpoint)
+	if err != nil {
+		return errors.Wrap(err, "invalid target endpoint")
+	}
+
+	targetClient, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: endpoint.Scheme == "https",
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create target client")
+	}
+
+	obj, err := c.client.GetObject(ctx, c.bucket, objectId, minio.GetObjectOptions{})
+	if err != nil {
+		return c.wrapError(err, "failed to get object from S3")
+	}
+	defer obj.Close()
+
+	// The known size lets the upload stream the object in parts, instead of buffering it
+	stat, err := obj.Stat()
+	if err != nil {
+		res := minio.ToErrorResponse(err)
+		if res.StatusCode == http.StatusNotFound {
+			return ErrObjectNotFound
+		}
+
+		return c.wrapError(err, "failed to get object from S3")
+	}
+
+	_, err = targetClient.PutObject(ctx, targetBucket, objectId, obj, stat.Size, minio.PutObjectOptions{ContentType: stat.ContentType})
+	if err != nil {
+		return errors.Wrap(err, "failed to put object to the target")
+	}
+
+	return nil
+}
+
+// GetObjects Get all objectsIds matching the filter from the S3 instance
+func (c *MinioClient) GetObjects(ctx context.Context, filter ListFilter) ([]string, error) {
+	c.logger.Info("Getting objects from s3 instance", zap.String("prefix", filter.Prefix), zap.String("suffix", filter.Suffix))
+
+	options := ListOptions{Prefix: filter.Prefix}
+	objectIds := []string{}
 	for {
-		select {
-		case object, ok := <-objectChan:
-			// If the channel is closed return the objectIds
-			if !ok {
-				return objectIds, nil
+		page, nextStartAfter, err := c.ListObjectsPage(ctx, options)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, err
+		case ctx.Err() != nil:
+			// A cancelled listing returns the objects listed so far
+			return objectIds, nil
+		case err != nil:
+			return nil, err
+		}
+
+		for _, objectId := range page {
+			if !filter.Matches(objectId) {
+				continue
 			}
 
-			if !errors.Is(object.Err, nil) {
-				return nil, object.Err
+			objectIds = append(objectIds, objectId)
+			if filter.Limit > 0 && len(objectIds) >= filter.Limit {
+				return objectIds, nil
 			}
+		}
+
+		if nextStartAfter == "" {
+			return objectIds, nil
+		}
+		options.StartAfter = nextStartAfter
+	}
+}
+
+// CountObjects counts the objects in the S3 instance, without collecting their ids
+func (c *MinioClient) CountObjects(ctx context.Context) (int, error) {
+	c.logger.Info("Counting objects in s3 instance")
+
+	count := 0
+	for object := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return 0, c.wrapError(object.Err, "failed to list objects")
+		}
+
+		count++
+	}
+
+	return count, ctx.Err()
+}
+
+// StreamObjects sends the objectIds from the S3 instance to the channel, one at a time. Blocks until all objects are
+// sent, an error occurs or the context is cancelled. The channel is not closed.
+func (c *MinioClient) StreamObjects(ctx context.Context, objectIds chan<- string) error {
+	c.logger.Info("Streaming objects from s3 instance")
 
-			objectIds = append(objectIds, object.Key)
+	for object := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return c.wrapError(object.Err, "failed to list objects")
+		}
+
+		select {
+		case objectIds <- object.Key:
 		case <-ctx.Done():
-			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-				return nil, ctx.Err()
+			return ctx.Err()
+		}
+	}
+
+	return ctx.Err()
+}
+
+// GetUsage counts the objects and their total size in the S3 instance
+func (c *MinioClient) GetUsage(ctx context.Context) (Usage, error) {
+	c.logger.Info("Getting storage usage from s3 instance")
+
+	usage := Usage{}
+	for object := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			res := minio.ToErrorResponse(object.Err)
+			// A missing bucket holds no objects
+			if res.Code == "NoSuchBucket" {
+				return Usage{}, nil
 			}
 
-			return objectIds, nil
+			return Usage{}, c.wrapError(object.Err, "failed to list objects")
 		}
+
+		usage.Objects++
+		usage.Bytes += object.Size
 	}
+
+	return usage, nil
 }
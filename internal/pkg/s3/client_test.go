@@ -0,0 +1,132 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddOrUpdateObject_SizeMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		size int64
+	}{
+		{name: "longer than declared", data: "new data", size: 4},
+		{name: "shorter than declared", data: "new", size: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeS3(t, BucketName)
+			server.put(BucketName, "object", []byte("previous"))
+			client := server.client(Options{})
+
+			_, err := client.AddOrUpdateObject(context.Background(), "object", strings.NewReader(tt.data), PutOptions{Size: tt.size})
+			assert.ErrorIs(t, err, ErrSizeMismatch)
+
+			// The upload is never committed, so the previous object is kept
+			data, ok := server.object(BucketName, "object")
+			require.True(t, ok)
+			assert.Equal(t, []byte("previous"), data)
+			assert.Equal(t, 0, server.puts)
+		})
+	}
+}
+
+func TestAddOrUpdateObject_DeclaredSize(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	client := server.client(Options{})
+
+	info, err := client.AddOrUpdateObject(context.Background(), "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, info.Size)
+
+	data, ok := server.object(BucketName, "object")
+	require.True(t, ok)
+	assert.Equal(t, []byte("data"), data)
+}
+
+func TestGetObject_SizeOfObjectRead(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	server.put(BucketName, "object", []byte("data"))
+	client := server.client(Options{})
+
+	object, err := client.GetObject(context.Background(), "object")
+	require.NoError(t, err)
+	defer object.(io.Closer).Close()
+
+	// The size is of the object read, not of the object overwriting it
+	server.put(BucketName, "object", []byte("overwritten"))
+	size, ok := ObjectSize(object)
+	require.True(t, ok)
+	assert.EqualValues(t, 4, size)
+
+	data, err := io.ReadAll(object)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+}
+
+func TestObjectSize(t *testing.T) {
+	size, ok := ObjectSize(bytes.NewReader([]byte("data")))
+	assert.True(t, ok)
+	assert.EqualValues(t, 4, size)
+
+	_, ok = ObjectSize(strings.NewReader("data"))
+	assert.True(t, ok)
+
+	_, ok = ObjectSize(io.LimitReader(strings.NewReader("data"), 2))
+	assert.False(t, ok)
+}
+
+func BenchmarkAddOrUpdateObject(b *testing.B) {
+	server := newFakeS3(b, BucketName)
+	client := server.client(Options{})
+	data := bytes.Repeat([]byte("a"), 1<<20)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := client.AddOrUpdateObject(context.Background(), "object", bytes.NewReader(data), PutOptions{Size: int64(len(data))})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetObject_NotFound(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	client := server.client(Options{})
+
+	_, err := client.GetObject(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	_, err = client.StatObject(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	exists, err := client.ObjectExists(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestGetObject_StatNotFound(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	server.put(BucketName, "object", []byte("data"))
+	client := server.client(Options{})
+
+	// The object is deleted between the listing and the read - the instance reports it missing
+	server.fail(http.StatusNotFound, "NoSuchKey")
+
+	_, err := client.StatObject(context.Background(), "object")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	_, err = client.GetObject(context.Background(), "object")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
@@ -0,0 +1,149 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientCalls calls each method of the client talking to the S3 instance, returning its error
+var clientCalls = map[string]func(ctx context.Context, c *MinioClient) error{
+	"AddOrUpdateObject": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.AddOrUpdateObject(ctx, "object", strings.NewReader("data"), PutOptions{Size: 4})
+		return err
+	},
+	"GetObject": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.GetObject(ctx, "object")
+		return err
+	},
+	"StatObject": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.StatObject(ctx, "object")
+		return err
+	},
+	"ObjectExists": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.ObjectExists(ctx, "object")
+		return err
+	},
+	"DeleteObject": func(ctx context.Context, c *MinioClient) error {
+		return c.DeleteObject(ctx, "object")
+	},
+	"CopyObject": func(ctx context.Context, c *MinioClient) error {
+		return c.CopyObject(ctx, "object", "copy", nil)
+	},
+	"GetObjects": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.GetObjects(ctx, ListFilter{})
+		return err
+	},
+	"GetObjectsSummary": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.GetObjectsSummary(ctx, ListFilter{})
+		return err
+	},
+	"ListObjectsPage": func(ctx context.Context, c *MinioClient) error {
+		_, _, err := c.ListObjectsPage(ctx, ListOptions{})
+		return err
+	},
+	"ListObjectsByPrefix": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.ListObjectsByPrefix(ctx, "object")
+		return err
+	},
+	"CountObjects": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.CountObjects(ctx)
+		return err
+	},
+	"StreamObjects": func(ctx context.Context, c *MinioClient) error {
+		return c.StreamObjects(ctx, make(chan string, 1))
+	},
+	"DeleteObjects": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.DeleteObjects(ctx, []string{"object"})
+		return err
+	},
+	"GetUsage": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.GetUsage(ctx)
+		return err
+	},
+	"DeleteExpiredObjects": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.DeleteExpiredObjects(ctx, time.Now())
+		return err
+	},
+	"GetObjectVersion": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.GetObjectVersion(ctx, "object", "version")
+		return err
+	},
+	"StatObjectVersion": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.StatObjectVersion(ctx, "object", "version")
+		return err
+	},
+	"DeleteObjectVersion": func(ctx context.Context, c *MinioClient) error {
+		return c.DeleteObjectVersion(ctx, "object", "version")
+	},
+	"ListObjectVersions": func(ctx context.Context, c *MinioClient) error {
+		_, err := c.ListObjectVersions(ctx, "object")
+		return err
+	},
+}
+
+func TestClient_MapsErrorCodes(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected error
+	}{
+		{code: "SignatureDoesNotMatch", expected: ErrInvalidCredentials},
+		{code: "InvalidAccessKeyId", expected: ErrInvalidCredentials},
+		{code: "InvalidToken", expected: ErrInvalidCredentials},
+		{code: "ExpiredToken", expected: ErrInvalidCredentials},
+		{code: "AccessDenied", expected: ErrAccessDenied},
+		{code: "AllAccessDisabled", expected: ErrAccessDenied},
+	}
+
+	// The expired tokens are retried by the Minio client
+	maxRetry := minio.MaxRetry
+	minio.MaxRetry = 1
+	t.Cleanup(func() { minio.MaxRetry = maxRetry })
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			server := newFakeS3(t, BucketName)
+			server.put(BucketName, "object", []byte("data"))
+			client := server.client(Options{})
+			server.fail(http.StatusForbidden, tt.code)
+
+			for method, call := range clientCalls {
+				err := call(context.Background(), client)
+				require.Error(t, err, method)
+
+				assert.ErrorIs(t, err, tt.expected, method)
+			}
+		})
+	}
+}
+
+func TestClient_OtherErrorsNotMapped(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	client := server.client(Options{})
+	server.fail(http.StatusBadRequest, "InvalidArgument")
+
+	for method, call := range clientCalls {
+		err := call(context.Background(), client)
+		require.Error(t, err, method)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials, method)
+		assert.NotErrorIs(t, err, ErrAccessDenied, method)
+	}
+}
+
+func TestDeleteObjects_DeniedBatch(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	server.put(BucketName, "a", []byte("a"))
+	server.put(BucketName, "b", []byte("b"))
+	client := server.client(Options{})
+	server.fail(http.StatusForbidden, "AccessDenied")
+
+	deleted, err := client.DeleteObjects(context.Background(), []string{"a", "b"})
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.Zero(t, deleted)
+}
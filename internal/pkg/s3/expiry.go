@@ -0,0 +1,95 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"go.uber.org/zap"
+)
+
+// expiresAtMetadata is the user metadata holding the unix time the object expires at
+const expiresAtMetadata = "Expires-At"
+
+// expirationMetadata returns the user metadata marking the object to expire at the given time
+func expirationMetadata(expiresAt time.Time) map[string]string {
+	return map[string]string{expiresAtMetadata: strconv.FormatInt(expiresAt.Unix(), 10)}
+}
+
+// IsExpired decides whether an object with the given user metadata is expired at the given time.
+// Objects without a valid expiry never expire.
+func IsExpired(metadata map[string]string, now time.Time) bool {
+	for key, value := range metadata {
+		// Minio returns the user metadata with the X-Amz-Meta- prefix, in varying case
+		key = strings.TrimPrefix(http.CanonicalHeaderKey(key), "X-Amz-Meta-")
+		if key != expiresAtMetadata {
+			continue
+		}
+
+		expiresAt, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+
+		return !now.Before(time.Unix(expiresAt, 0))
+	}
+
+	return false
+}
+
+// DeleteExpiredObjects deletes the objects expired at the given time and returns the number of deleted objects
+func (c *MinioClient) DeleteExpiredObjects(ctx context.Context, now time.Time) (int, error) {
+	c.logger.Info("Deleting expired objects from s3 instance")
+
+	deleted := 0
+	for object := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Recursive: true, WithMetadata: true}) {
+		if object.Err != nil {
+			res := minio.ToErrorResponse(object.Err)
+			// A missing bucket holds no objects
+			if res.Code == "NoSuchBucket" {
+				return deleted, nil
+			}
+
+			return deleted, c.wrapError(object.Err, "failed to list objects")
+		}
+
+		if !IsExpired(object.UserMetadata, now) {
+			continue
+		}
+
+		err := c.client.RemoveObject(ctx, c.bucket, object.Key, minio.RemoveObjectOptions{})
+		if err != nil {
+			return deleted, c.wrapError(err, "failed to delete expired object "+object.Key)
+		}
+
+		c.logger.Debug("Deleted expired object", zap.String("objectId", object.Key))
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// setBucketExpiration configures a lifecycle rule expiring all objects in the bucket after the configured days
+func (c *MinioClient) setBucketExpiration(ctx context.Context) error {
+	config := lifecycle.NewConfiguration()
+	config.Rules = []lifecycle.Rule{
+		{
+			ID:     "expire-objects",
+			Status: "Enabled",
+			Expiration: lifecycle.Expiration{
+				Days: lifecycle.ExpirationDays(c.options.ExpirationDays),
+			},
+		},
+	}
+
+	err := c.client.SetBucketLifecycle(ctx, c.bucket, config)
+	if err != nil {
+		return c.wrapError(err, "failed to set bucket lifecycle")
+	}
+
+	return nil
+}
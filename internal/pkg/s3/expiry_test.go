@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	unix := func(t time.Time) string {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		expired  bool
+	}{
+		{name: "no metadata"},
+		{name: "other metadata", metadata: map[string]string{"X-Amz-Meta-Owner": "team"}},
+		{name: "expires later", metadata: map[string]string{"X-Amz-Meta-Expires-At": unix(now.Add(time.Second))}},
+		{name: "expires now", metadata: map[string]string{"X-Amz-Meta-Expires-At": unix(now)}, expired: true},
+		{name: "expired", metadata: map[string]string{"X-Amz-Meta-Expires-At": unix(now.Add(-time.Hour))}, expired: true},
+		{name: "lowercase key", metadata: map[string]string{"x-amz-meta-expires-at": unix(now.Add(-time.Hour))}, expired: true},
+		{name: "without prefix", metadata: map[string]string{"Expires-At": unix(now.Add(-time.Hour))}, expired: true},
+		{name: "invalid expiry", metadata: map[string]string{"X-Amz-Meta-Expires-At": "tomorrow"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expired, IsExpired(tt.metadata, now))
+		})
+	}
+}
+
+func TestMinioClient_ExpiryTagging(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	client := server.client(Options{})
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	_, err := client.AddOrUpdateObject(ctx, "temporary", strings.NewReader("data"), PutOptions{Size: 4, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	_, err = client.AddOrUpdateObject(ctx, "permanent", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+
+	// The expiry is stored in the user metadata of the object
+	assert.Equal(t, userMetadata{"X-Amz-Meta-Expires-At": strconv.FormatInt(expiresAt.Unix(), 10)}, server.metadata[BucketName+"/temporary"])
+	assert.Empty(t, server.metadata[BucketName+"/permanent"])
+}
+
+func TestMinioClient_DeleteExpiredObjects(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	client := server.client(Options{})
+	ctx := context.Background()
+	now := time.Now()
+
+	for objectId, expiresAt := range map[string]time.Time{
+		"expired":   now.Add(-time.Minute),
+		"expiring":  now.Add(time.Minute),
+		"permanent": {},
+	} {
+		_, err := client.AddOrUpdateObject(ctx, objectId, strings.NewReader("data"), PutOptions{Size: 4, ExpiresAt: expiresAt})
+		require.NoError(t, err)
+	}
+
+	deleted, err := client.DeleteExpiredObjects(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	objectIds, err := client.GetObjects(ctx, ListFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"expiring", "permanent"}, objectIds)
+
+	// The expiring object is deleted once it expires
+	deleted, err = client.DeleteExpiredObjects(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+}
+
+func TestMinioClient_DeleteExpiredObjectsMissingBucket(t *testing.T) {
+	server := newFakeS3(t)
+
+	deleted, err := server.client(Options{}).DeleteExpiredObjects(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestMinioClient_BucketExpiration(t *testing.T) {
+	server := newFakeS3(t)
+	client := server.client(Options{AutoCreateBucket: true, ExpirationDays: 7})
+
+	_, err := client.AddOrUpdateObject(context.Background(), "object", strings.NewReader("data"), PutOptions{Size: 4})
+	require.NoError(t, err)
+
+	// The lifecycle rule expires all objects of the bucket
+	assert.Contains(t, server.lifecycles[BucketName], "<Status>Enabled</Status>")
+	assert.Contains(t, server.lifecycles[BucketName], "<Expiration><Days>7</Days></Expiration>")
+}
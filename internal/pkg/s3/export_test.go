@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportObject(t *testing.T) {
+	source := newFakeS3(t, BucketName)
+	target := newFakeS3(t, "backup")
+	data := bytes.Repeat([]byte("exported"), 1024)
+	source.put(BucketName, "object", data)
+
+	err := source.client(Options{}).ExportObject(context.Background(), "object", target.server.URL, "backup", "access", "secret")
+	require.NoError(t, err)
+
+	exported, ok := target.object("backup", "object")
+	require.True(t, ok)
+	assert.Equal(t, data, exported)
+}
+
+func TestExportObject_NotFound(t *testing.T) {
+	source := newFakeS3(t, BucketName)
+	target := newFakeS3(t, "backup")
+
+	err := source.client(Options{}).ExportObject(context.Background(), "missing", target.server.URL, "backup", "access", "secret")
+	require.ErrorIs(t, err, ErrObjectNotFound)
+
+	_, ok := target.object("backup", "missing")
+	assert.False(t, ok)
+}
+
+func TestExportObject_TargetFails(t *testing.T) {
+	source := newFakeS3(t, BucketName)
+	target := newFakeS3(t, "backup")
+	source.put(BucketName, "object", []byte("data"))
+	target.fail(http.StatusForbidden, "AccessDenied")
+
+	err := source.client(Options{}).ExportObject(context.Background(), "object", target.server.URL, "backup", "access", "secret")
+	require.Error(t, err)
+}
@@ -0,0 +1,455 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a minimal path-style S3 server, serving the requests of the Minio client used by the gateway
+type fakeS3 struct {
+	t      testing.TB
+	server *httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+	// status and code fail all requests with the S3 error, if set
+	status int
+	code   string
+	// puts counts the object uploads
+	puts int
+	// bucketChecks and bucketCreations count the bucket existence checks and creations
+	bucketChecks, bucketCreations int
+	// metadata is the user metadata of the objects, keyed by the bucket and the key
+	metadata map[string]userMetadata
+	// lifecycles are the lifecycle configurations of the buckets
+	lifecycles map[string]string
+	// locations are the location constraints the buckets were created with
+	locations map[string]string
+	// regions are the regions the requests were signed for
+	regions map[string]bool
+	// connections counts the accepted connections
+	connections atomic.Int64
+	// open counts the connections not closed yet
+	open atomic.Int64
+	// notifications are streamed to the bucket notification listeners
+	notifications chan notification.Info
+}
+
+// newFakeS3 starts a fake S3 server with the buckets
+func newFakeS3(t testing.TB, buckets ...string) *fakeS3 {
+	f := newUnstartedFakeS3(t, buckets...)
+	f.server.Start()
+	return f
+}
+
+// newTLSFakeS3 starts a fake S3 server with the buckets, serving TLS with a self-signed certificate
+func newTLSFakeS3(t testing.TB, buckets ...string) *fakeS3 {
+	f := newUnstartedFakeS3(t, buckets...)
+	f.server.StartTLS()
+	return f
+}
+
+func newUnstartedFakeS3(t testing.TB, buckets ...string) *fakeS3 {
+	f := &fakeS3{t: t, buckets: map[string]map[string][]byte{}, metadata: map[string]userMetadata{}, lifecycles: map[string]string{}, locations: map[string]string{}, regions: map[string]bool{}, notifications: make(chan notification.Info)}
+	for _, bucket := range buckets {
+		f.buckets[bucket] = map[string][]byte{}
+	}
+
+	f.server = httptest.NewUnstartedServer(f)
+	f.server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			f.connections.Add(1)
+			f.open.Add(1)
+		case http.StateClosed, http.StateHijacked:
+			f.open.Add(-1)
+		}
+	}
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// instance returns the S3 instance of the server
+func (f *fakeS3) instance() discovery.S3Instance {
+	host, port, err := net.SplitHostPort(f.server.Listener.Addr().String())
+	require.NoError(f.t, err)
+
+	return discovery.S3Instance{InstanceNum: 1, Hostname: host, Port: port, AccessKey: "access", SecretKey: "secret"}
+}
+
+// client creates a Minio client of the server
+func (f *fakeS3) client(options Options) *MinioClient {
+	client, err := NewMinioClient(f.instance(), options)
+	require.NoError(f.t, err)
+	return client
+}
+
+func (f *fakeS3) object(bucket, key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.buckets[bucket][key]
+	return data, ok
+}
+
+func (f *fakeS3) put(bucket, key string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.buckets[bucket] == nil {
+		f.buckets[bucket] = map[string][]byte{}
+	}
+	f.buckets[bucket][key] = data
+}
+
+// removeBucket removes the bucket with its objects
+func (f *fakeS3) removeBucket(bucket string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.buckets, bucket)
+}
+
+// bucketCalls returns the count of the bucket existence checks and creations
+func (f *fakeS3) bucketCalls() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bucketChecks, f.bucketCreations
+}
+
+// fail fails all following requests with the S3 error. The 5xx errors are retried by the Minio client.
+func (f *fakeS3) fail(status int, code string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+	f.code = code
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The listeners block until the client disconnects, so they don't hold the lock
+	if r.URL.Query().Has("events") {
+		f.listen(w, r)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != 0 {
+		f.error(w, f.status, f.code)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	objects, bucketExists := f.buckets[bucket]
+	if key == "" && r.Method == http.MethodHead {
+		f.bucketChecks++
+	}
+	if region := signedRegion(r); region != "" {
+		f.regions[region] = true
+	}
+
+	switch {
+	case query.Has("location"):
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`)
+	case query.Has("lifecycle") && r.Method == http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		require.NoError(f.t, err)
+		f.lifecycles[bucket] = string(body)
+	case key == "" && r.Method == http.MethodPut:
+		f.bucketCreations++
+		if !bucketExists {
+			f.buckets[bucket] = map[string][]byte{}
+			f.locations[bucket] = locationConstraint(f.t, r.Body)
+		}
+	case !bucketExists:
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+	case key == "" && r.Method == http.MethodHead:
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, bucket, objects, query.Get("metadata") == "true", query.Get("prefix"), query.Get("delimiter"), query.Get("start-after"), query.Get("continuation-token"), query.Get("max-keys"))
+	case key == "" && r.Method == http.MethodPost && query.Has("delete"):
+		f.deleteObjects(w, r, objects)
+	case r.Method == http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			// The client aborted the upload, it's never committed
+			return
+		}
+
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = decodeChunked(f.t, body)
+		}
+
+		if declared := r.Header.Get("X-Amz-Decoded-Content-Length"); declared != "" && declared != strconv.Itoa(len(body)) {
+			f.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		objects[key] = body
+		f.puts++
+		f.metadata[bucket+"/"+key] = userMetadata{}
+		for name, values := range r.Header {
+			if strings.HasPrefix(name, "X-Amz-Meta-") {
+				f.metadata[bucket+"/"+key][name] = values[0]
+			}
+		}
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		w.Header().Set("ETag", etag(data))
+		for name, value := range f.metadata[bucket+"/"+key] {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("Last-Modified", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// signedRegion returns the region of the AWS Signature V4 credential scope of the request
+func signedRegion(r *http.Request) string {
+	_, credential, ok := strings.Cut(r.Header.Get("Authorization"), "Credential=")
+	if !ok {
+		return ""
+	}
+
+	// <access key>/<date>/<region>/s3/aws4_request
+	scope := strings.Split(strings.Split(credential, ",")[0], "/")
+	if len(scope) < 3 {
+		return ""
+	}
+
+	return scope[2]
+}
+
+// locationConstraint reads the location constraint of the bucket creation, empty for the default region
+func locationConstraint(t testing.TB, body io.Reader) string {
+	configuration := struct {
+		LocationConstraint string
+	}{}
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	if len(data) > 0 {
+		require.NoError(t, xml.Unmarshal(data, &configuration))
+	}
+
+	return configuration.LocationConstraint
+}
+
+// notify streams the notification to the bucket notification listener
+func (f *fakeS3) notify(eventName, key string) {
+	info := notification.Info{Records: []notification.Event{{EventName: eventName}}}
+	info.Records[0].S3.Object.Key = key
+	f.notifications <- info
+}
+
+// listen streams the notifications as JSON lines until the client disconnects
+func (f *fakeS3) listen(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case info := <-f.notifications:
+			require.NoError(f.t, encoder.Encode(info))
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string
+	Prefix                string
+	KeyCount              int
+	MaxKeys               int
+	IsTruncated           bool
+	NextContinuationToken string `xml:",omitempty"`
+	Contents              []listedObject
+	CommonPrefixes        []commonPrefix
+}
+
+type commonPrefix struct {
+	Prefix string
+}
+
+type listedObject struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int
+	StorageClass string
+	UserMetadata userMetadata `xml:",omitempty"`
+}
+
+// userMetadata is the user metadata of an object, listed as <X-Amz-Meta-Name>value</X-Amz-Meta-Name> elements
+type userMetadata map[string]string
+
+func (m userMetadata) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(m) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tokens := []xml.Token{start}
+	for _, key := range keys {
+		element := xml.StartElement{Name: xml.Name{Local: key}}
+		tokens = append(tokens, element, xml.CharData(m[key]), element.End())
+	}
+	tokens = append(tokens, start.End())
+
+	for _, token := range tokens {
+		if err := e.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+
+	return e.Flush()
+}
+
+// list serves a ListObjectsV2 page, continuing after the token or the start-after key. With a delimiter, the keys
+// nested under the prefix are grouped into their common prefixes.
+func (f *fakeS3) list(w http.ResponseWriter, bucket string, objects map[string][]byte, withMetadata bool, prefix, delimiter, startAfter, token, maxKeys string) {
+	limit, err := strconv.Atoi(maxKeys)
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	if token != "" {
+		startAfter = token
+	}
+
+	keys := []string{}
+	for key := range objects {
+		if strings.HasPrefix(key, prefix) && key > startAfter {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := listBucketResult{Name: bucket, Prefix: prefix, MaxKeys: limit}
+	if len(keys) > limit {
+		keys = keys[:limit]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[limit-1]
+	}
+
+	prefixes := map[string]bool{}
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				nested := key[:len(prefix)+i+len(delimiter)]
+				if !prefixes[nested] {
+					prefixes[nested] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: nested})
+				}
+				continue
+			}
+		}
+
+		listed := listedObject{
+			Key:          key,
+			LastModified: time.Unix(0, 0).UTC().Format(time.RFC3339),
+			ETag:         etag(objects[key]),
+			Size:         len(objects[key]),
+			StorageClass: "STANDARD",
+		}
+		if withMetadata {
+			listed.UserMetadata = f.metadata[bucket+"/"+key]
+		}
+		result.Contents = append(result.Contents, listed)
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+
+	w.Header().Set("Content-Type", "application/xml")
+	require.NoError(f.t, xml.NewEncoder(w).Encode(result))
+}
+
+// deleteObjects serves a multi-object delete
+func (f *fakeS3) deleteObjects(w http.ResponseWriter, r *http.Request, objects map[string][]byte) {
+	request := struct {
+		Objects []struct {
+			Key string
+		} `xml:"Object"`
+	}{}
+	require.NoError(f.t, xml.NewDecoder(r.Body).Decode(&request))
+
+	for _, object := range request.Objects {
+		delete(objects, object.Key)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><DeleteResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></DeleteResult>`)
+}
+
+// decodeChunked decodes an aws-chunked body, dropping the chunk signatures
+func decodeChunked(t testing.TB, body []byte) []byte {
+	decoded := []byte{}
+	for {
+		header, rest, found := bytes.Cut(body, []byte("\r\n"))
+		require.True(t, found, "malformed aws-chunked body")
+
+		size, _, _ := strings.Cut(string(header), ";")
+		chunkSize, err := strconv.ParseInt(size, 16, 64)
+		require.NoError(t, err)
+
+		if chunkSize == 0 {
+			return decoded
+		}
+
+		decoded = append(decoded, rest[:chunkSize]...)
+		body = bytes.TrimPrefix(rest[chunkSize:], []byte("\r\n"))
+	}
+}
@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetObjects_Filter(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+
+	// 20 objects: report_00.csv, report_01.json... and image_00.png...
+	for i := 0; i < 10; i++ {
+		extension := "csv"
+		if i%2 == 1 {
+			extension = "json"
+		}
+		server.put(BucketName, fmt.Sprintf("report_%02d.%s", i, extension), []byte("data"))
+		server.put(BucketName, fmt.Sprintf("image_%02d.png", i), []byte("data"))
+	}
+	client := server.client(Options{})
+
+	tests := []struct {
+		name     string
+		filter   ListFilter
+		expected []string
+	}{
+		{
+			name:   "no filter",
+			filter: ListFilter{},
+			expected: []string{
+				"image_00.png", "image_01.png", "image_02.png", "image_03.png", "image_04.png",
+				"image_05.png", "image_06.png", "image_07.png", "image_08.png", "image_09.png",
+				"report_00.csv", "report_01.json", "report_02.csv", "report_03.json", "report_04.csv",
+				"report_05.json", "report_06.csv", "report_07.json", "report_08.csv", "report_09.json",
+			},
+		},
+		{
+			name:     "prefix",
+			filter:   ListFilter{Prefix: "image_0"},
+			expected: []string{"image_00.png", "image_01.png", "image_02.png", "image_03.png", "image_04.png", "image_05.png", "image_06.png", "image_07.png", "image_08.png", "image_09.png"},
+		},
+		{
+			name:     "suffix",
+			filter:   ListFilter{Suffix: ".csv"},
+			expected: []string{"report_00.csv", "report_02.csv", "report_04.csv", "report_06.csv", "report_08.csv"},
+		},
+		{
+			name:     "prefix and suffix",
+			filter:   ListFilter{Prefix: "report_0", Suffix: "5.json"},
+			expected: []string{"report_05.json"},
+		},
+		{
+			name:     "no match",
+			filter:   ListFilter{Prefix: "image", Suffix: ".csv"},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objectIds, err := client.GetObjects(context.Background(), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, objectIds)
+		})
+	}
+}
+
+func TestListFilter_Matches(t *testing.T) {
+	filter := ListFilter{Prefix: "team/", Suffix: ".txt"}
+
+	assert.True(t, filter.Matches("team/notes.txt"))
+	assert.False(t, filter.Matches("team/notes.csv"))
+	assert.False(t, filter.Matches("other/notes.txt"))
+	assert.True(t, ListFilter{}.Matches("anything"))
+}
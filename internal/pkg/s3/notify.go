@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"context"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/notification"
+)
+
+const (
+	ObjectEventCreated = "created"
+	ObjectEventDeleted = "deleted"
+)
+
+// ObjectEvent is a change of an object. S3 notifications don't distinguish a created object from an overwritten one,
+// both are reported as ObjectEventCreated.
+type ObjectEvent struct {
+	Type     string
+	ObjectId string
+}
+
+// WatchObject sends the changes of the object to the channel, until the context is cancelled or the notifications fail.
+// Requires the bucket notifications to be enabled on the S3 instance. The channel is not closed.
+func (c *MinioClient) WatchObject(ctx context.Context, objectId string, events chan<- ObjectEvent) error {
+	c.logger.Info("Watching object in s3 instance")
+
+	notifications := c.client.ListenBucketNotification(ctx, c.bucket, objectId, objectId, []string{
+		string(notification.ObjectCreatedAll),
+		string(notification.ObjectRemovedAll),
+	})
+
+	for info := range notifications {
+		if info.Err != nil {
+			return c.wrapError(info.Err, "failed to listen for bucket notifications")
+		}
+
+		for _, record := range info.Records {
+			// The prefix and suffix filters also match longer keys
+			if record.S3.Object.Key != objectId {
+				continue
+			}
+
+			event := ObjectEvent{Type: ObjectEventCreated, ObjectId: objectId}
+			if strings.HasPrefix(record.EventName, "s3:ObjectRemoved:") {
+				event.Type = ObjectEventDeleted
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchObject(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	client := server.client(Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan ObjectEvent)
+	done := make(chan error, 1)
+	go func() { done <- client.WatchObject(ctx, "object", events) }()
+
+	receive := func() ObjectEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no object event")
+			return ObjectEvent{}
+		}
+	}
+
+	server.notify("s3:ObjectCreated:Put", "object")
+	assert.Equal(t, ObjectEvent{Type: ObjectEventCreated, ObjectId: "object"}, receive())
+
+	// Longer keys matched by the prefix and suffix filters are skipped
+	server.notify("s3:ObjectCreated:Put", "object-object")
+	server.notify("s3:ObjectRemoved:Delete", "object")
+	assert.Equal(t, ObjectEvent{Type: ObjectEventDeleted, ObjectId: "object"}, receive())
+
+	server.notify("s3:ObjectCreated:CompleteMultipartUpload", "object")
+	assert.Equal(t, ObjectEvent{Type: ObjectEventCreated, ObjectId: "object"}, receive())
+
+	// Cancelling unsubscribes
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not stopped")
+	}
+}
@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+)
+
+// defaultMaxKeys is the page size used when ListOptions.MaxKeys is not set, matching the S3 default
+const defaultMaxKeys = 1000
+
+// ListOptions select a page of the listed objects
+type ListOptions struct {
+	// Prefix is matched by the S3 backend
+	Prefix string
+	// StartAfter resumes the listing after this key, as returned by the previous page
+	StartAfter string
+	// MaxKeys is the size of the page. Zero defaults to 1000.
+	MaxKeys int
+	// Recursive lists the objects under all '/' separated prefixes
+	Recursive bool
+}
+
+// ListObjectsPage lists a single page of the objectIds. The returned key is passed as ListOptions.StartAfter to list
+// the next page, and is empty after the last page.
+func (c *MinioClient) ListObjectsPage(ctx context.Context, options ListOptions) ([]string, string, error) {
+	c.logger.Info("Listing a page of objects from s3 instance", zap.String("prefix", options.Prefix), zap.String("startAfter", options.StartAfter))
+
+	maxKeys := options.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = defaultMaxKeys
+	}
+
+	// Stop the listing when returning early
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objectIds := make([]string, 0, maxKeys)
+	for object := range c.client.ListObjects(listCtx, c.bucket, listObjectsOptions(options, maxKeys)) {
+		if object.Err != nil {
+			return nil, "", c.wrapError(object.Err, "failed to list objects")
+		}
+
+		// Another object after a full page - the listing continues on the next page
+		if len(objectIds) == maxKeys {
+			return objectIds, objectIds[len(objectIds)-1], nil
+		}
+
+		objectIds = append(objectIds, object.Key)
+	}
+
+	// The listing is cut short when the context is cancelled
+	if ctx.Err() != nil {
+		return nil, "", ctx.Err()
+	}
+
+	return objectIds, "", nil
+}
+
+// listObjectsOptions converts the options, requesting the pages of maxKeys objects from the S3 backend
+func listObjectsOptions(options ListOptions, maxKeys int) minio.ListObjectsOptions {
+	return minio.ListObjectsOptions{
+		Prefix:     options.Prefix,
+		StartAfter: options.StartAfter,
+		MaxKeys:    maxKeys,
+		Recursive:  options.Recursive,
+	}
+}
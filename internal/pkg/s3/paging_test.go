@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putObjects stores n objects with the sorted keys object0000, object0001...
+func putObjects(server *fakeS3, n int) []string {
+	keys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("object%04d", i)
+		server.put(BucketName, key, []byte("data"))
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func TestListObjectsPage(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	keys := putObjects(server, 5000)
+	client := server.client(Options{})
+
+	listed := []string{}
+	options := ListOptions{MaxKeys: 1000}
+	pages := 0
+	for {
+		page, nextStartAfter, err := client.ListObjectsPage(context.Background(), options)
+		require.NoError(t, err)
+		pages++
+
+		assert.LessOrEqual(t, len(page), 1000)
+		listed = append(listed, page...)
+		if nextStartAfter == "" {
+			break
+		}
+
+		assert.Equal(t, page[len(page)-1], nextStartAfter)
+		options.StartAfter = nextStartAfter
+	}
+
+	assert.Equal(t, 5, pages)
+	assert.Equal(t, keys, listed)
+}
+
+func TestGetObjects_Paged(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	keys := putObjects(server, 5000)
+	server.put(BucketName, "other", []byte("data"))
+	client := server.client(Options{})
+
+	objectIds, err := client.GetObjects(context.Background(), ListFilter{Prefix: "object"})
+	require.NoError(t, err)
+	assert.Equal(t, keys, objectIds)
+
+	// The limit stops the listing within a page
+	objectIds, err = client.GetObjects(context.Background(), ListFilter{Prefix: "object", Suffix: "5", Limit: 150})
+	require.NoError(t, err)
+	require.Len(t, objectIds, 150)
+	assert.Equal(t, "object1495", objectIds[149])
+}
+
+func TestGetObjects_Cancelled(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	putObjects(server, 10)
+	client := server.client(Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	objectIds, err := client.GetObjects(ctx, ListFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, objectIds)
+}
+
+func TestCountObjects(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	putObjects(server, 2500)
+
+	count, err := server.client(Options{}).CountObjects(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2500, count)
+}
+
+func TestCountObjects_NestedKeys(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	for _, key := range []string{"team-a/x", "team-a/y", "team-b/photos/z", "object"} {
+		server.put(BucketName, key, []byte("data"))
+	}
+
+	// The nested keys are counted one by one, not by their common prefix
+	count, err := server.client(Options{}).CountObjects(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func TestStreamObjects_NestedKeys(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	keys := []string{"object", "team-a/x", "team-a/y", "team-b/photos/z"}
+	for _, key := range keys {
+		server.put(BucketName, key, []byte("data"))
+	}
+
+	objectIds := make(chan string, len(keys))
+	require.NoError(t, server.client(Options{}).StreamObjects(context.Background(), objectIds))
+	close(objectIds)
+
+	streamed := []string{}
+	for objectId := range objectIds {
+		streamed = append(streamed, objectId)
+	}
+	assert.Equal(t, keys, streamed)
+}
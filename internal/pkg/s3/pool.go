@@ -0,0 +1,270 @@
+package s3
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"go.uber.org/zap"
+	"golang.org/x/sync/semaphore"
+)
+
+// DefaultClientIdleTimeout is how long an unused client is pooled, unless configured otherwise
+const DefaultClientIdleTimeout = time.Minute * 5
+
+// ClientFactory creates the client of an instance
+type ClientFactory func(instance discovery.S3Instance) (Client, error)
+
+// ClientPool shares the client of each instance across the goroutines, so the requests reuse its connections instead
+// of dialing the instance again. A client is replaced when the address or the credentials of its instance change, and
+// evicted when it is not used for the idle timeout. The pooled clients are usually Minio clients, but any Client
+// created by the factory is pooled, so the tests can pool the in-memory ones.
+type ClientPool struct {
+	factory     ClientFactory
+	idleTimeout time.Duration
+	logger      *zap.Logger
+
+	// clients holds the *pooledClient of each instance, keyed by the instance number
+	clients sync.Map
+	// slots caps the number of pooled clients, evicting the least recently used one. Nil means no limit.
+	slots *semaphore.Weighted
+}
+
+type pooledClient struct {
+	// mu serializes creating the client, so the concurrent requests to a new instance share a single client
+	mu       sync.Mutex
+	instance discovery.S3Instance
+	client   Client
+	// evicted is set once the entry is removed from the pool, the requests waiting for it retry with a new one
+	evicted bool
+	// lastUsed is the time of the last use in Unix nanoseconds, read without the mutex by the eviction
+	lastUsed atomic.Int64
+}
+
+// NewClientPool creates a new instance of the ClientPool, creating the clients with the factory. The pool holds up
+// to maxSize clients and evicts the clients not used for the idle timeout. Zero keeps the defaults: no size limit and
+// a 5 minute idle timeout.
+func NewClientPool(factory ClientFactory, maxSize int, idleTimeout time.Duration) *ClientPool {
+	pool := &ClientPool{
+		factory:     factory,
+		idleTimeout: idleTimeout,
+		logger:      zap.L().Named("client-pool"),
+	}
+
+	if pool.idleTimeout <= 0 {
+		pool.idleTimeout = DefaultClientIdleTimeout
+	}
+
+	if maxSize > 0 {
+		pool.slots = semaphore.NewWeighted(int64(maxSize))
+	}
+
+	return pool
+}
+
+// Client returns the client of the instance, creating it if needed
+func (p *ClientPool) Client(instance discovery.S3Instance) (Client, error) {
+	// The health doesn't affect the connection
+	instance.Healthy, instance.LastChecked = false, time.Time{}
+	key := strconv.Itoa(instance.InstanceNum)
+
+	for {
+		value, _ := p.clients.LoadOrStore(key, &pooledClient{})
+		entry := value.(*pooledClient)
+
+		entry.mu.Lock()
+		if entry.evicted {
+			entry.mu.Unlock()
+			continue
+		}
+
+		client, err := p.entryClient(key, entry, instance)
+		entry.mu.Unlock()
+		return client, err
+	}
+}
+
+// entryClient returns the client of the entry, creating it if the entry is new or its instance changed. Must be
+// called with the entry locked.
+func (p *ClientPool) entryClient(key string, entry *pooledClient, instance discovery.S3Instance) (Client, error) {
+	if entry.client != nil && entry.instance == instance {
+		entry.lastUsed.Store(time.Now().UnixNano())
+		return entry.client, nil
+	}
+
+	// A new entry takes a slot of the pool, the replaced client keeps its slot
+	if entry.client == nil && !p.acquireSlot(key) {
+		p.logger.Warn("S3 client pool is full, the client isn't pooled", zap.Int("instance", instance.InstanceNum))
+		p.remove(key, entry)
+		return p.factory(instance)
+	}
+
+	client, err := p.factory(instance)
+	if err != nil {
+		if entry.client == nil {
+			p.remove(key, entry)
+			p.releaseSlot()
+		}
+
+		return nil, err
+	}
+
+	// The instance changed - release the previous client
+	if entry.client != nil {
+		p.closeClient(entry.instance.InstanceNum, entry.client)
+	}
+
+	entry.instance, entry.client = instance, client
+	entry.lastUsed.Store(time.Now().UnixNano())
+	return client, nil
+}
+
+// acquireSlot takes a slot of the pool, evicting the least recently used clients to make room for it. Returns false
+// if all slots are taken by the clients being created.
+func (p *ClientPool) acquireSlot(key string) bool {
+	if p.slots == nil {
+		return true
+	}
+
+	for !p.slots.TryAcquire(1) {
+		if !p.evictLeastRecentlyUsed(key) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *ClientPool) releaseSlot() {
+	if p.slots != nil {
+		p.slots.Release(1)
+	}
+}
+
+// evictLeastRecentlyUsed evicts the client used the longest time ago, except the one of the key. The entries locked
+// by other requests are skipped. Returns false if there was no client to evict.
+func (p *ClientPool) evictLeastRecentlyUsed(except string) bool {
+	var (
+		oldestKey   string
+		oldestEntry *pooledClient
+	)
+	p.clients.Range(func(key, value any) bool {
+		entry := value.(*pooledClient)
+		// The entries without a used client are being created
+		lastUsed := entry.lastUsed.Load()
+		if key != except && lastUsed != 0 && (oldestEntry == nil || lastUsed < oldestEntry.lastUsed.Load()) {
+			oldestKey, oldestEntry = key.(string), entry
+		}
+		return true
+	})
+
+	if oldestEntry == nil || !oldestEntry.mu.TryLock() {
+		return false
+	}
+	defer oldestEntry.mu.Unlock()
+
+	return p.evict(oldestKey, oldestEntry)
+}
+
+// EvictIdle releases the clients not used for the idle timeout. Returns the number of evicted clients.
+func (p *ClientPool) EvictIdle(now time.Time) int {
+	evicted := 0
+	p.clients.Range(func(key, value any) bool {
+		entry := value.(*pooledClient)
+		if now.Sub(time.Unix(0, entry.lastUsed.Load())) < p.idleTimeout {
+			return true
+		}
+
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+
+		// The client may have been used while waiting for the lock
+		if now.Sub(time.Unix(0, entry.lastUsed.Load())) >= p.idleTimeout && p.evict(key.(string), entry) {
+			evicted++
+		}
+		return true
+	})
+
+	return evicted
+}
+
+// EvictIdleClients releases the clients not used for the idle timeout, checking every half of it.
+// Blocks until the context is cancelled.
+func (p *ClientPool) EvictIdleClients(ctx context.Context) {
+	ticker := time.NewTicker(p.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		evicted := p.EvictIdle(time.Now())
+		if evicted > 0 {
+			p.logger.Debug("Evicted idle S3 clients", zap.Int("evicted", evicted))
+		}
+	}
+}
+
+// evict removes the entry with its client from the pool, releasing its slot. Must be called with the entry locked.
+// Returns false if the entry holds no client.
+func (p *ClientPool) evict(key string, entry *pooledClient) bool {
+	if entry.evicted || entry.client == nil {
+		return false
+	}
+
+	p.remove(key, entry)
+	p.closeClient(entry.instance.InstanceNum, entry.client)
+	p.releaseSlot()
+	return true
+}
+
+// remove removes the entry from the pool. Must be called with the entry locked.
+func (p *ClientPool) remove(key string, entry *pooledClient) {
+	entry.evicted = true
+	p.clients.CompareAndDelete(key, entry)
+}
+
+// Len returns the number of pooled clients
+func (p *ClientPool) Len() int {
+	n := 0
+	p.clients.Range(func(_, value any) bool {
+		entry := value.(*pooledClient)
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+
+		if entry.client != nil {
+			n++
+		}
+		return true
+	})
+
+	return n
+}
+
+// Close releases all pooled clients and empties the pool. It is idempotent and safe to call while requests are
+// draining: the requests in flight keep their clients, whose connections are released once they complete.
+func (p *ClientPool) Close() error {
+	p.clients.Range(func(key, value any) bool {
+		entry := value.(*pooledClient)
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+
+		p.evict(key.(string), entry)
+		return true
+	})
+
+	return nil
+}
+
+func (p *ClientPool) closeClient(instanceNum int, client Client) {
+	err := client.Close()
+	if err != nil {
+		p.logger.Warn("Failed to close S3 client", zap.Int("instance", instanceNum), zap.Error(err))
+	}
+}
@@ -0,0 +1,270 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// closingClient records it was closed
+type closingClient struct {
+	Client
+	closed atomic.Bool
+}
+
+func (c *closingClient) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// countingFactory creates the closingClients, counting them
+type countingFactory struct {
+	mu      sync.Mutex
+	created []*closingClient
+}
+
+func (f *countingFactory) create(discovery.S3Instance) (Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Creating a client takes a while, so the concurrent requests would create several
+	time.Sleep(time.Millisecond)
+	client := &closingClient{}
+	f.created = append(f.created, client)
+	return client, nil
+}
+
+func (f *countingFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.created)
+}
+
+func testInstance(n int) discovery.S3Instance {
+	return discovery.S3Instance{InstanceNum: n, Hostname: fmt.Sprintf("minio-%d", n), Port: "9000", AccessKey: "access", SecretKey: "secret"}
+}
+
+func TestClientPool_SharesClientAcrossGoroutines(t *testing.T) {
+	factory := &countingFactory{}
+	pool := NewClientPool(factory.create, 0, 0)
+
+	var wg sync.WaitGroup
+	clients := make([]Client, 50)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := pool.Client(testInstance(1))
+			assert.NoError(t, err)
+			clients[i] = client
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, factory.count())
+	for _, client := range clients {
+		assert.Same(t, clients[0], client)
+	}
+}
+
+func TestClientPool_ReplacesChangedInstance(t *testing.T) {
+	factory := &countingFactory{}
+	pool := NewClientPool(factory.create, 0, 0)
+
+	first, err := pool.Client(testInstance(1))
+	require.NoError(t, err)
+
+	// The health doesn't replace the client
+	healthy := testInstance(1)
+	healthy.Healthy = true
+	same, err := pool.Client(healthy)
+	require.NoError(t, err)
+	assert.Same(t, first, same)
+
+	rotated := testInstance(1)
+	rotated.SecretKey = "rotated"
+	second, err := pool.Client(rotated)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.True(t, first.(*closingClient).closed.Load())
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestClientPool_MaxSize(t *testing.T) {
+	factory := &countingFactory{}
+	pool := NewClientPool(factory.create, 2, 0)
+
+	first, err := pool.Client(testInstance(1))
+	require.NoError(t, err)
+	_, err = pool.Client(testInstance(2))
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = pool.Client(testInstance(2))
+	require.NoError(t, err)
+
+	// The least recently used client makes room for the new one
+	_, err = pool.Client(testInstance(3))
+	require.NoError(t, err)
+	assert.Equal(t, 2, pool.Len())
+	assert.True(t, first.(*closingClient).closed.Load())
+
+	_, err = pool.Client(testInstance(1))
+	require.NoError(t, err)
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, 4, factory.count())
+}
+
+func TestClientPool_EvictIdle(t *testing.T) {
+	factory := &countingFactory{}
+	pool := NewClientPool(factory.create, 1, time.Minute)
+
+	idle, err := pool.Client(testInstance(1))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, pool.EvictIdle(time.Now()))
+	assert.Equal(t, 1, pool.EvictIdle(time.Now().Add(time.Minute)))
+	assert.True(t, idle.(*closingClient).closed.Load())
+	assert.Equal(t, 0, pool.Len())
+
+	// The evicted client released its slot
+	_, err = pool.Client(testInstance(2))
+	require.NoError(t, err)
+	assert.Equal(t, 1, pool.Len())
+
+	require.NoError(t, pool.Close())
+	assert.Equal(t, 0, pool.Len())
+}
+
+func TestMinioClient_CloseKeepsSharedConnections(t *testing.T) {
+	first, second := newFakeS3(t, BucketName), newFakeS3(t, BucketName)
+	first.put(BucketName, "object", []byte("data"))
+	second.put(BucketName, "object", []byte("data"))
+
+	// The clients of both instances share the transport
+	firstClient, secondClient := first.client(Options{}), second.client(Options{})
+	ctx := context.Background()
+	_, err := firstClient.StatObject(ctx, "object")
+	require.NoError(t, err)
+	_, err = secondClient.StatObject(ctx, "object")
+	require.NoError(t, err)
+
+	// Closing the client of one instance keeps the connections to the other one
+	require.NoError(t, firstClient.Close())
+	_, err = secondClient.StatObject(ctx, "object")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, second.connections.Load())
+}
+
+// BenchmarkClientPool compares the connections opened by the pooled clients with a client created per request, each
+// with its own transport, under concurrent load
+func BenchmarkClientPool(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		client func(b *testing.B, server *fakeS3, pool *ClientPool) Client
+	}{
+		{name: "pooled", client: func(b *testing.B, server *fakeS3, pool *ClientPool) Client {
+			client, err := pool.Client(server.instance())
+			if err != nil {
+				b.Fatal(err)
+			}
+			return client
+		}},
+		{name: "client per request", client: func(b *testing.B, server *fakeS3, pool *ClientPool) Client {
+			instance := server.instance()
+			transport, err := minio.DefaultTransport(false)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			client, err := minio.New(instance.Hostname+":"+instance.Port, &minio.Options{Creds: credentials.NewStaticV4("access", "secret", ""), Transport: transport})
+			if err != nil {
+				b.Fatal(err)
+			}
+			return &MinioClient{client: client, bucket: BucketName, logger: zap.NewNop()}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			server := newFakeS3(b, BucketName)
+			server.put(BucketName, "object", []byte("data"))
+			pool := NewClientPool(func(instance discovery.S3Instance) (Client, error) {
+				return NewMinioClient(instance, Options{})
+			}, 0, 0)
+			defer pool.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, err := bm.client(b, server, pool).StatObject(context.Background(), "object")
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(server.connections.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func TestClientPool_CloseReleasesConnections(t *testing.T) {
+	server := newFakeS3(t, BucketName)
+	server.put(BucketName, "object", []byte("data"))
+
+	pool := NewClientPool(func(instance discovery.S3Instance) (Client, error) {
+		return NewMinioClient(instance, Options{})
+	}, 0, 0)
+
+	client, err := pool.Client(server.instance())
+	require.NoError(t, err)
+	_, err = client.StatObject(context.Background(), "object")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, server.open.Load())
+
+	require.NoError(t, pool.Close())
+	CloseIdleConnections()
+	assert.Eventually(t, func() bool { return server.open.Load() == 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, pool.Len())
+
+	// Closing again is a no-op
+	require.NoError(t, pool.Close())
+}
+
+func TestClientPool_CloseWhileDraining(t *testing.T) {
+	factory := &countingFactory{}
+	pool := NewClientPool(factory.create, 0, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := pool.Client(testInstance(i % 5))
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	require.NoError(t, pool.Close())
+	wg.Wait()
+	require.NoError(t, pool.Close())
+
+	// The clients created while closing are released by the next Close
+	assert.Equal(t, 0, pool.Len())
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	for _, client := range factory.created {
+		assert.True(t, client.closed.Load())
+	}
+}
@@ -0,0 +1,573 @@
+// Package s3test provides an in-memory s3.Client for the tests of the packages using the S3 instances
+package s3test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/pkg/s3"
+)
+
+// Object is an object stored in the Client
+type Object struct {
+	Data         []byte
+	ETag         string
+	VersionId    string
+	LastModified time.Time
+	ContentType  string
+	Metadata     map[string]string
+	ExpiresAt    time.Time
+}
+
+// Client is an in-memory s3.Client. The objects are stored in a single bucket, versioned on each write.
+type Client struct {
+	mu       sync.Mutex
+	objects  map[string]*Object
+	versions map[string][]*Object
+	version  int
+	err      error
+	// methodErrs fail the calls of the single methods
+	methodErrs map[string]error
+	closed     bool
+
+	// Exported records the objects exported to the external targets, keyed by "<endpoint>/<bucket>/<objectId>"
+	Exported map[string][]byte
+	// Calls counts the calls of each method, e.g. "GetObject"
+	Calls map[string]int
+}
+
+// NewClient creates an empty Client
+func NewClient() *Client {
+	return &Client{
+		objects:    map[string]*Object{},
+		versions:   map[string][]*Object{},
+		methodErrs: map[string]error{},
+		Exported:   map[string][]byte{},
+		Calls:      map[string]int{},
+	}
+}
+
+// Fail makes all following calls return the error, until called with nil
+func (c *Client) Fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// FailMethod makes the following calls of the method, e.g. "AddOrUpdateObject", return the error, until called with nil
+func (c *Client) FailMethod(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.methodErrs[method] = err
+}
+
+// Put stores the object directly, bypassing the failures
+func (c *Client) Put(objectId string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(objectId, data, s3.PutOptions{})
+}
+
+// Object returns the stored object, or nil if it doesn't exist
+func (c *Client) Object(objectId string) *Object {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.objects[objectId]
+}
+
+// Keys returns the sorted keys of the stored objects
+func (c *Client) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.keys("")
+}
+
+// CallCount returns the number of calls of the method
+func (c *Client) CallCount(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Calls[method]
+}
+
+// Closed reports if the client was closed
+func (c *Client) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// call records the call and returns the configured failure
+func (c *Client) call(ctx context.Context, method string) error {
+	c.Calls[method]++
+	if c.err != nil {
+		return c.err
+	}
+
+	if err := c.methodErrs[method]; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (c *Client) put(objectId string, data []byte, options s3.PutOptions) *Object {
+	c.version++
+	sum := md5.Sum(data)
+	object := &Object{
+		Data:         data,
+		ETag:         hex.EncodeToString(sum[:]),
+		VersionId:    strconv.Itoa(c.version),
+		LastModified: time.Now(),
+		ContentType:  "application/octet-stream",
+		Metadata:     options.Metadata,
+		ExpiresAt:    options.ExpiresAt,
+	}
+	c.objects[objectId] = object
+	c.versions[objectId] = append(c.versions[objectId], object)
+	return object
+}
+
+func (c *Client) keys(prefix string) []string {
+	keys := []string{}
+	for key := range c.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func (o *Object) info() *s3.ObjectInfo {
+	return &s3.ObjectInfo{
+		Size:         int64(len(o.Data)),
+		LastModified: o.LastModified,
+		ETag:         o.ETag,
+		VersionId:    o.VersionId,
+		ContentType:  o.ContentType,
+		Metadata:     o.Metadata,
+	}
+}
+
+// AddOrUpdateObject stores the data. Data not matching the declared size fails with s3.ErrSizeMismatch, without
+// storing anything.
+func (c *Client) AddOrUpdateObject(ctx context.Context, objectId string, data io.Reader, options s3.PutOptions) (*s3.ObjectInfo, error) {
+	c.mu.Lock()
+	err := c.call(ctx, "AddOrUpdateObject")
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// The data is read unlocked, as a real upload does
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Size > 0 && int64(len(content)) != options.Size {
+		return nil, errors.Wrapf(s3.ErrSizeMismatch, "read %d of %d bytes", len(content), options.Size)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.put(objectId, content, options).info(), nil
+}
+
+// GetObject returns a reader of the object content
+func (c *Client) GetObject(ctx context.Context, objectId string) (io.Reader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetObject"); err != nil {
+		return nil, err
+	}
+
+	object, ok := c.objects[objectId]
+	if !ok {
+		return nil, s3.ErrObjectNotFound
+	}
+
+	return bytes.NewReader(object.Data), nil
+}
+
+// GetObjects lists the matching objectIds in the key order
+func (c *Client) GetObjects(ctx context.Context, filter s3.ListFilter) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetObjects"); err != nil {
+		return nil, err
+	}
+
+	objectIds := []string{}
+	for _, key := range c.keys(filter.Prefix) {
+		if !filter.Matches(key) {
+			continue
+		}
+
+		objectIds = append(objectIds, key)
+		if filter.Limit > 0 && len(objectIds) >= filter.Limit {
+			break
+		}
+	}
+
+	return objectIds, nil
+}
+
+// GetObjectsSummary lists the matching objects with their metadata in the key order
+func (c *Client) GetObjectsSummary(ctx context.Context, filter s3.ListFilter) ([]s3.ObjectSummary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetObjectsSummary"); err != nil {
+		return nil, err
+	}
+
+	summaries := []s3.ObjectSummary{}
+	for _, key := range c.keys(filter.Prefix) {
+		if !filter.Matches(key) {
+			continue
+		}
+
+		object := c.objects[key]
+		summaries = append(summaries, s3.ObjectSummary{ObjectId: key, Size: int64(len(object.Data)), LastModified: object.LastModified, ETag: object.ETag})
+		if filter.Limit > 0 && len(summaries) >= filter.Limit {
+			break
+		}
+	}
+
+	return summaries, nil
+}
+
+// StreamObjects sends the objectIds in the key order
+func (c *Client) StreamObjects(ctx context.Context, objectIds chan<- string) error {
+	c.mu.Lock()
+	err := c.call(ctx, "StreamObjects")
+	keys := c.keys("")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		select {
+		case objectIds <- key:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// ListObjectsPage lists a page of the objectIds in the key order
+func (c *Client) ListObjectsPage(ctx context.Context, options s3.ListOptions) ([]string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "ListObjectsPage"); err != nil {
+		return nil, "", err
+	}
+
+	maxKeys := options.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	objectIds := []string{}
+	for _, key := range c.keys(options.Prefix) {
+		if key <= options.StartAfter {
+			continue
+		}
+
+		if len(objectIds) == maxKeys {
+			return objectIds, objectIds[len(objectIds)-1], nil
+		}
+
+		objectIds = append(objectIds, key)
+	}
+
+	return objectIds, "", nil
+}
+
+// CountObjects counts the stored objects
+func (c *Client) CountObjects(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "CountObjects"); err != nil {
+		return 0, err
+	}
+
+	return len(c.objects), nil
+}
+
+// WatchObject blocks until the context is cancelled, no changes are reported
+func (c *Client) WatchObject(ctx context.Context, objectId string, events chan<- s3.ObjectEvent) error {
+	c.mu.Lock()
+	err := c.call(ctx, "WatchObject")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// StatObject returns the metadata of the object
+func (c *Client) StatObject(ctx context.Context, objectId string) (*s3.ObjectInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "StatObject"); err != nil {
+		return nil, err
+	}
+
+	object, ok := c.objects[objectId]
+	if !ok {
+		return nil, s3.ErrObjectNotFound
+	}
+
+	return object.info(), nil
+}
+
+// ObjectExists checks if the object is stored
+func (c *Client) ObjectExists(ctx context.Context, objectId string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "ObjectExists"); err != nil {
+		return false, err
+	}
+
+	_, ok := c.objects[objectId]
+	return ok, nil
+}
+
+// DeleteObject deletes the object. Deleting a missing object succeeds, as in S3.
+func (c *Client) DeleteObject(ctx context.Context, objectId string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "DeleteObject"); err != nil {
+		return err
+	}
+
+	delete(c.objects, objectId)
+	return nil
+}
+
+// CopyObject copies the object, adding the metadata to its user metadata
+func (c *Client) CopyObject(ctx context.Context, sourceId, targetId string, metadata map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "CopyObject"); err != nil {
+		return err
+	}
+
+	source, ok := c.objects[sourceId]
+	if !ok {
+		return s3.ErrObjectNotFound
+	}
+
+	userMetadata := map[string]string{}
+	for key, value := range source.Metadata {
+		userMetadata[key] = value
+	}
+	for key, value := range metadata {
+		userMetadata[key] = value
+	}
+
+	c.put(targetId, source.Data, s3.PutOptions{Metadata: userMetadata, ExpiresAt: source.ExpiresAt})
+	return nil
+}
+
+// findVersion finds the version of the object
+func (c *Client) findVersion(objectId, versionId string) (*Object, error) {
+	for _, version := range c.versions[objectId] {
+		if version.VersionId == versionId {
+			return version, nil
+		}
+	}
+
+	return nil, s3.ErrObjectNotFound
+}
+
+// GetObjectVersion returns a reader of the version content
+func (c *Client) GetObjectVersion(ctx context.Context, objectId, versionId string) (io.Reader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetObjectVersion"); err != nil {
+		return nil, err
+	}
+
+	version, err := c.findVersion(objectId, versionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(version.Data), nil
+}
+
+// StatObjectVersion returns the metadata of the version
+func (c *Client) StatObjectVersion(ctx context.Context, objectId, versionId string) (*s3.ObjectInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "StatObjectVersion"); err != nil {
+		return nil, err
+	}
+
+	version, err := c.findVersion(objectId, versionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return version.info(), nil
+}
+
+// DeleteObjectVersion permanently deletes the version
+func (c *Client) DeleteObjectVersion(ctx context.Context, objectId, versionId string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "DeleteObjectVersion"); err != nil {
+		return err
+	}
+
+	versions := c.versions[objectId]
+	for i, version := range versions {
+		if version.VersionId != versionId {
+			continue
+		}
+
+		c.versions[objectId] = append(versions[:i:i], versions[i+1:]...)
+		if c.objects[objectId] == version {
+			delete(c.objects, objectId)
+			if i > 0 {
+				c.objects[objectId] = versions[i-1]
+			}
+		}
+
+		return nil
+	}
+
+	return s3.ErrObjectNotFound
+}
+
+// ListObjectVersions lists the versions of the object, the latest first
+func (c *Client) ListObjectVersions(ctx context.Context, objectId string) ([]s3.ObjectVersion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "ListObjectVersions"); err != nil {
+		return nil, err
+	}
+
+	versions := c.versions[objectId]
+	if len(versions) == 0 {
+		return nil, s3.ErrObjectNotFound
+	}
+
+	result := make([]s3.ObjectVersion, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		result = append(result, s3.ObjectVersion{
+			VersionId:    version.VersionId,
+			Size:         int64(len(version.Data)),
+			LastModified: version.LastModified,
+			ETag:         version.ETag,
+			IsLatest:     c.objects[objectId] == version,
+		})
+	}
+
+	return result, nil
+}
+
+// ListObjectsByPrefix lists the objectIds starting with the prefix
+func (c *Client) ListObjectsByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "ListObjectsByPrefix"); err != nil {
+		return nil, err
+	}
+
+	return c.keys(prefix), nil
+}
+
+// DeleteObjects deletes the objects and returns the number of deleted objects
+func (c *Client) DeleteObjects(ctx context.Context, objectIds []string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "DeleteObjects"); err != nil {
+		return 0, err
+	}
+
+	for _, objectId := range objectIds {
+		delete(c.objects, objectId)
+	}
+
+	return len(objectIds), nil
+}
+
+// ExportObject records the object content in Exported
+func (c *Client) ExportObject(ctx context.Context, objectId string, targetEndpoint, targetBucket, accessKey, secretKey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "ExportObject"); err != nil {
+		return err
+	}
+
+	object, ok := c.objects[objectId]
+	if !ok {
+		return s3.ErrObjectNotFound
+	}
+
+	c.Exported[targetEndpoint+"/"+targetBucket+"/"+objectId] = object.Data
+	return nil
+}
+
+// GetUsage counts the stored objects and bytes
+func (c *Client) GetUsage(ctx context.Context) (s3.Usage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "GetUsage"); err != nil {
+		return s3.Usage{}, err
+	}
+
+	usage := s3.Usage{}
+	for _, object := range c.objects {
+		usage.Objects++
+		usage.Bytes += int64(len(object.Data))
+	}
+
+	return usage, nil
+}
+
+// DeleteExpiredObjects deletes the objects expired at the given time
+func (c *Client) DeleteExpiredObjects(ctx context.Context, now time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call(ctx, "DeleteExpiredObjects"); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for key, object := range c.objects {
+		if !object.ExpiresAt.IsZero() && !now.Before(object.ExpiresAt) {
+			delete(c.objects, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// Close marks the client closed
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"context"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+)
+
+// ObjectSummary is an object listed with its metadata
+type ObjectSummary struct {
+	ObjectId     string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+}
+
+// GetObjectsSummary lists the objects matching the filter with their size, last modification and ETag
+func (c *MinioClient) GetObjectsSummary(ctx context.Context, filter ListFilter) ([]ObjectSummary, error) {
+	c.logger.Info("Getting object summaries from s3 instance", zap.String("prefix", filter.Prefix), zap.String("suffix", filter.Suffix))
+
+	// Stop the listing when returning early
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	summaries := []ObjectSummary{}
+	for object := range c.client.ListObjects(listCtx, c.bucket, minio.ListObjectsOptions{Prefix: filter.Prefix, Recursive: true, WithMetadata: true}) {
+		if object.Err != nil {
+			return nil, c.wrapError(object.Err, "failed to list objects")
+		}
+
+		if !filter.Matches(object.Key) {
+			continue
+		}
+
+		summaries = append(summaries, objectSummary(object))
+		if filter.Limit > 0 && len(summaries) >= filter.Limit {
+			break
+		}
+	}
+
+	return summaries, nil
+}
+
+// objectSummary converts the listed Minio object
+func objectSummary(object minio.ObjectInfo) ObjectSummary {
+	return ObjectSummary{
+		ObjectId:     object.Key,
+		Size:         object.Size,
+		LastModified: object.LastModified,
+		ETag:         object.ETag,
+	}
+}
@@ -0,0 +1,129 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// caBundle writes the certificate of the TLS server to a CA bundle file
+func (f *fakeS3) caBundle(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, bundle, 0o600))
+	return path
+}
+
+// roundTrip uploads and downloads an object through the client
+func roundTrip(client *MinioClient) error {
+	_, err := client.AddOrUpdateObject(context.Background(), "object", bytes.NewReader([]byte("data")), PutOptions{Size: 4})
+	if err != nil {
+		return err
+	}
+
+	object, err := client.GetObject(context.Background(), "object")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.ReadAll(object)
+	return err
+}
+
+func TestMinioClient_TLS(t *testing.T) {
+	server := newTLSFakeS3(t, BucketName)
+
+	tests := []struct {
+		name     string
+		instance func(instance discovery.S3Instance) discovery.S3Instance
+		err      string
+	}{
+		{
+			name: "verified with the CA bundle",
+			instance: func(instance discovery.S3Instance) discovery.S3Instance {
+				instance.Secure = true
+				instance.CACert = server.caBundle(t)
+				return instance
+			},
+		},
+		{
+			name: "skip verify",
+			instance: func(instance discovery.S3Instance) discovery.S3Instance {
+				instance.Secure = true
+				instance.InsecureSkipVerify = true
+				return instance
+			},
+		},
+		{
+			name: "unknown authority",
+			instance: func(instance discovery.S3Instance) discovery.S3Instance {
+				instance.Secure = true
+				return instance
+			},
+			err: "certificate",
+		},
+		{
+			name: "plaintext",
+			instance: func(instance discovery.S3Instance) discovery.S3Instance {
+				return instance
+			},
+			err: "HTTP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewMinioClient(tt.instance(server.instance()), Options{})
+			require.NoError(t, err)
+
+			err = roundTrip(client)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMinioClient_MixedFleet(t *testing.T) {
+	secureServer := newTLSFakeS3(t, BucketName)
+	plainServer := newFakeS3(t, BucketName)
+
+	secure := secureServer.instance()
+	secure.Secure = true
+	secure.CACert = secureServer.caBundle(t)
+	secureClient, err := NewMinioClient(secure, Options{})
+	require.NoError(t, err)
+
+	plainClient, err := NewMinioClient(plainServer.instance(), Options{})
+	require.NoError(t, err)
+
+	require.NoError(t, roundTrip(secureClient))
+	require.NoError(t, roundTrip(plainClient))
+
+	_, ok := secureServer.object(BucketName, "object")
+	assert.True(t, ok)
+	_, ok = plainServer.object(BucketName, "object")
+	assert.True(t, ok)
+}
+
+func TestMinioClient_InvalidCABundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+
+	instance := newFakeS3(t, BucketName).instance()
+	instance.Secure = true
+	instance.CACert = path
+
+	_, err := NewMinioClient(instance, Options{})
+	assert.ErrorContains(t, err, "no certificates found")
+}
@@ -0,0 +1,117 @@
+package s3
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/homework-object-storage/internal/discovery"
+)
+
+// Keep-alive period of the connections to the S3 instances, as in the Minio default transport
+const dialKeepAlive = 30 * time.Second
+
+// ClientOptions configure the connections of the Minio clients. Zero values keep the Minio defaults.
+type ClientOptions struct {
+	// DialTimeout bounds connecting to an instance, separately from the request. An instance whose address went stale
+	// fails fast instead of hanging until the OS gives up on the connection.
+	DialTimeout time.Duration
+	// ResponseHeaderTimeout bounds waiting for the response headers after the request is sent
+	ResponseHeaderTimeout time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake with the instances serving TLS
+	TLSHandshakeTimeout time.Duration
+	// MaxIdleConns limits the idle connections kept across all instances
+	MaxIdleConns int
+	// OperationTimeout bounds the short operations, such as stat and delete. The object transfers and the listings
+	// are bounded only by the context.
+	OperationTimeout time.Duration
+}
+
+// transportKey identifies the transports which can be shared - the clients with the same TLS and connection settings
+type transportKey struct {
+	secure             bool
+	insecureSkipVerify bool
+	caCert             string
+	options            ClientOptions
+}
+
+// The transports shared by the clients, so the connections are reused across them
+var transports = struct {
+	mu sync.Mutex
+	m  map[transportKey]*http.Transport
+}{m: map[transportKey]*http.Transport{}}
+
+// sharedTransport returns the transport used to dial the instance, creating it on the first use
+func sharedTransport(instance discovery.S3Instance, options ClientOptions) (*http.Transport, error) {
+	key := transportKey{
+		secure:             instance.Secure,
+		insecureSkipVerify: instance.InsecureSkipVerify,
+		caCert:             instance.CACert,
+		options:            options,
+	}
+
+	transports.mu.Lock()
+	defer transports.mu.Unlock()
+
+	if transport, ok := transports.m[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := minio.DefaultTransport(instance.Secure)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the transport")
+	}
+
+	// Instances may serve TLS with a private CA
+	tlsConfig, err := instance.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if options.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: options.DialTimeout, KeepAlive: dialKeepAlive}).DialContext
+	}
+
+	if options.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = options.ResponseHeaderTimeout
+	}
+
+	if options.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = options.TLSHandshakeTimeout
+	}
+
+	if options.MaxIdleConns > 0 {
+		transport.MaxIdleConns = options.MaxIdleConns
+	}
+
+	transports.m[key] = transport
+	return transport, nil
+}
+
+// CloseIdleConnections closes the idle connections of all shared transports, e.g. on the shutdown. The requests in
+// flight are not interrupted, their connections are closed once they complete.
+func CloseIdleConnections() {
+	transports.mu.Lock()
+	defer transports.mu.Unlock()
+
+	for _, transport := range transports.m {
+		transport.CloseIdleConnections()
+	}
+}
+
+// operationContext bounds a short operation by the OperationTimeout, if configured
+func (c *MinioClient) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.options.Client.OperationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.options.Client.OperationTimeout)
+}
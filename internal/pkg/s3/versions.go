@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+)
+
+// ObjectVersion describes a version of an object in a versioned bucket
+type ObjectVersion struct {
+	VersionId    string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	IsLatest     bool
+	// IsDeleteMarker is set for the versions recording a deletion
+	IsDeleteMarker bool
+}
+
+// GetObjectVersion gets a version of the object from the S3 instance
+func (c *MinioClient) GetObjectVersion(ctx context.Context, objectId, versionId string) (io.Reader, error) {
+	c.logger.Info("Getting the object version from S3", zap.String("objectId", objectId), zap.String("versionId", versionId))
+	return c.getObject(ctx, objectId, minio.GetObjectOptions{VersionID: versionId})
+}
+
+// StatObjectVersion fetches the metadata of a version of the object from the S3 instance
+func (c *MinioClient) StatObjectVersion(ctx context.Context, objectId, versionId string) (*ObjectInfo, error) {
+	c.logger.Info("Getting the object version metadata from S3", zap.String("objectId", objectId), zap.String("versionId", versionId))
+	return c.statObject(ctx, objectId, minio.StatObjectOptions{VersionID: versionId})
+}
+
+// DeleteObjectVersion permanently deletes a version of the object from the S3 instance
+func (c *MinioClient) DeleteObjectVersion(ctx context.Context, objectId, versionId string) error {
+	c.logger.Info("Deleting the object version from S3", zap.String("objectId", objectId), zap.String("versionId", versionId))
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+
+	err := c.client.RemoveObject(ctx, c.bucket, objectId, minio.RemoveObjectOptions{VersionID: versionId})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return ErrObjectNotFound
+		}
+
+		return c.wrapError(err, "failed to delete object version from S3")
+	}
+
+	return nil
+}
+
+// ListObjectVersions lists the versions of the object, the latest first. Empty if the object has no versions.
+func (c *MinioClient) ListObjectVersions(ctx context.Context, objectId string) ([]ObjectVersion, error) {
+	c.logger.Info("Listing the object versions in S3", zap.String("objectId", objectId))
+
+	versions := []ObjectVersion{}
+	for object := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Prefix: objectId, WithVersions: true}) {
+		if object.Err != nil {
+			return nil, c.wrapError(object.Err, "failed to list object versions")
+		}
+
+		// The prefix also matches the longer keys
+		if object.Key != objectId {
+			continue
+		}
+
+		versions = append(versions, ObjectVersion{
+			VersionId:      object.VersionID,
+			Size:           object.Size,
+			LastModified:   object.LastModified,
+			ETag:           object.ETag,
+			IsLatest:       object.IsLatest,
+			IsDeleteMarker: object.IsDeleteMarker,
+		})
+	}
+
+	return versions, nil
+}
@@ -2,6 +2,11 @@ package main
 
 import "github.com/spacelift-io/homework-object-storage/cmd"
 
+// @title			S3 Gateway
+// @version		1.0
+// @description	Distributed object storage gateway in front of multiple Minio instances.
+// @host			localhost:3000
+// @BasePath		/
 func main() {
 	cmd.Execute()
 }